@@ -12,6 +12,7 @@ import (
 	"mneme/internal/storage"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -27,7 +28,9 @@ type BenchmarkResult struct {
 	CorpusSize      int
 	TotalDataSize   int64
 	CrawlTime       time.Duration
+	SequentialTime  time.Duration // Index time with a single worker
 	IndexTime       time.Duration
+	SpeedUp         float64 // SequentialTime / IndexTime
 	SearchTime      time.Duration
 	IndexThroughput float64 // files/sec
 	InputThroughput float64 // MB/s (input processing speed)
@@ -107,6 +110,7 @@ func TestBenchmarkSuite(t *testing.T) {
 
 	fmt.Println("\nRunning Mneme Benchmarks...")
 	fmt.Println("============================")
+	fmt.Printf("Indexing with %d workers\n", runtime.NumCPU())
 
 	for _, size := range sizes {
 		fmt.Printf("\nBenchmarking corpus size: %d files...\n", size)
@@ -143,17 +147,25 @@ func TestBenchmarkSuite(t *testing.T) {
 		require.NoError(t, err)
 		assert.Equal(t, size, len(paths))
 
-		// 3. Measure Indexing (including I/O)
+		// 3. Measure Indexing (including I/O), first with a single worker as the baseline
 		batchConfig := core.DefaultBatchConfig()
 		batchConfig.SuppressLogs = true // Reduce noise
 		// Ensure max tokens allows our files
 		batchConfig.IndexConfig.MaxTokensPerDocument = 100000
+		batchConfig.IndexConfig.Workers = 1
 
+		start = time.Now()
+		_, err = index.IndexBuilderBatched(paths, &crawlerOpts, batchConfig)
+		res.SequentialTime = time.Since(start)
+		require.NoError(t, err)
+
+		batchConfig.IndexConfig.Workers = 0 // One worker per CPU
 		start = time.Now()
 		manifest, err := index.IndexBuilderBatched(paths, &crawlerOpts, batchConfig)
 		res.IndexTime = time.Since(start)
 		require.NoError(t, err)
 		require.NotNil(t, manifest)
+		res.SpeedUp = res.SequentialTime.Seconds() / res.IndexTime.Seconds()
 
 		// Calculate indexing throughput
 		res.IndexThroughput = float64(size) / res.IndexTime.Seconds()
@@ -212,7 +224,7 @@ func printResultsTable(results []BenchmarkResult) {
 	headerFmt := color.New(color.FgGreen, color.Underline).SprintfFunc()
 	columnFmt := color.New(color.FgYellow).SprintfFunc()
 
-	tbl := table.New("Files", "Data Size", "Crawl", "Index", "Search (avg)", "Files/sec", "Input (MB/s)", "Write (MB/s)", "Read (MB/s)")
+	tbl := table.New("Files", "Data Size", "Crawl", "Index (1 worker)", "Index", "Speed-up", "Search (avg)", "Files/sec", "Input (MB/s)", "Write (MB/s)", "Read (MB/s)")
 	tbl.WithHeaderFormatter(headerFmt).WithFirstColumnFormatter(columnFmt)
 
 	for _, r := range results {
@@ -220,7 +232,9 @@ func printResultsTable(results []BenchmarkResult) {
 			r.CorpusSize,
 			formatBytes(r.TotalDataSize),
 			r.CrawlTime.Round(time.Millisecond),
+			r.SequentialTime.Round(time.Millisecond),
 			r.IndexTime.Round(time.Millisecond),
+			fmt.Sprintf("%.1fx", r.SpeedUp),
 			r.SearchTime.Round(time.Microsecond),
 			fmt.Sprintf("%.0f", r.IndexThroughput),
 			fmt.Sprintf("%.2f", r.InputThroughput),
@@ -0,0 +1,105 @@
+package cli
+
+import (
+	"errors"
+
+	"mneme/internal/constants"
+	"mneme/internal/index"
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+	"mneme/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var compactCmd = &cobra.Command{
+	Use:   "compact",
+	Short: "Merge index chunks and purge deleted documents",
+	Long: `Merges all index chunks into a single chunk on disk, dropping documents that
+were deleted or superseded by incremental updates and renumbering the rest.
+
+Incremental updates already merge small chunks automatically with a tiered merge
+policy; use --tiered to apply only that policy. Replaced chunks are moved to the
+tombstones folder. Run 'mneme clean' to free the space they use.`,
+	Example: `  mneme compact
+  mneme compact --tiered`,
+	Run: compactCmdExecute,
+}
+
+// compactTiered applies only the automatic tiered merge policy
+var compactTiered bool
+
+func init() {
+	compactCmd.Flags().BoolVar(&compactTiered, "tiered", false, "Only merge chunks selected by the tiered merge policy")
+}
+
+func compactCmdExecute(cmd *cobra.Command, args []string) {
+	initialized, err := IsInitialized()
+	if err != nil {
+		logger.Errorf("Failed to check if initialized: %+v", err)
+		return
+	}
+
+	if !initialized {
+		logger.Error("Mneme is not initialized. Please run 'mneme init' first.")
+		return
+	}
+
+	dataDir, err := utils.ExpandFilePath(constants.DirPath)
+	if err != nil {
+		logger.Errorf("Failed to expand data directory path: %+v", err)
+		return
+	}
+
+	if err := acquireIndexLock(dataDir); err != nil {
+		logger.PrintError("Failed to acquire lock: %+v", err)
+		return
+	}
+	defer storage.ReleaseLock(dataDir)
+
+	migrateStorage()
+
+	var stats *index.CompactionStats
+	if compactTiered {
+		stats, err = index.CompactTiered(index.DefaultCompactionPolicy())
+	} else {
+		stats, err = index.CompactAll()
+	}
+
+	if errors.Is(err, index.ErrFullRebuildRequired) {
+		logger.PrintError("The index was built by an older version. Please run 'mneme index --full' first.")
+		return
+	}
+	if errors.Is(err, storage.ErrCorruptChunk) {
+		logger.PrintError("The index has a corrupt chunk. Please run 'mneme index --full' to rebuild it.")
+		return
+	}
+	if err != nil {
+		logger.Errorf("Failed to compact index: %+v", err)
+		return
+	}
+
+	if !stats.HasChanges() {
+		logger.Print("Index is already compact: %d chunks, %d docs", stats.TotalChunks, stats.TotalDocs)
+		return
+	}
+
+	logger.Print("Compacted %d chunks into %d, purged %d deleted docs (%d chunks, %d docs)",
+		stats.MergedChunks, stats.NewChunks, stats.PurgedDocs, stats.TotalChunks, stats.TotalDocs)
+	CheckTombstonesAndHint()
+}
+
+// autoCompact applies the tiered merge policy after an incremental update.
+// The caller must hold the index lock.
+func autoCompact() {
+	stats, err := index.CompactTiered(index.DefaultCompactionPolicy())
+	if err != nil {
+		logger.Warnf("Automatic compaction failed: %+v", err)
+		return
+	}
+
+	if stats.HasChanges() {
+		logger.Print("Compacted %d chunks into %d, purged %d deleted docs (%d chunks, %d docs)",
+			stats.MergedChunks, stats.NewChunks, stats.PurgedDocs, stats.TotalChunks, stats.TotalDocs)
+	}
+}
@@ -0,0 +1,190 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"mneme/internal/config"
+	"mneme/internal/core"
+	"mneme/internal/eval"
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+
+	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
+	"github.com/spf13/cobra"
+)
+
+var evalCmd = &cobra.Command{
+	Use:   "eval <judgements>",
+	Short: "Evaluate the ranking against judged queries",
+	Long: `Runs queries with known relevant documents against the current index, the
+same way 'mneme find' does, and reports nDCG, MRR, precision and recall of the
+top results per query and on average.
+
+Judgements are read from a JSON file:
+  {"queries": [{"id": "helm", "query": "helm chart values",
+                "relevant": {"notes/helm.md": 2, "notes/k8s.md": 1}}]}
+or from TREC qrels ("query-id iteration document relevance" per line) with
+the query texts in a separate file ("query-id<TAB>query" per line, --queries).
+Relevance is graded: 0 is not relevant, higher is more relevant. Relative
+document paths are resolved against --root, by default the directory of the
+judgements file.
+
+Use --compare with a second config file to evaluate its [ranking] settings
+side by side with the current ones; it only needs the settings that differ.`,
+	Example: `  mneme eval judgements.json
+  mneme eval -k 5 judgements.json
+  mneme eval --queries queries.tsv qrels.txt
+  mneme eval --compare tuned.toml judgements.json
+  mneme eval --json judgements.json`,
+	Args: cobra.ExactArgs(1),
+	Run:  evalCmdExecute,
+}
+
+var (
+	evalQueries string
+	evalRoot    string
+	evalCutoff  int
+	evalCompare string
+	evalJSON    bool
+)
+
+func init() {
+	evalCmd.Flags().StringVar(&evalQueries, "queries", "", "Query texts of TREC qrels, one \"query-id<TAB>query\" per line")
+	evalCmd.Flags().StringVar(&evalRoot, "root", "", "Directory relative document paths are resolved against (default: the directory of the judgements)")
+	evalCmd.Flags().IntVarP(&evalCutoff, "cutoff", "k", eval.DefaultCutoff, "Number of results evaluated per query")
+	evalCmd.Flags().StringVar(&evalCompare, "compare", "", "Config file whose ranking settings are compared with the current ones")
+	evalCmd.Flags().BoolVar(&evalJSON, "json", false, "Write the evaluation as JSON")
+}
+
+// evalOutput is the JSON document written by mneme eval --json
+type evalOutput struct {
+	Base  *eval.Report     `json:"base"`
+	Other *eval.Report     `json:"other,omitempty"` // Evaluation with the --compare config
+	Diffs []eval.QueryDiff `json:"diffs,omitempty"`
+}
+
+func evalCmdExecute(cmd *cobra.Command, args []string) {
+	// Keep stdout free for the JSON document
+	if evalJSON {
+		logger.SetOutput(os.Stderr)
+		color.Output = colorable.NewColorableStderr()
+	}
+
+	if evalCutoff <= 0 {
+		logger.PrintError("Invalid --cutoff %d: at least one result must be evaluated", evalCutoff)
+		return
+	}
+
+	initialized, err := IsInitialized()
+	if err != nil {
+		logger.Errorf("Failed to check if initialized: %+v", err)
+		return
+	}
+	if !initialized {
+		logger.Error("Mneme is not initialized. Please run 'mneme init' first.")
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Errorf("Failed to load config: %+v", err)
+		return
+	}
+
+	var compareCfg *core.Config
+	if evalCompare != "" {
+		if compareCfg, err = config.LoadOverlay(cfg, evalCompare); err != nil {
+			logger.PrintError("Failed to load %s: %v", evalCompare, err)
+			return
+		}
+	}
+
+	root := evalRoot
+	if root == "" {
+		root = filepath.Dir(args[0])
+	}
+	if root, err = filepath.Abs(root); err != nil {
+		logger.Errorf("Failed to resolve %s: %+v", root, err)
+		return
+	}
+
+	judgements, err := eval.LoadJudgements(args[0], evalQueries, root)
+	if err != nil {
+		logger.PrintError("Failed to load judgements: %v", err)
+		return
+	}
+
+	indexReader, err := storage.OpenIndexReader()
+	if err != nil {
+		logger.PrintError("No index found. Please run 'mneme index' to build the search index first.")
+		return
+	}
+	defer indexReader.Close()
+
+	output := evalOutput{Base: eval.Run(indexReader, judgements, &cfg.Ranking, evalCutoff)}
+	if compareCfg != nil {
+		output.Other = eval.Run(indexReader, judgements, &compareCfg.Ranking, evalCutoff)
+		output.Diffs = eval.Compare(output.Base, output.Other)
+	}
+
+	if evalJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			logger.Errorf("Failed to write evaluation: %+v", err)
+		}
+		return
+	}
+
+	for _, result := range output.Base.Queries {
+		if result.Error != "" {
+			logger.Warning("Query %s (%q) failed: %s", result.ID, result.Query, result.Error)
+		}
+	}
+
+	if output.Other == nil {
+		logger.Header("Evaluated %d queries with %s", len(output.Base.Queries), output.Base.Scorer)
+		writeEvalReport(os.Stdout, output.Base)
+		return
+	}
+	logger.Header("Evaluated %d queries: %s (current) vs %s (%s)", len(output.Base.Queries), output.Base.Scorer, output.Other.Scorer, evalCompare)
+	writeEvalDiffs(os.Stdout, output.Base, output.Other, output.Diffs)
+}
+
+// writeEvalReport writes the metrics of every query and their mean as a table
+func writeEvalReport(w io.Writer, report *eval.Report) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	k := report.Cutoff
+	fmt.Fprintf(table, "Query\tnDCG@%d\tMRR@%d\tP@%d\tR@%d\n", k, k, k, k)
+	row := func(name string, m eval.Metrics) {
+		fmt.Fprintf(table, "%s\t%.3f\t%.3f\t%.3f\t%.3f\n", name, m.NDCG, m.MRR, m.Precision, m.Recall)
+	}
+	for _, result := range report.Queries {
+		row(result.ID, result.Metrics)
+	}
+	row("Mean", report.Mean)
+	table.Flush()
+}
+
+// writeEvalDiffs writes the nDCG and MRR of every query under both
+// configurations and their difference as a table
+func writeEvalDiffs(w io.Writer, base, other *eval.Report, diffs []eval.QueryDiff) {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	k := base.Cutoff
+	fmt.Fprintf(table, "Query\tnDCG@%d\tother\tΔ\tMRR@%d\tother\tΔ\n", k, k)
+	row := func(name string, base, other eval.Metrics) {
+		delta := other.Sub(base)
+		fmt.Fprintf(table, "%s\t%.3f\t%.3f\t%+.3f\t%.3f\t%.3f\t%+.3f\n", name, base.NDCG, other.NDCG, delta.NDCG, base.MRR, other.MRR, delta.MRR)
+	}
+	for _, diff := range diffs {
+		row(diff.ID, diff.Base, diff.Other)
+	}
+	row("Mean", base.Mean, other.Mean)
+	table.Flush()
+}
@@ -0,0 +1,47 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mneme/internal/eval"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvalCmdFlags(t *testing.T) {
+	for _, name := range []string{"queries", "root", "cutoff", "compare", "json"} {
+		require.NotNil(t, evalCmd.Flags().Lookup(name), name)
+	}
+	assert.Equal(t, "k", evalCmd.Flags().Lookup("cutoff").Shorthand)
+	assert.Equal(t, "10", evalCmd.Flags().Lookup("cutoff").DefValue)
+}
+
+func TestWriteEvalTables(t *testing.T) {
+	base := &eval.Report{
+		Cutoff:  5,
+		Queries: []eval.QueryResult{{ID: "helm", Metrics: eval.Metrics{NDCG: 1, MRR: 1, Precision: 0.2, Recall: 1}}},
+		Mean:    eval.Metrics{NDCG: 1, MRR: 1, Precision: 0.2, Recall: 1},
+	}
+
+	var buf bytes.Buffer
+	writeEvalReport(&buf, base)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"Query", "nDCG@5", "MRR@5", "P@5", "R@5"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"helm", "1.000", "1.000", "0.200", "1.000"}, strings.Fields(lines[1]))
+	assert.Equal(t, "Mean", strings.Fields(lines[2])[0])
+
+	other := &eval.Report{
+		Cutoff:  5,
+		Queries: []eval.QueryResult{{ID: "helm", Metrics: eval.Metrics{NDCG: 0.5, MRR: 0.5}}},
+		Mean:    eval.Metrics{NDCG: 0.5, MRR: 0.5},
+	}
+	buf.Reset()
+	writeEvalDiffs(&buf, base, other, eval.Compare(base, other))
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, []string{"helm", "1.000", "0.500", "-0.500", "1.000", "0.500", "-0.500"}, strings.Fields(lines[1]))
+}
@@ -1,6 +1,9 @@
 package cli
 
 import (
+	"errors"
+	"fmt"
+	"os"
 	"strings"
 
 	"mneme/internal/config"
@@ -12,6 +15,7 @@ import (
 	"mneme/internal/storage"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
 	"github.com/spf13/cobra"
 )
 
@@ -25,14 +29,109 @@ Fuzzy matching is automatically applied to catch typos and near-matches.
 Use quotes to search for exact phrases:
   mneme find "aws region"       → matches the exact phrase "aws region"
   mneme find deploy production  → matches documents containing "deploy" or "production"
-  mneme find "error handling" go → matches the phrase "error handling" and the word "go"`,
+  mneme find "error handling" go → matches the phrase "error handling" and the word "go"
+
+Combine terms with AND, OR, NOT and parentheses, require a term with + and
+exclude it with -. Flags must come before the query:
+  mneme find kubernetes -helm                   → "kubernetes" but not "helm"
+  mneme find +deploy production                 → must contain "deploy"
+  mneme find "(postgres OR mysql) AND migration" → either database and "migration"
+
+Restrict the searched documents with field filters:
+  ext:go,md              → files with one of the extensions
+  path:internal/query    → paths containing the text
+  dir:~/notes            → files below the directory
+  source:filesystem      → documents from the source
+  modified:>2026-01-01   → modified after the date (also >=, <, <=, or a day)
+  title:runbook          → titles containing the text
+  tag:go,search          → documents with one of the tags
+  date:<2025-01-01       → front matter dates, compared like modified:
+
+Recently modified documents rank higher, measured from the front matter date
+of documents that have one. Their boost halves every
+ranking.recency_half_life_days days; override it with --recency or disable
+it with --recency 0.
+
+Use --explain to see why a document ranked where it did: every result shows
+the relevance and VSM scores of each term, the weights they were combined
+with, fuzzy expansions, phrase and recency boosts, and the tie-break that
+ordered it. With --format json the breakdown is included as "explanation".`,
 	Example: `  mneme find "machine learning"
   mneme find python tutorial
-  mneme find "error handling" in go`,
+  mneme find "error handling" in go
+  mneme find kubernetes -helm
+  mneme find "(postgres OR mysql) AND migration"
+  mneme find retry ext:go path:internal/query
+  mneme find --format json kubernetes
+  mneme find --format vimgrep "error handling"
+  mneme find --recency 7 standup notes
+  mneme find --explain --json deploy production`,
 	Run: findCmdExecute,
 }
 
+var (
+	findFormat string
+	findJSON   bool
+	findNDJSON bool
+	// findRecency overrides ranking.recency_half_life_days when set
+	findRecency int
+	findExplain bool
+)
+
+func init() {
+	// Stop parsing flags at the query, so excluded terms like -helm are not
+	// mistaken for flags
+	findCmd.Flags().SetInterspersed(false)
+
+	findCmd.Flags().StringVar(&findFormat, "format", string(display.FormatText), "Output format: text, json, ndjson, paths or vimgrep")
+	findCmd.Flags().BoolVar(&findJSON, "json", false, "Shorthand for --format json")
+	findCmd.Flags().BoolVar(&findNDJSON, "ndjson", false, "Shorthand for --format ndjson")
+	findCmd.Flags().IntVar(&findRecency, "recency", 0, "Half-life of the recency boost in days, 0 disables it (default from ranking.recency_half_life_days)")
+	findCmd.Flags().BoolVar(&findExplain, "explain", false, "Show the breakdown of the score of every result")
+}
+
+// resolveFindFormat returns the output format selected by --format, --json or --ndjson
+func resolveFindFormat(cmd *cobra.Command) (display.OutputFormat, error) {
+	format, err := display.ParseOutputFormat(findFormat)
+	if err != nil {
+		return "", err
+	}
+
+	var selected []display.OutputFormat
+	if cmd.Flags().Changed("format") {
+		selected = append(selected, format)
+	}
+	if findJSON {
+		selected = append(selected, display.FormatJSON)
+	}
+	if findNDJSON {
+		selected = append(selected, display.FormatNDJSON)
+	}
+
+	for _, other := range selected {
+		if other != selected[0] {
+			return "", fmt.Errorf("conflicting output formats %q and %q", selected[0], other)
+		}
+	}
+	if len(selected) > 0 {
+		return selected[0], nil
+	}
+	return format, nil
+}
+
 func findCmdExecute(cmd *cobra.Command, args []string) {
+	format, err := resolveFindFormat(cmd)
+	if err != nil {
+		logger.PrintError("Invalid output format: %v", err)
+		return
+	}
+
+	// Keep stdout free for the results of machine-readable formats
+	if format.IsMachineReadable() {
+		logger.SetOutput(os.Stderr)
+		color.Output = colorable.NewColorableStderr()
+	}
+
 	initialized, err := IsInitialized()
 	if err != nil {
 		logger.Errorf("Failed to check if initialized: %+v", err)
@@ -62,104 +161,92 @@ func findCmdExecute(cmd *cobra.Command, args []string) {
 		logger.Errorf("Failed to load config: %+v", err)
 		return
 	}
+	configureExtractors(cfg)
+
+	if cmd.Flags().Changed("recency") {
+		if findRecency < 0 {
+			logger.PrintError("Invalid --recency %d: the half-life must not be negative", findRecency)
+			return
+		}
+		cfg.Ranking.RecencyHalfLifeDays = findRecency
+	}
 
 	// Load the index first to enable auto-correction
-	var segmentIndex *core.Segment
+	var indexReader *core.IndexReader
 
-	if display.ShouldShowProgress() {
+	if display.ShouldShowProgress() && !format.IsMachineReadable() {
 		pb := display.NewProgressBar("Initializing", 0)
 		pb.Start()
 		pb.SetMessage("Loading index...")
-		segmentIndex, err = storage.LoadSegmentIndex()
+		indexReader, err = storage.OpenIndexReader()
 		pb.Complete()
 	} else {
-		segmentIndex, err = storage.LoadSegmentIndex()
+		indexReader, err = storage.OpenIndexReader()
 	}
 
+	if errors.Is(err, storage.ErrCorruptChunk) {
+		logger.PrintError("The index is corrupt. Please run 'mneme index' to rebuild it.")
+		return
+	}
 	if err != nil {
 		logger.PrintError("No index found. Please run 'mneme index' to build the search index first.")
 		return
 	}
+	defer indexReader.Close()
 
-	// Build the query string from args directly
-	queryString := strings.Join(args, " ")
-	queryString = strings.TrimSpace(queryString)
-
-	if queryString == "" {
-		logger.PrintError("Search query cannot be empty.")
+	search, err := query.PrepareSearch(indexReader, args)
+	switch {
+	case errors.Is(err, query.ErrNoSearchTerms):
+		logger.PrintError("No valid search tokens found in query: %s", strings.Join(args, " "))
 		return
-	}
-
-	// Auto-correct typos in the raw query terms before tokenizing
-	correctedArgs, corrections := query.AutoCorrectQuery(segmentIndex, args)
-	if len(corrections) > 0 {
-		for original, corrected := range corrections {
-			color.Cyan("💡 Typo detected: %q → %q", original, corrected)
-		}
-		queryString = strings.Join(correctedArgs, " ")
-	}
-
-	// Parse the query string into stemmed tokens
-	// Use the corrected query string if available
-	stemmedTokens := query.ParseQuery(queryString)
-
-	if len(stemmedTokens) == 0 {
-		logger.PrintError("No valid search tokens found in query: %s", queryString)
+	case err != nil:
+		logger.PrintError("Invalid query: %v", err)
 		return
 	}
+	search.Explain = findExplain
+	for original, corrected := range search.Corrections {
+		color.Cyan("💡 Typo detected: %q → %q", original, corrected)
+	}
+	queryString := search.Text
 
 	// Check if we should show progress bar for ranking
 	var rankedDocs []core.RankedDocument
 
-	if display.ShouldShowProgress() {
+	if display.ShouldShowProgress() && !format.IsMachineReadable() {
 		pb := display.NewProgressBar("Searching", 0)
 		pb.Start()
 		pb.SetMessage("Ranking documents...")
 
-		rankedDocs = query.RankDocuments(segmentIndex, stemmedTokens, cfg.Search.DefaultLimit, &cfg.Ranking)
+		rankedDocs = search.Rank(indexReader, cfg.Search.DefaultLimit, &cfg.Ranking)
 		pb.Complete()
 	} else {
-		rankedDocs = query.RankDocuments(segmentIndex, stemmedTokens, cfg.Search.DefaultLimit, &cfg.Ranking)
+		rankedDocs = search.Rank(indexReader, cfg.Search.DefaultLimit, &cfg.Ranking)
 	}
 
 	if len(rankedDocs) == 0 {
 		logger.PrintError("No documents found for query: %s", queryString)
+		writeFindResults(nil, format, search)
 		return
 	}
 
-	// Use original searchTerms (which preserve quoted phrases) for snippet matching?
-	// Actually, we should use correctedArgs for snippet matching too, so we highlight "find" instead of "fnid".
-	// Use user's corrected query terms for snippet generation first.
-	// This ensures better highlighting accuracy as it uses the user's intended terms
-	// (e.g., "find") rather than just the stemmed/fuzzy matches (e.g., "fnid").
-	var results []*core.SearchResult
-	for _, doc := range rankedDocs {
-		// Attempt to format with corrected user input first
-		result, err := display.FormatSearchResult(doc.Path, correctedArgs, doc.Score)
-		if err != nil {
-			logger.Debugf("Failed to format result for %s: %v", doc.Path, err)
-			continue
-		}
-
-		// Only include results that have actual text matches (snippets)
-		// This filters out false positives from BM25 stemming
-		if len(result.Snippets) > 0 {
-			results = append(results, result)
-		} else {
-			// Fallback: if corrected terms didn't yield snippets (maybe due to stem mismatch),
-			// use the actual terms that matched during ranking (including fuzzy expansions).
-			result, err = display.FormatSearchResult(doc.Path, doc.MatchedTerms, doc.Score)
-			if err == nil && len(result.Snippets) > 0 {
-				results = append(results, result)
-			}
-		}
-	}
-
+	results := display.FormatSearchResults(rankedDocs, search.HighlightTerms)
 	if len(results) == 0 {
 		logger.PrintError("No matching documents found for: %s", queryString)
+		writeFindResults(nil, format, search)
 		return
 	}
 
-	// Print formatted results
-	display.PrintResults(results, true, queryString)
+	writeFindResults(results, format, search)
+}
+
+// writeFindResults prints the results in the selected format. Machine-readable
+// formats also write an empty result set, so that consumers always get valid output.
+func writeFindResults(results []*core.SearchResult, format display.OutputFormat, search *query.Search) {
+	if len(results) == 0 && !format.IsMachineReadable() {
+		return
+	}
+	response := display.JSONResults{Query: search.Text, Corrections: search.Corrections, Results: results}
+	if err := display.WriteResults(os.Stdout, response, format); err != nil {
+		logger.Errorf("Failed to write results: %+v", err)
+	}
 }
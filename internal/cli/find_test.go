@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"testing"
+
+	"mneme/internal/display"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCmdFormatFlags(t *testing.T) {
+	for _, name := range []string{"format", "json", "ndjson", "recency", "explain"} {
+		require.NotNil(t, findCmd.Flags().Lookup(name), name)
+	}
+	assert.Equal(t, "text", findCmd.Flags().Lookup("format").DefValue)
+}
+
+func TestResolveFindFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected display.OutputFormat
+		invalid  bool
+	}{
+		{name: "default", args: nil, expected: display.FormatText},
+		{name: "format", args: []string{"--format", "vimgrep"}, expected: display.FormatVimgrep},
+		{name: "json shorthand", args: []string{"--json"}, expected: display.FormatJSON},
+		{name: "ndjson shorthand", args: []string{"--ndjson"}, expected: display.FormatNDJSON},
+		{name: "matching format and shorthand", args: []string{"--format", "json", "--json"}, expected: display.FormatJSON},
+		{name: "conflicting shorthands", args: []string{"--json", "--ndjson"}, invalid: true},
+		{name: "conflicting format", args: []string{"--format", "paths", "--json"}, invalid: true},
+		{name: "unknown format", args: []string{"--format", "xml"}, invalid: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A fresh command, so flag state does not leak between cases
+			cmd := &cobra.Command{}
+			cmd.Flags().StringVar(&findFormat, "format", string(display.FormatText), "")
+			cmd.Flags().BoolVar(&findJSON, "json", false, "")
+			cmd.Flags().BoolVar(&findNDJSON, "ndjson", false, "")
+			require.NoError(t, cmd.Flags().Parse(tt.args))
+
+			format, err := resolveFindFormat(cmd)
+			if tt.invalid {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, format)
+		})
+	}
+}
@@ -1,15 +1,24 @@
 package cli
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
 	"mneme/internal/config"
 	"mneme/internal/constants"
 	"mneme/internal/core"
 	"mneme/internal/display"
+	"mneme/internal/extract"
 	"mneme/internal/index"
 	"mneme/internal/ingest"
 	"mneme/internal/logger"
 	"mneme/internal/storage"
 	"mneme/internal/utils"
+	"mneme/internal/version"
 
 	"github.com/spf13/cobra"
 )
@@ -18,9 +27,30 @@ var indexCmd = &cobra.Command{
 	Use:   "index",
 	Short: "Create or update the search index",
 	Long: `Scans the configured paths and builds a search index for fast retrieval.
-Uses LSM-style batch processing to handle large datasets efficiently with minimal memory usage.`,
-	Example: `  mneme index`,
-	Run:     indexCmdExecute,
+Uses LSM-style batch processing to handle large datasets efficiently with minimal memory usage.
+
+When reindex_on_modify is enabled (the default) and an index exists, only new,
+modified and deleted documents are processed and written as a delta chunk.
+Use --full to rebuild the whole index.
+
+Files excluded by .gitignore, .ignore or .mnemeignore files in any crawled
+directory are skipped. Use --no-ignore to index them anyway.`,
+	Example: `  mneme index
+  mneme index --full
+  mneme index --no-ignore`,
+	Run: indexCmdExecute,
+}
+
+var (
+	// indexFullRebuild forces a rebuild from scratch even when an incremental update is possible
+	indexFullRebuild bool
+	// indexNoIgnore disables .gitignore, .ignore and .mnemeignore files
+	indexNoIgnore bool
+)
+
+func init() {
+	indexCmd.Flags().BoolVar(&indexFullRebuild, "full", false, "Rebuild the whole index instead of updating it incrementally")
+	indexCmd.Flags().BoolVar(&indexNoIgnore, "no-ignore", false, "Do not skip files excluded by .gitignore, .ignore or .mnemeignore files")
 }
 
 func indexCmdExecute(cmd *cobra.Command, args []string) {
@@ -40,6 +70,7 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 		logger.Errorf("Failed to load config: %+v", err)
 		return
 	}
+	configureExtractors(config)
 
 	// after the config is loaded, check and get the paths
 	paths := config.Sources.Paths
@@ -55,31 +86,8 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// check if an existing lock is stale before trying to acquire
-	if err := storage.CheckLock(dataDir); err != nil {
-		// A lock exists, check if it's stale
-		isStale, staleErr := storage.IsLockStale(dataDir)
-		if staleErr != nil {
-			logger.PrintError("Failed to check if lock is stale: %+v", staleErr)
-			return
-		}
-
-		if isStale {
-			logger.Warn("Found stale lock, clearing it...")
-			if releaseErr := storage.ReleaseLock(dataDir); releaseErr != nil {
-				logger.PrintError("Failed to release stale lock: %+v", releaseErr)
-				return
-			}
-		} else {
-			// Lock is held by an active process
-			logger.PrintError("Failed to acquire lock: %+v", err)
-			return
-		}
-	}
-
-	// Now acquire the lock
-	err = storage.AcquireLock(dataDir)
-	if err != nil {
+	// Acquire the lock, clearing a stale one left by a crashed process
+	if err := acquireIndexLock(dataDir); err != nil {
 		logger.PrintError("Failed to acquire lock: %+v", err)
 		return
 	}
@@ -87,6 +95,26 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 	// defer the release of the lock
 	defer storage.ReleaseLock(dataDir)
 
+	// Stop on Ctrl-C after the current document; completed chunks stay in the manifest
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	migrateStorage()
+
+	crawlerOptions := newCrawlerOptions(config)
+	crawlerOptions.DisableIgnoreFiles = indexNoIgnore
+
+	// Create ingestor registry and register enabled sources
+	registry := newIngestRegistry(config)
+
+	// Update the existing index in place when possible
+	if config.Index.ReindexOnModify && !indexFullRebuild {
+		if runIncrementalIndex(ctx, registry, &crawlerOptions, config.Index) {
+			return
+		}
+		logger.Info("No incrementally updatable index found, rebuilding from scratch")
+	}
+
 	// Move existing segments to tombstones before re-indexing
 	err = storage.MoveSegmentsToTombstones()
 	if err != nil {
@@ -94,28 +122,10 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	crawlerOptions := core.CrawlerOptions{
-		IncludeExtensions: config.Sources.IncludeExtensions,
-		ExcludeExtensions: config.Sources.ExcludeExtensions,
-		SkipFolders:       config.Sources.Ignore,
-		MaxFilesPerFolder: 0,
-		IncludeHidden:     false,
-		SkipBinaryFiles:   config.Index.SkipBinaryFiles,
-	}
-
-	// Create ingestor registry and register enabled sources
-	registry := ingest.NewRegistry()
-
-	// Register filesystem ingestor (enabled by default)
-	fsIngestor := ingest.NewFilesystemIngestor(paths, &config.Sources.Filesystem)
-	if fsIngestor.IsEnabled() {
-		registry.Register(fsIngestor)
-		logger.Debugf("Registered filesystem ingestor with %d paths", len(paths))
-	}
-
 	// Use batch indexing to reduce memory usage
 	batchConfig := core.DefaultBatchConfig()
 	batchConfig.IndexConfig = config.Index
+	batchConfig.Context = ctx
 
 	// Check if we should show progress bar (only when log level is "info")
 	if display.ShouldShowProgress() {
@@ -136,6 +146,10 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 		manifest, err := index.IndexBuilderBatchedWithRegistry(registry, &crawlerOptions, batchConfig)
 		pb.Complete()
 
+		if errors.Is(err, context.Canceled) {
+			printIndexInterrupted(manifest)
+			return
+		}
 		if err != nil {
 			logger.Errorf("Failed to build index: %+v", err)
 			return
@@ -154,6 +168,10 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 		logger.Infof("Starting batch indexing (batch size: %d files)", batchConfig.BatchSize)
 
 		manifest, err := index.IndexBuilderBatchedWithRegistry(registry, &crawlerOptions, batchConfig)
+		if errors.Is(err, context.Canceled) {
+			printIndexInterrupted(manifest)
+			return
+		}
 		if err != nil {
 			logger.Errorf("Failed to build index: %+v", err)
 			return
@@ -169,3 +187,151 @@ func indexCmdExecute(cmd *cobra.Command, args []string) {
 		CheckTombstonesAndHint()
 	}
 }
+
+// runIncrementalIndex applies an incremental update to the existing index.
+// It returns false when the index cannot be updated incrementally and a full
+// rebuild is required, and true once the update has been handled.
+func runIncrementalIndex(ctx context.Context, registry *ingest.Registry, crawlerOptions *core.CrawlerOptions, indexConfig core.IndexConfig) bool {
+	batchConfig := core.DefaultBatchConfig()
+	batchConfig.IndexConfig = indexConfig
+	batchConfig.Context = ctx
+
+	var pb *display.ProgressBar
+	if display.ShouldShowProgress() {
+		pb = display.NewProgressBar("Updating", 0)
+		pb.Start()
+
+		batchConfig.ProgressCallback = func(current, total int, message string) {
+			pb.SetTotal(total)
+			pb.SetCurrent(current)
+			pb.SetMessage(message)
+		}
+		batchConfig.SuppressLogs = true
+	}
+
+	manifest, stats, err := index.IndexIncrementalWithRegistry(registry, crawlerOptions, batchConfig)
+	if pb != nil {
+		pb.Complete()
+	}
+
+	if errors.Is(err, index.ErrFullRebuildRequired) {
+		return false
+	}
+	if errors.Is(err, context.Canceled) {
+		printIndexInterrupted(manifest)
+		return true
+	}
+	if err != nil {
+		logger.Errorf("Failed to update index: %+v", err)
+		return true
+	}
+
+	if !stats.HasChanges() {
+		logger.Print("Index is up to date: %d docs unchanged", stats.Unchanged)
+		return true
+	}
+
+	logger.Print("Index updated: %d added, %d modified, %d deleted, %d unchanged (%d chunks, %d docs)",
+		stats.Added, stats.Modified, stats.Deleted, stats.Unchanged, len(manifest.Chunks), manifest.TotalDocs)
+	autoCompact()
+	CheckTombstonesAndHint()
+	return true
+}
+
+// printIndexInterrupted reports an indexing run stopped by Ctrl-C. Every chunk
+// in the manifest is complete, so the next run continues incrementally.
+func printIndexInterrupted(manifest *core.Manifest) {
+	if manifest == nil || len(manifest.Chunks) == 0 {
+		logger.Warning("Indexing interrupted before any chunk was completed")
+		return
+	}
+	logger.Warning("Indexing interrupted: kept %d chunks with %d docs. Run 'mneme index' again to finish.",
+		len(manifest.Chunks), manifest.TotalDocs)
+}
+
+// acquireIndexLock acquires the data directory lock, clearing it first if the
+// process that held it no longer exists.
+func acquireIndexLock(dataDir string) error {
+	// check if an existing lock is stale before trying to acquire
+	if err := storage.CheckLock(dataDir); err != nil {
+		isStale, staleErr := storage.IsLockStale(dataDir)
+		if staleErr != nil {
+			return fmt.Errorf("failed to check if lock is stale: %w", staleErr)
+		}
+
+		// Lock is held by an active process
+		if !isStale {
+			return err
+		}
+
+		logger.Warn("Found stale lock, clearing it...")
+		if releaseErr := storage.ReleaseLock(dataDir); releaseErr != nil {
+			return fmt.Errorf("failed to release stale lock: %w", releaseErr)
+		}
+	}
+
+	return storage.AcquireLock(dataDir)
+}
+
+// migrateStorage rewrites the chunks of an older storage engine in the current
+// format. The caller must hold the index lock.
+func migrateStorage() {
+	needsMigration, err := storage.NeedsMigration()
+	if err != nil {
+		logger.Debugf("Skipping storage migration: %+v", err)
+		return
+	}
+	if !needsMigration {
+		return
+	}
+
+	migrated, err := storage.MigrateChunks()
+	if err != nil {
+		logger.Warnf("Storage migration failed: %+v", err)
+		return
+	}
+
+	if migrated > 0 {
+		logger.Print("Migrated %d chunks to storage engine %s", migrated, version.MnemeStorageEngineVersion)
+	}
+}
+
+// newCrawlerOptions builds the crawler options from the sources and index config
+func newCrawlerOptions(config *core.Config) core.CrawlerOptions {
+	return core.CrawlerOptions{
+		IncludeExtensions: config.Sources.IncludeExtensions,
+		ExcludeExtensions: config.Sources.ExcludeExtensions,
+		SkipFolders:       config.Sources.Ignore,
+		IncludeGlobs:      config.Sources.IncludeGlobs,
+		ExcludeGlobs:      config.Sources.ExcludeGlobs,
+		SourceOverrides:   config.Sources.Overrides,
+		MaxFilesPerFolder: 0,
+		IncludeHidden:     false,
+		SkipBinaryFiles:   config.Index.SkipBinaryFiles,
+		ArchiveDepth:      config.Index.ArchiveDepth,
+		ArchiveMaxSize:    int64(config.Index.ArchiveMaxSizeMB) << 20,
+	}
+}
+
+// configureExtractors applies the extraction settings of the config to the
+// default extractor registry. It must be called before documents are read.
+func configureExtractors(config *core.Config) {
+	extract.DefaultRegistry().SetArchiveMaxSize(int64(config.Index.ArchiveMaxSizeMB) << 20)
+	if config.Index.NotebookOutputs {
+		extract.DefaultRegistry().Register(extract.NewNotebookExtractor(true), "ipynb")
+	}
+}
+
+// newIngestRegistry creates an ingestor registry with all enabled sources registered
+func newIngestRegistry(config *core.Config) *ingest.Registry {
+	registry := ingest.NewRegistry()
+
+	// Register filesystem ingestor (enabled by default)
+	fsIngestor := ingest.NewFilesystemIngestor(config.Sources.Paths, &config.Sources.Filesystem)
+	if fsIngestor.IsEnabled() {
+		registry.Register(fsIngestor)
+		logger.Debugf("Registered filesystem ingestor with %d paths", len(config.Sources.Paths))
+	}
+
+	return registry
+}
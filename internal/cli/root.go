@@ -95,6 +95,11 @@ func init() {
 	rootCmd.AddCommand(indexCmd)
 	rootCmd.AddCommand(findCmd)
 	rootCmd.AddCommand(cleanCmd)
+	rootCmd.AddCommand(watchCmd)
+	rootCmd.AddCommand(compactCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(evalCmd)
+	rootCmd.AddCommand(verifyCmd)
 }
 
 // IsInitialized checks if the init command was run by verifying that the
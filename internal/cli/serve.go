@@ -0,0 +1,155 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"mneme/internal/config"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/index"
+	"mneme/internal/logger"
+	"mneme/internal/server"
+	"mneme/internal/storage"
+	"mneme/internal/utils"
+
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the search index over a local HTTP API",
+	Long: `Loads the index once and answers queries over a JSON HTTP API, so that editors
+and scripts issuing many queries do not reload the index for each of them.
+The index is reloaded automatically whenever it changes on disk, e.g. after
+'mneme index' or while 'mneme watch' is running.
+
+Endpoints:
+  GET  /search?q=<query>&limit=<n>   search results, as 'mneme find --json'
+  GET  /suggest?q=<prefix>&limit=<n> index terms completing a prefix
+  GET  /docs/{id}                    metadata of an indexed document
+  GET  /stats                        document, chunk and term counts
+  POST /reindex                      incremental index update
+
+Requests must be sent to a loopback host name or to the host of --addr, and
+must not come from another origin, so that web pages cannot query the index.
+POST /reindex requires the Content-Type application/json.`,
+	Example: `  mneme serve
+  mneme serve --addr 127.0.0.1:9000
+  curl 'http://127.0.0.1:7171/search?q=kubernetes+-helm'`,
+	Run: serveCmdExecute,
+}
+
+var (
+	serveAddr     string
+	serveNoIgnore bool
+)
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", constants.DefaultServeAddr, "Address to listen on")
+	serveCmd.Flags().BoolVar(&serveNoIgnore, "no-ignore", false, "Do not skip files excluded by .gitignore, .ignore or .mnemeignore files when reindexing")
+}
+
+func serveCmdExecute(cmd *cobra.Command, args []string) {
+	initialized, err := IsInitialized()
+	if err != nil {
+		logger.Errorf("Failed to check if initialized: %+v", err)
+		return
+	}
+
+	if !initialized {
+		logger.Error("Mneme is not initialized. Please run 'mneme init' first.")
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Errorf("Failed to load config: %+v", err)
+		return
+	}
+	configureExtractors(cfg)
+
+	dataDir, err := utils.ExpandFilePath(constants.DirPath)
+	if err != nil {
+		logger.Errorf("Failed to expand data directory path: %+v", err)
+		return
+	}
+
+	srv := server.New(cfg, serveAddr, func() (*index.IncrementalStats, error) {
+		return reindexForServer(cfg, dataDir)
+	})
+	if err := srv.Load(); err != nil {
+		logger.Debugf("Failed to load index: %+v", err)
+		logger.PrintError("No index found. Please run 'mneme index' to build the search index first.")
+		return
+	}
+	defer srv.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	httpServer := &http.Server{
+		Addr:              serveAddr,
+		Handler:           srv.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+	logger.Print("Serving the index on http://%s. Press Ctrl+C to stop.", serveAddr)
+
+	select {
+	case err := <-serveErr:
+		logger.PrintError("Server failed: %v", err)
+		return
+	case <-ctx.Done():
+	}
+
+	// Let in-flight requests finish before the index is closed
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), constants.ServeShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		logger.Warnf("Graceful shutdown failed: %+v", err)
+	}
+	logger.Print("Server stopped")
+}
+
+// reindexForServer runs an incremental update over all sources for the reindex
+// endpoint. It fails instead of waiting while another process holds the index lock.
+func reindexForServer(cfg *core.Config, dataDir string) (*index.IncrementalStats, error) {
+	if err := acquireIndexLock(dataDir); err != nil {
+		return nil, fmt.Errorf("index is locked: %w", err)
+	}
+	defer storage.ReleaseLock(dataDir)
+
+	migrateStorage()
+
+	crawlerOptions := newCrawlerOptions(cfg)
+	crawlerOptions.DisableIgnoreFiles = serveNoIgnore
+	registry := newIngestRegistry(cfg)
+
+	batchConfig := core.DefaultBatchConfig()
+	batchConfig.IndexConfig = cfg.Index
+	batchConfig.SuppressLogs = true
+
+	_, stats, err := index.IndexIncrementalWithRegistry(registry, &crawlerOptions, batchConfig)
+	if errors.Is(err, index.ErrFullRebuildRequired) {
+		return nil, errors.New("the index must be rebuilt with 'mneme index'")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update index: %w", err)
+	}
+
+	if stats.HasChanges() {
+		autoCompact()
+	}
+	return stats, nil
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+
+	"github.com/spf13/cobra"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the index chunks against their checksums",
+	Long: `Reads every index chunk completely and verifies it against the checksum
+recorded when it was written, together with its deletion bitmap.
+
+Searches only read the parts of a chunk they need and do not verify checksums;
+chunks are verified whenever they are read completely, e.g. by incremental
+updates, compaction and migration. Run 'mneme index --full' to rebuild an index
+with damaged chunks.`,
+	Example: `  mneme verify`,
+	Run:     verifyCmdExecute,
+}
+
+func verifyCmdExecute(cmd *cobra.Command, args []string) {
+	initialized, err := IsInitialized()
+	if err != nil {
+		logger.Errorf("Failed to check if initialized: %+v", err)
+		return
+	}
+
+	if !initialized {
+		logger.Error("Mneme is not initialized. Please run 'mneme init' first.")
+		return
+	}
+
+	manifest, err := storage.LoadManifest()
+	if err != nil {
+		logger.Errorf("Failed to load manifest: %+v", err)
+		return
+	}
+	if manifest == nil || len(manifest.GetCompleteChunks()) == 0 {
+		logger.PrintError("No index found. Please run 'mneme index' to build the search index first.")
+		return
+	}
+
+	chunks := manifest.GetCompleteChunks()
+	corrupt := 0
+	for _, chunkInfo := range chunks {
+		if err := storage.VerifyChunk(chunkInfo); err != nil {
+			logger.PrintError("Chunk %03d: %v", chunkInfo.ID, err)
+			corrupt++
+		}
+	}
+
+	if corrupt > 0 {
+		logger.PrintError("%d of %d chunks are damaged. Please run 'mneme index --full' to rebuild the index.", corrupt, len(chunks))
+		return
+	}
+	logger.Success("Verified %d chunks (%d docs)", len(chunks), manifest.TotalDocs)
+}
@@ -0,0 +1,26 @@
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCmd(t *testing.T) {
+	t.Run("has correct configuration", func(t *testing.T) {
+		assert.Equal(t, "verify", verifyCmd.Use)
+		assert.Contains(t, verifyCmd.Short, "checksums")
+		assert.NotNil(t, verifyCmd.Run)
+	})
+
+	t.Run("is registered on root", func(t *testing.T) {
+		found := false
+		for _, cmd := range rootCmd.Commands() {
+			if cmd.Use == "verify" {
+				found = true
+				break
+			}
+		}
+		assert.True(t, found, "verify command should be registered")
+	})
+}
@@ -0,0 +1,447 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"mneme/internal/config"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/index"
+	"mneme/internal/ingest"
+	"mneme/internal/logger"
+	"mneme/internal/platform"
+	"mneme/internal/storage"
+	"mneme/internal/utils"
+	"mneme/internal/watcher"
+
+	"github.com/spf13/cobra"
+)
+
+// watchDaemonEnv marks the re-executed background process started by --daemon
+const watchDaemonEnv = "MNEME_WATCH_DAEMON"
+
+// maxWatchRetryDelay bounds the backoff between attempts to apply changes
+const maxWatchRetryDelay = time.Minute
+
+var watchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Watch sources and keep the index up to date",
+	Long: `Watches all configured source paths for changes and incrementally updates the
+index as files are created, modified or deleted.
+
+Bursts of changes are grouped according to debounce_ms in the [watcher] config
+section, and files are filtered with the same ignore and extension rules as
+'mneme index', including .gitignore, .ignore and .mnemeignore files unless
+--no-ignore is given. The watcher must be enabled with 'enabled = true' under [watcher].
+
+Runs in the foreground until interrupted, or in the background with --daemon.`,
+	Example: `  mneme watch
+  mneme watch --daemon
+  mneme watch --stop`,
+	Run: watchCmdExecute,
+}
+
+var (
+	watchDaemon   bool
+	watchStop     bool
+	watchNoIgnore bool
+)
+
+func init() {
+	watchCmd.Flags().BoolVarP(&watchDaemon, "daemon", "d", false, "Run the watcher in the background")
+	watchCmd.Flags().BoolVar(&watchStop, "stop", false, "Stop the background watcher")
+	watchCmd.Flags().BoolVar(&watchNoIgnore, "no-ignore", false, "Do not skip files excluded by .gitignore, .ignore or .mnemeignore files")
+}
+
+func watchCmdExecute(cmd *cobra.Command, args []string) {
+	initialized, err := IsInitialized()
+	if err != nil {
+		logger.Errorf("Failed to check if initialized: %+v", err)
+		return
+	}
+
+	if !initialized {
+		logger.Error("Mneme is not initialized. Please run 'mneme init' first.")
+		return
+	}
+
+	dataDir, err := utils.ExpandFilePath(constants.DirPath)
+	if err != nil {
+		logger.Errorf("Failed to expand data directory path: %+v", err)
+		return
+	}
+
+	if watchStop {
+		stopWatchDaemon(dataDir)
+		return
+	}
+
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		logger.Errorf("Failed to load config: %+v", err)
+		return
+	}
+	configureExtractors(cfg)
+
+	if !cfg.Watcher.Enabled {
+		logger.PrintError("The watcher is disabled. Set 'enabled = true' under [watcher] in your config to use 'mneme watch'.")
+		return
+	}
+
+	if len(cfg.Sources.Paths) == 0 {
+		logger.Error("No paths found in config")
+		return
+	}
+
+	if pid, running := runningWatchDaemon(dataDir); running && os.Getenv(watchDaemonEnv) == "" {
+		logger.PrintError("A background watcher is already running (PID %d). Stop it with 'mneme watch --stop'.", pid)
+		return
+	}
+
+	if watchDaemon {
+		startWatchDaemon(dataDir)
+		return
+	}
+
+	runWatcher(cfg, dataDir)
+}
+
+// runWatcher watches the sources in the foreground until interrupted
+func runWatcher(cfg *core.Config, dataDir string) {
+	if os.Getenv(watchDaemonEnv) != "" {
+		defer removeWatchPIDFile(dataDir)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	crawlerOptions := newCrawlerOptions(cfg)
+	crawlerOptions.DisableIgnoreFiles = watchNoIgnore
+	registry := newIngestRegistry(cfg)
+
+	// Catch up with changes made while nobody was watching; a sync that cannot
+	// run now, e.g. while 'mneme index' holds the lock, is retried later
+	resync := false
+	if err := syncIndex(ctx, registry, &crawlerOptions, cfg.Index, dataDir); errors.Is(err, index.ErrFullRebuildRequired) || errors.Is(err, context.Canceled) {
+		return
+	} else if err != nil {
+		resync = true
+	}
+
+	w, err := watcher.New(cfg.Sources.Paths, crawlerOptions)
+	if err != nil {
+		logger.Errorf("Failed to start watcher: %+v", err)
+		return
+	}
+	defer w.Close()
+
+	debounce := cfg.Watcher.DebounceMS
+	if debounce <= 0 {
+		debounce = constants.DefaultWatcherDebounceMS
+	}
+	delay := time.Duration(debounce) * time.Millisecond
+	batches := watcher.Debounce(w.Events(), delay)
+
+	logger.Print("Watching %d paths for changes (debounce %s). Press Ctrl+C to stop.", len(cfg.Sources.Paths), delay)
+
+	// Paths that could not be applied yet, and whether all sources have to be
+	// rescanned, until an attempt to apply them succeeds
+	var pending []string
+	var retry <-chan time.Time
+	failures := 0
+
+	// flush applies the pending changes, retrying with a growing delay while
+	// they cannot be applied. A rescan of all sources covers the pending paths.
+	flush := func() {
+		applied := false
+		if resync {
+			if applied = syncIndex(ctx, registry, &crawlerOptions, cfg.Index, dataDir) == nil; applied {
+				resync, pending = false, nil
+			}
+		} else if applied = applyWatchBatch(registry, pending, &crawlerOptions, cfg.Index, dataDir); applied {
+			pending = nil
+		}
+
+		if applied {
+			failures, retry = 0, nil
+			return
+		}
+		failures++
+		retry = time.After(watchRetryDelay(delay, failures))
+	}
+	if resync {
+		retry = time.After(delay)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Print("Watcher stopped")
+			return
+
+		case batch, ok := <-batches:
+			if !ok {
+				return
+			}
+			pending = appendPending(pending, batch)
+			// Changes arriving while an attempt failed wait for its retry
+			if retry == nil {
+				flush()
+			}
+
+		case <-retry:
+			flush()
+
+		case err, ok := <-w.Errors():
+			if !ok {
+				return
+			}
+			if errors.Is(err, watcher.ErrOverflow) {
+				logger.Warn("Missed file events, rescanning all sources")
+				resync = true
+				if retry == nil {
+					flush()
+				}
+				continue
+			}
+			logger.Warnf("Watcher error: %+v", err)
+		}
+	}
+}
+
+// watchRetryDelay returns the delay before the next attempt to apply changes
+// after a number of failed attempts, doubling delay with every further failure
+// up to maxWatchRetryDelay
+func watchRetryDelay(delay time.Duration, failures int) time.Duration {
+	for i := 1; i < failures && delay < maxWatchRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxWatchRetryDelay)
+}
+
+// syncIndex runs an incremental update over all sources. It returns
+// index.ErrFullRebuildRequired if the index has to be built with 'mneme index'
+// first, and another error if the update could not be applied, e.g. because
+// the index is locked.
+func syncIndex(ctx context.Context, registry *ingest.Registry, crawlerOptions *core.CrawlerOptions, indexConfig core.IndexConfig, dataDir string) error {
+	if err := acquireIndexLock(dataDir); err != nil {
+		logger.Debugf("Index is locked, rescanning later: %+v", err)
+		return err
+	}
+	defer storage.ReleaseLock(dataDir)
+
+	migrateStorage()
+
+	batchConfig := core.DefaultBatchConfig()
+	batchConfig.IndexConfig = indexConfig
+	batchConfig.SuppressLogs = true
+	batchConfig.Context = ctx
+
+	_, stats, err := index.IndexIncrementalWithRegistry(registry, crawlerOptions, batchConfig)
+	if errors.Is(err, index.ErrFullRebuildRequired) {
+		logger.PrintError("No index found. Please run 'mneme index' to build the search index first.")
+		return err
+	}
+	if err != nil {
+		if !errors.Is(err, context.Canceled) {
+			logger.Errorf("Failed to update index: %+v", err)
+		}
+		return err
+	}
+
+	printWatchStats(stats)
+	if stats.HasChanges() {
+		autoCompact()
+	}
+	return nil
+}
+
+// applyWatchBatch feeds changed paths into an incremental index update.
+// It returns false if the update should be retried later.
+func applyWatchBatch(registry *ingest.Registry, paths []string, crawlerOptions *core.CrawlerOptions, indexConfig core.IndexConfig, dataDir string) bool {
+	if err := acquireIndexLock(dataDir); err != nil {
+		logger.Debugf("Index is locked, retrying later: %+v", err)
+		return false
+	}
+	defer storage.ReleaseLock(dataDir)
+
+	batchConfig := core.DefaultBatchConfig()
+	batchConfig.IndexConfig = indexConfig
+	batchConfig.SuppressLogs = true
+
+	_, stats, err := index.IndexChangedPathsWithRegistry(registry, expandArchives(paths, crawlerOptions), batchConfig)
+	if err != nil {
+		logger.Errorf("Failed to update index, retrying later: %+v", err)
+		return false
+	}
+
+	printWatchStats(stats)
+	if stats.HasChanges() {
+		autoCompact()
+	}
+	return true
+}
+
+// appendPending adds the paths of a batch to the paths waiting to be applied,
+// keeping every path once in the order it was first reported
+func appendPending(pending, batch []string) []string {
+	seen := make(map[string]bool, len(pending)+len(batch))
+	for _, path := range pending {
+		seen[path] = true
+	}
+	for _, path := range batch {
+		if !seen[path] {
+			seen[path] = true
+			pending = append(pending, path)
+		}
+	}
+	return pending
+}
+
+// expandArchives adds the members of changed archives to the changed paths, as
+// archives are indexed through their members
+func expandArchives(paths []string, crawlerOptions *core.CrawlerOptions) []string {
+	if crawlerOptions.ArchiveDepth <= 0 {
+		return paths
+	}
+
+	expanded := slices.Clone(paths)
+	for _, path := range paths {
+		if !storage.IsArchive(path) {
+			continue
+		}
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue
+		}
+		members, err := storage.Crawler(path, *crawlerOptions)
+		if err != nil {
+			logger.Warnf("Failed to list archive %s: %+v", path, err)
+			continue
+		}
+		expanded = append(expanded, members...)
+	}
+	return expanded
+}
+
+// printWatchStats reports an applied update, staying silent when nothing changed
+func printWatchStats(stats *index.IncrementalStats) {
+	if stats == nil || !stats.HasChanges() {
+		return
+	}
+	logger.Print("Index updated: %d added, %d modified, %d deleted",
+		stats.Added, stats.Modified, stats.Deleted)
+}
+
+// startWatchDaemon re-executes 'mneme watch' as a detached background process
+func startWatchDaemon(dataDir string) {
+	executable, err := os.Executable()
+	if err != nil {
+		logger.Errorf("Failed to locate mneme executable: %+v", err)
+		return
+	}
+
+	logPath := filepath.Join(dataDir, constants.WatcherLogFile)
+	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Errorf("Failed to open watcher log %s: %+v", logPath, err)
+		return
+	}
+	defer logFile.Close()
+
+	childArgs := []string{"watch"}
+	if watchNoIgnore {
+		childArgs = append(childArgs, "--no-ignore")
+	}
+	child := exec.Command(executable, childArgs...)
+	child.Env = append(os.Environ(), watchDaemonEnv+"=1")
+	child.Stdout = logFile
+	child.Stderr = logFile
+	child.SysProcAttr = platform.DetachedProcAttr()
+
+	if err := child.Start(); err != nil {
+		logger.Errorf("Failed to start background watcher: %+v", err)
+		return
+	}
+
+	pid := child.Process.Pid
+	pidPath := filepath.Join(dataDir, constants.WatcherPIDFile)
+	if err := os.WriteFile(pidPath, []byte(strconv.Itoa(pid)), 0644); err != nil {
+		logger.Errorf("Failed to write watcher PID file: %+v", err)
+	}
+
+	// The daemon outlives this process
+	child.Process.Release()
+
+	logger.Print("Watcher started in the background (PID %d)", pid)
+	logger.Print("Logs: %s", logPath)
+}
+
+// stopWatchDaemon stops the background watcher recorded in the PID file
+func stopWatchDaemon(dataDir string) {
+	pid, running := runningWatchDaemon(dataDir)
+	if !running {
+		removeWatchPIDFile(dataDir)
+		logger.Print("No background watcher is running.")
+		return
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		logger.Errorf("Failed to find watcher process %d: %+v", pid, err)
+		return
+	}
+
+	// Interrupt lets the watcher finish the current update; it is not
+	// supported on Windows, where the process is killed instead
+	if err := process.Signal(os.Interrupt); err != nil {
+		if err := process.Kill(); err != nil {
+			logger.Errorf("Failed to stop watcher process %d: %+v", pid, err)
+			return
+		}
+	}
+
+	removeWatchPIDFile(dataDir)
+	logger.Print("Stopped background watcher (PID %d)", pid)
+}
+
+// runningWatchDaemon returns the PID of the background watcher, if it is alive
+func runningWatchDaemon(dataDir string) (int, bool) {
+	data, err := os.ReadFile(filepath.Join(dataDir, constants.WatcherPIDFile))
+	if err != nil {
+		return 0, false
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0, false
+	}
+
+	// Signal 0 checks that the process exists without affecting it
+	if err := process.Signal(syscall.Signal(0)); err != nil {
+		return 0, false
+	}
+	return pid, true
+}
+
+// removeWatchPIDFile deletes the PID file of the background watcher
+func removeWatchPIDFile(dataDir string) {
+	pidPath := filepath.Join(dataDir, constants.WatcherPIDFile)
+	if err := os.Remove(pidPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Debugf("Failed to remove watcher PID file: %+v", err)
+	}
+}
@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"mneme/internal/constants"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchCmd(t *testing.T) {
+	t.Run("has correct configuration", func(t *testing.T) {
+		assert.Equal(t, "watch", watchCmd.Use)
+		assert.Contains(t, watchCmd.Short, "Watch")
+		assert.Contains(t, watchCmd.Long, "debounce_ms")
+	})
+
+	t.Run("has Run function set", func(t *testing.T) {
+		assert.NotNil(t, watchCmd.Run)
+	})
+
+	t.Run("daemon and stop flags are registered", func(t *testing.T) {
+		daemon := watchCmd.Flags().Lookup("daemon")
+		require.NotNil(t, daemon)
+		assert.Equal(t, "d", daemon.Shorthand)
+		assert.Equal(t, "false", daemon.DefValue)
+
+		stop := watchCmd.Flags().Lookup("stop")
+		require.NotNil(t, stop)
+		assert.Equal(t, "false", stop.DefValue)
+	})
+
+	t.Run("no-ignore flag is registered", func(t *testing.T) {
+		noIgnore := watchCmd.Flags().Lookup("no-ignore")
+		require.NotNil(t, noIgnore)
+		assert.Equal(t, "false", noIgnore.DefValue)
+	})
+
+	t.Run("is registered on root", func(t *testing.T) {
+		found := false
+		for _, cmd := range rootCmd.Commands() {
+			if cmd.Use == "watch" {
+				found = true
+				break
+			}
+		}
+		assert.True(t, found, "watch command should be registered")
+	})
+}
+
+func TestRunningWatchDaemon(t *testing.T) {
+	dataDir := t.TempDir()
+	pidPath := filepath.Join(dataDir, constants.WatcherPIDFile)
+
+	t.Run("no PID file", func(t *testing.T) {
+		_, running := runningWatchDaemon(dataDir)
+		assert.False(t, running)
+	})
+
+	t.Run("PID of a live process", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(pidPath, []byte(strconv.Itoa(os.Getpid())), 0644))
+		pid, running := runningWatchDaemon(dataDir)
+		assert.True(t, running)
+		assert.Equal(t, os.Getpid(), pid)
+	})
+
+	t.Run("malformed PID file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(pidPath, []byte("not-a-pid"), 0644))
+		_, running := runningWatchDaemon(dataDir)
+		assert.False(t, running)
+	})
+}
+
+func TestAppendPending(t *testing.T) {
+	pending := appendPending(nil, []string{"/a.md", "/b.md", "/a.md"})
+	assert.Equal(t, []string{"/a.md", "/b.md"}, pending)
+
+	// A batch retried after a lock failure is merged without duplicates
+	pending = appendPending(pending, []string{"/c.md", "/b.md"})
+	assert.Equal(t, []string{"/a.md", "/b.md", "/c.md"}, pending)
+}
+
+func TestWatchRetryDelay(t *testing.T) {
+	delay := 500 * time.Millisecond
+	assert.Equal(t, delay, watchRetryDelay(delay, 1))
+	assert.Equal(t, 2*delay, watchRetryDelay(delay, 2))
+	assert.Equal(t, 8*delay, watchRetryDelay(delay, 4))
+	assert.Equal(t, maxWatchRetryDelay, watchRetryDelay(delay, 20))
+	assert.Equal(t, maxWatchRetryDelay, watchRetryDelay(2*time.Minute, 1))
+}
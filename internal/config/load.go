@@ -13,6 +13,9 @@ import (
 	"mneme/internal/utils"
 )
 
+// defaultBM25B is the BM25 length normalization of DefaultConfig
+var defaultBM25B = 0.75
+
 // DefaultConfig is the default configuration for mneme
 var DefaultConfig = core.Config{
 	Version: 1,
@@ -21,11 +24,17 @@ var DefaultConfig = core.Config{
 		MaxTokensPerDocument: 0, // 0 disables the per-document token limit
 		ReindexOnModify:      true,
 		SkipBinaryFiles:      true,
+		Workers:              0, // 0 uses one worker per CPU
+		ArchiveDepth:         2,
+		ArchiveMaxSizeMB:     64,
+		NotebookOutputs:      false,
 	},
 	Sources: core.SourcesConfig{
 		Paths:             []string{},
 		IncludeExtensions: []string{},
 		ExcludeExtensions: []string{},
+		IncludeGlobs:      []string{},
+		ExcludeGlobs:      []string{},
 		Ignore:            []string{".git", "node_modules", ".vscode", ".idea", "vendor", ".cache", "target", "build", "dist"},
 		Filesystem: core.FilesystemSourceConfig{
 			Enabled: true,
@@ -41,9 +50,21 @@ var DefaultConfig = core.Config{
 		Language:     "en",
 	},
 	Ranking: core.RankingConfig{
+		Scorer:              "bm25f",
 		BM25Weight:          0.7,
 		VSMWeight:           0.3,
+		BM25K1:              1.2,
+		BM25B:               &defaultBM25B,
+		DirichletMu:         2000,
 		RecencyHalfLifeDays: 30,
+		FieldWeights: core.FieldWeights{
+			Body:     1,
+			Filename: 3,
+			Path:     1,
+			Heading:  2,
+			Title:    3,
+			Tags:     2,
+		},
 	},
 	Logging: core.LoggingConfig{
 		Level: "info",
@@ -97,6 +118,28 @@ func LoadConfig() (*core.Config, error) {
 	return &config, nil
 }
 
+// LoadOverlay reads a configuration file on top of a copy of base, so that the
+// file only needs the settings that differ from base
+func LoadOverlay(base *core.Config, path string) (*core.Config, error) {
+	configBytes, err := os.ReadFile(path)
+	if err != nil {
+		logger.Errorf("Failed to read config file %s: %+v", path, err)
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Unmarshaling writes through pointers, which must not change base
+	overlay := *base
+	if b := base.Ranking.BM25B; b != nil {
+		value := *b
+		overlay.Ranking.BM25B = &value
+	}
+	if err := toml.Unmarshal(configBytes, &overlay); err != nil {
+		logger.Errorf("Error unmarshaling config %s: %+v", path, err)
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	return &overlay, nil
+}
+
 func ShowCmdExecute(cmd *cobra.Command, args []string) {
 	logger.Info("showCmdExecute")
 
@@ -78,6 +78,9 @@ func TestDefaultConfig(t *testing.T) {
 		assert.Equal(t, 0, config.Index.MaxTokensPerDocument)
 		assert.True(t, config.Index.ReindexOnModify)
 		assert.True(t, config.Index.SkipBinaryFiles)
+		assert.Equal(t, 2, config.Index.ArchiveDepth)
+		assert.Equal(t, 64, config.Index.ArchiveMaxSizeMB)
+		assert.False(t, config.Index.NotebookOutputs)
 	})
 
 	t.Run("has correct sources defaults", func(t *testing.T) {
@@ -110,7 +113,13 @@ func TestDefaultConfig(t *testing.T) {
 
 		assert.Equal(t, 0.7, config.Ranking.BM25Weight)
 		assert.Equal(t, 0.3, config.Ranking.VSMWeight)
+		assert.Equal(t, "bm25f", config.Ranking.Scorer)
+		assert.Equal(t, 1.2, config.Ranking.BM25K1)
+		require.NotNil(t, config.Ranking.BM25B)
+		assert.Equal(t, 0.75, *config.Ranking.BM25B)
+		assert.Equal(t, 2000.0, config.Ranking.DirichletMu)
 		assert.Equal(t, 30, config.Ranking.RecencyHalfLifeDays)
+		assert.Equal(t, core.FieldWeights{Body: 1, Filename: 3, Path: 1, Heading: 2, Title: 3, Tags: 2}, config.Ranking.FieldWeights)
 	})
 
 	t.Run("has correct logging defaults", func(t *testing.T) {
@@ -124,6 +133,7 @@ func TestDefaultConfig(t *testing.T) {
 func TestConfigMarshaling(t *testing.T) {
 	t.Run("can marshal and unmarshal config", func(t *testing.T) {
 		// Create a test config
+		bm25B := 0.4
 		testConfig := core.Config{
 			Version: 1,
 			Index: core.IndexConfig{
@@ -137,6 +147,11 @@ func TestConfigMarshaling(t *testing.T) {
 				IncludeExtensions: []string{".txt", ".md"},
 				ExcludeExtensions: []string{}, // TOML unmarshals to [] not nil
 				Ignore:            []string{".git"},
+				IncludeGlobs:      []string{"docs/**/*.md"},
+				ExcludeGlobs:      []string{"docs/archive/**"},
+				Overrides: []core.SourceOverride{
+					{Path: "/test/path", IncludeGlobs: []string{"**/*.txt"}, ExcludeGlobs: []string{}},
+				},
 			},
 			Watcher: core.WatcherConfig{
 				Enabled:    false,
@@ -148,8 +163,12 @@ func TestConfigMarshaling(t *testing.T) {
 				Language:     "es",
 			},
 			Ranking: core.RankingConfig{
+				Scorer:              "bm25l",
 				BM25Weight:          0.8,
 				VSMWeight:           0.2,
+				BM25K1:              0.9,
+				BM25B:               &bm25B,
+				DirichletMu:         500,
 				RecencyHalfLifeDays: 60,
 			},
 			Logging: core.LoggingConfig{
@@ -199,6 +218,8 @@ func TestConfigFileOperations(t *testing.T) {
 				IncludeExtensions: []string{".txt"},
 				ExcludeExtensions: []string{}, // TOML unmarshals to [] not nil
 				Ignore:            []string{".git", "node_modules"},
+				IncludeGlobs:      []string{},
+				ExcludeGlobs:      []string{},
 			},
 			Watcher: core.WatcherConfig{
 				Enabled:    true,
@@ -259,3 +280,23 @@ func TestConfigFileOperations(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestLoadOverlay(t *testing.T) {
+	base := DefaultConfig
+	path := filepath.Join(t.TempDir(), "tuned.toml")
+	require.NoError(t, os.WriteFile(path, []byte("[ranking]\nscorer = \"bm25\"\nbm25_weight = 0.9\nbm25_b = 0\n"), 0644))
+
+	overlay, err := LoadOverlay(&base, path)
+	require.NoError(t, err)
+	assert.Equal(t, "bm25", overlay.Ranking.Scorer)
+	assert.Equal(t, 0.9, overlay.Ranking.BM25Weight)
+	assert.Equal(t, base.Ranking.VSMWeight, overlay.Ranking.VSMWeight)
+	assert.Equal(t, base.Search, overlay.Search)
+	require.NotNil(t, overlay.Ranking.BM25B)
+	assert.Equal(t, 0.0, *overlay.Ranking.BM25B)
+	assert.Equal(t, "bm25f", base.Ranking.Scorer, "the base must not change")
+	assert.Equal(t, 0.75, *base.Ranking.BM25B, "the base must not change")
+
+	_, err = LoadOverlay(&base, filepath.Join(t.TempDir(), "missing.toml"))
+	assert.Error(t, err)
+}
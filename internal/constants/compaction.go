@@ -0,0 +1,15 @@
+package constants
+
+const (
+	// CompactionMergeFactor is the number of chunks of similar size that are
+	// merged into one by the tiered merge policy.
+	CompactionMergeFactor = 10
+
+	// CompactionTierBaseDocs is the size in live documents of the smallest tier.
+	// Every further tier holds chunks CompactionMergeFactor times larger.
+	CompactionTierBaseDocs = 100
+
+	// CompactionMaxDeletedRatio is the share of deleted documents above which a
+	// chunk is rewritten to purge them, even if its tier is not full.
+	CompactionMaxDeletedRatio = 0.5
+)
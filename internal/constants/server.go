@@ -0,0 +1,13 @@
+package constants
+
+import "time"
+
+const (
+	// DefaultServeAddr is the address 'mneme serve' listens on. It only accepts
+	// local connections unless another address is given with --addr.
+	DefaultServeAddr = "127.0.0.1:7171"
+
+	// ServeShutdownTimeout is how long 'mneme serve' waits for in-flight
+	// requests to finish when stopped
+	ServeShutdownTimeout = 5 * time.Second
+)
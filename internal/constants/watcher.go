@@ -0,0 +1,17 @@
+package constants
+
+import "time"
+
+const (
+	// DefaultWatcherDebounceMS is used when [watcher] debounce_ms is not set
+	DefaultWatcherDebounceMS = 500
+
+	// WatcherPollInterval is how often the polling watcher rescans the sources
+	// on platforms without inotify
+	WatcherPollInterval = 2 * time.Second
+
+	// WatcherPIDFile and WatcherLogFile live in the data directory and are used
+	// by `mneme watch --daemon` and `mneme watch --stop`
+	WatcherPIDFile = "watch.pid"
+	WatcherLogFile = "watch.log"
+)
@@ -0,0 +1,163 @@
+package core
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+// deletionBitmapMagic identifies a serialized DeletionBitmap
+const deletionBitmapMagic = "MDEL"
+
+// deletionBitmapVersion is the current version of the serialized bitmap format
+const deletionBitmapVersion = 1
+
+// ErrInvalidBitmap is returned when a serialized deletion bitmap cannot be decoded
+var ErrInvalidBitmap = errors.New("invalid deletion bitmap")
+
+// DeletionBitmap records deleted documents by ID, one bit per document.
+// Document IDs are global, so the bitmap only stores words from the first
+// word containing a deleted document onwards. The zero value is an empty bitmap.
+type DeletionBitmap struct {
+	offset uint     // Index of the first stored word (docID / 64)
+	words  []uint64 // Bit i of words[j] marks document (offset+j)*64 + i
+}
+
+// NewDeletionBitmap creates a bitmap with the given documents marked as deleted
+func NewDeletionBitmap(docIDs ...uint) *DeletionBitmap {
+	bitmap := &DeletionBitmap{}
+	for _, docID := range docIDs {
+		bitmap.Add(docID)
+	}
+	return bitmap
+}
+
+// Add marks a document as deleted. It returns false if it was already deleted.
+func (b *DeletionBitmap) Add(docID uint) bool {
+	word := docID / 64
+	mask := uint64(1) << (docID % 64)
+
+	switch {
+	case len(b.words) == 0:
+		b.offset = word
+		b.words = []uint64{0}
+	case word < b.offset:
+		grown := make([]uint64, b.offset-word+uint(len(b.words)))
+		copy(grown[b.offset-word:], b.words)
+		b.words = grown
+		b.offset = word
+	case word >= b.offset+uint(len(b.words)):
+		b.words = append(b.words, make([]uint64, word-b.offset-uint(len(b.words))+1)...)
+	}
+
+	i := word - b.offset
+	if b.words[i]&mask != 0 {
+		return false
+	}
+	b.words[i] |= mask
+	return true
+}
+
+// Contains reports whether a document is marked as deleted. A nil bitmap is empty.
+func (b *DeletionBitmap) Contains(docID uint) bool {
+	if b == nil {
+		return false
+	}
+	word := docID / 64
+	if word < b.offset || word >= b.offset+uint(len(b.words)) {
+		return false
+	}
+	return b.words[word-b.offset]&(uint64(1)<<(docID%64)) != 0
+}
+
+// Count returns the number of deleted documents
+func (b *DeletionBitmap) Count() uint {
+	if b == nil {
+		return 0
+	}
+	var count int
+	for _, word := range b.words {
+		count += bits.OnesCount64(word)
+	}
+	return uint(count)
+}
+
+// IsEmpty reports whether no document is marked as deleted
+func (b *DeletionBitmap) IsEmpty() bool {
+	return b.Count() == 0
+}
+
+// Union marks every document deleted in other as deleted in b
+func (b *DeletionBitmap) Union(other *DeletionBitmap) {
+	other.ForEach(func(docID uint) {
+		b.Add(docID)
+	})
+}
+
+// ForEach calls fn for every deleted document in ascending order
+func (b *DeletionBitmap) ForEach(fn func(docID uint)) {
+	if b == nil {
+		return
+	}
+	for i, word := range b.words {
+		for word != 0 {
+			bit := uint(bits.TrailingZeros64(word))
+			fn((b.offset+uint(i))*64 + bit)
+			word &= word - 1
+		}
+	}
+}
+
+// MarshalBinary encodes the bitmap as the magic "MDEL", followed by the format
+// version, the word offset and word count as uvarints and the little-endian words.
+func (b *DeletionBitmap) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 0, len(deletionBitmapMagic)+3*binary.MaxVarintLen64+8*len(b.words))
+	data = append(data, deletionBitmapMagic...)
+	data = binary.AppendUvarint(data, deletionBitmapVersion)
+	data = binary.AppendUvarint(data, uint64(b.offset))
+	data = binary.AppendUvarint(data, uint64(len(b.words)))
+	for _, word := range b.words {
+		data = binary.LittleEndian.AppendUint64(data, word)
+	}
+	return data, nil
+}
+
+// UnmarshalBinary decodes a bitmap written by MarshalBinary
+func (b *DeletionBitmap) UnmarshalBinary(data []byte) error {
+	rest, err := b.UnmarshalPrefix(data)
+	if err == nil && len(rest) > 0 {
+		return ErrInvalidBitmap
+	}
+	return err
+}
+
+// UnmarshalPrefix decodes a bitmap written by MarshalBinary at the start of
+// data and returns the bytes following it
+func (b *DeletionBitmap) UnmarshalPrefix(data []byte) ([]byte, error) {
+	if len(data) < len(deletionBitmapMagic) || string(data[:len(deletionBitmapMagic)]) != deletionBitmapMagic {
+		return nil, ErrInvalidBitmap
+	}
+	data = data[len(deletionBitmapMagic):]
+
+	var header [3]uint64
+	for i := range header {
+		value, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, ErrInvalidBitmap
+		}
+		header[i] = value
+		data = data[n:]
+	}
+
+	version, offset, count := header[0], header[1], header[2]
+	if version != deletionBitmapVersion || uint64(len(data)/8) < count {
+		return nil, ErrInvalidBitmap
+	}
+
+	b.offset = uint(offset)
+	b.words = make([]uint64, count)
+	for i := range b.words {
+		b.words[i] = binary.LittleEndian.Uint64(data[i*8:])
+	}
+	return data[count*8:], nil
+}
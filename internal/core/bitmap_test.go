@@ -0,0 +1,102 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeletionBitmap(t *testing.T) {
+	t.Run("zero value is empty", func(t *testing.T) {
+		var bitmap DeletionBitmap
+		assert.True(t, bitmap.IsEmpty())
+		assert.False(t, bitmap.Contains(0))
+	})
+
+	t.Run("nil bitmap is empty", func(t *testing.T) {
+		var bitmap *DeletionBitmap
+		assert.True(t, bitmap.IsEmpty())
+		assert.False(t, bitmap.Contains(42))
+	})
+
+	t.Run("add grows in both directions", func(t *testing.T) {
+		bitmap := NewDeletionBitmap(500, 1000)
+		assert.True(t, bitmap.Add(3))
+		assert.False(t, bitmap.Add(500), "already deleted")
+
+		for _, docID := range []uint{3, 500, 1000} {
+			assert.True(t, bitmap.Contains(docID), "doc %d", docID)
+		}
+		for _, docID := range []uint{0, 4, 499, 501, 999, 1001, 5000} {
+			assert.False(t, bitmap.Contains(docID), "doc %d", docID)
+		}
+		assert.Equal(t, uint(3), bitmap.Count())
+	})
+
+	t.Run("for each visits in ascending order", func(t *testing.T) {
+		bitmap := NewDeletionBitmap(130, 7, 64, 63)
+		var visited []uint
+		bitmap.ForEach(func(docID uint) { visited = append(visited, docID) })
+		assert.Equal(t, []uint{7, 63, 64, 130}, visited)
+	})
+
+	t.Run("union", func(t *testing.T) {
+		bitmap := NewDeletionBitmap(1, 2)
+		bitmap.Union(NewDeletionBitmap(2, 300))
+		bitmap.Union(nil)
+		assert.Equal(t, uint(3), bitmap.Count())
+		assert.True(t, bitmap.Contains(300))
+	})
+
+	t.Run("binary round trip", func(t *testing.T) {
+		original := NewDeletionBitmap(10000, 10001, 10200)
+		data, err := original.MarshalBinary()
+		require.NoError(t, err)
+
+		restored := &DeletionBitmap{}
+		require.NoError(t, restored.UnmarshalBinary(data))
+		assert.Equal(t, original.Count(), restored.Count())
+		assert.True(t, restored.Contains(10200))
+		assert.False(t, restored.Contains(10002))
+	})
+
+	t.Run("rejects invalid data", func(t *testing.T) {
+		data, err := NewDeletionBitmap(5).MarshalBinary()
+		require.NoError(t, err)
+
+		bitmap := &DeletionBitmap{}
+		assert.ErrorIs(t, bitmap.UnmarshalBinary([]byte("nope")), ErrInvalidBitmap)
+		assert.ErrorIs(t, bitmap.UnmarshalBinary(data[:len(data)-1]), ErrInvalidBitmap)
+		assert.ErrorIs(t, bitmap.UnmarshalBinary(append(data, 0)), ErrInvalidBitmap)
+	})
+
+	t.Run("prefix of other data", func(t *testing.T) {
+		data, err := NewDeletionBitmap(5, 70).MarshalBinary()
+		require.NoError(t, err)
+
+		bitmap := &DeletionBitmap{}
+		rest, err := bitmap.UnmarshalPrefix(append(data, "next"...))
+		require.NoError(t, err)
+		assert.Equal(t, "next", string(rest))
+		assert.True(t, bitmap.Contains(70))
+		assert.Equal(t, uint(2), bitmap.Count())
+	})
+}
+
+func TestManifestUpdateTotalsWithDeletions(t *testing.T) {
+	manifest := NewManifest()
+	manifest.AddChunk(ChunkInfo{ID: 1, Status: ChunkStatusComplete, DocCount: 4, TokenCount: 400})
+	manifest.AddChunk(ChunkInfo{ID: 2, Status: ChunkStatusComplete, DocCount: 2, TokenCount: 200})
+
+	chunk := manifest.GetChunk(1)
+	require.NotNil(t, chunk)
+	chunk.DeletesFile = "001.del"
+	chunk.DeletedCount = 2
+	assert.Nil(t, manifest.GetChunk(3))
+
+	manifest.UpdateTotals()
+	assert.Equal(t, uint(4), manifest.TotalDocs)
+	assert.Equal(t, uint(400), manifest.TotalTokens)
+	assert.Equal(t, uint(100), manifest.AvgDocLen)
+}
@@ -1,6 +1,10 @@
 package core
 
-import "mneme/internal/constants"
+import (
+	"context"
+
+	"mneme/internal/constants"
+)
 
 type Config struct {
 	Version uint8         `toml:"version"`
@@ -17,6 +21,10 @@ type IndexConfig struct {
 	MaxTokensPerDocument int  `toml:"max_tokens_per_document"`
 	ReindexOnModify      bool `toml:"reindex_on_modify"`
 	SkipBinaryFiles      bool `toml:"skip_binary_files"`
+	Workers              int  `toml:"workers"`             // Documents read and tokenized concurrently; 0 uses one worker per CPU
+	ArchiveDepth         int  `toml:"archive_depth"`       // Nested levels of zip, tar and tar.gz archives indexed; 0 skips archives
+	ArchiveMaxSizeMB     int  `toml:"archive_max_size_mb"` // Largest archive and archive member indexed; 0 disables the limit
+	NotebookOutputs      bool `toml:"notebook_outputs"`    // Index the text outputs of Jupyter notebook code cells
 }
 
 type SourcesConfig struct {
@@ -24,9 +32,20 @@ type SourcesConfig struct {
 	IncludeExtensions []string               `toml:"include_extensions"`
 	ExcludeExtensions []string               `toml:"exclude_extensions"`
 	Ignore            []string               `toml:"ignore"`
+	IncludeGlobs      []string               `toml:"include_globs"`
+	ExcludeGlobs      []string               `toml:"exclude_globs"`
+	Overrides         []SourceOverride       `toml:"overrides,omitempty"`
 	Filesystem        FilesystemSourceConfig `toml:"filesystem"`
 }
 
+// SourceOverride replaces the include and exclude globs of [sources] for a single
+// source path. Globs not listed in the override do not apply to that path.
+type SourceOverride struct {
+	Path         string   `toml:"path"`
+	IncludeGlobs []string `toml:"include_globs"`
+	ExcludeGlobs []string `toml:"exclude_globs"`
+}
+
 // FilesystemSourceConfig holds configuration for local filesystem source.
 // Source is enabled when paths are provided or when enabled is explicitly set.
 // See IsEnabled() for logic.
@@ -51,9 +70,28 @@ type SearchConfig struct {
 }
 
 type RankingConfig struct {
-	BM25Weight          float64 `toml:"bm25_weight"`
-	VSMWeight           float64 `toml:"vsm_weight"`
-	RecencyHalfLifeDays int     `toml:"recency_half_life_days"`
+	// Scorer selects the relevance scorer by name: bm25, bm25+, bm25l, tfidf or dirichlet
+	Scorer     string  `toml:"scorer"`
+	BM25Weight float64 `toml:"bm25_weight"` // Weight of the selected scorer
+	VSMWeight  float64 `toml:"vsm_weight"`
+	BM25K1     float64 `toml:"bm25_k1"`
+	// BM25B is nil when unset, as 0 turns off length normalization
+	BM25B               *float64 `toml:"bm25_b"`
+	DirichletMu         float64  `toml:"dirichlet_mu"`
+	RecencyHalfLifeDays int      `toml:"recency_half_life_days"`
+	// FieldWeights weights matches per field in the bm25f scorer
+	FieldWeights FieldWeights `toml:"field_weights"`
+}
+
+// FieldWeights holds the weight of every field of a document in BM25F. A
+// weight of 0 ignores the field.
+type FieldWeights struct {
+	Body     float64 `toml:"body"`
+	Filename float64 `toml:"filename"`
+	Path     float64 `toml:"path"`
+	Heading  float64 `toml:"heading"`
+	Title    float64 `toml:"title"`
+	Tags     float64 `toml:"tags"`
 }
 
 type LoggingConfig struct {
@@ -67,6 +105,7 @@ type BatchConfig struct {
 	ProgressCallback func(current, total int, message string) // Optional callback for progress updates
 	SuppressLogs     bool                                     // If true, suppress info logs (used when progress bar is active)
 	IndexConfig      IndexConfig                              // Index configuration (for MaxTokensPerDocument etc.)
+	Context          context.Context                          // Optional; cancelling it stops indexing, keeping the chunks completed so far
 }
 
 // DefaultBatchConfig returns the default batch configuration
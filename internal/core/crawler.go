@@ -13,6 +13,17 @@ type CrawlerOptions struct {
 	// SkipFolders is a list of folder names to skip entirely
 	SkipFolders []string
 
+	// IncludeGlobs is a list of doublestar patterns matched against the path relative
+	// to the source root. If non-empty, only files matching one of them are included.
+	IncludeGlobs []string
+
+	// ExcludeGlobs is a list of doublestar patterns for files and folders to skip,
+	// matched against the path relative to the source root
+	ExcludeGlobs []string
+
+	// SourceOverrides replace IncludeGlobs and ExcludeGlobs for individual source paths
+	SourceOverrides []SourceOverride
+
 	// MaxFilesPerFolder is the maximum number of files to process per folder
 	// If a folder contains more files than this limit, it will be skipped
 	// Set to 0 or negative to disable this limit
@@ -24,6 +35,21 @@ type CrawlerOptions struct {
 	// SkipBinaryFiles determines whether to skip binary and non-readable file types
 	// When true, files with extensions in BinaryExtensions will be skipped
 	SkipBinaryFiles bool
+
+	// DisableIgnoreFiles stops the crawler from reading .gitignore, .ignore and
+	// .mnemeignore files. By default their rules exclude files in every directory.
+	DisableIgnoreFiles bool
+
+	// ArchiveDepth is the number of nested levels of zip, tar and tar.gz archives
+	// the crawler descends into, listing their members as virtual paths such as
+	// "backup.zip!/docs/readme.md". 1 lists the members of archives on disk but
+	// no archives inside them. Set to 0 to treat archives like any other file.
+	ArchiveDepth int
+
+	// ArchiveMaxSize is the maximum size in bytes of an archive to open and of
+	// an archive member to list, uncompressed. Set to 0 or negative to disable
+	// this limit.
+	ArchiveMaxSize int64
 }
 
 // DefaultCrawlerOptions returns a CrawlerOptions with sensible defaults
@@ -1,12 +1,119 @@
 package core
 
+import (
+	"path/filepath"
+	"strings"
+)
+
 type Document struct {
 	ID         uint   `json:"id"`
 	Path       string `json:"path"`
 	TokenCount uint   `json:"token_count"`
+	// ModTime, Size and ContentHash describe the source as it was indexed and are
+	// used by incremental indexing to detect modified documents.
+	ModTime     int64  `json:"mod_time,omitempty"`     // Unix nanoseconds
+	Size        int64  `json:"size,omitempty"`         // Bytes
+	ContentHash string `json:"content_hash,omitempty"` // Hex SHA-256 of the contents
+	// Source is the name of the ingestor that provided the document (e.g. "filesystem")
+	Source string `json:"source,omitempty"`
+	// FieldLengths holds the number of tokens in every Field, indexed by Field.
+	// It is nil for documents indexed before fields were introduced.
+	FieldLengths []uint `json:"field_lengths,omitempty"`
+	// Title, Tags and Date are metadata extracted from the document, such as
+	// the HTML <title> or the front matter of Markdown files
+	Title string   `json:"title,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+	Date  int64    `json:"date,omitempty"` // Unix nanoseconds, 0 if unknown
+}
+
+// Field is a part of a document that is indexed separately from its contents,
+// so that matches in it can be weighted on their own (BM25F). The contents of a
+// document form the implicit body field, described by Posting.Freq and
+// Document.TokenCount.
+type Field int
+
+const (
+	// FieldFilename holds the tokens of the file name without its extension
+	FieldFilename Field = iota
+	// FieldPath holds the tokens of the names of the nearest parent directories
+	FieldPath
+	// FieldHeading holds the tokens of the headings of Markdown and HTML documents
+	FieldHeading
+	// FieldTitle holds the tokens of the document title, such as the HTML
+	// <title> or the title of Markdown front matter
+	FieldTitle
+	// FieldTags holds the tokens of the tags of Markdown front matter
+	FieldTags
+	// NumFields is the number of fields
+	NumFields
+)
+
+// FieldNames holds the name of every Field, indexed by Field
+var FieldNames = [NumFields]string{"filename", "path", "heading", "title", "tags"}
+
+// String returns the name of the field
+func (f Field) String() string {
+	if f < 0 || f >= NumFields {
+		return "unknown"
+	}
+	return FieldNames[f]
+}
+
+// FieldLength returns the number of tokens of the document in a field
+func (d Document) FieldLength(field Field) uint {
+	if int(field) >= len(d.FieldLengths) {
+		return 0
+	}
+	return d.FieldLengths[field]
+}
+
+// Extension returns the lower case file extension of the document without the
+// leading dot, or an empty string if the path has none
+func (d Document) Extension() string {
+	return strings.ToLower(strings.TrimPrefix(filepath.Ext(d.Path), "."))
+}
+
+// Dir returns the directory containing the document
+func (d Document) Dir() string {
+	return filepath.Dir(d.Path)
+}
+
+// HasTag reports whether the document has the tag, ignoring case
+func (d Document) HasTag(tag string) bool {
+	for _, t := range d.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// RecencyTime returns the time the recency of the document is measured from
+// (Unix nanoseconds): its Date if known, otherwise its ModTime
+func (d Document) RecencyTime() int64 {
+	if d.Date != 0 {
+		return d.Date
+	}
+	return d.ModTime
 }
 
 type Posting struct {
 	DocID uint `json:"doc_id"`
 	Freq  uint `json:"freq"`
+	// Positions holds the ascending word positions of the term in the document.
+	// Tokens derived from the same word share a position, so len(Positions) may be
+	// smaller than Freq.
+	Positions []uint `json:"positions,omitempty"`
+	// FieldFreqs holds the occurrences of the term in every Field, indexed by
+	// Field. It is nil if the term only occurs in the contents. Freq is 0 for
+	// terms that only occur in fields.
+	FieldFreqs []uint `json:"field_freqs,omitempty"`
+}
+
+// FieldFreq returns the occurrences of the term in a field of the document
+func (p Posting) FieldFreq(field Field) uint {
+	if int(field) >= len(p.FieldFreqs) {
+		return 0
+	}
+	return p.FieldFreqs[field]
 }
@@ -0,0 +1,56 @@
+package core
+
+// Explanation breaks the score of a ranked document down into the parts it was
+// computed from. It is only built on request, e.g. by mneme find --explain.
+type Explanation struct {
+	Scorer  string             `json:"scorer"` // Name of the relevance scorer
+	Weights ExplanationWeights `json:"weights"`
+	// Passes holds the exact pass and, if the query was expanded, the fuzzy pass
+	Passes        []PassExplanation `json:"passes"`
+	PhraseBoost   float64           `json:"phrase_boost,omitempty"` // Added for matching phrases
+	RecencyFactor float64           `json:"recency_factor"`         // Multiplier of the recency boost
+	// Score is (sum of the pass scores + PhraseBoost) × RecencyFactor
+	Score float64 `json:"score"`
+	// TieBreak names the criterion that ordered the document after the previous
+	// result: "score", "match_count" or "filename". It is empty for the first result.
+	TieBreak string `json:"tie_break,omitempty"`
+}
+
+// ExplanationWeights holds the weights the parts of a score were combined with
+type ExplanationWeights struct {
+	Relevance           float64 `json:"relevance"`     // ranking.bm25_weight
+	VSM                 float64 `json:"vsm"`           // ranking.vsm_weight
+	FuzzyPenalty        float64 `json:"fuzzy_penalty"` // Scales the VSM score of fuzzy terms
+	PhraseBoost         float64 `json:"phrase_boost"`  // Scales the normalized phrase score
+	RecencyHalfLifeDays int     `json:"recency_half_life_days"`
+}
+
+// PassExplanation explains the score of a document in one pass over the query
+// terms
+type PassExplanation struct {
+	Pass         string  `json:"pass"`          // "exact" or "fuzzy"
+	Relevance    float64 `json:"relevance"`     // Raw score of the scorer
+	MaxRelevance float64 `json:"max_relevance"` // Best raw score in the index, normalizing Relevance
+	VSM          float64 `json:"vsm"`           // Cosine similarity of the TF-IDF vectors
+	// Score is relevance weight × Relevance / MaxRelevance + VSM weight × VSM,
+	// with the VSM score of the fuzzy pass scaled by the fuzzy penalty
+	Score float64           `json:"score"`
+	Terms []TermExplanation `json:"terms"`
+}
+
+// TermExplanation holds the statistics of a single term in a document
+type TermExplanation struct {
+	Term string `json:"term"`
+	// ExpandedFrom is the query term a fuzzy term was expanded from, at
+	// Distance edits
+	ExpandedFrom string          `json:"expanded_from,omitempty"`
+	Distance     int             `json:"distance,omitempty"`
+	TF           uint            `json:"tf"`                 // Occurrences in the contents
+	FieldTF      map[string]uint `json:"field_tf,omitempty"` // Occurrences in other fields, by field name
+	DF           int             `json:"df"`                 // Live documents containing the term
+	IDF          float64         `json:"idf"`
+	DocLen       float64         `json:"doc_len"`
+	AvgDocLen    float64         `json:"avg_doc_len"`
+	LengthNorm   float64         `json:"length_norm"` // 1 - b + b × DocLen / AvgDocLen
+	Score        float64         `json:"score"`       // Raw score of the scorer for this term alone
+}
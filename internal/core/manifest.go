@@ -11,6 +11,7 @@ type Manifest struct {
 	TotalDocs   uint        `json:"total_docs"`
 	TotalTokens uint        `json:"total_tokens"`
 	AvgDocLen   uint        `json:"avg_doc_len"`
+	NextDocID   uint        `json:"next_doc_id"` // Next free document ID for delta chunks
 	Chunks      []ChunkInfo `json:"chunks"`
 }
 
@@ -22,6 +23,13 @@ type ChunkInfo struct {
 	DocCount   uint      `json:"doc_count"`   // Number of documents in this chunk
 	TokenCount uint      `json:"token_count"` // Number of unique tokens in this chunk
 	CreatedAt  time.Time `json:"created_at"`
+	// DeletesFile names the deletion bitmap of documents in this chunk that were
+	// removed or superseded by a newer version (e.g., "001.del"); empty if none
+	DeletesFile  string `json:"deletes_file,omitempty"`
+	DeletedCount uint   `json:"deleted_count,omitempty"` // Number of documents set in the deletion bitmap
+	// Checksum is the CRC32C of the chunk file, verified when the chunk is loaded;
+	// zero for chunks written before checksums were recorded
+	Checksum uint32 `json:"checksum,omitempty"`
 }
 
 // ChunkStatus constants
@@ -30,8 +38,13 @@ const (
 	ChunkStatusInProgress = "in_progress"
 )
 
-// ManifestVersion is the current version of the manifest format
-const ManifestVersion = "1.0"
+// ManifestVersion is the current version of the manifest format.
+// Version 1.1 added per-document metadata, NextDocID and per-chunk deletion
+// bitmaps, which incremental indexing relies on. Version 1.2 indexes the fields
+// of every document (see Field); older indexes are rebuilt so that all
+// documents have them. Version 1.3 adds the title and tags fields and the
+// metadata of HTML and Markdown documents.
+const ManifestVersion = "1.3"
 
 // NewManifest creates a new empty manifest
 func NewManifest() *Manifest {
@@ -61,30 +74,70 @@ func (m *Manifest) GetCompleteChunks() []ChunkInfo {
 	return complete
 }
 
-// MarkChunkComplete marks a chunk as complete by ID
-func (m *Manifest) MarkChunkComplete(chunkID int) {
+// MarkChunkComplete marks a chunk as complete by ID once its file was saved
+// with the given checksum
+func (m *Manifest) MarkChunkComplete(chunkID int, checksum uint32) {
 	for i := range m.Chunks {
 		if m.Chunks[i].ID == chunkID {
 			m.Chunks[i].Status = ChunkStatusComplete
+			m.Chunks[i].Checksum = checksum
 			m.UpdatedAt = time.Now()
 			return
 		}
 	}
 }
 
-// UpdateTotals recalculates total docs, tokens, and average doc length
+// GetChunk returns the chunk with the given ID, or nil if it does not exist
+func (m *Manifest) GetChunk(chunkID int) *ChunkInfo {
+	for i := range m.Chunks {
+		if m.Chunks[i].ID == chunkID {
+			return &m.Chunks[i]
+		}
+	}
+	return nil
+}
+
+// NextChunkID returns the ID to use for a new chunk appended to the manifest
+func (m *Manifest) NextChunkID() int {
+	maxID := 0
+	for _, chunk := range m.Chunks {
+		if chunk.ID > maxID {
+			maxID = chunk.ID
+		}
+	}
+	return maxID + 1
+}
+
+// LiveDocCount returns the number of documents in the chunk that are not deleted
+func (c *ChunkInfo) LiveDocCount() uint {
+	if c.DeletedCount > c.DocCount {
+		return 0
+	}
+	return c.DocCount - c.DeletedCount
+}
+
+// UpdateTotals recalculates total docs, tokens, and average doc length.
+// Deleted documents are excluded; the token count of a chunk with deletions is
+// scaled down by its share of live documents.
 func (m *Manifest) UpdateTotals() {
 	var totalDocs, totalTokens uint
 	for _, chunk := range m.Chunks {
 		if chunk.Status == ChunkStatusComplete {
-			totalDocs += chunk.DocCount
-			totalTokens += chunk.TokenCount
+			liveDocs := chunk.LiveDocCount()
+			totalDocs += liveDocs
+			if chunk.DocCount > 0 && liveDocs < chunk.DocCount {
+				totalTokens += uint(uint64(chunk.TokenCount) * uint64(liveDocs) / uint64(chunk.DocCount))
+			} else {
+				totalTokens += chunk.TokenCount
+			}
 		}
 	}
 	m.TotalDocs = totalDocs
 	m.TotalTokens = totalTokens
 	if totalDocs > 0 {
 		m.AvgDocLen = totalTokens / totalDocs
+	} else {
+		m.AvgDocLen = 0
 	}
 	m.UpdatedAt = time.Now()
 }
@@ -23,10 +23,20 @@ const (
 
 // Document represents an indexed file
 type Document struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Path          string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
-	TokenCount    uint32                 `protobuf:"varint,3,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Path       string                 `protobuf:"bytes,2,opt,name=path,proto3" json:"path,omitempty"`
+	TokenCount uint32                 `protobuf:"varint,3,opt,name=token_count,json=tokenCount,proto3" json:"token_count,omitempty"`
+	// mod_time is the source modification time in Unix nanoseconds when indexed
+	ModTime int64 `protobuf:"varint,4,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	// size is the source size in bytes when indexed
+	Size int64 `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	// content_hash is the hex SHA-256 of the indexed contents
+	ContentHash string `protobuf:"bytes,6,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"`
+	// source is the name of the ingestor that provided the document
+	Source string `protobuf:"bytes,7,opt,name=source,proto3" json:"source,omitempty"`
+	// field_lengths holds the number of tokens in every field (file name, path, headings)
+	FieldLengths  []uint32 `protobuf:"varint,8,rep,packed,name=field_lengths,json=fieldLengths,proto3" json:"field_lengths,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -82,11 +92,51 @@ func (x *Document) GetTokenCount() uint32 {
 	return 0
 }
 
+func (x *Document) GetModTime() int64 {
+	if x != nil {
+		return x.ModTime
+	}
+	return 0
+}
+
+func (x *Document) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Document) GetContentHash() string {
+	if x != nil {
+		return x.ContentHash
+	}
+	return ""
+}
+
+func (x *Document) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Document) GetFieldLengths() []uint32 {
+	if x != nil {
+		return x.FieldLengths
+	}
+	return nil
+}
+
 // Posting represents a term occurrence in a document
 type Posting struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	DocId         uint32                 `protobuf:"varint,1,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
-	Freq          uint32                 `protobuf:"varint,2,opt,name=freq,proto3" json:"freq,omitempty"`
+	state protoimpl.MessageState `protogen:"open.v1"`
+	DocId uint32                 `protobuf:"varint,1,opt,name=doc_id,json=docId,proto3" json:"doc_id,omitempty"`
+	Freq  uint32                 `protobuf:"varint,2,opt,name=freq,proto3" json:"freq,omitempty"`
+	// positions lists the word positions of the term in the document, ascending
+	Positions []uint32 `protobuf:"varint,3,rep,packed,name=positions,proto3" json:"positions,omitempty"`
+	// field_freqs holds the occurrences of the term in every field, empty if it
+	// only occurs in the contents
+	FieldFreqs    []uint32 `protobuf:"varint,4,rep,packed,name=field_freqs,json=fieldFreqs,proto3" json:"field_freqs,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -135,6 +185,20 @@ func (x *Posting) GetFreq() uint32 {
 	return 0
 }
 
+func (x *Posting) GetPositions() []uint32 {
+	if x != nil {
+		return x.Positions
+	}
+	return nil
+}
+
+func (x *Posting) GetFieldFreqs() []uint32 {
+	if x != nil {
+		return x.FieldFreqs
+	}
+	return nil
+}
+
 // PostingList holds all postings for a single term
 type PostingList struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
@@ -261,15 +325,23 @@ var File_proto_segment_proto protoreflect.FileDescriptor
 
 const file_proto_segment_proto_rawDesc = "" +
 	"\n" +
-	"\x13proto/segment.proto\x12\x05mneme\"O\n" +
+	"\x13proto/segment.proto\x12\x05mneme\"\xde\x01\n" +
 	"\bDocument\x12\x0e\n" +
 	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
 	"\x04path\x18\x02 \x01(\tR\x04path\x12\x1f\n" +
 	"\vtoken_count\x18\x03 \x01(\rR\n" +
-	"tokenCount\"4\n" +
+	"tokenCount\x12\x19\n" +
+	"\bmod_time\x18\x04 \x01(\x03R\amodTime\x12\x12\n" +
+	"\x04size\x18\x05 \x01(\x03R\x04size\x12!\n" +
+	"\fcontent_hash\x18\x06 \x01(\tR\vcontentHash\x12\x16\n" +
+	"\x06source\x18\a \x01(\tR\x06source\x12#\n" +
+	"\rfield_lengths\x18\b \x03(\rR\ffieldLengths\"s\n" +
 	"\aPosting\x12\x15\n" +
 	"\x06doc_id\x18\x01 \x01(\rR\x05docId\x12\x12\n" +
-	"\x04freq\x18\x02 \x01(\rR\x04freq\"9\n" +
+	"\x04freq\x18\x02 \x01(\rR\x04freq\x12\x1c\n" +
+	"\tpositions\x18\x03 \x03(\rR\tpositions\x12\x1f\n" +
+	"\vfield_freqs\x18\x04 \x03(\rR\n" +
+	"fieldFreqs\"9\n" +
 	"\vPostingList\x12*\n" +
 	"\bpostings\x18\x01 \x03(\v2\x0e.mneme.PostingR\bpostings\"\xb0\x02\n" +
 	"\aSegment\x12#\n" +
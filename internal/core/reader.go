@@ -0,0 +1,175 @@
+package core
+
+import (
+	"sort"
+	"sync"
+)
+
+// SegmentReader provides read access to a single immutable segment chunk.
+// Implementations must be safe for concurrent use by multiple goroutines.
+// Postings of deleted documents may still be returned and must be skipped
+// with IsDeleted.
+type SegmentReader interface {
+	// Postings returns the postings of a term ordered by document ID, or nil
+	Postings(term string) []Posting
+	// Terms returns the terms with at least one live posting
+	Terms() []string
+	// Document returns a document by ID
+	Document(docID uint) (Document, bool)
+	// Documents returns all live documents
+	Documents() []Document
+	// IsDeleted reports whether a document was deleted
+	IsDeleted(docID uint) bool
+	// Close releases resources held by the reader
+	Close() error
+}
+
+// MemoryReader is a SegmentReader over a fully decoded Segment
+type MemoryReader struct {
+	segment *Segment
+	docs    map[uint]int // Document ID -> index in segment.Docs
+}
+
+// NewSegmentReader returns a reader over a decoded segment. Deletions are taken
+// from segment.Deleted.
+func NewSegmentReader(segment *Segment) *MemoryReader {
+	docs := make(map[uint]int, len(segment.Docs))
+	for i, doc := range segment.Docs {
+		docs[doc.ID] = i
+	}
+	return &MemoryReader{segment: segment, docs: docs}
+}
+
+func (r *MemoryReader) Postings(term string) []Posting {
+	return r.segment.InvertedIndex[term]
+}
+
+func (r *MemoryReader) Terms() []string {
+	terms := make([]string, 0, len(r.segment.InvertedIndex))
+	for term := range r.segment.InvertedIndex {
+		if r.segment.Deleted != nil && r.segment.LiveDocFrequency(term) == 0 {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+func (r *MemoryReader) Document(docID uint) (Document, bool) {
+	i, ok := r.docs[docID]
+	if !ok {
+		return Document{}, false
+	}
+	return r.segment.Docs[i], true
+}
+
+func (r *MemoryReader) Documents() []Document {
+	if r.segment.Deleted == nil {
+		return r.segment.Docs
+	}
+	docs := make([]Document, 0, len(r.segment.Docs))
+	for _, doc := range r.segment.Docs {
+		if !r.segment.IsDeleted(doc.ID) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+func (r *MemoryReader) IsDeleted(docID uint) bool {
+	return r.segment.IsDeleted(docID)
+}
+
+func (r *MemoryReader) Close() error {
+	return nil
+}
+
+// IndexReader is a read-only view over all segment chunks of an index together
+// with the corpus statistics of the whole index. Document IDs are unique across
+// segments, so per-segment results can be merged by ID.
+type IndexReader struct {
+	Segments    []SegmentReader
+	TotalDocs   uint // Live documents across all segments
+	TotalTokens uint
+	AvgDocLen   uint
+
+	vocabularyOnce sync.Once
+	vocabulary     []string
+
+	fieldLengthsOnce sync.Once
+	avgFieldLengths  [NumFields]float64
+}
+
+// NewIndexReader wraps a single decoded segment, using its own statistics
+func NewIndexReader(segment *Segment) *IndexReader {
+	return &IndexReader{
+		Segments:    []SegmentReader{NewSegmentReader(segment)},
+		TotalDocs:   segment.TotalDocs,
+		TotalTokens: segment.TotalTokens,
+		AvgDocLen:   segment.AvgDocLen,
+	}
+}
+
+// Vocabulary returns the sorted union of the live terms of all segments.
+// It is computed once and shared by all callers.
+func (r *IndexReader) Vocabulary() []string {
+	r.vocabularyOnce.Do(func() {
+		if len(r.Segments) == 1 {
+			r.vocabulary = r.Segments[0].Terms()
+			sort.Strings(r.vocabulary)
+			return
+		}
+
+		seen := make(map[string]struct{})
+		for _, segment := range r.Segments {
+			for _, term := range segment.Terms() {
+				seen[term] = struct{}{}
+			}
+		}
+		r.vocabulary = make([]string, 0, len(seen))
+		for term := range seen {
+			r.vocabulary = append(r.vocabulary, term)
+		}
+		sort.Strings(r.vocabulary)
+	})
+	return r.vocabulary
+}
+
+// AvgFieldLengths returns the average number of tokens of the live documents
+// in every Field. Documents indexed without fields are not counted. It is
+// computed once and shared by all callers.
+func (r *IndexReader) AvgFieldLengths() [NumFields]float64 {
+	r.fieldLengthsOnce.Do(func() {
+		var totals [NumFields]uint
+		docCount := 0
+		for _, segment := range r.Segments {
+			for _, doc := range segment.Documents() {
+				if doc.FieldLengths == nil {
+					continue
+				}
+				for field := range NumFields {
+					totals[field] += doc.FieldLength(field)
+				}
+				docCount++
+			}
+		}
+		if docCount == 0 {
+			return
+		}
+		for field := range NumFields {
+			r.avgFieldLengths[field] = float64(totals[field]) / float64(docCount)
+		}
+	})
+	return r.avgFieldLengths
+}
+
+// Close closes all segment readers
+func (r *IndexReader) Close() error {
+	var firstErr error
+	for _, segment := range r.Segments {
+		if err := segment.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
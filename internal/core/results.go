@@ -4,8 +4,9 @@ type RankedDocument struct {
 	DocID        uint
 	Path         string
 	Score        float64
-	MatchCount   int      // Total frequency of matched terms (for tie-breaking)
-	MatchedTerms []string // Terms used for matching (including fuzzy expansions)
+	MatchCount   int          // Total frequency of matched terms (for tie-breaking)
+	MatchedTerms []string     // Terms used for matching (including fuzzy expansions)
+	Explanation  *Explanation // Score breakdown, if requested
 }
 
 // GetScore implements utils.Scored interface
@@ -19,23 +20,33 @@ type TFIDFVector struct {
 	Norm    float64 // L2 norm for cosine similarity
 }
 
-// SearchResult represents a formatted search result with snippet
+// SearchResult represents a formatted search result with snippet. Its JSON
+// encoding is the stable schema of machine-readable search output.
 type SearchResult struct {
-	DocPath    string
-	Score      float64
-	Snippets   []Snippet
-	MatchCount int
+	DocPath      string    `json:"path"`
+	Title        string    `json:"title,omitempty"` // HTML <title> or front matter title, if any
+	Score        float64   `json:"score"`
+	MatchedTerms []string  `json:"matched_terms"` // Index terms the document matched, after stemming and fuzzy expansion
+	Snippets     []Snippet `json:"snippets"`
+	MatchCount   int       `json:"match_count"`
+	// Explanation is the score breakdown of mneme find --explain
+	Explanation *Explanation `json:"explanation,omitempty"`
 }
 
 // Snippet represents a preview of the matched content
 type Snippet struct {
-	LineNumber int
-	Content    string
-	Highlights []HighlightRange
+	Page       int    `json:"page,omitempty"`    // 1-based page of paged documents such as PDFs, 0 otherwise
+	Section    string `json:"section,omitempty"` // Sheet or slide of the line, e.g. "Sheet Budget", empty for most documents
+	LineNumber int    `json:"line"`              // 1-based line number, counted from the start of the page or section if any
+	Column     int    `json:"column"`            // 1-based byte column of the first match in the original line, 0 without matches
+	Content    string `json:"content"`
+	// Highlights are byte offsets into Content, which is trimmed and may be
+	// shortened around the first match
+	Highlights []HighlightRange `json:"highlights"`
 }
 
 // HighlightRange marks the start and end positions of a match within a snippet
 type HighlightRange struct {
-	Start int
-	End   int
+	Start int `json:"start"` // Inclusive byte offset
+	End   int `json:"end"`   // Exclusive byte offset
 }
@@ -10,6 +10,31 @@ type Segment struct {
 	TotalDocs     uint                 `json:"total_docs"`
 	TotalTokens   uint                 `json:"total_tokens"`
 	AvgDocLen     uint                 `json:"avg_doc_len"`
+	// Deleted marks documents whose postings are still in InvertedIndex but that
+	// were removed from the index. Scoring must skip them; nil means none.
+	Deleted *DeletionBitmap `json:"-"`
+}
+
+// IsDeleted reports whether a document was deleted from the segment
+func (s *Segment) IsDeleted(docID uint) bool {
+	return s.Deleted.Contains(docID)
+}
+
+// LiveDocFrequency returns the number of documents containing a term,
+// not counting deleted documents
+func (s *Segment) LiveDocFrequency(term string) int {
+	postings := s.InvertedIndex[term]
+	if s.Deleted.IsEmpty() {
+		return len(postings)
+	}
+
+	df := 0
+	for _, posting := range postings {
+		if !s.Deleted.Contains(posting.DocID) {
+			df++
+		}
+	}
+	return df
 }
 
 // ToPB converts a Segment to its protobuf representation
@@ -17,9 +42,14 @@ func (s *Segment) ToPB() *pb.Segment {
 	pbDocs := make([]*pb.Document, len(s.Docs))
 	for i, doc := range s.Docs {
 		pbDocs[i] = &pb.Document{
-			Id:         uint32(doc.ID),
-			Path:       doc.Path,
-			TokenCount: uint32(doc.TokenCount),
+			Id:           uint32(doc.ID),
+			Path:         doc.Path,
+			TokenCount:   uint32(doc.TokenCount),
+			ModTime:      doc.ModTime,
+			Size:         doc.Size,
+			ContentHash:  doc.ContentHash,
+			Source:       doc.Source,
+			FieldLengths: uintsToPB(doc.FieldLengths),
 		}
 	}
 
@@ -28,8 +58,10 @@ func (s *Segment) ToPB() *pb.Segment {
 		pbPostings := make([]*pb.Posting, len(postings))
 		for i, p := range postings {
 			pbPostings[i] = &pb.Posting{
-				DocId: uint32(p.DocID),
-				Freq:  uint32(p.Freq),
+				DocId:      uint32(p.DocID),
+				Freq:       uint32(p.Freq),
+				Positions:  uintsToPB(p.Positions),
+				FieldFreqs: uintsToPB(p.FieldFreqs),
 			}
 		}
 		pbIndex[term] = &pb.PostingList{Postings: pbPostings}
@@ -49,9 +81,14 @@ func SegmentFromPB(pbSeg *pb.Segment) *Segment {
 	docs := make([]Document, len(pbSeg.Docs))
 	for i, pbDoc := range pbSeg.Docs {
 		docs[i] = Document{
-			ID:         uint(pbDoc.Id),
-			Path:       pbDoc.Path,
-			TokenCount: uint(pbDoc.TokenCount),
+			ID:           uint(pbDoc.Id),
+			Path:         pbDoc.Path,
+			TokenCount:   uint(pbDoc.TokenCount),
+			ModTime:      pbDoc.ModTime,
+			Size:         pbDoc.Size,
+			ContentHash:  pbDoc.ContentHash,
+			Source:       pbDoc.Source,
+			FieldLengths: uintsFromPB(pbDoc.FieldLengths),
 		}
 	}
 
@@ -60,8 +97,10 @@ func SegmentFromPB(pbSeg *pb.Segment) *Segment {
 		postings := make([]Posting, len(pbList.Postings))
 		for i, pbP := range pbList.Postings {
 			postings[i] = Posting{
-				DocID: uint(pbP.DocId),
-				Freq:  uint(pbP.Freq),
+				DocID:      uint(pbP.DocId),
+				Freq:       uint(pbP.Freq),
+				Positions:  uintsFromPB(pbP.Positions),
+				FieldFreqs: uintsFromPB(pbP.FieldFreqs),
 			}
 		}
 		invertedIndex[term] = postings
@@ -75,3 +114,28 @@ func SegmentFromPB(pbSeg *pb.Segment) *Segment {
 		AvgDocLen:     uint(pbSeg.AvgDocLen),
 	}
 }
+
+// uintsToPB converts term positions or field counts to their protobuf representation
+func uintsToPB(values []uint) []uint32 {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]uint32, len(values))
+	for i, v := range values {
+		out[i] = uint32(v)
+	}
+	return out
+}
+
+// uintsFromPB converts protobuf term positions or field counts back to their
+// in-memory representation
+func uintsFromPB(values []uint32) []uint {
+	if len(values) == 0 {
+		return nil
+	}
+	out := make([]uint, len(values))
+	for i, v := range values {
+		out[i] = uint(v)
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package display
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"mneme/internal/core"
+)
+
+// printExplanation prints the score breakdown of a result as a tree
+func printExplanation(explanation *core.Explanation) {
+	writeExplanation(os.Stdout, explanation)
+}
+
+// writeExplanation writes the score breakdown of a result as a tree
+func writeExplanation(w io.Writer, e *core.Explanation) {
+	fmt.Fprintf(w, "  %s\n", lineNumColor("Explanation"))
+
+	var lines []string
+	passTotals := make([]string, len(e.Passes))
+	for i, pass := range e.Passes {
+		passTotals[i] = fmt.Sprintf("%s %.4f", pass.Pass, pass.Score)
+	}
+	lines = append(lines, fmt.Sprintf("score %s = (%s + phrase %.4f) × recency %.4f",
+		scoreColor(fmt.Sprintf("%.4f", e.Score)), strings.Join(passTotals, " + "), e.PhraseBoost, e.RecencyFactor))
+	lines = append(lines, fmt.Sprintf("weights: %s %.2f, vsm %.2f, fuzzy penalty %.2f, phrase %.2f, recency half-life %d days",
+		e.Scorer, e.Weights.Relevance, e.Weights.VSM, e.Weights.FuzzyPenalty, e.Weights.PhraseBoost, e.Weights.RecencyHalfLifeDays))
+
+	var passChildren [][]string
+	for _, pass := range e.Passes {
+		lines = append(lines, fmt.Sprintf("%s: %s %.4f / max %.4f, vsm cosine %.4f → %.4f",
+			pass.Pass, e.Scorer, pass.Relevance, pass.MaxRelevance, pass.VSM, pass.Score))
+
+		children := make([]string, len(pass.Terms))
+		for i, term := range pass.Terms {
+			children[i] = formatTermExplanation(term)
+		}
+		passChildren = append(passChildren, children)
+	}
+	if e.TieBreak != "" {
+		lines = append(lines, "ranked after the previous result by "+e.TieBreak)
+	}
+
+	for i, line := range lines {
+		last := i == len(lines)-1
+		fmt.Fprintf(w, "  %s%s\n", treeBranch(last), line)
+
+		// Passes follow the score and weights lines
+		if pass := i - 2; pass >= 0 && pass < len(passChildren) {
+			for j, child := range passChildren[pass] {
+				fmt.Fprintf(w, "  %s%s%s\n", treeIndent(last), treeBranch(j == len(passChildren[pass])-1), child)
+			}
+		}
+	}
+}
+
+// formatTermExplanation formats the statistics of a term in a document
+func formatTermExplanation(term core.TermExplanation) string {
+	var b strings.Builder
+	b.WriteString(matchColor(term.Term))
+	if term.ExpandedFrom != "" {
+		fmt.Fprintf(&b, " (fuzzy from %q, distance %d)", term.ExpandedFrom, term.Distance)
+	}
+	fmt.Fprintf(&b, ": tf %d", term.TF)
+
+	if len(term.FieldTF) > 0 {
+		fields := make([]string, 0, len(term.FieldTF))
+		for field, tf := range term.FieldTF {
+			fields = append(fields, fmt.Sprintf("%s %d", field, tf))
+		}
+		sort.Strings(fields)
+		fmt.Fprintf(&b, " (%s)", strings.Join(fields, ", "))
+	}
+
+	fmt.Fprintf(&b, ", df %d, idf %.4f, length %.0f / avg %.1f → norm %.4f, score %.4f",
+		term.DF, term.IDF, term.DocLen, term.AvgDocLen, term.LengthNorm, term.Score)
+	return b.String()
+}
+
+// treeBranch returns the branch drawn before a node of a tree
+func treeBranch(last bool) string {
+	if last {
+		return "└─ "
+	}
+	return "├─ "
+}
+
+// treeIndent returns the indentation of the children of a node of a tree
+func treeIndent(last bool) string {
+	if last {
+		return "   "
+	}
+	return "│  "
+}
@@ -0,0 +1,44 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"mneme/internal/core"
+)
+
+func TestWriteExplanation(t *testing.T) {
+	explanation := &core.Explanation{
+		Scorer:        "bm25f",
+		Weights:       core.ExplanationWeights{Relevance: 0.7, VSM: 0.3, FuzzyPenalty: 0.8, PhraseBoost: 0.5, RecencyHalfLifeDays: 30},
+		RecencyFactor: 0.9,
+		Score:         0.9,
+		TieBreak:      "match_count",
+		Passes: []core.PassExplanation{
+			{Pass: "exact", Relevance: 2, MaxRelevance: 2, VSM: 1, Score: 1, Terms: []core.TermExplanation{
+				{Term: "deploy", TF: 2, FieldTF: map[string]uint{"filename": 1}, DF: 1, IDF: 0.98, DocLen: 10, AvgDocLen: 20, LengthNorm: 0.625, Score: 2},
+			}},
+			{Pass: "fuzzy", Terms: []core.TermExplanation{
+				{Term: "deplay", ExpandedFrom: "deploy", Distance: 1},
+			}},
+		},
+	}
+
+	var buf bytes.Buffer
+	writeExplanation(&buf, explanation)
+	output := buf.String()
+
+	for _, expected := range []string{
+		"├─ score 0.9000 = (exact 1.0000 + fuzzy 0.0000 + phrase 0.0000) × recency 0.9000",
+		"├─ weights: bm25f 0.70, vsm 0.30, fuzzy penalty 0.80, phrase 0.50, recency half-life 30 days",
+		"├─ exact: bm25f 2.0000 / max 2.0000, vsm cosine 1.0000 → 1.0000",
+		"│  └─ deploy: tf 2 (filename 1), df 1, idf 0.9800, length 10 / avg 20.0 → norm 0.6250, score 2.0000",
+		`│  └─ deplay (fuzzy from "deploy", distance 1): tf 0`,
+		"└─ ranked after the previous result by match_count",
+	} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected %q in\n%s", expected, output)
+		}
+	}
+}
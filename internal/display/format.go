@@ -0,0 +1,111 @@
+package display
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"mneme/internal/core"
+)
+
+// OutputFormat selects how search results are written
+type OutputFormat string
+
+const (
+	// FormatText prints colourised results for humans
+	FormatText OutputFormat = "text"
+	// FormatJSON writes a single JSON object holding the query and all results
+	FormatJSON OutputFormat = "json"
+	// FormatNDJSON writes one JSON encoded core.SearchResult per line
+	FormatNDJSON OutputFormat = "ndjson"
+	// FormatPaths writes the path of every result on its own line
+	FormatPaths OutputFormat = "paths"
+	// FormatVimgrep writes one path:line:column:content line per snippet, as
+	// understood by Vim's quickfix list and most editors
+	FormatVimgrep OutputFormat = "vimgrep"
+)
+
+// OutputFormats lists the supported output formats
+var OutputFormats = []OutputFormat{FormatText, FormatJSON, FormatNDJSON, FormatPaths, FormatVimgrep}
+
+// JSONResults is the document written by FormatJSON and returned by the
+// search endpoint of mneme serve
+type JSONResults struct {
+	Query       string               `json:"query"`
+	Corrections map[string]string    `json:"corrections,omitempty"` // Misspelled words and their corrections
+	Results     []*core.SearchResult `json:"results"`
+}
+
+// ParseOutputFormat parses the name of an output format
+func ParseOutputFormat(name string) (OutputFormat, error) {
+	for _, format := range OutputFormats {
+		if strings.EqualFold(name, string(format)) {
+			return format, nil
+		}
+	}
+
+	names := make([]string, len(OutputFormats))
+	for i, format := range OutputFormats {
+		names[i] = string(format)
+	}
+	return "", fmt.Errorf("unknown output format %q, expected one of %s", name, strings.Join(names, ", "))
+}
+
+// IsMachineReadable reports whether the format is meant for other programs.
+// Machine-readable output must be the only output on stdout.
+func (f OutputFormat) IsMachineReadable() bool {
+	return f != FormatText
+}
+
+// WriteResults writes search results in the given format. Text output is
+// printed to stdout like PrintResults; every other format is written to w.
+func WriteResults(w io.Writer, response JSONResults, format OutputFormat) error {
+	results := response.Results
+	switch format {
+	case FormatText:
+		PrintResults(results, true, response.Query)
+		return nil
+	case FormatJSON:
+		if response.Results == nil {
+			response.Results = []*core.SearchResult{}
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(response)
+	case FormatNDJSON:
+		encoder := json.NewEncoder(w)
+		for _, result := range results {
+			if err := encoder.Encode(result); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatPaths:
+		for _, result := range results {
+			if _, err := fmt.Fprintln(w, result.DocPath); err != nil {
+				return err
+			}
+		}
+		return nil
+	case FormatVimgrep:
+		for _, result := range results {
+			for _, snippet := range result.Snippets {
+				line, column := snippet.LineNumber, max(snippet.Column, 1)
+				content := strings.ReplaceAll(snippet.Content, "\n", " ")
+				if snippet.Page > 0 || snippet.Section != "" {
+					// Lines of pages, sheets, slides and cells are not lines of the
+					// file, so editors jump to its start and the text names the location
+					line, column = 1, 1
+					content = fmt.Sprintf("[%s] %s", snippetLocation(snippet), content)
+				}
+				if _, err := fmt.Fprintf(w, "%s:%d:%d:%s\n", result.DocPath, line, column, content); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("unknown output format %q", format)
+}
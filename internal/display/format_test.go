@@ -0,0 +1,162 @@
+package display
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"mneme/internal/core"
+)
+
+func createTestResults() []*core.SearchResult {
+	return []*core.SearchResult{
+		{
+			DocPath:      "/notes/k8s.md",
+			Score:        1,
+			MatchedTerms: []string{"kubernet"},
+			MatchCount:   2,
+			Snippets: []core.Snippet{
+				{LineNumber: 3, Column: 5, Content: "use kubernetes", Highlights: []core.HighlightRange{{Start: 4, End: 14}}},
+				{LineNumber: 9, Column: 1, Content: "kubernetes: yes", Highlights: []core.HighlightRange{{Start: 0, End: 10}}},
+			},
+		},
+		{
+			DocPath:      "/notes/helm.md",
+			Score:        0.5,
+			MatchedTerms: []string{"kubernet"},
+			MatchCount:   1,
+			Snippets:     []core.Snippet{{LineNumber: 1, Content: "no highlights", Highlights: []core.HighlightRange{}}},
+		},
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, format := range OutputFormats {
+		parsed, err := ParseOutputFormat(strings.ToUpper(string(format)))
+		if err != nil || parsed != format {
+			t.Errorf("ParseOutputFormat(%q) = %q, %v", format, parsed, err)
+		}
+	}
+
+	if _, err := ParseOutputFormat("xml"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if FormatText.IsMachineReadable() || !FormatJSON.IsMachineReadable() {
+		t.Error("Only the text format is meant for humans")
+	}
+}
+
+func TestWriteResults(t *testing.T) {
+	results := createTestResults()
+
+	t.Run("json", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, JSONResults{Query: "kubernetes", Results: results}, FormatJSON); err != nil {
+			t.Fatalf("WriteResults failed: %v", err)
+		}
+
+		var decoded map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		if decoded["query"] != "kubernetes" {
+			t.Errorf("Expected the query, got %v", decoded["query"])
+		}
+
+		// The documented schema
+		first := decoded["results"].([]any)[0].(map[string]any)
+		for _, key := range []string{"path", "score", "matched_terms", "snippets", "match_count"} {
+			if _, ok := first[key]; !ok {
+				t.Errorf("Missing key %q in %v", key, first)
+			}
+		}
+		snippet := first["snippets"].([]any)[0].(map[string]any)
+		for _, key := range []string{"line", "column", "content", "highlights"} {
+			if _, ok := snippet[key]; !ok {
+				t.Errorf("Missing key %q in %v", key, snippet)
+			}
+		}
+		highlight := snippet["highlights"].([]any)[0].(map[string]any)
+		if highlight["start"] != 4.0 || highlight["end"] != 14.0 {
+			t.Errorf("Unexpected highlight %v", highlight)
+		}
+	})
+
+	t.Run("json without results", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, JSONResults{Query: "nothing", Results: nil}, FormatJSON); err != nil {
+			t.Fatalf("WriteResults failed: %v", err)
+		}
+		if !strings.Contains(buf.String(), `"results": []`) {
+			t.Errorf("Expected an empty result list, got %s", buf.String())
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, JSONResults{Query: "kubernetes", Results: results}, FormatNDJSON); err != nil {
+			t.Fatalf("WriteResults failed: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("Expected one line per result, got %q", buf.String())
+		}
+		var decoded core.SearchResult
+		if err := json.Unmarshal([]byte(lines[1]), &decoded); err != nil {
+			t.Fatalf("Invalid JSON line: %v", err)
+		}
+		if decoded.DocPath != "/notes/helm.md" {
+			t.Errorf("Expected the second result, got %s", decoded.DocPath)
+		}
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, JSONResults{Query: "kubernetes", Results: results}, FormatPaths); err != nil {
+			t.Fatalf("WriteResults failed: %v", err)
+		}
+		if expected := "/notes/k8s.md\n/notes/helm.md\n"; buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	t.Run("vimgrep", func(t *testing.T) {
+		var buf bytes.Buffer
+		if err := WriteResults(&buf, JSONResults{Query: "kubernetes", Results: results}, FormatVimgrep); err != nil {
+			t.Fatalf("WriteResults failed: %v", err)
+		}
+		expected := "/notes/k8s.md:3:5:use kubernetes\n/notes/k8s.md:9:1:kubernetes: yes\n/notes/helm.md:1:1:no highlights\n"
+		if buf.String() != expected {
+			t.Errorf("Expected %q, got %q", expected, buf.String())
+		}
+	})
+
+	// Lines counted within pages and sections point at the start of the file
+	extracted := []struct {
+		name     string
+		result   *core.SearchResult
+		expected string
+	}{
+		{"pdf", &core.SearchResult{DocPath: "/docs/manual.pdf", Snippets: []core.Snippet{{Page: 2, LineNumber: 5, Column: 3, Content: "a kubernetes cluster"}}},
+			"/docs/manual.pdf:1:1:[Pg 2, Ln 5] a kubernetes cluster\n"},
+		{"spreadsheet", &core.SearchResult{DocPath: "/docs/budget.xlsx", Snippets: []core.Snippet{{Section: "Sheet Budget", LineNumber: 12, Column: 1, Content: "kubernetes | 400"}}},
+			"/docs/budget.xlsx:1:1:[Sheet Budget, Ln 12] kubernetes | 400\n"},
+		{"presentation", &core.SearchResult{DocPath: "/docs/roadmap.pptx", Snippets: []core.Snippet{{Section: "Slide 2 (Roadmap)", LineNumber: 1, Column: 7, Content: "Move kubernetes"}}},
+			"/docs/roadmap.pptx:1:1:[Slide 2 (Roadmap), Ln 1] Move kubernetes\n"},
+		{"notebook", &core.SearchResult{DocPath: "/docs/churn.ipynb", Snippets: []core.Snippet{{Section: "Cell 3 (output)", LineNumber: 2, Column: 4, Content: "kubernetes ready"}}},
+			"/docs/churn.ipynb:1:1:[Cell 3 (output), Ln 2] kubernetes ready\n"},
+	}
+	for _, tt := range extracted {
+		t.Run("vimgrep "+tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteResults(&buf, JSONResults{Results: []*core.SearchResult{tt.result}}, FormatVimgrep); err != nil {
+				t.Fatalf("WriteResults failed: %v", err)
+			}
+			if buf.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, buf.String())
+			}
+		})
+	}
+}
@@ -8,8 +8,9 @@ import (
 	"unicode"
 
 	"mneme/internal/core"
+	"mneme/internal/extract"
 	"mneme/internal/index"
-	"mneme/internal/storage"
+	"mneme/internal/logger"
 
 	"github.com/fatih/color"
 )
@@ -34,19 +35,21 @@ var (
 // FormatSearchResult takes a document path and query tokens, reads the file,
 // and returns a formatted SearchResult with snippets
 func FormatSearchResult(docPath string, queryTokens []string, score float64) (*core.SearchResult, error) {
-	lines, err := storage.ReadFileContents(docPath)
+	text, err := extract.ReadFile(docPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read file %s: %w", docPath, err)
 	}
 
 	result := &core.SearchResult{
 		DocPath:  docPath,
+		Title:    text.Title,
 		Score:    score,
 		Snippets: []core.Snippet{},
 	}
 
 	type matchLine struct {
-		lineNum     int
+		index       int // Index of the line in the extracted text
+		location    extract.Location
 		line        string
 		matches     []core.HighlightRange
 		score       int // score based on unique terms + total matches
@@ -57,7 +60,7 @@ func FormatSearchResult(docPath string, queryTokens []string, score float64) (*c
 	totalMatches := 0
 
 	// Find all matching lines
-	for lineNum, line := range lines {
+	for i, line := range text.Lines {
 		matches := findMatchesInLine(line, queryTokens)
 		if len(matches) > 0 {
 			totalMatches += len(matches)
@@ -79,7 +82,8 @@ func FormatSearchResult(docPath string, queryTokens []string, score float64) (*c
 			lineScore := (uniqueCount * 100) + len(matches)
 
 			candidates = append(candidates, matchLine{
-				lineNum:     lineNum + 1,
+				index:       i,
+				location:    text.Location(i),
 				line:        line,
 				matches:     matches,
 				score:       lineScore,
@@ -105,12 +109,14 @@ func FormatSearchResult(docPath string, queryTokens []string, score float64) (*c
 
 	// Re-sort selected snippets by line number for coherent display
 	sort.Slice(topCandidates, func(i, j int) bool {
-		return topCandidates[i].lineNum < topCandidates[j].lineNum
+		return topCandidates[i].index < topCandidates[j].index
 	})
 
 	// Create snippets
 	for _, c := range topCandidates {
-		snippet := createSnippet(c.lineNum, c.line, c.matches)
+		snippet := createSnippet(c.location.Line, c.line, c.matches)
+		snippet.Page = c.location.Page
+		snippet.Section = c.location.Section
 		result.Snippets = append(result.Snippets, snippet)
 	}
 
@@ -118,6 +124,57 @@ func FormatSearchResult(docPath string, queryTokens []string, score float64) (*c
 	return result, nil
 }
 
+// FormatSearchResults formats ranked documents with snippets of the highlight
+// terms. Documents without a matching line are dropped, which filters out false
+// positives of stemming, unless their path contains one of the terms: such
+// documents matched by their file name or directories and are kept without
+// snippets.
+func FormatSearchResults(rankedDocs []core.RankedDocument, highlightTerms []string) []*core.SearchResult {
+	var results []*core.SearchResult
+	for _, doc := range rankedDocs {
+		// The terms as typed and corrected highlight better than the stemmed
+		// and fuzzy matched terms, e.g. "find" instead of "fnid"
+		result, err := FormatSearchResult(doc.Path, highlightTerms, doc.Score)
+		if err != nil {
+			logger.Debugf("Failed to format result for %s: %v", doc.Path, err)
+			continue
+		}
+
+		// Fall back to the terms that matched during ranking, including fuzzy
+		// expansions, if the query terms do not occur literally
+		if len(result.Snippets) == 0 {
+			result, err = FormatSearchResult(doc.Path, doc.MatchedTerms, doc.Score)
+			if err != nil {
+				continue
+			}
+			if len(result.Snippets) == 0 && !pathContainsAny(doc.Path, highlightTerms, doc.MatchedTerms) {
+				continue
+			}
+		}
+
+		result.MatchedTerms = doc.MatchedTerms
+		result.Explanation = doc.Explanation
+		if result.MatchedTerms == nil {
+			result.MatchedTerms = []string{}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// pathContainsAny reports whether a path contains any of the terms, ignoring case
+func pathContainsAny(path string, termLists ...[]string) bool {
+	path = strings.ToLower(path)
+	for _, terms := range termLists {
+		for _, term := range terms {
+			if term != "" && strings.Contains(path, strings.ToLower(term)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // findMatchesInLine finds all positions where query tokens match in a line
 func findMatchesInLine(line string, queryTokens []string) []core.HighlightRange {
 	var matches []core.HighlightRange
@@ -332,8 +389,14 @@ func createSnippet(lineNum int, line string, matches []core.HighlightRange) core
 		highlights = newHighlights
 	}
 
+	column := 0
+	if len(matches) > 0 {
+		column = matches[0].Start + 1
+	}
+
 	return core.Snippet{
 		LineNumber: lineNum,
+		Column:     column,
 		Content:    content,
 		Highlights: highlights,
 	}
@@ -343,6 +406,9 @@ func createSnippet(lineNum int, line string, matches []core.HighlightRange) core
 func PrintResult(result *core.SearchResult, showScore bool) {
 	// Print document path
 	fmt.Printf("%s\n", pathColor(result.DocPath))
+	if result.Title != "" {
+		fmt.Printf("  %s\n", result.Title)
+	}
 
 	// Print score if requested
 	if showScore && result.Score > 0 {
@@ -351,7 +417,7 @@ func PrintResult(result *core.SearchResult, showScore bool) {
 
 	// Print snippets
 	for _, snippet := range result.Snippets {
-		linePrefix := fmt.Sprintf("  %s: ", lineNumColor(fmt.Sprintf("Ln %d", snippet.LineNumber)))
+		linePrefix := fmt.Sprintf("  %s: ", lineNumColor(snippetLocation(snippet)))
 		fmt.Print(linePrefix)
 
 		// Print content with highlights
@@ -359,10 +425,26 @@ func PrintResult(result *core.SearchResult, showScore bool) {
 		fmt.Println()
 	}
 
+	if result.Explanation != nil {
+		printExplanation(result.Explanation)
+	}
+
 	// Print separator
 	fmt.Println()
 }
 
+// snippetLocation formats the page or section and the line of a snippet,
+// e.g. "Pg 2, Ln 5" or "Sheet Budget, Ln 12"
+func snippetLocation(snippet core.Snippet) string {
+	if snippet.Section != "" {
+		return fmt.Sprintf("%s, Ln %d", snippet.Section, snippet.LineNumber)
+	}
+	if snippet.Page > 0 {
+		return fmt.Sprintf("Pg %d, Ln %d", snippet.Page, snippet.LineNumber)
+	}
+	return fmt.Sprintf("Ln %d", snippet.LineNumber)
+}
+
 // printHighlightedContent prints the content with highlighted matches
 func printHighlightedContent(content string, highlights []core.HighlightRange) {
 	lastEnd := 0
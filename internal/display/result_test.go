@@ -0,0 +1,149 @@
+package display
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"mneme/internal/core"
+	"mneme/internal/storage"
+)
+
+// testPDF is a two page PDF without an xref table, which readers recover from
+const testPDF = `%PDF-1.4
+1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj
+2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 /Resources << /Font << /F1 5 0 R >> >> >> endobj
+3 0 obj << /Type /Page /Parent 2 0 R /Contents 6 0 R >> endobj
+4 0 obj << /Type /Page /Parent 2 0 R /Contents 7 0 R >> endobj
+5 0 obj << /Type /Font /Subtype /Type1 /BaseFont /Helvetica >> endobj
+6 0 obj << /Length 44 >>
+stream
+BT /F1 12 Tf 72 720 Td (Introduction) Tj ET
+endstream
+endobj
+7 0 obj << /Length 75 >>
+stream
+BT /F1 12 Tf 14 TL 72 720 Td (Background) Tj T* (Kubernetes clusters) Tj ET
+endstream
+endobj
+%%EOF
+`
+
+func TestFormatSearchResultPDF(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "guide.pdf")
+	if err := os.WriteFile(path, []byte(testPDF), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := FormatSearchResult(path, []string{"kubernetes"}, 1)
+	if err != nil {
+		t.Fatalf("FormatSearchResult failed: %v", err)
+	}
+	if len(result.Snippets) != 1 {
+		t.Fatalf("Expected one snippet, got %+v", result.Snippets)
+	}
+
+	snippet := result.Snippets[0]
+	if snippet.Page != 2 || snippet.LineNumber != 2 || snippet.Content != "Kubernetes clusters" {
+		t.Errorf("Expected line 2 of page 2, got %+v", snippet)
+	}
+	if location := snippetLocation(snippet); location != "Pg 2, Ln 2" {
+		t.Errorf("Expected the page in the location, got %q", location)
+	}
+}
+
+func TestFormatSearchResultMarkup(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]struct {
+		content string
+		line    int
+	}{
+		"page.html": {"<html><head><title>Cluster guide</title></head>\n<body>\n<p>Managing <b>Kubernetes</b> clusters</p>\n</body></html>", 3},
+		"notes.md":  {"---\ntitle: Cluster notes\n---\n\nManaging [Kubernetes](https://kubernetes.io) clusters", 5},
+	}
+
+	for name, file := range files {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(file.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		result, err := FormatSearchResult(path, []string{"kubernetes"}, 1)
+		if err != nil {
+			t.Fatalf("FormatSearchResult(%s) failed: %v", name, err)
+		}
+		if len(result.Snippets) != 1 {
+			t.Fatalf("%s: expected one snippet, got %+v", name, result.Snippets)
+		}
+		// Line numbers are those of the source, without the markup
+		if snippet := result.Snippets[0]; snippet.Content != "Managing Kubernetes clusters" || snippet.LineNumber != file.line {
+			t.Errorf("%s: expected the text of the source line, got %+v", name, snippet)
+		}
+		if result.Title == "" {
+			t.Errorf("%s: expected the title of the document", name)
+		}
+	}
+}
+
+func TestFormatSearchResultArchiveMember(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	fw, err := w.Create("docs/readme.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write([]byte("# Readme\n\nManaging Kubernetes clusters\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(t.TempDir(), "backup.zip")
+	if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := FormatSearchResult(archive+storage.ArchiveSeparator+"docs/readme.md", []string{"kubernetes"}, 1)
+	if err != nil {
+		t.Fatalf("FormatSearchResult failed: %v", err)
+	}
+	if len(result.Snippets) != 1 || result.Snippets[0].LineNumber != 3 || result.Snippets[0].Content != "Managing Kubernetes clusters" {
+		t.Errorf("Expected line 3 of the member, got %+v", result.Snippets)
+	}
+}
+
+func TestFormatSearchResultNotebook(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "analysis.ipynb")
+	notebook := `{"cells": [
+		{"cell_type": "markdown", "source": ["# Analysis"]},
+		{"cell_type": "code", "source": ["import pandas\n", "clusters = pandas.read_csv('kubernetes.csv')"], "outputs": []}
+	]}`
+	if err := os.WriteFile(path, []byte(notebook), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := FormatSearchResult(path, []string{"kubernetes"}, 1)
+	if err != nil {
+		t.Fatalf("FormatSearchResult failed: %v", err)
+	}
+	if len(result.Snippets) != 1 {
+		t.Fatalf("Expected one snippet, got %+v", result.Snippets)
+	}
+	if location := snippetLocation(result.Snippets[0]); location != "Cell 2, Ln 2" {
+		t.Errorf("Expected the cell and line in the location, got %q", location)
+	}
+}
+
+func TestSnippetLocation(t *testing.T) {
+	if location := snippetLocation(core.Snippet{LineNumber: 7}); location != "Ln 7" {
+		t.Errorf("Expected only the line of a text file, got %q", location)
+	}
+	if location := snippetLocation(core.Snippet{LineNumber: 3, Page: 2}); location != "Pg 2, Ln 3" {
+		t.Errorf("Expected the page and line of a PDF, got %q", location)
+	}
+	if location := snippetLocation(core.Snippet{LineNumber: 12, Section: "Sheet Budget"}); location != "Sheet Budget, Ln 12" {
+		t.Errorf("Expected the section and line of a spreadsheet, got %q", location)
+	}
+}
@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"path/filepath"
+
+	"mneme/internal/core"
+	"mneme/internal/query"
+)
+
+// DefaultCutoff is the number of results evaluated per query when no cutoff is given
+const DefaultCutoff = 10
+
+// QueryResult holds the evaluation of a single query
+type QueryResult struct {
+	ID      string   `json:"id"`
+	Query   string   `json:"query"`
+	Metrics Metrics  `json:"metrics"`
+	Ranked  []string `json:"ranked"`          // Paths of the top results
+	Error   string   `json:"error,omitempty"` // Why the query could not be run
+}
+
+// Report holds the evaluation of all queries of a set of judgements
+type Report struct {
+	Cutoff  int           `json:"cutoff"`
+	Scorer  string        `json:"scorer"`
+	Queries []QueryResult `json:"queries"`
+	Mean    Metrics       `json:"mean"`
+}
+
+// QueryDiff compares the evaluation of a query under two configurations
+type QueryDiff struct {
+	ID    string  `json:"id"`
+	Query string  `json:"query"`
+	Base  Metrics `json:"base"`
+	Other Metrics `json:"other"`
+	Delta Metrics `json:"delta"` // Other minus Base
+}
+
+// Run ranks the documents of the index for every judged query with the same
+// search pipeline as mneme find and evaluates the top cutoff results
+func Run(index *core.IndexReader, judgements *Judgements, rankingCfg *core.RankingConfig, cutoff int) *Report {
+	if cutoff <= 0 {
+		cutoff = DefaultCutoff
+	}
+
+	report := &Report{Cutoff: cutoff, Scorer: scorerName(rankingCfg), Queries: make([]QueryResult, len(judgements.Queries))}
+	all := make([]Metrics, len(judgements.Queries))
+	for i, judged := range judgements.Queries {
+		result := QueryResult{ID: judged.ID, Query: judged.Query, Ranked: []string{}}
+
+		search, err := query.PrepareSearch(index, query.SplitQueryArgs(judged.Query))
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			for _, doc := range search.Rank(index, cutoff, rankingCfg) {
+				result.Ranked = append(result.Ranked, filepath.Clean(doc.Path))
+			}
+		}
+
+		result.Metrics = Evaluate(result.Ranked, judged.Relevant, cutoff)
+		report.Queries[i] = result
+		all[i] = result.Metrics
+	}
+	report.Mean = meanMetrics(all)
+	return report
+}
+
+// Compare returns the differences between two evaluations of the same judgements
+func Compare(base, other *Report) []QueryDiff {
+	otherByID := make(map[string]QueryResult, len(other.Queries))
+	for _, result := range other.Queries {
+		otherByID[result.ID] = result
+	}
+
+	diffs := make([]QueryDiff, 0, len(base.Queries))
+	for _, result := range base.Queries {
+		otherResult, ok := otherByID[result.ID]
+		if !ok {
+			continue
+		}
+		diffs = append(diffs, QueryDiff{
+			ID:    result.ID,
+			Query: result.Query,
+			Base:  result.Metrics,
+			Other: otherResult.Metrics,
+			Delta: otherResult.Metrics.Sub(result.Metrics),
+		})
+	}
+	return diffs
+}
+
+// scorerName returns the name of the scorer selected by a ranking configuration
+func scorerName(rankingCfg *core.RankingConfig) string {
+	scorer, err := query.NewScorer(rankingCfg)
+	if err != nil {
+		return query.ScorerBM25F
+	}
+	return scorer.Name()
+}
@@ -0,0 +1,194 @@
+package eval
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mneme/internal/config"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/index"
+	"mneme/internal/ingest"
+	"mneme/internal/storage"
+)
+
+// Minimum mean metrics of the default ranking over the fixtures at
+// fixtureCutoff. Lower them only for deliberate ranking changes.
+const (
+	fixtureCutoff  = 5
+	minFixtureNDCG = 0.9
+	minFixtureMRR  = 0.95
+)
+
+// openFixtureIndex indexes the synthetic corpus in testdata into a temporary
+// data directory and opens it
+func openFixtureIndex(t *testing.T) *core.IndexReader {
+	t.Helper()
+
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+	if err := os.MkdirAll(filepath.Join(constants.DirPath, "segments"), 0755); err != nil {
+		t.Fatalf("Failed to create segments dir: %v", err)
+	}
+
+	corpusDir, err := filepath.Abs(filepath.Join("testdata", "corpus"))
+	if err != nil {
+		t.Fatalf("Failed to resolve corpus: %v", err)
+	}
+	registry := ingest.NewRegistry()
+	registry.Register(ingest.NewFilesystemIngestor([]string{corpusDir}, nil))
+
+	options := core.DefaultCrawlerOptions()
+	batchConfig := core.DefaultBatchConfig()
+	batchConfig.SuppressLogs = true
+	if _, err := index.IndexBuilderBatchedWithRegistry(registry, &options, batchConfig); err != nil {
+		t.Fatalf("Failed to index the corpus: %v", err)
+	}
+
+	reader, err := storage.OpenIndexReader()
+	if err != nil {
+		t.Fatalf("Failed to open the index: %v", err)
+	}
+	t.Cleanup(func() { reader.Close() })
+	return reader
+}
+
+// loadFixtureJudgements loads the JSON judgements of the fixtures
+func loadFixtureJudgements(t *testing.T) *Judgements {
+	t.Helper()
+	root, _ := filepath.Abs("testdata")
+	judgements, err := LoadJudgements(filepath.Join("testdata", "judgements.json"), "", root)
+	if err != nil {
+		t.Fatalf("Failed to load judgements: %v", err)
+	}
+	return judgements
+}
+
+// fixtureRanking returns the default ranking configuration without the
+// recency boost, which would depend on the modification times of the checkout
+func fixtureRanking() *core.RankingConfig {
+	ranking := config.DefaultConfig.Ranking
+	ranking.RecencyHalfLifeDays = 0
+	return &ranking
+}
+
+func TestEvaluate(t *testing.T) {
+	relevant := map[string]int{"/a": 2, "/b": 1, "/c": 0}
+
+	perfect := Evaluate([]string{"/a", "/b", "/c"}, relevant, 3)
+	if perfect.NDCG != 1 || perfect.MRR != 1 || perfect.Recall != 1 || math.Abs(perfect.Precision-2.0/3) > 1e-9 {
+		t.Errorf("Unexpected metrics of the ideal ranking: %+v", perfect)
+	}
+
+	swapped := Evaluate([]string{"/c", "/b", "/a"}, relevant, 3)
+	expectedNDCG := (1/math.Log2(3) + 3/math.Log2(4)) / (3 + 1/math.Log2(3))
+	if math.Abs(swapped.NDCG-expectedNDCG) > 1e-9 || swapped.MRR != 0.5 {
+		t.Errorf("Unexpected metrics of the reversed ranking: %+v", swapped)
+	}
+
+	cut := Evaluate([]string{"/c", "/b", "/a"}, relevant, 1)
+	if cut != (Metrics{}) {
+		t.Errorf("Expected no matches in the top 1, got %+v", cut)
+	}
+
+	if empty := Evaluate(nil, relevant, 10); empty != (Metrics{}) {
+		t.Errorf("Expected zero metrics without results, got %+v", empty)
+	}
+}
+
+func TestLoadJudgements(t *testing.T) {
+	root, _ := filepath.Abs("testdata")
+	judgements := loadFixtureJudgements(t)
+
+	trec, err := LoadJudgements(filepath.Join("testdata", "qrels.txt"), filepath.Join("testdata", "queries.tsv"), root)
+	if err != nil {
+		t.Fatalf("Failed to load TREC judgements: %v", err)
+	}
+	if !reflect.DeepEqual(judgements, trec) {
+		t.Errorf("Expected the TREC fixtures to match the JSON fixtures, got %+v", trec)
+	}
+
+	helm := judgements.Queries[1]
+	expectedPath := filepath.Join(root, "corpus", "infra", "helm-charts.md")
+	if helm.ID != "helm" || helm.Relevant[expectedPath] != 2 {
+		t.Errorf("Expected resolved document paths, got %+v", helm)
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, content := range map[string]string{
+			"syntax.json":      `{"queries": [`,
+			"empty.json":       `{"queries": []}`,
+			"no-relevant.json": `{"queries": [{"id": "q", "query": "helm", "relevant": {"a.md": 0}}]}`,
+			"duplicate.json":   `{"queries": [{"id": "q", "query": "a", "relevant": {"a.md": 1}}, {"id": "q", "query": "b", "relevant": {"b.md": 1}}]}`,
+		} {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadJudgements(path, "", dir); !errors.Is(err, ErrInvalidJudgements) {
+				t.Errorf("%s: expected ErrInvalidJudgements, got %v", name, err)
+			}
+		}
+
+		if _, err := LoadJudgements(filepath.Join("testdata", "qrels.txt"), "", root); !errors.Is(err, ErrInvalidJudgements) {
+			t.Errorf("Expected an error for qrels without queries, got %v", err)
+		}
+	})
+}
+
+// TestFixtureRanking guards the ranking quality: it fails when a change to
+// indexing or ranking makes the default configuration rank the judged
+// documents of the fixtures worse
+func TestFixtureRanking(t *testing.T) {
+	reader := openFixtureIndex(t)
+	judgements := loadFixtureJudgements(t)
+
+	report := Run(reader, judgements, fixtureRanking(), fixtureCutoff)
+	for _, result := range report.Queries {
+		t.Logf("%-16s ndcg %.3f mrr %.3f %v", result.ID, result.Metrics.NDCG, result.Metrics.MRR, result.Ranked)
+		if result.Error != "" {
+			t.Errorf("Query %s failed: %s", result.ID, result.Error)
+		}
+	}
+
+	if report.Scorer != "bm25f" || report.Cutoff != fixtureCutoff {
+		t.Errorf("Unexpected report settings: %s at %d", report.Scorer, report.Cutoff)
+	}
+	if report.Mean.NDCG < minFixtureNDCG {
+		t.Errorf("Mean nDCG@%d dropped to %.3f (minimum %.3f)", fixtureCutoff, report.Mean.NDCG, minFixtureNDCG)
+	}
+	if report.Mean.MRR < minFixtureMRR {
+		t.Errorf("Mean MRR@%d dropped to %.3f (minimum %.3f)", fixtureCutoff, report.Mean.MRR, minFixtureMRR)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	reader := openFixtureIndex(t)
+	judgements := loadFixtureJudgements(t)
+
+	base := Run(reader, judgements, fixtureRanking(), fixtureCutoff)
+	same := Compare(base, Run(reader, judgements, fixtureRanking(), fixtureCutoff))
+	if len(same) != len(judgements.Queries) {
+		t.Fatalf("Expected a diff per query, got %d", len(same))
+	}
+	for _, diff := range same {
+		if diff.Delta != (Metrics{}) {
+			t.Errorf("Expected no difference for %s, got %+v", diff.ID, diff.Delta)
+		}
+	}
+
+	tfidf := fixtureRanking()
+	tfidf.Scorer = "tfidf"
+	other := Run(reader, judgements, tfidf, fixtureCutoff)
+	for _, diff := range Compare(base, other) {
+		if expected := diff.Other.Sub(diff.Base); diff.Delta != expected {
+			t.Errorf("Expected the delta %+v for %s, got %+v", expected, diff.ID, diff.Delta)
+		}
+	}
+}
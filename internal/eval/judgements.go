@@ -0,0 +1,215 @@
+// Package eval measures the quality of the ranking against queries with known
+// relevant documents (relevance judgements), so that ranking changes can be
+// compared with numbers instead of impressions.
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidJudgements is returned for judgement files that cannot be used
+var ErrInvalidJudgements = errors.New("invalid relevance judgements")
+
+// Judgements holds queries with their judged documents
+type Judgements struct {
+	Queries []JudgedQuery `json:"queries"`
+}
+
+// JudgedQuery is a query with the relevance of documents to it. Relevance is
+// graded: 0 is not relevant, higher grades are more relevant. Documents are
+// identified by their path.
+type JudgedQuery struct {
+	ID       string         `json:"id"`
+	Query    string         `json:"query"`
+	Relevant map[string]int `json:"relevant"`
+}
+
+// LoadJudgements reads relevance judgements. Files ending in .json use the
+// JSON format of Judgements; any other file is read as TREC qrels
+// ("query-id iteration document relevance" per line), whose query texts are
+// read from queriesPath ("query-id<TAB>query" per line). Relative document
+// paths are resolved against root.
+func LoadJudgements(path, queriesPath, root string) (*Judgements, error) {
+	var judgements *Judgements
+	var err error
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		judgements, err = loadJSONJudgements(path)
+	} else {
+		judgements, err = loadTRECJudgements(path, queriesPath)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := judgements.validate(); err != nil {
+		return nil, err
+	}
+	judgements.resolve(root)
+	return judgements, nil
+}
+
+// loadJSONJudgements reads judgements in the JSON format
+func loadJSONJudgements(path string) (*Judgements, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read judgements: %w", err)
+	}
+
+	var judgements Judgements
+	if err := json.Unmarshal(data, &judgements); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", ErrInvalidJudgements, path, err)
+	}
+	return &judgements, nil
+}
+
+// loadTRECJudgements reads TREC qrels and the texts of their queries
+func loadTRECJudgements(qrelsPath, queriesPath string) (*Judgements, error) {
+	if queriesPath == "" {
+		return nil, fmt.Errorf("%w: TREC qrels need a queries file", ErrInvalidJudgements)
+	}
+
+	queries := make(map[string]*JudgedQuery)
+	var order []string
+	err := readLines(queriesPath, func(lineNumber int, line string) error {
+		id, text, ok := strings.Cut(line, "\t")
+		if !ok {
+			fields := strings.Fields(line)
+			id, text = fields[0], strings.Join(fields[1:], " ")
+		}
+		id, text = strings.TrimSpace(id), strings.TrimSpace(text)
+		if text == "" {
+			return fmt.Errorf("%w: %s:%d: missing query text", ErrInvalidJudgements, queriesPath, lineNumber)
+		}
+		if _, duplicate := queries[id]; duplicate {
+			return fmt.Errorf("%w: %s:%d: duplicate query %q", ErrInvalidJudgements, queriesPath, lineNumber, id)
+		}
+		queries[id] = &JudgedQuery{ID: id, Query: text, Relevant: make(map[string]int)}
+		order = append(order, id)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = readLines(qrelsPath, func(lineNumber int, line string) error {
+		fields := strings.Fields(line)
+		if len(fields) != 4 {
+			return fmt.Errorf("%w: %s:%d: expected \"query-id iteration document relevance\"", ErrInvalidJudgements, qrelsPath, lineNumber)
+		}
+		query, ok := queries[fields[0]]
+		if !ok {
+			return fmt.Errorf("%w: %s:%d: unknown query %q", ErrInvalidJudgements, qrelsPath, lineNumber, fields[0])
+		}
+		relevance, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("%w: %s:%d: invalid relevance %q", ErrInvalidJudgements, qrelsPath, lineNumber, fields[3])
+		}
+		query.Relevant[fields[2]] = relevance
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	judgements := &Judgements{Queries: make([]JudgedQuery, len(order))}
+	for i, id := range order {
+		judgements.Queries[i] = *queries[id]
+	}
+	return judgements, nil
+}
+
+// readLines calls fn with every line of a file that is neither empty nor a
+// comment starting with #
+func readLines(path string, fn func(lineNumber int, line string) error) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := fn(lineNumber, line); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return nil
+}
+
+// validate checks that every query has an ID, a text and a relevant document
+func (j *Judgements) validate() error {
+	if len(j.Queries) == 0 {
+		return fmt.Errorf("%w: no queries", ErrInvalidJudgements)
+	}
+
+	seen := make(map[string]bool, len(j.Queries))
+	for _, query := range j.Queries {
+		switch {
+		case query.ID == "":
+			return fmt.Errorf("%w: query %q has no id", ErrInvalidJudgements, query.Query)
+		case seen[query.ID]:
+			return fmt.Errorf("%w: duplicate query %q", ErrInvalidJudgements, query.ID)
+		case strings.TrimSpace(query.Query) == "":
+			return fmt.Errorf("%w: query %q has no text", ErrInvalidJudgements, query.ID)
+		case countRelevant(query.Relevant) == 0:
+			return fmt.Errorf("%w: query %q has no relevant documents", ErrInvalidJudgements, query.ID)
+		}
+		seen[query.ID] = true
+	}
+	return nil
+}
+
+// resolve makes the document paths of all queries absolute and clean
+func (j *Judgements) resolve(root string) {
+	for i, query := range j.Queries {
+		relevant := make(map[string]int, len(query.Relevant))
+		for path, relevance := range query.Relevant {
+			path = filepath.FromSlash(path)
+			if !filepath.IsAbs(path) {
+				path = filepath.Join(root, path)
+			}
+			relevant[filepath.Clean(path)] = relevance
+		}
+		j.Queries[i].Relevant = relevant
+	}
+}
+
+// countRelevant returns the number of documents with a relevance above 0
+func countRelevant(relevant map[string]int) int {
+	count := 0
+	for _, relevance := range relevant {
+		if relevance > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// idealGains returns the relevance grades of the relevant documents, highest first
+func idealGains(relevant map[string]int) []int {
+	gains := make([]int, 0, len(relevant))
+	for _, relevance := range relevant {
+		if relevance > 0 {
+			gains = append(gains, relevance)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(gains)))
+	return gains
+}
@@ -0,0 +1,90 @@
+package eval
+
+import "math"
+
+// Metrics holds the quality of a ranking at a cutoff k
+type Metrics struct {
+	NDCG      float64 `json:"ndcg"`      // Normalized discounted cumulative gain of the top k
+	MRR       float64 `json:"mrr"`       // Reciprocal rank of the first relevant document in the top k
+	Precision float64 `json:"precision"` // Share of the top k that is relevant
+	Recall    float64 `json:"recall"`    // Share of the relevant documents in the top k
+}
+
+// Evaluate computes the metrics of a ranked list of document paths against the
+// relevance of documents to the query, considering the top k documents
+func Evaluate(ranked []string, relevant map[string]int, k int) Metrics {
+	if k <= 0 {
+		return Metrics{}
+	}
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	var metrics Metrics
+	dcg, hits := 0.0, 0
+	for i, path := range ranked {
+		relevance := relevant[path]
+		if relevance <= 0 {
+			continue
+		}
+		dcg += gain(relevance, i)
+		hits++
+		if metrics.MRR == 0 {
+			metrics.MRR = 1 / float64(i+1)
+		}
+	}
+
+	idcg := 0.0
+	for i, relevance := range idealGains(relevant) {
+		if i == k {
+			break
+		}
+		idcg += gain(relevance, i)
+	}
+	if idcg > 0 {
+		metrics.NDCG = dcg / idcg
+	}
+
+	metrics.Precision = float64(hits) / float64(k)
+	if total := countRelevant(relevant); total > 0 {
+		metrics.Recall = float64(hits) / float64(total)
+	}
+	return metrics
+}
+
+// gain returns the discounted gain of a document with the given relevance at
+// a 0-based rank
+// Formula: (2^relevance - 1) / log2(rank + 2)
+func gain(relevance, rank int) float64 {
+	return (math.Exp2(float64(relevance)) - 1) / math.Log2(float64(rank+2))
+}
+
+// Sub returns the difference of two metrics
+func (m Metrics) Sub(other Metrics) Metrics {
+	return Metrics{
+		NDCG:      m.NDCG - other.NDCG,
+		MRR:       m.MRR - other.MRR,
+		Precision: m.Precision - other.Precision,
+		Recall:    m.Recall - other.Recall,
+	}
+}
+
+// meanMetrics returns the mean of the metrics of several queries
+func meanMetrics(all []Metrics) Metrics {
+	var mean Metrics
+	if len(all) == 0 {
+		return mean
+	}
+	for _, m := range all {
+		mean.NDCG += m.NDCG
+		mean.MRR += m.MRR
+		mean.Precision += m.Precision
+		mean.Recall += m.Recall
+	}
+	n := float64(len(all))
+	mean.NDCG /= n
+	mean.MRR /= n
+	mean.Precision /= n
+	mean.Recall /= n
+	return mean
+}
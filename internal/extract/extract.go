@@ -0,0 +1,63 @@
+// Package extract reads the text of documents to index. Plain text files are
+// read line by line; formats such as PDF, DOCX, HTML or Markdown have their
+// text and metadata extracted by the Extractor registered for them.
+package extract
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrNoText is returned for documents that have no text that can be
+// extracted, such as encrypted or scanned PDFs and damaged files. Such
+// documents are skipped.
+var ErrNoText = errors.New("no extractable text")
+
+// Location is where a line of extracted text is found in its document
+type Location struct {
+	Page int // 1-based page of paged documents, 0 otherwise
+	// Section names the part of the document holding the line, such as
+	// "Sheet Budget" or "Slide 2 (Roadmap)", and is empty for most formats
+	Section string
+	Line    int // 1-based line number, counted from the start of the page or section if any
+}
+
+// Text is the extracted text of a document
+type Text struct {
+	Lines []string
+	// Locations holds the location of every line of paged or sectioned
+	// documents and is nil for plain text, whose lines are numbered from 1
+	// in order
+	Locations []Location
+
+	// Title is the title of the document, such as the HTML <title> or the
+	// title of Markdown front matter, and is not part of Lines
+	Title string
+	// Headings holds the text of the headings of the document, which are part
+	// of Lines as well
+	Headings []string
+	// Tags holds the tags of Markdown front matter
+	Tags []string
+	// Date is the date of Markdown front matter, zero if unknown
+	Date time.Time
+}
+
+// Location returns the location of the line at index i
+func (t *Text) Location(i int) Location {
+	if i < len(t.Locations) {
+		return t.Locations[i]
+	}
+	return Location{Line: i + 1}
+}
+
+// add appends a line found at the given location
+func (t *Text) add(line string, location Location) {
+	t.Lines = append(t.Lines, line)
+	t.Locations = append(t.Locations, location)
+}
+
+// ReadFile reads the text of the file at the given path with the extractors
+// of the default registry. Files without an extractor are read as plain text.
+func ReadFile(path string) (*Text, error) {
+	return DefaultRegistry().ReadFile(path)
+}
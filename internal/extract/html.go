@@ -0,0 +1,353 @@
+package extract
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlBlockElements end the current line of text when they start or end
+var htmlBlockElements = map[string]bool{
+	"address": true, "article": true, "aside": true, "blockquote": true, "body": true,
+	"br": true, "caption": true, "dd": true, "details": true, "dialog": true, "div": true,
+	"dl": true, "dt": true, "fieldset": true, "figcaption": true, "figure": true,
+	"footer": true, "form": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "head": true, "header": true, "hgroup": true, "hr": true,
+	"html": true, "legend": true, "li": true, "main": true, "nav": true, "ol": true,
+	"option": true, "p": true, "pre": true, "section": true, "summary": true,
+	"table": true, "tbody": true, "tfoot": true, "thead": true, "tr": true, "ul": true,
+}
+
+// htmlHiddenElements hold content that is not displayed as text
+var htmlHiddenElements = map[string]bool{
+	"canvas": true, "iframe": true, "noscript": true, "object": true, "svg": true, "template": true,
+}
+
+// htmlRawTextElements hold text that is not parsed for tags. The content of
+// script and style is skipped; title and textarea are text.
+var htmlRawTextElements = map[string]bool{
+	"script": true, "style": true, "title": true, "textarea": true,
+}
+
+// htmlVoidElements never have content or an end tag
+var htmlVoidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "param": true, "source": true,
+	"track": true, "wbr": true,
+}
+
+// htmlExtractor extracts the visible text of HTML pages
+type htmlExtractor struct{}
+
+// Name returns "HTML"
+func (htmlExtractor) Name() string {
+	return "HTML"
+}
+
+// Extract returns the visible text of the page without tags, scripts and
+// styles. Every block element starts a new line, and a line never spans
+// several lines of the source, so lines are located by their source line. The
+// <title> is returned as the title instead of a line, and the text of the
+// headings is returned as headings as well.
+func (htmlExtractor) Extract(data []byte) (*Text, error) {
+	src := strings.ToValidUTF8(string(data), "�")
+	w := &htmlTextWriter{text: &Text{}}
+
+	line := 1
+	pos := 0
+	// advance moves to end, counting the lines passed
+	advance := func(end int) {
+		line += strings.Count(src[pos:end], "\n")
+		pos = end
+	}
+	// rawText returns the end of the content of a raw text element and moves
+	// past its end tag
+	rawText := func(name string) int {
+		end := indexFold(src, pos, "</"+name)
+		if end < 0 {
+			end = len(src)
+		}
+		contentEnd := end
+		if close := strings.IndexByte(src[end:], '>'); close >= 0 {
+			end += close + 1
+		} else {
+			end = len(src)
+		}
+		advance(contentEnd)
+		return end
+	}
+
+	hiddenName, hiddenDepth := "", 0
+	for pos < len(src) {
+		next := strings.IndexByte(src[pos:], '<')
+		if next < 0 {
+			next = len(src) - pos
+		}
+		if next > 0 {
+			if hiddenDepth == 0 {
+				w.source(src[pos:pos+next], line)
+			}
+			advance(pos + next)
+			continue
+		}
+
+		// Comments, CDATA sections, doctypes and processing instructions
+		rest := src[pos:]
+		switch {
+		case strings.HasPrefix(rest, "<!--"):
+			end := strings.Index(rest[4:], "-->")
+			if end < 0 {
+				advance(len(src))
+			} else {
+				advance(pos + 4 + end + 3)
+			}
+			continue
+		case strings.HasPrefix(rest, "<![CDATA["):
+			end := strings.Index(rest, "]]>")
+			if end < 0 {
+				end = len(rest)
+			}
+			if hiddenDepth == 0 {
+				w.raw(rest[len("<![CDATA["):end], line)
+			}
+			advance(min(pos+end+3, len(src)))
+			continue
+		case strings.HasPrefix(rest, "<!") || strings.HasPrefix(rest, "<?"):
+			end := strings.IndexByte(rest, '>')
+			if end < 0 {
+				end = len(rest) - 1
+			}
+			advance(pos + end + 1)
+			continue
+		}
+
+		tag, ok := parseHTMLTag(rest)
+		if !ok {
+			// A "<" that does not start a tag is text
+			if hiddenDepth == 0 {
+				w.source("<", line)
+			}
+			advance(pos + 1)
+			continue
+		}
+		advance(pos + tag.length)
+
+		if hiddenDepth > 0 {
+			if tag.name == hiddenName && !tag.selfClosing {
+				if tag.closing {
+					hiddenDepth--
+				} else {
+					hiddenDepth++
+				}
+			}
+			continue
+		}
+
+		if tag.closing {
+			w.endElement(tag.name)
+			continue
+		}
+		if (htmlHiddenElements[tag.name] || tag.hidden) && !tag.selfClosing && !htmlVoidElements[tag.name] {
+			hiddenName, hiddenDepth = tag.name, 1
+			continue
+		}
+		w.startElement(tag.name)
+
+		if htmlRawTextElements[tag.name] && !tag.selfClosing {
+			start, startLine := pos, line
+			end := rawText(tag.name)
+			switch tag.name {
+			case "title":
+				w.text.Title = strings.Join(strings.Fields(html.UnescapeString(src[start:pos])), " ")
+			case "textarea":
+				w.source(src[start:pos], startLine)
+			}
+			advance(end)
+			w.endElement(tag.name)
+		} else if htmlVoidElements[tag.name] || tag.selfClosing {
+			w.endElement(tag.name)
+		}
+	}
+	w.flush()
+
+	return w.text, nil
+}
+
+// htmlTag is a start or end tag
+type htmlTag struct {
+	name        string // Lower case
+	closing     bool   // End tag
+	selfClosing bool   // Ends with "/>"
+	hidden      bool   // Has the hidden attribute
+	length      int    // Length in bytes including "<" and ">"
+}
+
+// parseHTMLTag parses the tag at the start of s, which starts with "<". It
+// returns false if s does not start with a tag.
+func parseHTMLTag(s string) (htmlTag, bool) {
+	tag := htmlTag{}
+	i := 1
+	if i < len(s) && s[i] == '/' {
+		tag.closing = true
+		i++
+	}
+	if i >= len(s) || !isASCIILetter(s[i]) {
+		return tag, false
+	}
+	start := i
+	for i < len(s) && (isASCIILetter(s[i]) || isASCIIDigit(s[i]) || s[i] == '-' || s[i] == ':') {
+		i++
+	}
+	tag.name = strings.ToLower(s[start:i])
+
+	// Attributes, with quoted values that may contain ">"
+	for i < len(s) {
+		switch c := s[i]; {
+		case c == '>':
+			tag.length = i + 1
+			tag.selfClosing = s[i-1] == '/'
+			return tag, true
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return tag, false
+			}
+			i += end + 2
+		case c == '=':
+			// Unquoted values end at whitespace or the end of the tag
+			i++
+			for i < len(s) && strings.IndexByte(" \t\r\n\f", s[i]) >= 0 {
+				i++
+			}
+			if i < len(s) && s[i] != '"' && s[i] != '\'' {
+				for i < len(s) && strings.IndexByte(" \t\r\n\f>", s[i]) < 0 {
+					i++
+				}
+			}
+		case isASCIILetter(c):
+			start := i
+			for i < len(s) && strings.IndexByte(" \t\r\n\f/>=", s[i]) < 0 {
+				i++
+			}
+			if strings.EqualFold(s[start:i], "hidden") {
+				tag.hidden = true
+			}
+		default:
+			i++
+		}
+	}
+	return tag, false
+}
+
+// htmlTextWriter collects the visible text of an HTML page as lines located
+// by their source line
+type htmlTextWriter struct {
+	text     *Text
+	line     strings.Builder
+	lineNo   int             // Source line of the current line
+	headings int             // Depth of open heading elements
+	heading  strings.Builder // Text of the open heading
+}
+
+// source writes text from the page source, decoding its character references
+func (w *htmlTextWriter) source(s string, lineNo int) {
+	w.raw(html.UnescapeString(s), lineNo)
+}
+
+// raw writes text starting at the given source line. Line breaks of the
+// source end the current line.
+func (w *htmlTextWriter) raw(s string, lineNo int) {
+	for i, part := range strings.Split(s, "\n") {
+		if i > 0 {
+			w.flush()
+			lineNo++
+		}
+		if strings.TrimSpace(part) == "" {
+			w.write(" ")
+			continue
+		}
+		if w.line.Len() > 0 && w.lineNo != lineNo {
+			w.flush()
+		}
+		if w.line.Len() == 0 {
+			w.lineNo = lineNo
+		}
+		w.write(part)
+	}
+}
+
+// write adds text to the current line and the open heading
+func (w *htmlTextWriter) write(s string) {
+	w.line.WriteString(s)
+	if w.headings > 0 {
+		w.heading.WriteString(s)
+	}
+}
+
+// startElement handles the start tag of a visible element
+func (w *htmlTextWriter) startElement(name string) {
+	switch {
+	case htmlBlockElements[name]:
+		w.flush()
+	case name == "td" || name == "th":
+		// The cells of a row on the same source line are separated
+		if strings.TrimSpace(w.line.String()) != "" {
+			w.line.WriteString(cellSeparator)
+		}
+	}
+	if isHTMLHeading(name) {
+		w.headings++
+	}
+}
+
+// endElement handles the end tag of an element
+func (w *htmlTextWriter) endElement(name string) {
+	if htmlBlockElements[name] {
+		w.flush()
+	}
+	if isHTMLHeading(name) && w.headings > 0 {
+		w.headings--
+		if w.headings == 0 {
+			if heading := strings.Join(strings.Fields(w.heading.String()), " "); heading != "" {
+				w.text.Headings = append(w.text.Headings, heading)
+			}
+			w.heading.Reset()
+		}
+	}
+}
+
+// flush ends the current line
+func (w *htmlTextWriter) flush() {
+	line := strings.Join(strings.Fields(w.line.String()), " ")
+	line = strings.TrimSuffix(line, strings.TrimSpace(cellSeparator))
+	if line = strings.TrimSpace(line); line != "" {
+		w.text.add(line, Location{Line: w.lineNo})
+	}
+	w.line.Reset()
+	if w.headings > 0 {
+		w.heading.WriteByte(' ')
+	}
+}
+
+// isHTMLHeading reports whether an element is a heading, h1 to h6
+func isHTMLHeading(name string) bool {
+	return len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6'
+}
+
+// indexFold returns the index of the first case-insensitive occurrence of the
+// ASCII string substr in s at or after start, or -1
+func indexFold(s string, start int, substr string) int {
+	for i := start; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isASCIILetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isASCIIDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
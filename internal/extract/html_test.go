@@ -0,0 +1,63 @@
+package extract
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHTMLExtract(t *testing.T) {
+	page := `<!DOCTYPE html>
+<html><head><title>Page &amp;
+ Title</title><style>p { color: red }</style>
+<script>if (a < b) { document.write("<p>hidden</p>") }</script></head>
+<body><h1>Main <em>heading</em></h1>
+<!-- a comment
+spanning lines -->
+<p>First para with <a href="/docs?a=1&b=2" title="x > y">link</a>
+second line</p>
+<table><tr><td>a</td><td>b</td></tr></table><div hidden><p>not shown</p><div>nested</div></div>
+<template><p>template</p></template><p>shown<br>1 &lt; 2</p>
+<h2>Setup</h2><textarea><b>raw</b></textarea>
+</body></html>`
+
+	text, err := htmlExtractor{}.Extract([]byte(page))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text,
+		[]string{"Main heading", "First para with link", "second line", "a | b", "shown", "1 < 2", "Setup", "<b>raw</b>"},
+		[]Location{{Line: 5}, {Line: 8}, {Line: 9}, {Line: 10}, {Line: 11}, {Line: 11}, {Line: 12}, {Line: 12}})
+
+	if text.Title != "Page & Title" {
+		t.Errorf("title = %q, want %q", text.Title, "Page & Title")
+	}
+	if want := []string{"Main heading", "Setup"}; !reflect.DeepEqual(text.Headings, want) {
+		t.Errorf("headings = %q, want %q", text.Headings, want)
+	}
+}
+
+func TestHTMLExtractMalformed(t *testing.T) {
+	tests := []struct {
+		name  string
+		page  string
+		lines []string
+	}{
+		{"unclosed tag", "<p>text <b", []string{"text <b"}},
+		{"unclosed comment", "text<!-- rest", []string{"text"}},
+		{"stray brackets", "a < b > c", []string{"a < b > c"}},
+		{"unclosed script", "<p>before</p><script>var x", []string{"before"}},
+		{"unclosed quote", `<a href="x>text`, []string{`<a href="x>text`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := htmlExtractor{}.Extract([]byte(tt.page))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if !reflect.DeepEqual(text.Lines, tt.lines) {
+				t.Errorf("lines = %q, want %q", text.Lines, tt.lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,521 @@
+package extract
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+
+	"mneme/internal/storage"
+)
+
+// frontMatterDateLayouts are the accepted formats of front matter dates
+var frontMatterDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// markdownExtractor extracts the text and front matter of Markdown documents
+type markdownExtractor struct{}
+
+// Name returns "Markdown"
+func (markdownExtractor) Name() string {
+	return "Markdown"
+}
+
+// Extract returns the text of every line without Markdown syntax: link
+// targets, emphasis, heading and list markers, fences and HTML tags are
+// removed, while code is kept as written. Every line of the text is the line
+// of the source with the same number, so lines without text, such as the
+// front matter, are empty. The title, tags and date of YAML (---) or TOML
+// (+++) front matter and the text of the headings are returned as metadata.
+func (markdownExtractor) Extract(data []byte) (*Text, error) {
+	lines, err := storage.ReadLines(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	text := &Text{Lines: make([]string, len(lines))}
+	m := &markdownStripper{text: text, paragraph: -1}
+	for i := readFrontMatter(lines, text); i < len(lines); i++ {
+		text.Lines[i] = m.line(lines[i], i)
+	}
+	return text, nil
+}
+
+// readFrontMatter reads the front matter at the start of a document into
+// text and returns the number of lines it spans. Documents without front
+// matter, or whose front matter is not a map, return 0.
+func readFrontMatter(lines []string, text *Text) int {
+	if len(lines) == 0 {
+		return 0
+	}
+
+	var closing []string
+	var unmarshal func(data []byte, v any) error
+	switch strings.TrimRight(strings.TrimPrefix(lines[0], "\ufeff"), " \t") {
+	case "---":
+		closing, unmarshal = []string{"---", "..."}, yaml.Unmarshal
+	case "+++":
+		closing, unmarshal = []string{"+++"}, toml.Unmarshal
+	default:
+		return 0
+	}
+
+	for end := 1; end < len(lines); end++ {
+		if !slices.Contains(closing, strings.TrimRight(lines[end], " \t")) {
+			continue
+		}
+		var fields map[string]any
+		if err := unmarshal([]byte(strings.Join(lines[1:end], "\n")), &fields); err != nil {
+			// A thematic break rather than front matter
+			return 0
+		}
+		for key, value := range fields {
+			switch strings.ToLower(key) {
+			case "title":
+				text.Title = strings.Join(strings.Fields(fmt.Sprint(value)), " ")
+			case "tags":
+				text.Tags = frontMatterList(value)
+			case "date":
+				text.Date = frontMatterDate(value)
+			}
+		}
+		return end + 1
+	}
+	return 0
+}
+
+// frontMatterList returns the items of a front matter list, which may also be
+// written as a comma-separated string
+func frontMatterList(value any) []string {
+	var items []string
+	switch v := value.(type) {
+	case string:
+		items = strings.Split(v, ",")
+	case []any:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	}
+
+	var list []string
+	for _, item := range items {
+		if item = strings.Join(strings.Fields(item), " "); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// frontMatterDate returns the time of a front matter date, which YAML and TOML
+// decode to time.Time or a local date type, or the zero time
+func frontMatterDate(value any) time.Time {
+	if t, ok := value.(time.Time); ok {
+		return t
+	}
+	s := strings.TrimSpace(fmt.Sprint(value))
+	for _, layout := range frontMatterDateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// markdownStripper removes the Markdown syntax of a document line by line
+type markdownStripper struct {
+	text      *Text
+	fence     string // Fence of the open code block, empty outside code blocks
+	comment   bool   // Inside an HTML comment spanning several lines
+	paragraph int    // Index of the previous line if it was paragraph text, -1 otherwise
+}
+
+// line returns the text of the line at index i
+func (m *markdownStripper) line(line string, i int) string {
+	if m.fence != "" {
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(trimmed, m.fence) && strings.Trim(trimmed, m.fence[:1]) == "" {
+			m.fence = ""
+			return ""
+		}
+		return strings.TrimRight(line, " \t")
+	}
+	if m.comment {
+		end := strings.Index(line, "-->")
+		if end < 0 {
+			return ""
+		}
+		m.comment = false
+		line = line[end+3:]
+	}
+
+	previous := m.paragraph
+	m.paragraph = -1
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case trimmed == "":
+		return ""
+	case isCodeFence(trimmed):
+		m.fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+		return ""
+	case previous >= 0 && isSetextUnderline(trimmed):
+		m.text.Headings = append(m.text.Headings, m.text.Lines[previous])
+		return ""
+	case isThematicBreak(trimmed) || isTableDelimiter(trimmed):
+		return ""
+	}
+
+	// Link reference definitions are no text, footnote definitions are
+	if label, definition, ok := strings.Cut(trimmed, "]:"); ok && strings.HasPrefix(label, "[") && !strings.ContainsAny(label[1:], "[]") && len(line)-len(strings.TrimLeft(line, " ")) < 4 {
+		if strings.HasPrefix(label, "[^") {
+			return m.inline(strings.TrimSpace(definition))
+		}
+		return ""
+	}
+
+	body, nested := stripMarkdownContainers(line)
+	if level := len(body) - len(strings.TrimLeft(body, "#")); level >= 1 && level <= 6 && (len(body) == level || body[level] == ' ' || body[level] == '\t') {
+		heading := m.inline(strings.TrimSpace(trimClosingHashes(body[level:])))
+		if heading != "" {
+			m.text.Headings = append(m.text.Headings, heading)
+		}
+		return heading
+	}
+	if strings.HasPrefix(body, "|") {
+		return m.tableRow(body)
+	}
+
+	text := m.inline(body)
+	if !nested && text != "" {
+		m.paragraph = i
+	}
+	return text
+}
+
+// tableRow returns the cells of a table row joined by cellSeparator
+func (m *markdownStripper) tableRow(row string) string {
+	row = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(row), "|"), "|")
+
+	var cells []string
+	start := 0
+	for i := 0; i <= len(row); i++ {
+		if i < len(row) && row[i] == '\\' {
+			i++
+			continue
+		}
+		if i == len(row) || row[i] == '|' {
+			cells = append(cells, m.inline(strings.TrimSpace(row[start:min(i, len(row))])))
+			start = i + 1
+		}
+	}
+	return joinCells(cells)
+}
+
+// inline returns the text of a line without inline syntax
+func (m *markdownStripper) inline(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && isASCIIPunct(s[i+1]) {
+				b.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+		case '`':
+			n := runLength(s, i)
+			if end := closingBackticks(s, i+n, n); end >= 0 {
+				code := s[i+n : end]
+				if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.TrimSpace(code) != "" {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString(code)
+				i = end + n
+				continue
+			}
+			b.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '!', '[':
+			start := i
+			if c == '!' {
+				start++
+			}
+			if start < len(s) && s[start] == '[' {
+				if strings.HasPrefix(s[start:], "[^") {
+					// Footnote references
+					if end := strings.IndexByte(s[start:], ']'); end > 2 && !strings.ContainsAny(s[start+2:start+end], " \t[") {
+						i = start + end + 1
+						continue
+					}
+				}
+				if label, next, ok := parseMarkdownLink(s, start); ok {
+					b.WriteString(m.inline(label))
+					i = next
+					continue
+				}
+			}
+		case '<':
+			if strings.HasPrefix(s[i:], "<!--") {
+				end := strings.Index(s[i+4:], "-->")
+				if end < 0 {
+					m.comment = true
+					return strings.TrimSpace(b.String())
+				}
+				i += 4 + end + 3
+				continue
+			}
+			if end := markdownAutolinkEnd(s[i:]); end > 0 {
+				b.WriteString(s[i+1 : i+end])
+				i += end + 1
+				continue
+			}
+			if tag, ok := parseHTMLTag(s[i:]); ok {
+				i += tag.length
+				continue
+			}
+		case '*', '_', '~':
+			n := runLength(s, i)
+			if !isEmphasisDelimiter(s, i, n) {
+				b.WriteString(s[i : i+n])
+			}
+			i += n
+			continue
+		case '&':
+			if end := strings.IndexByte(s[i:min(len(s), i+32)], ';'); end > 1 {
+				if entity := s[i : i+end+1]; html.UnescapeString(entity) != entity {
+					b.WriteString(html.UnescapeString(entity))
+					i += end + 1
+					continue
+				}
+			}
+		}
+		b.WriteByte(c)
+		i++
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// parseMarkdownLink parses an inline link "[label](target)" or a reference
+// link "[label][ref]" at s[start], which is "[". It returns the label and the
+// index after the link.
+func parseMarkdownLink(s string, start int) (string, int, bool) {
+	close := matchingBracket(s, start, '[', ']')
+	if close < 0 || close+1 >= len(s) {
+		return "", 0, false
+	}
+	label := s[start+1 : close]
+
+	switch s[close+1] {
+	case '(':
+		end := matchingBracket(s, close+1, '(', ')')
+		if end < 0 {
+			return "", 0, false
+		}
+		return label, end + 1, true
+	case '[':
+		end := strings.IndexByte(s[close+1:], ']')
+		if end < 0 {
+			return "", 0, false
+		}
+		return label, close + 1 + end + 1, true
+	}
+	return "", 0, false
+}
+
+// matchingBracket returns the index of the bracket closing the one at
+// s[start], skipping escaped and nested brackets, or -1
+func matchingBracket(s string, start int, open, close byte) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case open:
+			depth++
+		case close:
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// markdownAutolinkEnd returns the index of the ">" ending an autolink such as
+// <https://example.com> or <user@example.com> at the start of s, or -1
+func markdownAutolinkEnd(s string) int {
+	end := strings.IndexByte(s, '>')
+	if end < 3 || strings.ContainsAny(s[1:end], " \t<") {
+		return -1
+	}
+	inner := s[1:end]
+	if scheme, _, ok := strings.Cut(inner, ":"); ok && len(scheme) >= 2 && isASCIILetter(scheme[0]) &&
+		strings.Trim(strings.ToLower(scheme), "abcdefghijklmnopqrstuvwxyz0123456789+.-") == "" {
+		return end
+	}
+	if strings.Contains(inner, "@") && !strings.Contains(inner, "/") {
+		return end
+	}
+	return -1
+}
+
+// isEmphasisDelimiter reports whether the run of n "*", "_" or "~" at s[i] can
+// open or close emphasis or a strikethrough, following CommonMark's flanking
+// rules. Runs within words only count for "*", so snake_case is kept.
+func isEmphasisDelimiter(s string, i, n int) bool {
+	c := s[i]
+	if c == '~' && n != 2 {
+		return false
+	}
+
+	before, after := ' ', ' '
+	if i > 0 {
+		before, _ = utf8.DecodeLastRuneInString(s[:i])
+	}
+	if i+n < len(s) {
+		after, _ = utf8.DecodeRuneInString(s[i+n:])
+	}
+	isPunct := func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}
+	leftFlanking := !unicode.IsSpace(after) && (!isPunct(after) || unicode.IsSpace(before) || isPunct(before))
+	rightFlanking := !unicode.IsSpace(before) && (!isPunct(before) || unicode.IsSpace(after) || isPunct(after))
+
+	if c == '_' {
+		return leftFlanking && (!rightFlanking || isPunct(before)) || rightFlanking && (!leftFlanking || isPunct(after))
+	}
+	return leftFlanking || rightFlanking
+}
+
+// isASCIIPunct reports whether c is an ASCII punctuation character, which a
+// backslash escapes
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && (unicode.IsPunct(rune(c)) || unicode.IsSymbol(rune(c)))
+}
+
+// runLength returns the number of repetitions of s[i] starting at i
+func runLength(s string, i int) int {
+	n := 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// closingBackticks returns the index of the next run of exactly n backticks
+// at or after start, or -1
+func closingBackticks(s string, start, n int) int {
+	for i := start; i < len(s); {
+		if s[i] != '`' {
+			i++
+			continue
+		}
+		run := runLength(s, i)
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// stripMarkdownContainers removes block quote markers, list markers and task
+// list boxes from the start of a line. It reports whether there were any.
+func stripMarkdownContainers(line string) (string, bool) {
+	nested := false
+	for {
+		line = strings.TrimLeft(line, " \t")
+		if strings.HasPrefix(line, ">") {
+			line, nested = line[1:], true
+			continue
+		}
+		if n := listMarkerLength(line); n > 0 {
+			line, nested = line[n:], true
+			continue
+		}
+		break
+	}
+	for _, box := range []string{"[ ] ", "[x] ", "[X] "} {
+		if nested && strings.HasPrefix(line, box) {
+			return line[len(box):], nested
+		}
+	}
+	return line, nested
+}
+
+// listMarkerLength returns the length of the bullet ("-", "*", "+") or
+// ordered list marker ("1.", "2)") at the start of line, or 0
+func listMarkerLength(line string) int {
+	n := 0
+	switch {
+	case line == "":
+		return 0
+	case line[0] == '-' || line[0] == '*' || line[0] == '+':
+		n = 1
+	default:
+		for n < len(line) && n < 9 && isASCIIDigit(line[n]) {
+			n++
+		}
+		if n == 0 || n >= len(line) || (line[n] != '.' && line[n] != ')') {
+			return 0
+		}
+		n++
+	}
+	if n < len(line) && line[n] != ' ' && line[n] != '\t' {
+		return 0
+	}
+	return n
+}
+
+// trimClosingHashes removes the optional closing sequence of an ATX heading
+func trimClosingHashes(text string) string {
+	text = strings.TrimRight(text, " \t")
+	trimmed := strings.TrimRight(text, "#")
+	if trimmed == "" || strings.HasSuffix(trimmed, " ") || strings.HasSuffix(trimmed, "\t") {
+		return trimmed
+	}
+	return text
+}
+
+// isCodeFence reports whether a trimmed line opens a fenced code block
+func isCodeFence(trimmed string) bool {
+	if !strings.HasPrefix(trimmed, "```") && !strings.HasPrefix(trimmed, "~~~") {
+		return false
+	}
+	// The info string of a backtick fence cannot contain backticks
+	return trimmed[0] == '~' || !strings.Contains(strings.TrimLeft(trimmed, "`"), "`")
+}
+
+// isSetextUnderline reports whether a trimmed line underlines the previous
+// line as a heading ("===" or "---")
+func isSetextUnderline(trimmed string) bool {
+	return strings.Trim(trimmed, "=") == "" || len(trimmed) >= 2 && strings.Trim(trimmed, "-") == ""
+}
+
+// isThematicBreak reports whether a trimmed line is a thematic break such as
+// "---", "***" or "_ _ _"
+func isThematicBreak(trimmed string) bool {
+	compact := strings.ReplaceAll(strings.ReplaceAll(trimmed, " ", ""), "\t", "")
+	return len(compact) >= 3 && strings.Trim(compact, compact[:1]) == "" && strings.ContainsAny(compact[:1], "-*_")
+}
+
+// isTableDelimiter reports whether a trimmed line is the delimiter row of a
+// table, such as "| --- | :---: |"
+func isTableDelimiter(trimmed string) bool {
+	return strings.Contains(trimmed, "|") && strings.Contains(trimmed, "-") && strings.Trim(trimmed, "|:- \t") == ""
+}
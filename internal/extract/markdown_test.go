@@ -0,0 +1,141 @@
+package extract
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarkdownExtract(t *testing.T) {
+	doc := strings.Join([]string{
+		"---",
+		"title: My *Notes*",
+		"tags: [go, search]",
+		"date: 2024-03-05",
+		"---",
+		"# Deployment Runbook #",
+		"",
+		"See [the docs](https://example.com \"Docs\") and ![logo](logo.png) `code_x` \\*y\\* snake_case *em* __strong__ ~~del~~ 2 * 3 ~/path",
+		"",
+		"```bash",
+		"# a shell comment",
+		"```",
+		"Setext",
+		"======",
+		"- [ ] task item",
+		"> quoted <span>text</span>",
+		"| a | b | c |",
+		"|---|:-:|---|",
+		"[ref]: https://example.com",
+		"[^1]: Footnote text",
+		"Text <!-- comment",
+		"spanning --> visible",
+		"#NotAHeading",
+	}, "\n")
+
+	text, err := markdownExtractor{}.Extract([]byte(doc))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	// Every line keeps the number of its source line
+	want := []string{
+		"", "", "", "", "",
+		"Deployment Runbook",
+		"",
+		"See the docs and logo code_x *y* snake_case em strong del 2 * 3 ~/path",
+		"",
+		"",
+		"# a shell comment",
+		"",
+		"Setext",
+		"",
+		"task item",
+		"quoted text",
+		"a | b | c",
+		"",
+		"",
+		"Footnote text",
+		"Text",
+		"visible",
+		"#NotAHeading",
+	}
+	if !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+	if text.Locations != nil {
+		t.Errorf("locations = %v, want none", text.Locations)
+	}
+
+	if text.Title != "My *Notes*" {
+		t.Errorf("title = %q, want %q", text.Title, "My *Notes*")
+	}
+	if want := []string{"go", "search"}; !reflect.DeepEqual(text.Tags, want) {
+		t.Errorf("tags = %q, want %q", text.Tags, want)
+	}
+	if want := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC); !text.Date.Equal(want) {
+		t.Errorf("date = %v, want %v", text.Date, want)
+	}
+	if want := []string{"Deployment Runbook", "Setext"}; !reflect.DeepEqual(text.Headings, want) {
+		t.Errorf("headings = %q, want %q", text.Headings, want)
+	}
+}
+
+func TestMarkdownFrontMatter(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		title string
+		tags  []string
+		date  time.Time
+		lines int // Lines of text after the front matter
+	}{
+		{
+			name:  "toml",
+			doc:   "+++\nTitle = \"Release notes\"\ntags = \"ops, release\"\ndate = 2025-01-02T10:00:00Z\n+++\nBody",
+			title: "Release notes",
+			tags:  []string{"ops", "release"},
+			date:  time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC),
+			lines: 1,
+		},
+		{
+			name:  "yaml closed by dots",
+			doc:   "\ufeff---\ntitle: Dots\ndate: \"2025-01-02 10:00\"\n...\nBody",
+			title: "Dots",
+			date:  time.Date(2025, 1, 2, 10, 0, 0, 0, time.Local),
+			lines: 1,
+		},
+		{
+			name:  "not a map",
+			doc:   "---\njust text\n---\nBody",
+			lines: 2,
+		},
+		{
+			name:  "unclosed",
+			doc:   "---\ntitle: Open\nBody",
+			lines: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := markdownExtractor{}.Extract([]byte(tt.doc))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if text.Title != tt.title || !reflect.DeepEqual(text.Tags, tt.tags) || !text.Date.Equal(tt.date) {
+				t.Errorf("metadata = %q %q %v, want %q %q %v", text.Title, text.Tags, text.Date, tt.title, tt.tags, tt.date)
+			}
+			lines := 0
+			for _, line := range text.Lines {
+				if line != "" {
+					lines++
+				}
+			}
+			if lines != tt.lines {
+				t.Errorf("lines = %q, want %d lines of text", text.Lines, tt.lines)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package extract
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// notebookExtractor extracts the text of the cells of Jupyter notebooks
+type notebookExtractor struct {
+	outputs bool // Whether the text outputs of code cells are extracted
+}
+
+// NewNotebookExtractor returns the extractor of Jupyter notebooks. The text
+// outputs of code cells are extracted as well if outputs is set.
+func NewNotebookExtractor(outputs bool) Extractor {
+	return notebookExtractor{outputs: outputs}
+}
+
+// notebook is the JSON document of a notebook in nbformat 4, or in nbformat 3
+// with its cells in worksheets
+type notebook struct {
+	Metadata struct {
+		Title any `json:"title"`
+	} `json:"metadata"`
+	Cells      []notebookCell `json:"cells"`
+	Worksheets []struct {
+		Cells []notebookCell `json:"cells"`
+	} `json:"worksheets"`
+}
+
+// notebookCell is a cell of a notebook. Code cells hold their source in Input
+// in nbformat 3.
+type notebookCell struct {
+	CellType string           `json:"cell_type"`
+	Source   notebookText     `json:"source"`
+	Input    notebookText     `json:"input"`
+	Outputs  []notebookOutput `json:"outputs"`
+}
+
+// notebookOutput is an output of a code cell. Only the text/plain
+// representation of rich outputs is read; images and other binary data are
+// skipped.
+type notebookOutput struct {
+	OutputType string       `json:"output_type"`
+	Text       notebookText `json:"text"`
+	Data       struct {
+		Plain notebookText `json:"text/plain"`
+	} `json:"data"`
+	Ename  string `json:"ename"`
+	Evalue string `json:"evalue"`
+}
+
+// notebookText is a multiline string, stored as a string or as a list of
+// lines in notebooks
+type notebookText string
+
+// UnmarshalJSON joins a list of lines into a single string
+func (t *notebookText) UnmarshalJSON(data []byte) error {
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err == nil {
+		*t = notebookText(strings.Join(lines, ""))
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*t = notebookText(s)
+	return nil
+}
+
+// Name returns "Notebook"
+func (notebookExtractor) Name() string {
+	return "Notebook"
+}
+
+// Extract returns the text of the markdown and code cells of a notebook, with
+// Markdown syntax removed like in Markdown files. Lines are located by their
+// cell, numbered from 1 in notebook order ("Cell 3"), and their line in the
+// cell source. Text outputs, if enabled, are located in a section of their
+// own ("Cell 3 (output)"). Raw cells and binary outputs are skipped.
+func (e notebookExtractor) Extract(data []byte) (*Text, error) {
+	var nb notebook
+	if err := json.Unmarshal(data, &nb); err != nil {
+		return nil, fmt.Errorf("%w: malformed notebook: %v", ErrNoText, err)
+	}
+
+	cells := nb.Cells
+	for _, worksheet := range nb.Worksheets {
+		cells = append(cells, worksheet.Cells...)
+	}
+
+	text := &Text{}
+	if title, ok := nb.Metadata.Title.(string); ok {
+		text.Title = strings.TrimSpace(title)
+	}
+	for i, cell := range cells {
+		section := fmt.Sprintf("Cell %d", i+1)
+		switch cell.CellType {
+		case "heading":
+			// nbformat 3 stores headings in cells of their own, without markers
+			if heading := strings.TrimSpace(string(cell.Source)); heading != "" {
+				text.Headings = append(text.Headings, heading)
+			}
+			addNotebookLines(text, cell.Source, section)
+		case "markdown":
+			m := &markdownStripper{text: text, paragraph: -1}
+			for j, line := range notebookLines(cell.Source) {
+				if line = m.line(line, len(text.Lines)); line != "" {
+					text.add(line, Location{Section: section, Line: j + 1})
+				}
+			}
+		case "code":
+			source := cell.Source
+			if source == "" {
+				source = cell.Input
+			}
+			addNotebookLines(text, source, section)
+			if e.outputs {
+				addNotebookLines(text, notebookOutputText(cell.Outputs), section+" (output)")
+			}
+		}
+	}
+
+	if len(text.Lines) == 0 {
+		return nil, fmt.Errorf("%w: notebook without markdown or code", ErrNoText)
+	}
+	return text, nil
+}
+
+// addNotebookLines adds the non-empty lines of a cell to text
+func addNotebookLines(text *Text, s notebookText, section string) {
+	for j, line := range notebookLines(s) {
+		if line = strings.TrimRight(line, " \t"); strings.TrimSpace(line) != "" {
+			text.add(line, Location{Section: section, Line: j + 1})
+		}
+	}
+}
+
+// notebookOutputText joins the text of the outputs of a code cell: streams,
+// the text/plain representation of results and the message of errors
+func notebookOutputText(outputs []notebookOutput) notebookText {
+	var b strings.Builder
+	for _, output := range outputs {
+		var s string
+		switch output.OutputType {
+		case "stream":
+			s = string(output.Text)
+		case "execute_result", "display_data", "pyout":
+			s = string(output.Data.Plain)
+			if s == "" {
+				// nbformat 3 stores text/plain as text
+				s = string(output.Text)
+			}
+		case "error", "pyerr":
+			if output.Ename != "" {
+				s = output.Ename + ": " + output.Evalue
+			}
+		}
+		if s == "" {
+			continue
+		}
+		b.WriteString(s)
+		if !strings.HasSuffix(s, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	return notebookText(b.String())
+}
+
+// notebookLines splits a multiline string into lines
+func notebookLines(s notebookText) []string {
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(string(s), "\r\n", "\n"), "\n"), "\n")
+}
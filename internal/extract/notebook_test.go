@@ -0,0 +1,91 @@
+package extract
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// testNotebook is an nbformat 4 notebook with a raw cell and an image output
+const testNotebook = `{
+ "metadata": {"title": "Churn analysis", "kernelspec": {"name": "python3"}},
+ "nbformat": 4,
+ "cells": [
+  {"cell_type": "markdown", "metadata": {}, "source": ["# Churn *model*\n", "\n", "See [the data](data.csv)"]},
+  {"cell_type": "code", "execution_count": 1, "metadata": {}, "source": "import pandas as pd\n\ndf = pd.read_csv('data.csv')  \n",
+   "outputs": [
+    {"output_type": "stream", "name": "stdout", "text": ["loaded 120 rows\n"]},
+    {"output_type": "display_data", "data": {"image/png": "iVBORw0KGgoAAAANSUhEUgAA", "text/plain": ["<Figure size 640x480>"]}},
+    {"output_type": "error", "ename": "KeyError", "evalue": "'churned'", "traceback": ["\u001b[0;31mKeyError\u001b[0m"]}
+   ]},
+  {"cell_type": "raw", "metadata": {}, "source": "raw text"},
+  {"cell_type": "code", "metadata": {}, "source": [], "outputs": []},
+  {"cell_type": "markdown", "metadata": {}, "source": "Results\n======="}
+ ]
+}`
+
+func TestNotebookExtract(t *testing.T) {
+	text, err := notebookExtractor{}.Extract([]byte(testNotebook))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text,
+		[]string{"Churn model", "See the data", "import pandas as pd", "df = pd.read_csv('data.csv')", "Results"},
+		[]Location{
+			{Section: "Cell 1", Line: 1}, {Section: "Cell 1", Line: 3},
+			{Section: "Cell 2", Line: 1}, {Section: "Cell 2", Line: 3},
+			{Section: "Cell 5", Line: 1},
+		})
+
+	if text.Title != "Churn analysis" {
+		t.Errorf("title = %q, want %q", text.Title, "Churn analysis")
+	}
+	if want := []string{"Churn model", "Results"}; !reflect.DeepEqual(text.Headings, want) {
+		t.Errorf("headings = %q, want %q", text.Headings, want)
+	}
+}
+
+func TestNotebookExtractOutputs(t *testing.T) {
+	text, err := NewNotebookExtractor(true).Extract([]byte(testNotebook))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+
+	// The image is skipped, its text/plain representation is kept
+	var outputs []string
+	for i, line := range text.Lines {
+		if text.Location(i).Section == "Cell 2 (output)" {
+			outputs = append(outputs, line)
+		}
+	}
+	if want := []string{"loaded 120 rows", "<Figure size 640x480>", "KeyError: 'churned'"}; !reflect.DeepEqual(outputs, want) {
+		t.Errorf("outputs = %q, want %q", outputs, want)
+	}
+}
+
+func TestNotebookExtractV3(t *testing.T) {
+	notebook := `{"nbformat": 3, "metadata": {}, "worksheets": [{"cells": [
+		{"cell_type": "heading", "level": 1, "source": ["Setup"]},
+		{"cell_type": "code", "language": "python", "input": ["x = 1\n", "print(x)"],
+		 "outputs": [{"output_type": "pyout", "text": ["1"]}]}
+	]}]}`
+
+	text, err := NewNotebookExtractor(true).Extract([]byte(notebook))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text,
+		[]string{"Setup", "x = 1", "print(x)", "1"},
+		[]Location{{Section: "Cell 1", Line: 1}, {Section: "Cell 2", Line: 1}, {Section: "Cell 2", Line: 2}, {Section: "Cell 2 (output)", Line: 1}})
+	if want := []string{"Setup"}; !reflect.DeepEqual(text.Headings, want) {
+		t.Errorf("headings = %q, want %q", text.Headings, want)
+	}
+}
+
+func TestNotebookExtractNoText(t *testing.T) {
+	for _, notebook := range []string{`{"cells": [`, `{"cells": [{"cell_type": "raw", "source": "x"}]}`} {
+		if _, err := (notebookExtractor{}).Extract([]byte(notebook)); !errors.Is(err, ErrNoText) {
+			t.Errorf("Extract(%s) error = %v, want ErrNoText", notebook, err)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// odfExtractor extracts the text of OpenDocument text documents,
+// spreadsheets and presentations
+type odfExtractor struct{}
+
+// Name returns "ODF"
+func (odfExtractor) Name() string {
+	return "ODF"
+}
+
+// Extract returns the paragraphs and table rows of the document body.
+// Rows of spreadsheets are located by their sheet and row number, and the
+// text of presentations by its slide.
+func (odfExtractor) Extract(data []byte) (*Text, error) {
+	archive, err := openOfficeArchive(data, "ODF")
+	if err != nil {
+		return nil, err
+	}
+	content, err := archive.read("content.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := readODFContent(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed ODF document: %v", ErrNoText, err)
+	}
+	return text, nil
+}
+
+// readODFContent reads the body of an OpenDocument content.xml
+func readODFContent(content []byte) (*Text, error) {
+	text := &Text{}
+	w := &paragraphWriter{}
+
+	body := ""      // text, spreadsheet or presentation
+	section := ""   // Sheet or slide of spreadsheets and presentations
+	tableDepth := 0 // Nesting of tables
+	row, rowRepeat := 0, 1
+	slide := 0
+	titleStart, titleDepth, depth := 0, 0, 0
+	title := ""
+
+	// addLines moves the lines written so far to the text
+	addLines := func(line func(i int) int) {
+		for i, l := range w.lines {
+			text.add(l, Location{Section: section, Line: line(i)})
+		}
+		w.lines = w.lines[:0]
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch t.Name.Local {
+			case "text", "spreadsheet", "presentation":
+				if t.Name.Space == "urn:oasis:names:tc:opendocument:xmlns:office:1.0" {
+					body = t.Name.Local
+				}
+			case "p", "h":
+				w.startParagraph()
+			case "s":
+				count, err := strconv.Atoi(xmlAttr(t, "c"))
+				if err != nil || count < 1 {
+					count = 1
+				}
+				w.text(strings.Repeat(" ", min(count, 100)))
+			case "tab":
+				w.text("\t")
+			case "line-break":
+				w.text("\n")
+			case "table":
+				tableDepth++
+				if body == "spreadsheet" && tableDepth == 1 {
+					section, row = "Sheet "+xmlAttr(t, "name"), 0
+				}
+			case "table-row":
+				row++
+				rowRepeat = 1
+				if repeat, err := strconv.Atoi(xmlAttr(t, "number-rows-repeated")); err == nil && repeat > 1 {
+					rowRepeat = repeat
+				}
+			case "table-cell", "covered-table-cell":
+				w.startCell()
+			case "page":
+				if body == "presentation" {
+					slide++
+					title = ""
+				}
+			case "frame":
+				if xmlAttr(t, "class") == "title" && titleDepth == 0 {
+					titleStart, titleDepth = len(w.lines), depth
+				}
+			case "note-citation", "annotation", "notes", "tracked-changes":
+				// Footnote numbers, comments, speaker notes and deleted text
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+				depth--
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p", "h":
+				w.endParagraph()
+			case "table":
+				tableDepth--
+			case "table-cell", "covered-table-cell":
+				w.endCell()
+			case "table-row":
+				w.endRow()
+				if body == "spreadsheet" && tableDepth == 1 {
+					addLines(func(int) int { return row })
+				}
+				row += rowRepeat - 1
+			case "frame":
+				if depth == titleDepth {
+					title = strings.Join(w.lines[titleStart:], " ")
+					titleDepth = 0
+				}
+			case "page":
+				if body == "presentation" {
+					section = fmt.Sprintf("Slide %d", slide)
+					if title != "" {
+						section = fmt.Sprintf("Slide %d (%s)", slide, title)
+					}
+					addLines(func(i int) int { return i + 1 })
+				}
+			}
+			depth--
+		case xml.CharData:
+			if w.inParagraph() {
+				w.text(string(t))
+			}
+		}
+	}
+
+	if len(text.Lines) == 0 {
+		// Text documents have neither sheets nor slides
+		return &Text{Lines: w.lines}, nil
+	}
+	addLines(func(i int) int { return i + 1 })
+	return text, nil
+}
@@ -0,0 +1,407 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// officeMaxEntrySize bounds the decompressed size of a single archive entry,
+// which protects against zip bombs
+const officeMaxEntrySize = 64 << 20
+
+// cellSeparator joins the cells of a table row into one line
+const cellSeparator = " | "
+
+// officeArchive is the zip container of an OOXML or ODF document
+type officeArchive struct {
+	format string
+	files  map[string]*zip.File
+}
+
+// openOfficeArchive opens the zip container of a document of the given format
+func openOfficeArchive(data []byte, format string) (*officeArchive, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed %s archive: %v", ErrNoText, format, err)
+	}
+
+	archive := &officeArchive{format: format, files: make(map[string]*zip.File)}
+	for _, file := range reader.File {
+		archive.files[strings.TrimPrefix(file.Name, "/")] = file
+	}
+	return archive, nil
+}
+
+// has reports whether the archive contains the named entry
+func (a *officeArchive) has(name string) bool {
+	_, ok := a.files[name]
+	return ok
+}
+
+// read returns the decompressed contents of the named entry
+func (a *officeArchive) read(name string) ([]byte, error) {
+	file, ok := a.files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: malformed %s archive: missing %s", ErrNoText, a.format, name)
+	}
+
+	reader, err := file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed %s archive: %v", ErrNoText, a.format, err)
+	}
+	defer reader.Close()
+
+	data, err := io.ReadAll(io.LimitReader(reader, officeMaxEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed %s archive: %s: %v", ErrNoText, a.format, name, err)
+	}
+	if len(data) > officeMaxEntrySize {
+		return nil, fmt.Errorf("%w: %s archive entry %s exceeds %d bytes", ErrNoText, a.format, name, officeMaxEntrySize)
+	}
+	return data, nil
+}
+
+// relationships reads the targets of the relationships of an OOXML part by ID.
+// Targets are resolved to entry names in the archive.
+func (a *officeArchive) relationships(part string) map[string]string {
+	dir, file := path.Split(part)
+	data, err := a.read(dir + "_rels/" + file + ".rels")
+	if err != nil {
+		return nil
+	}
+
+	targets := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return targets
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "Relationship" {
+			target := xmlAttr(start, "Target")
+			if strings.HasPrefix(target, "/") {
+				target = strings.TrimPrefix(target, "/")
+			} else {
+				target = path.Join(dir, target)
+			}
+			targets[xmlAttr(start, "Id")] = target
+		}
+	}
+}
+
+// numberedEntries returns the entries named prefix + number + suffix, such as
+// ppt/slides/slide1.xml, ordered by number
+func (a *officeArchive) numberedEntries(prefix, suffix string) []string {
+	type entry struct {
+		name   string
+		number int
+	}
+	var entries []entry
+	for name := range a.files {
+		digits, ok := strings.CutPrefix(name, prefix)
+		if !ok {
+			continue
+		}
+		digits, ok = strings.CutSuffix(digits, suffix)
+		if number, err := strconv.Atoi(digits); ok && err == nil {
+			entries = append(entries, entry{name, number})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].number < entries[j].number
+	})
+
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// xmlAttr returns the value of the attribute with the given local name
+func xmlAttr(start xml.StartElement, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// relationshipsNS is the namespace of r:id attributes referring to relationships
+const relationshipsNS = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+
+// xmlRelationshipID returns the r:id attribute of an element, which unlike
+// xmlAttr is not confused by a plain id attribute
+func xmlRelationshipID(start xml.StartElement) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Space == relationshipsNS && attr.Name.Local == "id" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// paragraphWriter collects the paragraphs of a document as lines. The
+// paragraphs of a table cell are joined, and a table row becomes one line of
+// its cells. Nested tables are part of the cell containing them.
+type paragraphWriter struct {
+	lines      []string
+	paragraphs []*strings.Builder // Open paragraphs, which text boxes nest
+	cellDepth  int
+	cell       []string // Paragraphs of the current outermost cell
+	row        []string // Cells of the current outermost row
+}
+
+// startParagraph opens a paragraph
+func (w *paragraphWriter) startParagraph() {
+	w.paragraphs = append(w.paragraphs, &strings.Builder{})
+}
+
+// endParagraph closes the innermost paragraph
+func (w *paragraphWriter) endParagraph() {
+	if len(w.paragraphs) == 0 {
+		return
+	}
+	paragraph := w.paragraphs[len(w.paragraphs)-1].String()
+	w.paragraphs = w.paragraphs[:len(w.paragraphs)-1]
+
+	for _, line := range strings.Split(paragraph, "\n") {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			continue
+		}
+		if w.cellDepth > 0 {
+			w.cell = append(w.cell, line)
+		} else {
+			w.lines = append(w.lines, line)
+		}
+	}
+}
+
+// text adds text to the innermost paragraph
+func (w *paragraphWriter) text(s string) {
+	if len(w.paragraphs) > 0 {
+		w.paragraphs[len(w.paragraphs)-1].WriteString(s)
+	}
+}
+
+// inParagraph reports whether a paragraph is open
+func (w *paragraphWriter) inParagraph() bool {
+	return len(w.paragraphs) > 0
+}
+
+// startCell opens a table cell
+func (w *paragraphWriter) startCell() {
+	w.cellDepth++
+}
+
+// endCell closes a table cell, adding the outermost cells to the row
+func (w *paragraphWriter) endCell() {
+	if w.cellDepth == 0 {
+		return
+	}
+	w.cellDepth--
+	if w.cellDepth == 0 {
+		w.row = append(w.row, strings.Join(w.cell, " "))
+		w.cell = nil
+	}
+}
+
+// endRow closes a table row, writing the outermost rows as a line
+func (w *paragraphWriter) endRow() {
+	if w.cellDepth > 0 {
+		return
+	}
+	if line := joinCells(w.row); line != "" {
+		w.lines = append(w.lines, line)
+	}
+	w.row = nil
+}
+
+// joinCells joins the cells of a row, dropping empty cells at its end
+func joinCells(cells []string) string {
+	for len(cells) > 0 && cells[len(cells)-1] == "" {
+		cells = cells[:len(cells)-1]
+	}
+	return strings.Join(cells, cellSeparator)
+}
+
+// readOOXMLText reads the paragraphs and tables of WordprocessingML or
+// DrawingML markup. onElement, if not nil, is called with the start and end
+// of every other element.
+func readOOXMLText(data []byte, w *paragraphWriter, onElement func(token xml.Token)) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				w.startParagraph()
+			case "t":
+				inText = true
+			case "tab":
+				w.text("\t")
+			case "br", "cr":
+				w.text("\n")
+			case "tc":
+				w.startCell()
+			case "Fallback", "tabs":
+				// Alternate content repeats the text of its choice, and the
+				// tab stops of paragraph properties are no text
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+			default:
+				if onElement != nil {
+					onElement(t)
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				w.endParagraph()
+			case "t":
+				inText = false
+			case "tc":
+				w.endCell()
+			case "tr":
+				w.endRow()
+			default:
+				if onElement != nil {
+					onElement(t)
+				}
+			}
+		case xml.CharData:
+			if inText {
+				w.text(string(t))
+			}
+		}
+	}
+}
+
+// docxExtractor extracts the text of Word documents
+type docxExtractor struct{}
+
+// Name returns "DOCX"
+func (docxExtractor) Name() string {
+	return "DOCX"
+}
+
+// Extract returns the paragraphs and table rows of the main document body
+func (docxExtractor) Extract(data []byte) (*Text, error) {
+	archive, err := openOfficeArchive(data, "DOCX")
+	if err != nil {
+		return nil, err
+	}
+	body, err := archive.read("word/document.xml")
+	if err != nil {
+		return nil, err
+	}
+
+	w := &paragraphWriter{}
+	if err := readOOXMLText(body, w, nil); err != nil {
+		return nil, fmt.Errorf("%w: malformed DOCX document: %v", ErrNoText, err)
+	}
+	return &Text{Lines: w.lines}, nil
+}
+
+// pptxExtractor extracts the text of PowerPoint presentations
+type pptxExtractor struct{}
+
+// Name returns "PPTX"
+func (pptxExtractor) Name() string {
+	return "PPTX"
+}
+
+// Extract returns the text of every slide in presentation order. Lines are
+// located by their slide, named after its title.
+func (pptxExtractor) Extract(data []byte) (*Text, error) {
+	archive, err := openOfficeArchive(data, "PPTX")
+	if err != nil {
+		return nil, err
+	}
+
+	text := &Text{}
+	for i, slide := range pptxSlides(archive) {
+		content, err := archive.read(slide)
+		if err != nil {
+			return nil, err
+		}
+
+		// The title is the text of the shape holding the title placeholder
+		w := &paragraphWriter{}
+		title := ""
+		shapeStart, isTitle := 0, false
+		err = readOOXMLText(content, w, func(token xml.Token) {
+			switch t := token.(type) {
+			case xml.StartElement:
+				switch t.Name.Local {
+				case "sp":
+					shapeStart, isTitle = len(w.lines), false
+				case "ph":
+					kind := xmlAttr(t, "type")
+					isTitle = kind == "title" || kind == "ctrTitle"
+				}
+			case xml.EndElement:
+				if t.Name.Local == "sp" && isTitle && title == "" {
+					title = strings.Join(w.lines[shapeStart:], " ")
+				}
+			}
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed PPTX slide %d: %v", ErrNoText, i+1, err)
+		}
+
+		section := fmt.Sprintf("Slide %d", i+1)
+		if title != "" {
+			section = fmt.Sprintf("Slide %d (%s)", i+1, title)
+		}
+		for j, line := range w.lines {
+			text.add(line, Location{Section: section, Line: j + 1})
+		}
+	}
+	return text, nil
+}
+
+// pptxSlides returns the slides of a presentation in order, as listed by the
+// presentation or else by their numbers
+func pptxSlides(archive *officeArchive) []string {
+	data, err := archive.read("ppt/presentation.xml")
+	if err == nil {
+		targets := archive.relationships("ppt/presentation.xml")
+		var slides []string
+		decoder := xml.NewDecoder(bytes.NewReader(data))
+		for {
+			token, err := decoder.Token()
+			if err != nil {
+				break
+			}
+			if start, ok := token.(xml.StartElement); ok && start.Name.Local == "sldId" {
+				if target, ok := targets[xmlRelationshipID(start)]; ok && archive.has(target) {
+					slides = append(slides, target)
+				}
+			}
+		}
+		if len(slides) > 0 {
+			return slides
+		}
+	}
+	return archive.numberedEntries("ppt/slides/slide", ".xml")
+}
@@ -0,0 +1,236 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildZip writes a zip archive with the given entries
+func buildZip(t *testing.T, entries map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range entries {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const (
+	wordNS   = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" xmlns:mc="http://schemas.openxmlformats.org/markup-compatibility/2006"`
+	drawNS   = `xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	sheetNS  = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	relsNS   = `xmlns="http://schemas.openxmlformats.org/package/2006/relationships"`
+	officeNS = `xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0" xmlns:draw="urn:oasis:names:tc:opendocument:xmlns:drawing:1.0" xmlns:presentation="urn:oasis:names:tc:opendocument:xmlns:presentation:1.0"`
+)
+
+// checkText compares extracted lines and locations, ignoring locations if want is nil
+func checkText(t *testing.T, text *Text, lines []string, locations []Location) {
+	t.Helper()
+	if !reflect.DeepEqual(text.Lines, lines) {
+		t.Errorf("lines = %q, want %q", text.Lines, lines)
+	}
+	if !reflect.DeepEqual(text.Locations, locations) {
+		t.Errorf("locations = %v, want %v", text.Locations, locations)
+	}
+}
+
+func TestDOCXExtract(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8"?>
+<w:document ` + wordNS + `><w:body>
+<w:p><w:pPr><w:tabs><w:tab w:val="left" w:pos="720"/></w:tabs></w:pPr><w:r><w:t>Deployment</w:t></w:r><w:r><w:t xml:space="preserve"> guide</w:t></w:r></w:p>
+<w:p/>
+<w:p><w:r><w:t>Name</w:t><w:tab/><w:t>Value</w:t><w:br/><w:t>Second line</w:t></w:r><w:del><w:r><w:delText>removed</w:delText></w:r></w:del></w:p>
+<w:tbl>
+<w:tr><w:tc><w:p><w:r><w:t>Region</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Owner</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>eu-west</w:t></w:r></w:p><w:p><w:r><w:t>backup</w:t></w:r></w:p></w:tc><w:tc><w:p/></w:tc></w:tr>
+</w:tbl>
+<w:p><w:r><mc:AlternateContent><mc:Choice><w:drawing><w:txbxContent><w:p><w:r><w:t>Text box</w:t></w:r></w:p></w:txbxContent></w:drawing></mc:Choice><mc:Fallback><w:pict><w:txbxContent><w:p><w:r><w:t>Text box</w:t></w:r></w:p></w:txbxContent></w:pict></mc:Fallback></mc:AlternateContent></w:r><w:r><w:t>After</w:t></w:r></w:p>
+</w:body></w:document>`
+
+	text, err := docxExtractor{}.Extract(buildZip(t, map[string]string{"word/document.xml": document}))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text, []string{
+		"Deployment guide",
+		"Name Value",
+		"Second line",
+		"Region | Owner",
+		"eu-west backup",
+		"Text box",
+		"After",
+	}, nil)
+}
+
+func TestPPTXExtract(t *testing.T) {
+	slide := func(title, body string) string {
+		return `<p:sld ` + drawNS + `><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="body" idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + body + `</a:t></a:r><a:br/><a:r><a:t>more</a:t></a:r></a:p></p:txBody></p:sp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`
+	}
+
+	// The presentation lists slide 2 before slide 1
+	data := buildZip(t, map[string]string{
+		"ppt/presentation.xml":            `<p:presentation ` + drawNS + `><p:sldIdLst><p:sldId id="257" r:id="rId3"/><p:sldId id="256" r:id="rId2"/></p:sldIdLst></p:presentation>`,
+		"ppt/_rels/presentation.xml.rels": `<Relationships ` + relsNS + `><Relationship Id="rId2" Target="slides/slide1.xml"/><Relationship Id="rId3" Target="/ppt/slides/slide2.xml"/></Relationships>`,
+		"ppt/slides/slide1.xml":           slide("Roadmap", "Quarterly goals"),
+		"ppt/slides/slide2.xml":           slide("", "Agenda"),
+	})
+
+	text, err := pptxExtractor{}.Extract(data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text,
+		[]string{"Agenda", "more", "Quarterly goals", "more", "Roadmap"},
+		[]Location{
+			{Section: "Slide 1", Line: 1},
+			{Section: "Slide 1", Line: 2},
+			{Section: "Slide 2 (Roadmap)", Line: 1},
+			{Section: "Slide 2 (Roadmap)", Line: 2},
+			{Section: "Slide 2 (Roadmap)", Line: 3},
+		})
+}
+
+func TestPPTXExtractWithoutPresentation(t *testing.T) {
+	slide := `<p:sld ` + drawNS + `><p:cSld><p:spTree><p:sp><p:txBody><a:p><a:r><a:t>Slide %</a:t></a:r></a:p></p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	data := buildZip(t, map[string]string{
+		"ppt/slides/slide10.xml": strings.Replace(slide, "%", "ten", 1),
+		"ppt/slides/slide2.xml":  strings.Replace(slide, "%", "two", 1),
+	})
+
+	text, err := pptxExtractor{}.Extract(data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text, []string{"Slide two", "Slide ten"}, []Location{{Section: "Slide 1", Line: 1}, {Section: "Slide 2", Line: 1}})
+}
+
+func TestXLSXExtract(t *testing.T) {
+	data := buildZip(t, map[string]string{
+		"xl/workbook.xml": `<workbook ` + sheetNS + `><sheets><sheet name="Budget" sheetId="1" r:id="rId1"/><sheet name="Chart" sheetId="3" r:id="rId3"/><sheet name="Notes" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships ` + relsNS + `>` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/><Relationship Id="rId3" Target="chartsheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst ` + sheetNS + `><si><t>Item</t></si><si><t>Cost</t></si><si><r><t>Cloud</t></r><r><t xml:space="preserve"> hosting</t></r><rPh><t>phonetic</t></rPh></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet ` + sheetNS + `><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="4"><c r="A4" t="s"><v>2</v></c><c r="B4"><v>1200.5</v></c><c r="C4" t="b"><v>1</v></c></row>
+<row r="5"><c r="A5"/></row>
+<row r="6"><c r="B6" t="str"><f>A4&amp;"!"</f><v>Cloud hosting!</v></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml":  `<worksheet ` + sheetNS + `><sheetData><row><c t="inlineStr"><is><t>Reviewed</t></is></c></row></sheetData></worksheet>`,
+		"xl/chartsheets/sheet1.xml": `<chartsheet ` + sheetNS + `/>`,
+	})
+
+	text, err := xlsxExtractor{}.Extract(data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	checkText(t, text,
+		[]string{"Item | Cost", "Cloud hosting | 1200.5 | TRUE", "Cloud hosting!", "Reviewed"},
+		[]Location{
+			{Section: "Sheet Budget", Line: 1},
+			{Section: "Sheet Budget", Line: 4},
+			{Section: "Sheet Budget", Line: 6},
+			{Section: "Sheet Notes", Line: 1},
+		})
+}
+
+func TestODFExtract(t *testing.T) {
+	content := func(body string) map[string]string {
+		return map[string]string{"content.xml": `<office:document-content ` + officeNS + `><office:body>` + body + `</office:body></office:document-content>`}
+	}
+
+	t.Run("text", func(t *testing.T) {
+		data := buildZip(t, content(`<office:text>
+<text:h text:outline-level="1">Release <text:span>notes</text:span></text:h>
+<text:p>Fixed<text:s text:c="3"/>crash<text:note><text:note-citation>1</text:note-citation><text:note-body><text:p>Footnote text</text:p></text:note-body></text:note></text:p>
+<text:p>First<text:line-break/>Second<office:annotation><text:p>A comment</text:p></office:annotation></text:p>
+<table:table table:name="T1"><table:table-row><table:table-cell><text:p>a</text:p></table:table-cell><table:table-cell><text:p>b</text:p></table:table-cell></table:table-row></table:table>
+</office:text>`))
+
+		text, err := odfExtractor{}.Extract(data)
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		checkText(t, text, []string{"Release notes", "Footnote text", "Fixed crash", "First", "Second", "a | b"}, nil)
+	})
+
+	t.Run("spreadsheet", func(t *testing.T) {
+		data := buildZip(t, content(`<office:spreadsheet>
+<table:table table:name="Budget">
+<table:table-row><table:table-cell><text:p>Item</text:p></table:table-cell><table:table-cell table:number-columns-repeated="3"/><table:table-cell><text:p>Cost</text:p></table:table-cell></table:table-row>
+<table:table-row table:number-rows-repeated="5"><table:table-cell/></table:table-row>
+<table:table-row><table:table-cell><text:p>Hosting</text:p></table:table-cell></table:table-row>
+</table:table>
+<table:table table:name="Notes"><table:table-row><table:table-cell><text:p>Reviewed</text:p></table:table-cell></table:table-row></table:table>
+</office:spreadsheet>`))
+
+		text, err := odfExtractor{}.Extract(data)
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		checkText(t, text,
+			[]string{"Item |  | Cost", "Hosting", "Reviewed"},
+			[]Location{{Section: "Sheet Budget", Line: 1}, {Section: "Sheet Budget", Line: 7}, {Section: "Sheet Notes", Line: 1}})
+	})
+
+	t.Run("presentation", func(t *testing.T) {
+		data := buildZip(t, content(`<office:presentation>
+<draw:page draw:name="page1">
+<draw:frame presentation:class="title"><draw:text-box><text:p>Roadmap</text:p></draw:text-box></draw:frame>
+<draw:frame presentation:class="outline"><draw:text-box><text:p>Ship search</text:p></draw:text-box></draw:frame>
+<presentation:notes><draw:frame><draw:text-box><text:p>Speaker notes</text:p></draw:text-box></draw:frame></presentation:notes>
+</draw:page>
+<draw:page draw:name="page2"><draw:frame><draw:text-box><text:p>Questions</text:p></draw:text-box></draw:frame></draw:page>
+</office:presentation>`))
+
+		text, err := odfExtractor{}.Extract(data)
+		if err != nil {
+			t.Fatalf("Extract failed: %v", err)
+		}
+		checkText(t, text,
+			[]string{"Roadmap", "Ship search", "Questions"},
+			[]Location{{Section: "Slide 1 (Roadmap)", Line: 1}, {Section: "Slide 1 (Roadmap)", Line: 2}, {Section: "Slide 2", Line: 1}})
+	})
+}
+
+func TestOfficeExtractMalformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		extractor Extractor
+		data      []byte
+		reason    string
+	}{
+		{"not a zip archive", docxExtractor{}, []byte("PK\x03\x04 truncated"), "malformed DOCX archive"},
+		{"missing document", docxExtractor{}, buildZip(t, map[string]string{"word/styles.xml": "<styles/>"}), "missing word/document.xml"},
+		{"invalid XML", docxExtractor{}, buildZip(t, map[string]string{"word/document.xml": "<w:document><w:p>"}), "malformed DOCX document"},
+		{"missing workbook", xlsxExtractor{}, buildZip(t, map[string]string{}), "missing xl/workbook.xml"},
+		{"missing content", odfExtractor{}, buildZip(t, map[string]string{"mimetype": "application/vnd.oasis.opendocument.text"}), "missing content.xml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := extract(tt.extractor, tt.data)
+			if !errors.Is(err, ErrNoText) {
+				t.Fatalf("error = %v, want ErrNoText", err)
+			}
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,609 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/ascii85"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+const (
+	// pdfMaxStreamSize bounds the decoded size of a single stream
+	pdfMaxStreamSize = 64 << 20
+	// pdfMaxDepth bounds the nesting of page trees and form XObjects
+	pdfMaxDepth = 32
+)
+
+// pdfMagic starts every PDF file
+var pdfMagic = []byte("%PDF-")
+
+// pdfExtractor extracts the text of PDF files
+type pdfExtractor struct{}
+
+// Name returns "PDF"
+func (pdfExtractor) Name() string {
+	return "PDF"
+}
+
+// Detect recognizes PDF files by their header
+func (pdfExtractor) Detect(head []byte) bool {
+	return bytes.HasPrefix(head, pdfMagic)
+}
+
+// Extract extracts the lines of text of every page of a PDF file.
+// Encrypted PDFs and PDFs without a text layer return an error wrapping
+// ErrNoText, as do files too damaged to be read.
+func (pdfExtractor) Extract(data []byte) (*Text, error) {
+	if !bytes.Contains(data[:min(len(data), 1024)], pdfMagic) {
+		return nil, fmt.Errorf("%w: not a PDF file", ErrNoText)
+	}
+	doc, err := newPDFDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed PDF: %v", ErrNoText, err)
+	}
+	if doc.encrypted() {
+		return nil, fmt.Errorf("%w: PDF is encrypted", ErrNoText)
+	}
+
+	pages := doc.pages()
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: malformed PDF: no pages found", ErrNoText)
+	}
+
+	text := &Text{}
+	for i, page := range pages {
+		for j, line := range doc.pageText(page) {
+			text.add(line, Location{Page: i + 1, Line: j + 1})
+		}
+	}
+	if len(text.Lines) == 0 {
+		return nil, fmt.Errorf("%w: PDF has no text layer, it may be scanned or contain only images", ErrNoText)
+	}
+	return text, nil
+}
+
+// pdfDocument resolves the objects of a PDF file. Objects are located by
+// scanning the file for "N G obj" headers rather than trusting the xref
+// table, so files with a damaged xref table can still be read.
+type pdfDocument struct {
+	data       []byte
+	offsets    map[int]int       // Object number -> offset after its "obj" keyword
+	objects    map[int]pdfObject // Parsed objects
+	objStreams bool              // Whether objects of object streams were loaded
+	trailer    pdfDict
+	fonts      map[pdfRef]*pdfFont
+}
+
+// newPDFDocument indexes the objects of a PDF file and reads its trailer
+func newPDFDocument(data []byte) (*pdfDocument, error) {
+	d := &pdfDocument{
+		data:    data,
+		offsets: make(map[int]int),
+		objects: make(map[int]pdfObject),
+		fonts:   make(map[pdfRef]*pdfFont),
+	}
+	d.scanObjects()
+	if len(d.offsets) == 0 {
+		return nil, errors.New("no objects found")
+	}
+
+	d.trailer = d.findTrailer()
+	if d.trailer == nil {
+		return nil, errors.New("no trailer found")
+	}
+	return d, nil
+}
+
+// scanObjects records the offset of every object header. Objects updated
+// later in the file replace earlier versions, as with incremental updates.
+func (d *pdfDocument) scanObjects() {
+	data := d.data
+	for pos := 0; ; {
+		i := bytes.Index(data[pos:], []byte("obj"))
+		if i < 0 {
+			return
+		}
+		end := pos + i + 3
+		pos = end
+
+		if end < len(data) && !isPDFDelimiter(data[end]) {
+			continue
+		}
+		num, ok := objectHeader(data[:end-3])
+		if !ok {
+			continue
+		}
+		d.offsets[num] = end
+
+		// Skip stream data, which could contain anything
+		if s := bytes.Index(data[end:], []byte("endobj")); s >= 0 {
+			if st := bytes.Index(data[end:end+s], []byte("stream")); st >= 0 {
+				pos = end + s
+			}
+		}
+	}
+}
+
+// objectHeader parses the "N G " before an obj keyword and returns N
+func objectHeader(before []byte) (int, bool) {
+	i := len(before)
+	skipSpace := func() bool {
+		start := i
+		for i > 0 && isPDFSpace(before[i-1]) {
+			i--
+		}
+		return i < start
+	}
+	digits := func() (int, bool) {
+		end := i
+		for i > 0 && before[i-1] >= '0' && before[i-1] <= '9' {
+			i--
+		}
+		if i == end || end-i > 10 {
+			return 0, false
+		}
+		v, err := strconv.Atoi(string(before[i:end]))
+		return v, err == nil
+	}
+
+	if !skipSpace() {
+		return 0, false
+	}
+	if _, ok := digits(); !ok || !skipSpace() {
+		return 0, false
+	}
+	num, ok := digits()
+	if !ok || (i > 0 && !isPDFDelimiter(before[i-1])) {
+		return 0, false
+	}
+	return num, true
+}
+
+// findTrailer returns the trailer dictionary of the last update: the one
+// pointed to by startxref, or the last trailer in the file
+func (d *pdfDocument) findTrailer() pdfDict {
+	if i := bytes.LastIndex(d.data, []byte("startxref")); i >= 0 {
+		lexer := &pdfLexer{data: d.data, pos: i + len("startxref")}
+		if offset, ok := lexerInt(lexer); ok && offset > 0 && offset < len(d.data) {
+			if trailer := d.trailerAt(offset); trailer != nil {
+				return trailer
+			}
+		}
+	}
+
+	if i := bytes.LastIndex(d.data, []byte("trailer")); i >= 0 {
+		lexer := &pdfLexer{data: d.data, pos: i + len("trailer")}
+		if dict, err := lexer.readObject(); err == nil {
+			if trailer, ok := dict.(pdfDict); ok {
+				return trailer
+			}
+		}
+	}
+
+	// Without a trailer, use any document catalog
+	for num := range d.offsets {
+		if dict, ok := d.resolve(pdfRef{num: num}).(pdfDict); ok && dict["Type"] == pdfName("Catalog") {
+			return pdfDict{"Root": pdfRef{num: num}}
+		}
+	}
+	return nil
+}
+
+// trailerAt reads the trailer of the xref table or the dictionary of the
+// xref stream at offset
+func (d *pdfDocument) trailerAt(offset int) pdfDict {
+	lexer := &pdfLexer{data: d.data, pos: offset}
+	lexer.skipSpace()
+	if bytes.HasPrefix(d.data[lexer.pos:], []byte("xref")) {
+		i := bytes.Index(d.data[lexer.pos:], []byte("trailer"))
+		if i < 0 {
+			return nil
+		}
+		lexer.pos += i + len("trailer")
+		trailer, _ := lexer.readObject()
+		dict, _ := trailer.(pdfDict)
+		return dict
+	}
+
+	// An xref stream: N G obj << ... >> stream
+	for range 3 {
+		if _, err := lexer.readObject(); err != nil {
+			return nil
+		}
+	}
+	obj, _ := lexer.readObject()
+	dict, _ := obj.(pdfDict)
+	if dict == nil || dict["Type"] != pdfName("XRef") {
+		return nil
+	}
+	return dict
+}
+
+// lexerInt reads an integer with the lexer
+func lexerInt(lexer *pdfLexer) (int, bool) {
+	obj, err := lexer.readObject()
+	if err != nil {
+		return 0, false
+	}
+	v, ok := obj.(int64)
+	return int(v), ok
+}
+
+// encrypted reports whether the document is encrypted
+func (d *pdfDocument) encrypted() bool {
+	_, ok := d.trailer["Encrypt"]
+	return ok
+}
+
+// resolve follows references until it reaches a direct object.
+// Missing objects resolve to nil.
+func (d *pdfDocument) resolve(obj pdfObject) pdfObject {
+	for range pdfMaxDepth {
+		ref, ok := obj.(pdfRef)
+		if !ok {
+			return obj
+		}
+		obj = d.object(ref.num)
+	}
+	return nil
+}
+
+// object returns the object with the given number, parsing it on first use
+func (d *pdfDocument) object(num int) pdfObject {
+	if obj, ok := d.objects[num]; ok {
+		return obj
+	}
+
+	offset, ok := d.offsets[num]
+	if !ok {
+		if !d.objStreams {
+			d.loadObjectStreams()
+			return d.objects[num]
+		}
+		return nil
+	}
+
+	// Mark the object as being parsed so that a reference cycle through
+	// the stream length cannot recurse
+	d.objects[num] = nil
+	obj := d.parseObjectAt(offset)
+	d.objects[num] = obj
+	return obj
+}
+
+// parseObjectAt parses the object after an obj keyword, including its stream data
+func (d *pdfDocument) parseObjectAt(offset int) pdfObject {
+	lexer := &pdfLexer{data: d.data, pos: offset}
+	obj, err := lexer.readObject()
+	if err != nil {
+		return nil
+	}
+	dict, ok := obj.(pdfDict)
+	if !ok {
+		return obj
+	}
+
+	lexer.skipSpace()
+	if !bytes.HasPrefix(d.data[lexer.pos:], []byte("stream")) {
+		return dict
+	}
+
+	// Stream data starts after the end of line following the keyword
+	start := lexer.pos + len("stream")
+	if start < len(d.data) && d.data[start] == '\r' {
+		start++
+	}
+	if start < len(d.data) && d.data[start] == '\n' {
+		start++
+	}
+
+	if length, ok := d.resolve(dict["Length"]).(int64); ok && length >= 0 && start+int(length) <= len(d.data) {
+		end := start + int(length)
+		rest := bytes.TrimLeft(d.data[end:min(end+32, len(d.data))], "\x00\t\n\f\r ")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			return &pdfStream{dict: dict, data: d.data[start:end]}
+		}
+	}
+
+	// The length is missing or wrong, so look for the end of the stream
+	end := bytes.Index(d.data[start:], []byte("endstream"))
+	if end < 0 {
+		return &pdfStream{dict: dict, data: d.data[start:]}
+	}
+	data := d.data[start : start+end]
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	data = bytes.TrimSuffix(data, []byte("\r"))
+	return &pdfStream{dict: dict, data: data}
+}
+
+// loadObjectStreams parses the objects stored in object streams
+func (d *pdfDocument) loadObjectStreams() {
+	d.objStreams = true
+	for num := range d.offsets {
+		stream, ok := d.object(num).(*pdfStream)
+		if !ok || stream.dict["Type"] != pdfName("ObjStm") {
+			continue
+		}
+		data, err := d.streamData(stream)
+		if err != nil {
+			continue
+		}
+
+		count, _ := d.resolve(stream.dict["N"]).(int64)
+		first, _ := d.resolve(stream.dict["First"]).(int64)
+		header := &pdfLexer{data: data}
+		for range count {
+			objNum, ok1 := lexerInt(header)
+			objOffset, ok2 := lexerInt(header)
+			if !ok1 || !ok2 {
+				break
+			}
+			if _, exists := d.offsets[objNum]; exists {
+				continue
+			}
+			if _, exists := d.objects[objNum]; exists {
+				continue
+			}
+			lexer := &pdfLexer{data: data, pos: int(first) + objOffset}
+			if lexer.pos >= len(data) {
+				continue
+			}
+			obj, err := lexer.readObject()
+			if err == nil {
+				d.objects[objNum] = obj
+			}
+		}
+	}
+}
+
+// dict resolves obj to a dictionary, which is nil if it is none.
+// The dictionary of a stream is returned for streams.
+func (d *pdfDocument) dict(obj pdfObject) pdfDict {
+	switch v := d.resolve(obj).(type) {
+	case pdfDict:
+		return v
+	case *pdfStream:
+		return v.dict
+	}
+	return nil
+}
+
+// array resolves obj to an array, which is nil if it is none
+func (d *pdfDocument) array(obj pdfObject) []pdfObject {
+	array, _ := d.resolve(obj).([]pdfObject)
+	return array
+}
+
+// streamData returns the decoded data of a stream
+func (d *pdfDocument) streamData(stream *pdfStream) ([]byte, error) {
+	var filters, params []pdfObject
+	switch filter := d.resolve(stream.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []pdfObject{filter}
+		params = []pdfObject{stream.dict["DecodeParms"]}
+	case []pdfObject:
+		filters = filter
+		params = d.array(stream.dict["DecodeParms"])
+	}
+
+	data := stream.data
+	for i, filter := range filters {
+		var param pdfDict
+		if i < len(params) {
+			param = d.dict(params[i])
+		}
+
+		var err error
+		switch d.resolve(filter) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			data, err = flateDecode(data)
+			if err == nil {
+				data, err = d.applyPredictor(data, param)
+			}
+		case pdfName("ASCIIHexDecode"), pdfName("AHx"):
+			data = []byte((&pdfLexer{data: data}).readHexString())
+		case pdfName("ASCII85Decode"), pdfName("A85"):
+			data, err = ascii85Decode(data)
+		case pdfName("RunLengthDecode"), pdfName("RL"):
+			data = runLengthDecode(data)
+		default:
+			err = fmt.Errorf("unsupported filter %v", filter)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// flateDecode inflates zlib compressed data. Data that ends early, as in
+// many damaged files, is returned as far as it could be inflated.
+func flateDecode(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	defer reader.Close()
+
+	out, err := io.ReadAll(io.LimitReader(reader, pdfMaxStreamSize))
+	if err != nil && len(out) == 0 {
+		return nil, fmt.Errorf("failed to inflate stream: %w", err)
+	}
+	return out, nil
+}
+
+// applyPredictor reverses the PNG predictors of the decode parameters
+func (d *pdfDocument) applyPredictor(data []byte, params pdfDict) ([]byte, error) {
+	predictor, _ := d.resolve(params["Predictor"]).(int64)
+	if predictor < 10 {
+		// No predictor; TIFF predictors are not used for text or object data
+		return data, nil
+	}
+
+	columns, ok := d.resolve(params["Columns"]).(int64)
+	if !ok || columns <= 0 {
+		columns = 1
+	}
+	colors, ok := d.resolve(params["Colors"]).(int64)
+	if !ok || colors <= 0 {
+		colors = 1
+	}
+	bits, ok := d.resolve(params["BitsPerComponent"]).(int64)
+	if !ok || bits <= 0 {
+		bits = 8
+	}
+	bpp := int(max((colors*bits+7)/8, 1))
+	rowSize := int((columns*colors*bits + 7) / 8)
+
+	out := make([]byte, 0, len(data))
+	prev := make([]byte, rowSize)
+	for len(data) > rowSize {
+		filter, row := data[0], data[1:rowSize+1]
+		data = data[rowSize+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth is the Paeth predictor of the PNG specification
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	}
+	return c
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// ascii85Decode decodes ASCII base-85 data up to its ~> end marker
+func ascii85Decode(data []byte) ([]byte, error) {
+	data = bytes.TrimPrefix(bytes.TrimSpace(data), []byte("<~"))
+	if i := bytes.Index(data, []byte("~>")); i >= 0 {
+		data = data[:i]
+	}
+	out := make([]byte, 4*len(data)/5+4)
+	n, _, err := ascii85.Decode(out, data, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode ASCII85 stream: %w", err)
+	}
+	return out[:n], nil
+}
+
+// runLengthDecode decodes the PackBits style run length encoding of PDF
+func runLengthDecode(data []byte) []byte {
+	var out []byte
+	for i := 0; i < len(data); {
+		n := int(data[i])
+		i++
+		switch {
+		case n == 128:
+			return out
+		case n < 128:
+			end := min(i+n+1, len(data))
+			out = append(out, data[i:end]...)
+			i = end
+		case i < len(data):
+			out = append(out, bytes.Repeat(data[i:i+1], 257-n)...)
+			i++
+		}
+	}
+	return out
+}
+
+// pdfPage is a page with the resources it inherits from the page tree
+type pdfPage struct {
+	dict      pdfDict
+	resources pdfDict
+}
+
+// pages returns the pages of the document in order
+func (d *pdfDocument) pages() []pdfPage {
+	root := d.dict(d.trailer["Root"])
+	var pages []pdfPage
+	visited := make(map[pdfRef]bool)
+
+	var walk func(node pdfObject, resources pdfDict, depth int)
+	walk = func(node pdfObject, resources pdfDict, depth int) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		dict := d.dict(node)
+		if dict == nil || depth > pdfMaxDepth {
+			return
+		}
+		if r := d.dict(dict["Resources"]); r != nil {
+			resources = r
+		}
+
+		kids := d.array(dict["Kids"])
+		if dict["Type"] == pdfName("Page") || (kids == nil && dict["Contents"] != nil) {
+			pages = append(pages, pdfPage{dict: dict, resources: resources})
+			return
+		}
+		for _, kid := range kids {
+			walk(kid, resources, depth+1)
+		}
+	}
+	walk(root["Pages"], nil, 0)
+	return pages
+}
+
+// pageContents returns the decoded content streams of a page, concatenated
+func (d *pdfDocument) pageContents(page pdfPage) []byte {
+	var streams []pdfObject
+	switch contents := d.resolve(page.dict["Contents"]).(type) {
+	case *pdfStream:
+		streams = []pdfObject{contents}
+	case []pdfObject:
+		streams = contents
+	}
+
+	var buf []byte
+	for _, obj := range streams {
+		stream, ok := d.resolve(obj).(*pdfStream)
+		if !ok {
+			continue
+		}
+		data, err := d.streamData(stream)
+		if err != nil {
+			continue
+		}
+		buf = append(buf, data...)
+		buf = append(buf, '\n')
+	}
+	return buf
+}
@@ -0,0 +1,117 @@
+package extract
+
+// Predefined encodings of simple fonts, mapping character codes to runes.
+// Codes without a character map to 0.
+var (
+	standardEncoding [256]rune
+	winAnsiEncoding  [256]rune
+	macRomanEncoding [256]rune
+)
+
+// glyphNames maps the glyph names used in encoding differences to runes
+var glyphNames = make(map[string]rune)
+
+// asciiGlyphNames are the names of the printable ASCII characters, from 0x20
+var asciiGlyphNames = []string{
+	"space", "exclam", "quotedbl", "numbersign", "dollar", "percent", "ampersand", "quotesingle",
+	"parenleft", "parenright", "asterisk", "plus", "comma", "hyphen", "period", "slash",
+	"zero", "one", "two", "three", "four", "five", "six", "seven",
+	"eight", "nine", "colon", "semicolon", "less", "equal", "greater", "question",
+	"at", "A", "B", "C", "D", "E", "F", "G", "H", "I", "J", "K", "L", "M", "N", "O",
+	"P", "Q", "R", "S", "T", "U", "V", "W", "X", "Y", "Z",
+	"bracketleft", "backslash", "bracketright", "asciicircum", "underscore",
+	"grave", "a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k", "l", "m", "n", "o",
+	"p", "q", "r", "s", "t", "u", "v", "w", "x", "y", "z",
+	"braceleft", "bar", "braceright", "asciitilde",
+}
+
+// latin1GlyphNames are the names of the Latin-1 characters, from 0xA0
+var latin1GlyphNames = []string{
+	"nbspace", "exclamdown", "cent", "sterling", "currency", "yen", "brokenbar", "section",
+	"dieresis", "copyright", "ordfeminine", "guillemotleft", "logicalnot", "sfthyphen", "registered", "macron",
+	"degree", "plusminus", "twosuperior", "threesuperior", "acute", "mu", "paragraph", "periodcentered",
+	"cedilla", "onesuperior", "ordmasculine", "guillemotright", "onequarter", "onehalf", "threequarters", "questiondown",
+	"Agrave", "Aacute", "Acircumflex", "Atilde", "Adieresis", "Aring", "AE", "Ccedilla",
+	"Egrave", "Eacute", "Ecircumflex", "Edieresis", "Igrave", "Iacute", "Icircumflex", "Idieresis",
+	"Eth", "Ntilde", "Ograve", "Oacute", "Ocircumflex", "Otilde", "Odieresis", "multiply",
+	"Oslash", "Ugrave", "Uacute", "Ucircumflex", "Udieresis", "Yacute", "Thorn", "germandbls",
+	"agrave", "aacute", "acircumflex", "atilde", "adieresis", "aring", "ae", "ccedilla",
+	"egrave", "eacute", "ecircumflex", "edieresis", "igrave", "iacute", "icircumflex", "idieresis",
+	"eth", "ntilde", "ograve", "oacute", "ocircumflex", "otilde", "odieresis", "divide",
+	"oslash", "ugrave", "uacute", "ucircumflex", "udieresis", "yacute", "thorn", "ydieresis",
+}
+
+// winAnsiHigh are the characters of codes 0x80-0x9F in WinAnsiEncoding with their names
+var winAnsiHigh = []struct {
+	code byte
+	r    rune
+	name string
+}{
+	{0x80, '€', "Euro"}, {0x82, '‚', "quotesinglbase"}, {0x83, 'ƒ', "florin"}, {0x84, '„', "quotedblbase"},
+	{0x85, '…', "ellipsis"}, {0x86, '†', "dagger"}, {0x87, '‡', "daggerdbl"}, {0x88, 'ˆ', "circumflex"},
+	{0x89, '‰', "perthousand"}, {0x8A, 'Š', "Scaron"}, {0x8B, '‹', "guilsinglleft"}, {0x8C, 'Œ', "OE"},
+	{0x8E, 'Ž', "Zcaron"}, {0x91, '‘', "quoteleft"}, {0x92, '’', "quoteright"}, {0x93, '“', "quotedblleft"},
+	{0x94, '”', "quotedblright"}, {0x95, '•', "bullet"}, {0x96, '–', "endash"}, {0x97, '—', "emdash"},
+	{0x98, '˜', "tilde"}, {0x99, '™', "trademark"}, {0x9A, 'š', "scaron"}, {0x9B, '›', "guilsinglright"},
+	{0x9C, 'œ', "oe"}, {0x9E, 'ž', "zcaron"}, {0x9F, 'Ÿ', "Ydieresis"},
+}
+
+// macRomanHigh are the characters of codes 0x80-0xFF in MacRomanEncoding
+const macRomanHigh = "ÄÅÇÉÑÖÜáàâäãåçéè" +
+	"êëíìîïñóòôöõúùûü" +
+	"†°¢£§•¶ß®©™´¨≠ÆØ" +
+	"∞±≤≥¥µ∂∑∏π∫ªºΩæø" +
+	"¿¡¬√ƒ≈∆«»…\u00a0ÀÃÕŒœ" +
+	"–—“”‘’÷◊ÿŸ⁄€‹›ﬁﬂ" +
+	"‡·‚„‰ÂÊÁËÈÍÎÏÌÓÔ" +
+	"\uf8ffÒÚÛÙıˆ˜¯˘˙˚¸˝˛ˇ"
+
+// standardHigh are the characters of StandardEncoding that differ from ASCII
+var standardHigh = map[byte]rune{
+	0x27: '’', 0x60: '‘',
+	0xA1: '¡', 0xA2: '¢', 0xA3: '£', 0xA4: '⁄', 0xA5: '¥', 0xA6: 'ƒ', 0xA7: '§', 0xA8: '¤',
+	0xA9: '\'', 0xAA: '“', 0xAB: '«', 0xAC: '‹', 0xAD: '›', 0xAE: 'ﬁ', 0xAF: 'ﬂ',
+	0xB1: '–', 0xB2: '†', 0xB3: '‡', 0xB4: '·', 0xB6: '¶', 0xB7: '•', 0xB8: '‚', 0xB9: '„',
+	0xBA: '”', 0xBB: '»', 0xBC: '…', 0xBD: '‰', 0xBF: '¿',
+	0xC1: '`', 0xC2: '´', 0xC3: 'ˆ', 0xC4: '˜', 0xC5: '¯', 0xC6: '˘', 0xC7: '˙', 0xC8: '¨',
+	0xCA: '˚', 0xCB: '¸', 0xCD: '˝', 0xCE: '˛', 0xCF: 'ˇ', 0xD0: '—',
+	0xE1: 'Æ', 0xE3: 'ª', 0xE8: 'Ł', 0xE9: 'Ø', 0xEA: 'Œ', 0xEB: 'º',
+	0xF1: 'æ', 0xF5: 'ı', 0xF8: 'ł', 0xF9: 'ø', 0xFA: 'œ', 0xFB: 'ß',
+}
+
+// extraGlyphNames are glyph names outside of WinAnsiEncoding
+var extraGlyphNames = map[string]rune{
+	"ff": 'ﬀ', "fi": 'ﬁ', "fl": 'ﬂ', "ffi": 'ﬃ', "ffl": 'ﬄ',
+	"minus": '−', "fraction": '⁄', "dotlessi": 'ı', "Lslash": 'Ł', "lslash": 'ł',
+	"ring": '˚', "breve": '˘', "dotaccent": '˙', "hungarumlaut": '˝', "ogonek": '˛', "caron": 'ˇ',
+	"quoteright": '’', "quoteleft": '‘', "nbspace": ' ', "space": ' ', "hyphen": '-',
+}
+
+func init() {
+	for i, name := range asciiGlyphNames {
+		r := rune(0x20 + i)
+		glyphNames[name] = r
+		standardEncoding[r] = r
+		winAnsiEncoding[r] = r
+		macRomanEncoding[r] = r
+	}
+	for i, name := range latin1GlyphNames {
+		r := rune(0xA0 + i)
+		glyphNames[name] = r
+		winAnsiEncoding[r] = r
+	}
+	for _, c := range winAnsiHigh {
+		glyphNames[c.name] = c.r
+		winAnsiEncoding[c.code] = c.r
+	}
+	for name, r := range extraGlyphNames {
+		glyphNames[name] = r
+	}
+
+	for code, r := range standardHigh {
+		standardEncoding[code] = r
+	}
+	for i, r := range []rune(macRomanHigh) {
+		macRomanEncoding[0x80+i] = r
+	}
+}
@@ -0,0 +1,353 @@
+package extract
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// pdfFont maps the character codes of shown strings to text and glyph widths
+type pdfFont struct {
+	composite    bool        // Type0 font with multi-byte codes
+	codespace    []codespace // Code lengths of a composite font or of the ToUnicode CMap
+	toUnicode    map[uint32]string
+	encoding     *[256]rune         // Code to rune of simple fonts
+	widths       map[uint32]float64 // Code (simple) or CID (composite) to width in text space units
+	defaultWidth float64
+}
+
+// codespace is a range of codes with the same number of bytes
+type codespace struct {
+	bytes  int
+	lo, hi uint32
+}
+
+// pdfGlyph is a decoded character code
+type pdfGlyph struct {
+	text  string
+	width float64 // Horizontal displacement in text space units, before scaling by the font size
+	space bool    // Single-byte code 32, to which word spacing applies
+}
+
+// font returns the font of the current resources with the given name
+func (d *pdfDocument) font(resources pdfDict, name pdfName) *pdfFont {
+	ref, isRef := d.dict(resources["Font"])[name].(pdfRef)
+	if isRef {
+		if font, ok := d.fonts[ref]; ok {
+			return font
+		}
+	}
+
+	font := d.loadFont(d.dict(d.dict(resources["Font"])[name]))
+	if isRef {
+		d.fonts[ref] = font
+	}
+	return font
+}
+
+// loadFont reads the encoding, ToUnicode CMap and widths of a font dictionary
+func (d *pdfDocument) loadFont(dict pdfDict) *pdfFont {
+	font := &pdfFont{widths: make(map[uint32]float64), defaultWidth: 0.5}
+	if dict == nil {
+		font.encoding = &standardEncoding
+		return font
+	}
+
+	if stream, ok := d.resolve(dict["ToUnicode"]).(*pdfStream); ok {
+		if data, err := d.streamData(stream); err == nil {
+			font.toUnicode, font.codespace = parseCMap(data)
+		}
+	}
+
+	if dict["Subtype"] == pdfName("Type0") {
+		font.composite = true
+		font.defaultWidth = 1
+		if len(font.codespace) == 0 {
+			font.codespace = []codespace{{bytes: 2, lo: 0, hi: 0xffff}}
+		}
+		descendants := d.array(dict["DescendantFonts"])
+		if len(descendants) > 0 {
+			d.loadCIDWidths(font, d.dict(descendants[0]))
+		}
+		return font
+	}
+
+	font.encoding = d.simpleEncoding(dict)
+	if descriptor := d.dict(dict["FontDescriptor"]); descriptor != nil {
+		if missing, ok := number(d.resolve(descriptor["MissingWidth"])); ok && missing > 0 {
+			font.defaultWidth = missing / 1000
+		}
+	}
+	firstChar, _ := d.resolve(dict["FirstChar"]).(int64)
+	for i, width := range d.array(dict["Widths"]) {
+		if w, ok := number(d.resolve(width)); ok {
+			font.widths[uint32(firstChar)+uint32(i)] = w / 1000
+		}
+	}
+	return font
+}
+
+// loadCIDWidths reads the /W array and default width of a CID font
+func (d *pdfDocument) loadCIDWidths(font *pdfFont, cidFont pdfDict) {
+	if dw, ok := number(d.resolve(cidFont["DW"])); ok {
+		font.defaultWidth = dw / 1000
+	}
+
+	w := d.array(cidFont["W"])
+	for i := 0; i+1 < len(w); {
+		first, ok := d.resolve(w[i]).(int64)
+		if !ok {
+			return
+		}
+		if widths, ok := d.resolve(w[i+1]).([]pdfObject); ok {
+			// first [w1 w2 ...]
+			for j, width := range widths {
+				if v, ok := number(d.resolve(width)); ok {
+					font.widths[uint32(first)+uint32(j)] = v / 1000
+				}
+			}
+			i += 2
+			continue
+		}
+
+		// first last w
+		if i+2 >= len(w) {
+			return
+		}
+		last, ok1 := d.resolve(w[i+1]).(int64)
+		width, ok2 := number(d.resolve(w[i+2]))
+		if !ok1 || !ok2 || last < first || last-first > 0xffff {
+			return
+		}
+		for cid := first; cid <= last; cid++ {
+			font.widths[uint32(cid)] = width / 1000
+		}
+		i += 3
+	}
+}
+
+// simpleEncoding returns the encoding of a simple font with its differences applied
+func (d *pdfDocument) simpleEncoding(dict pdfDict) *[256]rune {
+	base := &standardEncoding
+	if dict["Subtype"] == pdfName("TrueType") {
+		base = &winAnsiEncoding
+	}
+
+	var differences []pdfObject
+	switch encoding := d.resolve(dict["Encoding"]).(type) {
+	case pdfName:
+		base = namedEncoding(encoding, base)
+	case pdfDict:
+		if name, ok := d.resolve(encoding["BaseEncoding"]).(pdfName); ok {
+			base = namedEncoding(name, base)
+		}
+		differences = d.array(encoding["Differences"])
+	}
+	if len(differences) == 0 {
+		return base
+	}
+
+	encoding := *base
+	code := 0
+	for _, obj := range differences {
+		switch v := d.resolve(obj).(type) {
+		case int64:
+			code = int(v)
+		case pdfName:
+			if code >= 0 && code < 256 {
+				if r, ok := glyphRune(string(v)); ok {
+					encoding[code] = r
+				}
+			}
+			code++
+		}
+	}
+	return &encoding
+}
+
+// namedEncoding returns a predefined encoding by name, or fallback if unknown
+func namedEncoding(name pdfName, fallback *[256]rune) *[256]rune {
+	switch name {
+	case "WinAnsiEncoding":
+		return &winAnsiEncoding
+	case "MacRomanEncoding":
+		return &macRomanEncoding
+	case "StandardEncoding":
+		return &standardEncoding
+	}
+	return fallback
+}
+
+// decode splits a shown string into glyphs
+func (f *pdfFont) decode(s string) []pdfGlyph {
+	glyphs := make([]pdfGlyph, 0, len(s))
+	for i := 0; i < len(s); {
+		n := f.codeLength(s[i:])
+		var code uint32
+		for j := 0; j < n; j++ {
+			code = code<<8 | uint32(s[i+j])
+		}
+		i += n
+
+		glyph := pdfGlyph{width: f.defaultWidth, space: n == 1 && code == 32}
+		if w, ok := f.widths[code]; ok {
+			glyph.width = w
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			glyph.text = text
+		} else if f.encoding != nil && code < 256 {
+			if r := f.encoding[code]; r != 0 {
+				glyph.text = string(r)
+			}
+		}
+		glyphs = append(glyphs, glyph)
+	}
+	return glyphs
+}
+
+// codeLength returns the number of bytes of the code at the start of s
+func (f *pdfFont) codeLength(s string) int {
+	if len(f.codespace) > 0 {
+		for n := 1; n <= 4 && n <= len(s); n++ {
+			var code uint32
+			for j := 0; j < n; j++ {
+				code = code<<8 | uint32(s[j])
+			}
+			for _, cs := range f.codespace {
+				if cs.bytes == n && code >= cs.lo && code <= cs.hi {
+					return n
+				}
+			}
+		}
+	}
+	if f.composite && len(s) >= 2 {
+		return 2
+	}
+	return 1
+}
+
+// parseCMap reads the character mappings and codespace ranges of a ToUnicode CMap
+func parseCMap(data []byte) (map[uint32]string, []codespace) {
+	mapping := make(map[uint32]string)
+	var spaces []codespace
+	lexer := &pdfLexer{data: data}
+
+	// Operands of the current section, read until its end keyword
+	readSection := func(end pdfKeyword) []pdfObject {
+		var operands []pdfObject
+		for {
+			obj, err := lexer.readObject()
+			if err != nil || obj == end {
+				return operands
+			}
+			operands = append(operands, obj)
+		}
+	}
+
+	for {
+		obj, err := lexer.readObject()
+		if err != nil {
+			break
+		}
+		switch obj {
+		case pdfKeyword("begincodespacerange"):
+			operands := readSection("endcodespacerange")
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if ok1 && ok2 && len(lo) > 0 && len(lo) <= 4 {
+					spaces = append(spaces, codespace{bytes: len(lo), lo: cmapCode(lo), hi: cmapCode(hi)})
+				}
+			}
+		case pdfKeyword("beginbfchar"):
+			operands := readSection("endbfchar")
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(string)
+				dst, ok2 := operands[i+1].(string)
+				if ok1 && ok2 {
+					mapping[cmapCode(src)] = utf16Text(dst)
+				}
+			}
+		case pdfKeyword("beginbfrange"):
+			operands := readSection("endbfrange")
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(string)
+				hi, ok2 := operands[i+1].(string)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := cmapCode(lo), cmapCode(hi)
+				if end < start || end-start > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case string:
+					// Consecutive codes map to consecutive values of the last UTF-16 unit
+					units := utf16Units(dst)
+					for code := start; code <= end && len(units) > 0; code++ {
+						mapping[code] = string(utf16.Decode(units))
+						units[len(units)-1]++
+					}
+				case []pdfObject:
+					for j, item := range dst {
+						if text, ok := item.(string); ok && start+uint32(j) <= end {
+							mapping[start+uint32(j)] = utf16Text(text)
+						}
+					}
+				}
+			}
+		}
+	}
+	return mapping, spaces
+}
+
+// cmapCode converts the bytes of a CMap code to a number
+func cmapCode(s string) uint32 {
+	var code uint32
+	for i := 0; i < len(s) && i < 4; i++ {
+		code = code<<8 | uint32(s[i])
+	}
+	return code
+}
+
+// utf16Units splits big-endian UTF-16 bytes into code units
+func utf16Units(s string) []uint16 {
+	units := make([]uint16, 0, len(s)/2+1)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	if len(s)%2 == 1 {
+		units = append(units, uint16(s[len(s)-1]))
+	}
+	return units
+}
+
+// utf16Text decodes big-endian UTF-16 bytes
+func utf16Text(s string) string {
+	return string(utf16.Decode(utf16Units(s)))
+}
+
+// glyphRune maps a glyph name of an encoding's differences to a rune
+func glyphRune(name string) (rune, bool) {
+	if r, ok := glyphNames[name]; ok {
+		return r, true
+	}
+	if len(name) == 1 {
+		return rune(name[0]), true
+	}
+	// Variants such as "a.sc" or "f_i" of ligature fonts
+	if base, _, found := strings.Cut(name, "."); found && base != "" {
+		return glyphRune(base)
+	}
+	for _, prefix := range []string{"uni", "u"} {
+		if hex, ok := strings.CutPrefix(name, prefix); ok && len(hex) >= 4 && len(hex) <= 6 {
+			if v, err := strconv.ParseUint(hex[:4], 16, 32); err == nil && prefix == "uni" {
+				return rune(v), true
+			}
+			if v, err := strconv.ParseUint(hex, 16, 32); err == nil {
+				return rune(v), true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,365 @@
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+)
+
+// PDF objects are represented by the Go values nil, bool, int64, float64,
+// string (the raw bytes of a PDF string), pdfName, pdfKeyword, []pdfObject,
+// pdfDict, pdfRef and *pdfStream
+type pdfObject any
+
+// pdfName is a name object such as /Type, without the leading slash
+type pdfName string
+
+// pdfKeyword is a bare keyword, e.g. obj, R or a content stream operator
+type pdfKeyword string
+
+// pdfDict is a dictionary object
+type pdfDict map[pdfName]pdfObject
+
+// pdfRef is an indirect reference to the object with the given number
+type pdfRef struct {
+	num int
+	gen int
+}
+
+// pdfStream is a stream object with its undecoded data
+type pdfStream struct {
+	dict pdfDict
+	data []byte
+}
+
+// errEndOfData is returned by the lexer when there are no more objects
+var errEndOfData = errors.New("end of data")
+
+// pdfLexer reads objects from PDF file data or a content stream
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+// isPDFSpace reports whether c is PDF whitespace
+func isPDFSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+// isPDFDelimiter reports whether c ends a name, number or keyword
+func isPDFDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isPDFSpace(c)
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+			continue
+		}
+		if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// readObject reads the next object. Integers followed by a generation number
+// and R are read as references. Keywords are returned as pdfKeyword.
+func (l *pdfLexer) readObject() (pdfObject, error) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, errEndOfData
+	}
+
+	switch c := l.data[l.pos]; {
+	case c == '/':
+		l.pos++
+		return l.readName(), nil
+	case c == '(':
+		l.pos++
+		return l.readLiteralString(), nil
+	case c == '<':
+		if l.pos+1 < len(l.data) && l.data[l.pos+1] == '<' {
+			l.pos += 2
+			return l.readDict()
+		}
+		l.pos++
+		return l.readHexString(), nil
+	case c == '[':
+		l.pos++
+		return l.readArray()
+	case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+		l.pos++
+		return pdfKeyword(c), nil
+	case c == '+' || c == '-' || c == '.' || (c >= '0' && c <= '9'):
+		return l.readNumberOrRef(), nil
+	}
+
+	keyword := l.readRegular()
+	switch keyword {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return pdfKeyword(keyword), nil
+}
+
+// readRegular reads a run of regular characters
+func (l *pdfLexer) readRegular() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		// A stray delimiter, skip it so that the lexer always advances
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// readName reads a name after its slash, decoding #xx escapes
+func (l *pdfLexer) readName() pdfName {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	raw := l.data[start:l.pos]
+	if bytes.IndexByte(raw, '#') < 0 {
+		return pdfName(raw)
+	}
+
+	name := make([]byte, 0, len(raw))
+	for i := 0; i < len(raw); i++ {
+		if raw[i] == '#' && i+2 < len(raw) {
+			if v, err := strconv.ParseUint(string(raw[i+1:i+3]), 16, 8); err == nil {
+				name = append(name, byte(v))
+				i += 2
+				continue
+			}
+		}
+		name = append(name, raw[i])
+	}
+	return pdfName(name)
+}
+
+// readLiteralString reads a string in parentheses after the opening one
+func (l *pdfLexer) readLiteralString() string {
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return string(buf)
+			}
+		case '\r':
+			// End of line markers are read as a single line feed
+			if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+				l.pos++
+			}
+			c = '\n'
+		case '\\':
+			if l.pos >= len(l.data) {
+				continue
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				// Line continuation
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					v := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						v = v*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(v)
+				} else {
+					c = e
+				}
+			}
+		}
+		buf = append(buf, c)
+	}
+	return string(buf)
+}
+
+// readHexString reads a hexadecimal string after the opening angle bracket
+func (l *pdfLexer) readHexString() string {
+	var buf []byte
+	var hi byte
+	odd := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		if c == '>' {
+			break
+		}
+
+		var v byte
+		switch {
+		case c >= '0' && c <= '9':
+			v = c - '0'
+		case c >= 'a' && c <= 'f':
+			v = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			v = c - 'A' + 10
+		default:
+			continue
+		}
+		if odd {
+			buf = append(buf, hi<<4|v)
+		} else {
+			hi = v
+		}
+		odd = !odd
+	}
+	// A missing final digit is taken as 0
+	if odd {
+		buf = append(buf, hi<<4)
+	}
+	return string(buf)
+}
+
+// readArray reads the elements of an array after the opening bracket
+func (l *pdfLexer) readArray() (pdfObject, error) {
+	array := make([]pdfObject, 0)
+	for {
+		obj, err := l.readObject()
+		if err != nil {
+			return array, err
+		}
+		if obj == pdfKeyword("]") {
+			return array, nil
+		}
+		array = append(array, obj)
+	}
+}
+
+// readDict reads the entries of a dictionary after the opening brackets
+func (l *pdfLexer) readDict() (pdfObject, error) {
+	dict := make(pdfDict)
+	for {
+		l.skipSpace()
+		if l.pos+1 < len(l.data) && l.data[l.pos] == '>' && l.data[l.pos+1] == '>' {
+			l.pos += 2
+			return dict, nil
+		}
+
+		key, err := l.readObject()
+		if err != nil {
+			return dict, err
+		}
+		name, ok := key.(pdfName)
+		if !ok {
+			// Skip anything that is not a key
+			continue
+		}
+
+		value, err := l.readObject()
+		if err != nil {
+			return dict, err
+		}
+		dict[name] = value
+	}
+}
+
+// readNumberOrRef reads a number, or a reference if the number is followed
+// by a generation number and R
+func (l *pdfLexer) readNumberOrRef() pdfObject {
+	number := l.readNumber()
+	num, ok := number.(int64)
+	if !ok || num < 0 {
+		return number
+	}
+
+	save := l.pos
+	l.skipSpace()
+	if l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '9' {
+		if gen, ok := l.readNumber().(int64); ok {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == 'R' && (l.pos+1 == len(l.data) || isPDFDelimiter(l.data[l.pos+1])) {
+				l.pos++
+				return pdfRef{num: int(num), gen: int(gen)}
+			}
+		}
+	}
+	l.pos = save
+	return number
+}
+
+// readNumber reads an integer or real number
+func (l *pdfLexer) readNumber() pdfObject {
+	start := l.pos
+	real := false
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '.' {
+			real = true
+		} else if !(c >= '0' && c <= '9') && !((c == '+' || c == '-') && l.pos == start) {
+			break
+		}
+		l.pos++
+	}
+
+	text := string(l.data[start:l.pos])
+	if !real {
+		if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return v
+		}
+	}
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return int64(0)
+	}
+	return v
+}
+
+// number converts a numeric object to float64
+func number(obj pdfObject) (float64, bool) {
+	switch v := obj.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return 0, false
+}
@@ -0,0 +1,338 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// pdfBuilder writes minimal PDF files for tests. Objects are numbered from 1
+// in the order they are added.
+type pdfBuilder struct {
+	objects []string
+}
+
+// add adds an object and returns its number
+func (b *pdfBuilder) add(object string) int {
+	b.objects = append(b.objects, object)
+	return len(b.objects)
+}
+
+// addStream adds a stream object, compressed with FlateDecode if flate is set
+func (b *pdfBuilder) addStream(dict string, data []byte, flate bool) int {
+	if flate {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		w.Write(data)
+		w.Close()
+		data = buf.Bytes()
+		dict += " /Filter /FlateDecode"
+	}
+	return b.add(fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data))
+}
+
+// build writes the file with an xref table and a trailer with the given extra entries
+func (b *pdfBuilder) build(root int, trailer string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(b.objects))
+	for i, object := range b.objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(b.objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root %d 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(b.objects)+1, root, trailer, xref)
+	return buf.Bytes()
+}
+
+// buildPDF writes a PDF with one page per content stream, all using the
+// Helvetica font as /F1
+func buildPDF(contents []string, flate bool, trailer string) []byte {
+	b := &pdfBuilder{}
+	catalog := b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	pages := b.add("") // Filled in once the pages are known
+	font := b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	var kids []string
+	for _, content := range contents {
+		stream := b.addStream("", []byte(content), flate)
+		page := b.add(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 612 792] /Contents %d 0 R >>", pages, stream))
+		kids = append(kids, fmt.Sprintf("%d 0 R", page))
+	}
+	b.objects[pages-1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d /Resources << /Font << /F1 %d 0 R >> >> >>",
+		strings.Join(kids, " "), len(kids), font)
+	return b.build(catalog, trailer)
+}
+
+func TestPDFExtract(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []string
+		flate    bool
+		want     []string
+		wantLocs []Location
+	}{
+		{
+			name:     "single line",
+			contents: []string{"BT /F1 12 Tf 72 720 Td (Hello World) Tj ET"},
+			want:     []string{"Hello World"},
+			wantLocs: []Location{{Page: 1, Line: 1}},
+		},
+		{
+			name: "lines and pages",
+			contents: []string{
+				"BT /F1 12 Tf 14 TL 72 720 Td (First line) Tj T* (Second line) Tj ET",
+				"BT /F1 12 Tf 72 720 Td (Page two) Tj 0 -14 Td (Escaped \\(parens\\)) Tj ET",
+			},
+			flate:    true,
+			want:     []string{"First line", "Second line", "Page two", "Escaped (parens)"},
+			wantLocs: []Location{{Page: 1, Line: 1}, {Page: 1, Line: 2}, {Page: 2, Line: 1}, {Page: 2, Line: 2}},
+		},
+		{
+			name:     "TJ kerning and word gaps",
+			contents: []string{"BT /F1 10 Tf 72 720 Td [(Ke) 20 (rning) -600 (gap)] TJ ET"},
+			want:     []string{"Kerning gap"},
+			wantLocs: []Location{{Page: 1, Line: 1}},
+		},
+		{
+			name:     "separately positioned words",
+			contents: []string{"BT /F1 10 Tf 72 720 Td (one) Tj ET BT /F1 10 Tf 200 720 Td (two) Tj ET BT /F1 10 Tf 72 700 Td (three) Tj ET"},
+			want:     []string{"one two", "three"},
+			wantLocs: []Location{{Page: 1, Line: 1}, {Page: 1, Line: 2}},
+		},
+		{
+			name:     "inline image and graphics",
+			contents: []string{"q 100 0 0 100 0 0 cm BI /W 1 /H 1 /BPC 8 /CS /G ID \xff EI Q 0 0 1 rg 10 10 50 50 re f BT /F1 12 Tf 72 720 Td (After image) Tj ET"},
+			want:     []string{"After image"},
+			wantLocs: []Location{{Page: 1, Line: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := pdfExtractor{}.Extract(buildPDF(tt.contents, tt.flate, ""))
+			if err != nil {
+				t.Fatalf("Extract failed: %v", err)
+			}
+			if !reflect.DeepEqual(text.Lines, tt.want) {
+				t.Errorf("lines = %q, want %q", text.Lines, tt.want)
+			}
+			if !reflect.DeepEqual(text.Locations, tt.wantLocs) {
+				t.Errorf("locations = %v, want %v", text.Locations, tt.wantLocs)
+			}
+		})
+	}
+}
+
+func TestPDFExtractToUnicode(t *testing.T) {
+	cmap := `/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+2 beginbfchar
+<0001> <0048>
+<0002> <00E9>
+endbfchar
+1 beginbfrange
+<0003> <0005> <006C>
+endbfrange
+endcmap
+end end`
+
+	b := &pdfBuilder{}
+	catalog := b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	b.add("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 7 0 R >>")
+	b.add("<< /Type /Font /Subtype /Type0 /BaseFont /Test /Encoding /Identity-H /DescendantFonts [5 0 R] /ToUnicode 6 0 R >>")
+	b.add("<< /Type /Font /Subtype /CIDFontType2 /DW 500 /W [1 [600 500] 3 5 250] >>")
+	b.addStream("", []byte(cmap), true)
+	// Codes 1 and 2 map to H and é, the range of codes 3 to 5 to l, m and n
+	b.addStream("", []byte("BT /F1 12 Tf 72 720 Td <00010002000300030004> Tj ET"), true)
+
+	text, err := pdfExtractor{}.Extract(b.build(catalog, ""))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if want := []string{"Héllm"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+}
+
+func TestPDFExtractEncodingDifferences(t *testing.T) {
+	b := &pdfBuilder{}
+	catalog := b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	b.add("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>")
+	b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Test /Encoding << /BaseEncoding /WinAnsiEncoding /Differences [1 /fi /uni00E9] >> >>")
+	b.addStream("", []byte("BT /F1 12 Tf 72 720 Td (\\001nd caf\\002 \\223quoted\\224) Tj ET"), false)
+
+	text, err := pdfExtractor{}.Extract(b.build(catalog, ""))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if want := []string{"find café “quoted”"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+}
+
+func TestPDFExtractFormXObject(t *testing.T) {
+	b := &pdfBuilder{}
+	catalog := b.add("<< /Type /Catalog /Pages 2 0 R >>")
+	b.add("<< /Type /Pages /Kids [3 0 R] /Count 1 >>")
+	b.add("<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 4 0 R >> /XObject << /X1 5 0 R >> >> /Contents 6 0 R >>")
+	b.add("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	b.addStream("/Type /XObject /Subtype /Form /BBox [0 0 612 792]", []byte("BT /F1 12 Tf 72 700 Td (Inside form) Tj ET"), false)
+	b.addStream("", []byte("BT /F1 12 Tf 72 720 Td (Page text) Tj ET /X1 Do"), false)
+
+	text, err := pdfExtractor{}.Extract(b.build(catalog, ""))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if want := []string{"Page text", "Inside form"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+}
+
+func TestPDFExtractDamagedXref(t *testing.T) {
+	data := buildPDF([]string{"BT /F1 12 Tf 72 720 Td (Still readable) Tj ET"}, true, "")
+
+	// Point startxref and the first xref entry at the wrong offsets
+	i := bytes.LastIndex(data, []byte("startxref"))
+	damaged := append(bytes.Clone(data[:i]), "startxref\n12\n%%EOF\n"...)
+	damaged = bytes.Replace(damaged, []byte("0000000009 00000 n"), []byte("0000000999 00000 n"), 1)
+
+	text, err := pdfExtractor{}.Extract(damaged)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if want := []string{"Still readable"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+}
+
+func TestPDFExtractNoText(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		reason string
+	}{
+		{
+			name:   "encrypted",
+			data:   buildPDF([]string{"BT /F1 12 Tf 72 720 Td (Secret) Tj ET"}, false, "/Encrypt << /Filter /Standard /V 2 >>"),
+			reason: "encrypted",
+		},
+		{
+			name:   "image only",
+			data:   buildPDF([]string{"q 612 0 0 792 0 0 cm /Im1 Do Q"}, true, ""),
+			reason: "no text layer",
+		},
+		{
+			name:   "truncated",
+			data:   []byte("%PDF-1.7\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R"),
+			reason: "malformed",
+		},
+		{
+			name:   "not a PDF",
+			data:   []byte("plain text"),
+			reason: "not a PDF",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pdfExtractor{}.Extract(tt.data)
+			if !errors.Is(err, ErrNoText) {
+				t.Fatalf("error = %v, want ErrNoText", err)
+			}
+			if !strings.Contains(err.Error(), tt.reason) {
+				t.Errorf("error = %q, want it to mention %q", err, tt.reason)
+			}
+		})
+	}
+}
+
+func TestPDFExtractGarbage(t *testing.T) {
+	// Damaged files must return an error, never panic
+	data := buildPDF([]string{"BT /F1 12 Tf 72 720 Td (Text) Tj ET"}, true, "")
+	for i := 0; i < len(data); i += 7 {
+		damaged := bytes.Clone(data)
+		damaged[i] ^= 0xff
+		extract(pdfExtractor{}, damaged)
+		extract(pdfExtractor{}, data[:i])
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	pdf := buildPDF([]string{"BT /F1 12 Tf 72 720 Td (From a PDF) Tj ET"}, true, "")
+
+	files := map[string][]byte{
+		"notes.txt":     []byte("first\nsecond\n"),
+		"report.pdf":    pdf,
+		"REPORT2.PDF":   pdf,
+		"no-extension":  pdf,
+		"broken.pdf":    []byte("not really a pdf"),
+		"encrypted.pdf": buildPDF([]string{"BT (x) Tj ET"}, false, "/Encrypt 9 0 R"),
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	text, err := ReadFile(filepath.Join(dir, "notes.txt"))
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+	if loc := text.Location(1); loc != (Location{Line: 2}) {
+		t.Errorf("Location(1) = %v, want line 2 without page", loc)
+	}
+
+	for _, name := range []string{"report.pdf", "REPORT2.PDF", "no-extension"} {
+		text, err := ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("ReadFile(%s) failed: %v", name, err)
+		}
+		if want := []string{"From a PDF"}; !reflect.DeepEqual(text.Lines, want) {
+			t.Errorf("ReadFile(%s) lines = %q, want %q", name, text.Lines, want)
+		}
+		if loc := text.Location(0); loc != (Location{Page: 1, Line: 1}) {
+			t.Errorf("ReadFile(%s) Location(0) = %v, want page 1 line 1", name, loc)
+		}
+	}
+
+	for _, name := range []string{"broken.pdf", "encrypted.pdf"} {
+		if _, err := ReadFile(filepath.Join(dir, name)); !errors.Is(err, ErrNoText) {
+			t.Errorf("ReadFile(%s) error = %v, want ErrNoText", name, err)
+		}
+	}
+
+	if _, err := ReadFile(filepath.Join(dir, "missing.pdf")); err == nil || errors.Is(err, ErrNoText) {
+		t.Errorf("ReadFile of a missing file error = %v, want a read error", err)
+	}
+}
+
+func TestMacRomanEncoding(t *testing.T) {
+	if n := len([]rune(macRomanHigh)); n != 128 {
+		t.Fatalf("macRomanHigh has %d characters, want 128", n)
+	}
+	if r := macRomanEncoding[0x8E]; r != 'é' {
+		t.Errorf("macRomanEncoding[0x8E] = %q, want é", r)
+	}
+}
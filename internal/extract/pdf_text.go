@@ -0,0 +1,306 @@
+package extract
+
+import (
+	"bytes"
+	"math"
+	"strings"
+)
+
+// pdfMatrix is a PDF transformation matrix [a b c d e f]
+type pdfMatrix [6]float64
+
+var identityMatrix = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n
+func (m pdfMatrix) multiply(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// translate returns the translation by (tx, ty) followed by m
+func (m pdfMatrix) translate(tx, ty float64) pdfMatrix {
+	return pdfMatrix{1, 0, 0, 1, tx, ty}.multiply(m)
+}
+
+// pdfGraphicsState is the part of the graphics state that affects text
+type pdfGraphicsState struct {
+	ctm         pdfMatrix
+	font        *pdfFont
+	fontSize    float64
+	charSpacing float64
+	wordSpacing float64
+	scale       float64 // Horizontal scaling, 1 for 100%
+	leading     float64
+	rise        float64
+}
+
+// pdfTextWriter assembles shown glyphs into lines by their position on the page
+type pdfTextWriter struct {
+	lines   []string
+	line    strings.Builder
+	started bool
+	y       float64 // Baseline of the current line
+	endX    float64 // Where the last glyph ended
+	size    float64 // Font size of the last glyph
+}
+
+// add appends the text of a glyph drawn at (x, y) ending at endX. Glyphs more
+// than half a line off the current baseline start a new line, and a gap
+// wider than a fraction of the font size separates words.
+func (w *pdfTextWriter) add(text string, x, y, endX, size float64) {
+	if text == "" {
+		return
+	}
+	size = math.Max(size, 1)
+
+	switch {
+	case !w.started:
+		w.started = true
+	case math.Abs(y-w.y) > 0.5*math.Max(size, w.size):
+		w.newLine()
+	case x-w.endX > 0.15*size || w.endX-x > 2*size:
+		w.space()
+	}
+
+	w.line.WriteString(text)
+	w.y = y
+	w.endX = endX
+	w.size = size
+}
+
+// space separates words, unless the line already ends with a space
+func (w *pdfTextWriter) space() {
+	s := w.line.String()
+	if s != "" && !strings.HasSuffix(s, " ") {
+		w.line.WriteByte(' ')
+	}
+}
+
+// newLine ends the current line
+func (w *pdfTextWriter) newLine() {
+	if line := strings.TrimSpace(w.line.String()); line != "" {
+		w.lines = append(w.lines, line)
+	}
+	w.line.Reset()
+}
+
+// pageText extracts the lines of text of a page
+func (d *pdfDocument) pageText(page pdfPage) []string {
+	w := &pdfTextWriter{}
+	state := pdfGraphicsState{ctm: identityMatrix, scale: 1}
+	d.runContent(d.pageContents(page), page.resources, state, w, 0)
+	w.newLine()
+	return w.lines
+}
+
+// runContent interprets a content stream, passing shown text to w
+func (d *pdfDocument) runContent(content []byte, resources pdfDict, gs pdfGraphicsState, w *pdfTextWriter, depth int) {
+	lexer := &pdfLexer{data: content}
+	var stack []pdfGraphicsState
+	var operands []pdfObject
+	tm, tlm := identityMatrix, identityMatrix
+
+	num := func(i int) float64 {
+		if i < len(operands) {
+			v, _ := number(operands[i])
+			return v
+		}
+		return 0
+	}
+	nums := func(n int) bool {
+		if len(operands) < n {
+			return false
+		}
+		operands = operands[len(operands)-n:]
+		return true
+	}
+	moveLine := func(tx, ty float64) {
+		tlm = tlm.translate(tx, ty)
+		tm = tlm
+	}
+
+	show := func(s string) {
+		if gs.font == nil {
+			gs.font = d.loadFont(nil)
+		}
+		for _, glyph := range gs.font.decode(s) {
+			trm := pdfMatrix{gs.fontSize * gs.scale, 0, 0, gs.fontSize, 0, gs.rise}.multiply(tm).multiply(gs.ctm)
+			advance := glyph.width*gs.fontSize + gs.charSpacing
+			if glyph.space {
+				advance += gs.wordSpacing
+			}
+			tm = tm.translate(advance*gs.scale, 0)
+			end := pdfMatrix{1, 0, 0, 1, 0, gs.rise}.multiply(tm).multiply(gs.ctm)
+			w.add(normalizeGlyphText(glyph.text), trm[4], trm[5], end[4], math.Hypot(trm[2], trm[3]))
+		}
+	}
+
+	for {
+		obj, err := lexer.readObject()
+		if err != nil {
+			return
+		}
+		op, ok := obj.(pdfKeyword)
+		if !ok {
+			operands = append(operands, obj)
+			continue
+		}
+
+		switch op {
+		case "q":
+			stack = append(stack, gs)
+		case "Q":
+			if len(stack) > 0 {
+				gs = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if nums(6) {
+				gs.ctm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}.multiply(gs.ctm)
+			}
+		case "BT":
+			tm, tlm = identityMatrix, identityMatrix
+		case "Tc":
+			if nums(1) {
+				gs.charSpacing = num(0)
+			}
+		case "Tw":
+			if nums(1) {
+				gs.wordSpacing = num(0)
+			}
+		case "Tz":
+			if nums(1) {
+				gs.scale = num(0) / 100
+			}
+		case "TL":
+			if nums(1) {
+				gs.leading = num(0)
+			}
+		case "Ts":
+			if nums(1) {
+				gs.rise = num(0)
+			}
+		case "Tf":
+			if len(operands) >= 2 {
+				if name, ok := operands[len(operands)-2].(pdfName); ok {
+					gs.font = d.font(resources, name)
+				}
+				gs.fontSize, _ = number(operands[len(operands)-1])
+			}
+		case "Td":
+			if nums(2) {
+				moveLine(num(0), num(1))
+			}
+		case "TD":
+			if nums(2) {
+				gs.leading = -num(1)
+				moveLine(num(0), num(1))
+			}
+		case "Tm":
+			if nums(6) {
+				tlm = pdfMatrix{num(0), num(1), num(2), num(3), num(4), num(5)}
+				tm = tlm
+			}
+		case "T*":
+			moveLine(0, -gs.leading)
+		case "Tj", "'", "\"":
+			if op != "Tj" {
+				if op == "\"" && len(operands) >= 3 {
+					gs.wordSpacing, _ = number(operands[len(operands)-3])
+					gs.charSpacing, _ = number(operands[len(operands)-2])
+				}
+				moveLine(0, -gs.leading)
+			}
+			if len(operands) > 0 {
+				if s, ok := operands[len(operands)-1].(string); ok {
+					show(s)
+				}
+			}
+		case "TJ":
+			if len(operands) == 0 {
+				break
+			}
+			array, _ := operands[len(operands)-1].([]pdfObject)
+			for _, item := range array {
+				if s, ok := item.(string); ok {
+					show(s)
+				} else if adjust, ok := number(item); ok {
+					tm = tm.translate(-adjust/1000*gs.fontSize*gs.scale, 0)
+				}
+			}
+		case "Do":
+			if len(operands) > 0 && depth < pdfMaxDepth {
+				if name, ok := operands[len(operands)-1].(pdfName); ok {
+					d.runForm(d.dict(resources["XObject"])[name], resources, gs, w, depth)
+				}
+			}
+		case "BI":
+			skipInlineImage(lexer)
+		}
+		operands = operands[:0]
+	}
+}
+
+// runForm interprets the content of a form XObject
+func (d *pdfDocument) runForm(obj pdfObject, resources pdfDict, gs pdfGraphicsState, w *pdfTextWriter, depth int) {
+	stream, ok := d.resolve(obj).(*pdfStream)
+	if !ok || stream.dict["Subtype"] != pdfName("Form") {
+		return
+	}
+	data, err := d.streamData(stream)
+	if err != nil {
+		return
+	}
+
+	if matrix := d.array(stream.dict["Matrix"]); len(matrix) == 6 {
+		var m pdfMatrix
+		for i := range m {
+			m[i], _ = number(d.resolve(matrix[i]))
+		}
+		gs.ctm = m.multiply(gs.ctm)
+	}
+	if r := d.dict(stream.dict["Resources"]); r != nil {
+		resources = r
+	}
+	d.runContent(data, resources, gs, w, depth+1)
+}
+
+// skipInlineImage skips the data of an inline image up to its EI operator
+func skipInlineImage(lexer *pdfLexer) {
+	i := bytes.Index(lexer.data[lexer.pos:], []byte("ID"))
+	if i < 0 {
+		lexer.pos = len(lexer.data)
+		return
+	}
+	lexer.pos += i + 2
+
+	for {
+		i := bytes.Index(lexer.data[lexer.pos:], []byte("EI"))
+		if i < 0 {
+			lexer.pos = len(lexer.data)
+			return
+		}
+		start, end := lexer.pos+i, lexer.pos+i+2
+		lexer.pos = end
+		if start > 0 && isPDFSpace(lexer.data[start-1]) && (end == len(lexer.data) || isPDFDelimiter(lexer.data[end])) {
+			return
+		}
+	}
+}
+
+// ligatures are expanded so that words containing them can be found
+var ligatures = strings.NewReplacer(
+	"ﬀ", "ff", "ﬁ", "fi", "ﬂ", "fl", "ﬃ", "ffi", "ﬄ", "ffl", "ﬅ", "st", "ﬆ", "st",
+	" ", " ", "­", "", "\x00", "",
+)
+
+// normalizeGlyphText expands ligatures and replaces non-breaking spaces
+func normalizeGlyphText(text string) string {
+	return ligatures.Replace(text)
+}
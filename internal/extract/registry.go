@@ -0,0 +1,185 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+	"mneme/internal/utils"
+)
+
+// sniffSize is the number of leading bytes passed to Detector.Detect
+const sniffSize = 512
+
+// Extractor extracts the text of a file format.
+type Extractor interface {
+	// Name returns the name of the format (e.g., "PDF", "DOCX") for messages
+	Name() string
+
+	// Extract returns the text of a file from its contents. Files without
+	// extractable text, including damaged files, return an error wrapping
+	// ErrNoText.
+	Extract(data []byte) (*Text, error)
+}
+
+// Detector is implemented by extractors that recognize their format by the
+// first bytes of a file, so that files with a missing or wrong extension are
+// extracted as well.
+type Detector interface {
+	// Detect reports whether a file starting with head has the extractor's format
+	Detect(head []byte) bool
+}
+
+// Registry maps file extensions to the extractors of their formats.
+// Extractors must be registered before the registry is used.
+type Registry struct {
+	extractors     map[string]Extractor // Lowercase extension without the dot -> extractor
+	detectors      []Extractor          // Extractors implementing Detector, in registration order
+	archiveMaxSize int64                // Largest archive member read, as in CrawlerOptions.ArchiveMaxSize
+}
+
+// NewRegistry creates a new empty registry, which reads every file as plain text.
+func NewRegistry() *Registry {
+	return &Registry{
+		extractors: make(map[string]Extractor),
+	}
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+// DefaultRegistry returns the registry with the extractors of all supported
+// formats: PDF, DOCX, XLSX, PPTX, ODT, ODS, ODP, HTML, Markdown and Jupyter
+// notebooks, whose text outputs are left out.
+func DefaultRegistry() *Registry {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry = NewRegistry()
+		defaultRegistry.Register(pdfExtractor{}, "pdf")
+		defaultRegistry.Register(docxExtractor{}, "docx", "docm", "dotx")
+		defaultRegistry.Register(xlsxExtractor{}, "xlsx", "xlsm")
+		defaultRegistry.Register(pptxExtractor{}, "pptx", "pptm")
+		defaultRegistry.Register(odfExtractor{}, "odt", "ods", "odp")
+		defaultRegistry.Register(htmlExtractor{}, "html", "htm", "xhtml")
+		defaultRegistry.Register(markdownExtractor{}, "md", "markdown", "mdx")
+		defaultRegistry.Register(notebookExtractor{}, "ipynb")
+	})
+	return defaultRegistry
+}
+
+// Register adds an extractor for the given file extensions, without the dot.
+// A later registration for the same extension replaces the earlier one.
+func (r *Registry) Register(extractor Extractor, extensions ...string) {
+	for _, ext := range extensions {
+		r.extractors[strings.ToLower(strings.TrimPrefix(ext, "."))] = extractor
+	}
+	if _, ok := extractor.(Detector); ok {
+		r.detectors = append(r.detectors, extractor)
+	}
+}
+
+// SetArchiveMaxSize sets the largest archive member, and nested archive, that
+// ReadFile reads into memory, in bytes. 0 or less applies a built-in limit.
+func (r *Registry) SetArchiveMaxSize(maxSize int64) {
+	r.archiveMaxSize = maxSize
+}
+
+// ArchiveMaxSize returns the largest archive member ReadFile reads
+func (r *Registry) ArchiveMaxSize() int64 {
+	return r.archiveMaxSize
+}
+
+// Lookup returns the extractor for a file by its extension, or by its first
+// bytes if the extension has none. It returns nil for plain text files.
+func (r *Registry) Lookup(name string, head []byte) Extractor {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(name), "."))
+	if extractor, ok := r.extractors[ext]; ok {
+		return extractor
+	}
+	for _, extractor := range r.detectors {
+		if extractor.(Detector).Detect(head) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+// ReadFile reads the text of the file at the given path. Files with an
+// extractor are read completely and have their text extracted; all other
+// files are read as plain text. Virtual paths of archive members, such as
+// "backup.zip!/docs/readme.md", are read from their archive.
+func (r *Registry) ReadFile(path string) (*Text, error) {
+	expandedPath, err := utils.ExpandFilePath(path)
+	if err != nil {
+		logger.Errorf("Error expanding path: %+v", err)
+		return nil, err
+	}
+
+	// Members of archives are extracted like files with their name
+	if _, _, ok := storage.SplitArchivePath(expandedPath); ok {
+		data, err := storage.ReadArchiveMember(expandedPath, r.archiveMaxSize)
+		if err != nil {
+			logger.Errorf("Error reading archive member %s: %+v", expandedPath, err)
+			return nil, err
+		}
+		return r.Extract(expandedPath, data)
+	}
+
+	file, err := os.Open(expandedPath)
+	if err != nil {
+		logger.Errorf("Error opening file %s: %+v", expandedPath, err)
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(sniffSize)
+	if extractor := r.Lookup(expandedPath, head); extractor != nil {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			logger.Errorf("Error reading file %s: %+v", expandedPath, err)
+			return nil, err
+		}
+		return extract(extractor, data)
+	}
+
+	lines, err := storage.ReadLines(reader)
+	if err != nil {
+		logger.Errorf("Error reading file %s: %+v", expandedPath, err)
+		return nil, err
+	}
+	return &Text{Lines: lines}, nil
+}
+
+// Extract returns the text of a file with the given name from its contents,
+// choosing the extractor like ReadFile
+func (r *Registry) Extract(name string, data []byte) (*Text, error) {
+	if extractor := r.Lookup(name, data[:min(len(data), sniffSize)]); extractor != nil {
+		return extract(extractor, data)
+	}
+
+	lines, err := storage.ReadLines(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	return &Text{Lines: lines}, nil
+}
+
+// extract runs an extractor, turning a panic on a malformed file into ErrNoText
+// so that a single damaged file cannot take down indexing
+func extract(extractor Extractor, data []byte) (text *Text, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = nil, fmt.Errorf("%w: malformed %s file: %v", ErrNoText, extractor.Name(), r)
+		}
+	}()
+	return extractor.Extract(data)
+}
@@ -0,0 +1,105 @@
+package extract
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mneme/internal/storage"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	registry := DefaultRegistry()
+	pdf := []byte("%PDF-1.4\n")
+
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"report.docx", nil, "DOCX"},
+		{"Budget.XLSX", nil, "XLSX"},
+		{"slides.pptx", nil, "PPTX"},
+		{"notes.odt", nil, "ODF"},
+		{"sheet.ods", nil, "ODF"},
+		{"paper.pdf", nil, "PDF"},
+		{"download", pdf, "PDF"},
+		{"readme.md", []byte("# Readme"), "Markdown"},
+		{"index.HTML", nil, "HTML"},
+		{"analysis.ipynb", []byte("{"), "Notebook"},
+		{"notes.txt", []byte("notes"), ""},
+	}
+
+	for _, tt := range tests {
+		extractor := registry.Lookup(tt.name, tt.head)
+		got := ""
+		if extractor != nil {
+			got = extractor.Name()
+		}
+		if got != tt.want {
+			t.Errorf("Lookup(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestRegistryRegister(t *testing.T) {
+	registry := NewRegistry()
+	if extractor := registry.Lookup("paper.pdf", []byte("%PDF-1.4")); extractor != nil {
+		t.Fatalf("empty registry returned %s extractor", extractor.Name())
+	}
+
+	registry.Register(docxExtractor{}, ".DOCX")
+	if extractor := registry.Lookup("report.docx", nil); extractor == nil || extractor.Name() != "DOCX" {
+		t.Errorf("Lookup(report.docx) = %v, want DOCX extractor", extractor)
+	}
+
+	// Overriding an extension replaces the earlier extractor
+	registry.Register(odfExtractor{}, "docx")
+	if extractor := registry.Lookup("report.docx", nil); extractor == nil || extractor.Name() != "ODF" {
+		t.Errorf("Lookup(report.docx) = %v, want ODF extractor", extractor)
+	}
+}
+
+func TestRegistryExtract(t *testing.T) {
+	registry := DefaultRegistry()
+
+	text, err := registry.Extract("notes.txt", []byte("first\nsecond\n"))
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if want := []string{"first", "second"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+
+	data := buildZip(t, map[string]string{"word/document.xml": `<w:document ` + wordNS + `><w:body><w:p><w:r><w:t>From Word</w:t></w:r></w:p></w:body></w:document>`})
+	text, err = registry.Extract("report.docx", data)
+	if err != nil {
+		t.Fatalf("Extract failed: %v", err)
+	}
+	if want := []string{"From Word"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+}
+
+func TestRegistryReadFileArchiveMember(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "backup.zip")
+	data := buildZip(t, map[string]string{"docs/readme.md": "# Readme\n\nSee [the guide](guide.md)"})
+	if err := os.WriteFile(archive, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The member is extracted by its own extension
+	text, err := DefaultRegistry().ReadFile(archive + storage.ArchiveSeparator + "docs/readme.md")
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if want := []string{"Readme", "", "See the guide"}; !reflect.DeepEqual(text.Lines, want) {
+		t.Errorf("lines = %q, want %q", text.Lines, want)
+	}
+
+	if _, err := DefaultRegistry().ReadFile(archive + storage.ArchiveSeparator + "missing.md"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadFile of a missing member = %v, want os.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,218 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// xlsxExtractor extracts the text of Excel workbooks
+type xlsxExtractor struct{}
+
+// Name returns "XLSX"
+func (xlsxExtractor) Name() string {
+	return "XLSX"
+}
+
+// xlsxSheet is a worksheet of a workbook
+type xlsxSheet struct {
+	name  string
+	entry string
+}
+
+// Extract returns every non-empty row of every worksheet as a line of its
+// cells. Lines are located by their sheet, and their line number is the row
+// number shown in the spreadsheet.
+func (xlsxExtractor) Extract(data []byte) (*Text, error) {
+	archive, err := openOfficeArchive(data, "XLSX")
+	if err != nil {
+		return nil, err
+	}
+
+	var sharedStrings []string
+	if archive.has("xl/sharedStrings.xml") {
+		content, err := archive.read("xl/sharedStrings.xml")
+		if err != nil {
+			return nil, err
+		}
+		if sharedStrings, err = xlsxSharedStrings(content); err != nil {
+			return nil, fmt.Errorf("%w: malformed XLSX shared strings: %v", ErrNoText, err)
+		}
+	}
+
+	sheets, err := xlsxSheets(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	text := &Text{}
+	for _, sheet := range sheets {
+		content, err := archive.read(sheet.entry)
+		if err != nil {
+			return nil, err
+		}
+		section := "Sheet " + sheet.name
+		err = xlsxRows(content, sharedStrings, func(row int, line string) {
+			text.add(line, Location{Section: section, Line: row})
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed XLSX sheet %s: %v", ErrNoText, sheet.name, err)
+		}
+	}
+	return text, nil
+}
+
+// xlsxSheets returns the worksheets of a workbook in the order of its tabs
+func xlsxSheets(archive *officeArchive) ([]xlsxSheet, error) {
+	content, err := archive.read("xl/workbook.xml")
+	if err != nil {
+		return nil, err
+	}
+	targets := archive.relationships("xl/workbook.xml")
+
+	var sheets []xlsxSheet
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return sheets, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed XLSX workbook: %v", ErrNoText, err)
+		}
+		if start, ok := token.(xml.StartElement); ok && start.Name.Local == "sheet" {
+			// Chart sheets have no cells and no worksheet entry
+			if target, ok := targets[xmlRelationshipID(start)]; ok && archive.has(target) && strings.Contains(target, "worksheets/") {
+				sheets = append(sheets, xlsxSheet{name: xmlAttr(start, "name"), entry: target})
+			}
+		}
+	}
+}
+
+// xlsxSharedStrings reads the shared string table, leaving out phonetic runs
+func xlsxSharedStrings(content []byte) ([]string, error) {
+	var table []string
+	var item strings.Builder
+	inText, inPhonetic := false, false
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return table, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				item.Reset()
+			case "t":
+				inText = !inPhonetic
+			case "rPh":
+				inPhonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				table = append(table, item.String())
+			case "t":
+				inText = false
+			case "rPh":
+				inPhonetic = false
+			}
+		case xml.CharData:
+			if inText {
+				item.Write(t)
+			}
+		}
+	}
+}
+
+// xlsxRows calls add with the number and the joined cells of every
+// non-empty row of a worksheet
+func xlsxRows(content []byte, sharedStrings []string, add func(row int, line string)) error {
+	var cells []string
+	var value strings.Builder
+	row, cellType := 0, ""
+	inValue := false
+
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				if r, err := strconv.Atoi(xmlAttr(t, "r")); err == nil {
+					row = r
+				} else {
+					row++
+				}
+				cells = cells[:0]
+			case "c":
+				cellType = xmlAttr(t, "t")
+				value.Reset()
+			case "v", "t":
+				// Inline strings hold their text in <is><t>
+				inValue = true
+			case "f":
+				// Formulas are not text; their cached result is in <v>
+				if err := decoder.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				if cell := xlsxCellText(value.String(), cellType, sharedStrings); cell != "" {
+					cells = append(cells, cell)
+				}
+			case "row":
+				if len(cells) > 0 {
+					add(row, strings.Join(cells, cellSeparator))
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
+
+// xlsxCellText returns the displayed text of a cell value of the given type
+func xlsxCellText(value, cellType string, sharedStrings []string) string {
+	switch cellType {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil || i < 0 || i >= len(sharedStrings) {
+			return ""
+		}
+		value = sharedStrings[i]
+	case "b":
+		switch strings.TrimSpace(value) {
+		case "1":
+			return "TRUE"
+		case "0":
+			return "FALSE"
+		}
+		return ""
+	}
+	return strings.Join(strings.Fields(value), " ")
+}
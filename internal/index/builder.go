@@ -1,8 +1,11 @@
 package index
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"mneme/internal/core"
+	"mneme/internal/extract"
 	"mneme/internal/ingest"
 	"mneme/internal/logger"
 	"mneme/internal/storage"
@@ -113,6 +116,7 @@ func IndexBuilderBatched(paths []string, crawlerOptions *core.CrawlerOptions, co
 	}
 
 	// Initialize manifest
+	ctx := batchContext(config)
 	manifest := core.NewManifest()
 	chunkID := 1
 	globalDocID := uint(1)
@@ -135,7 +139,11 @@ func IndexBuilderBatched(paths []string, crawlerOptions *core.CrawlerOptions, co
 		}
 
 		// Process this batch
-		chunk, docCount, tokenCount := processBatch(batchFiles, &globalDocID, config.IndexConfig.MaxTokensPerDocument)
+		archives := storage.NewArchiveBatch(batchFiles, crawlerOptions.ArchiveMaxSize)
+		chunk, docCount, tokenCount, err := processDocuments(ctx, batchFiles, fileReader(archives), &globalDocID, config.IndexConfig)
+		if err != nil {
+			return manifest, err
+		}
 
 		// Add chunk info to manifest (marked as in_progress)
 		chunkInfo := core.ChunkInfo{
@@ -149,16 +157,17 @@ func IndexBuilderBatched(paths []string, crawlerOptions *core.CrawlerOptions, co
 		manifest.AddChunk(chunkInfo)
 
 		// Save chunk to disk
-		err := storage.SaveChunk(chunk, chunkID)
+		checksum, err := storage.SaveChunk(chunk, chunkID)
 		if err != nil {
 			logger.Errorf("Error saving chunk %d: %+v", chunkID, err)
 			return manifest, err
 		}
 
 		// Mark chunk as complete
-		manifest.MarkChunkComplete(chunkID)
+		manifest.MarkChunkComplete(chunkID, checksum)
 
 		// Save manifest after each chunk (for crash recovery)
+		manifest.NextDocID = globalDocID
 		manifest.UpdateTotals()
 		err = storage.SaveManifest(manifest)
 		if err != nil {
@@ -214,6 +223,7 @@ func IndexBuilderBatchedWithRegistry(registry *ingest.Registry, crawlerOptions *
 	}
 
 	// Initialize manifest
+	ctx := batchContext(config)
 	manifest := core.NewManifest()
 	chunkID := 1
 	globalDocID := uint(1)
@@ -236,7 +246,12 @@ func IndexBuilderBatchedWithRegistry(registry *ingest.Registry, crawlerOptions *
 		}
 
 		// Process this batch using the registry
-		chunk, docCount, tokenCount := processBatchWithRegistry(batchDocIDs, registry, &globalDocID, config.IndexConfig.MaxTokensPerDocument)
+		release := registry.StartBatch(batchDocIDs)
+		chunk, docCount, tokenCount, err := processDocuments(ctx, batchDocIDs, registry.ReadDocument, &globalDocID, config.IndexConfig)
+		release()
+		if err != nil {
+			return manifest, err
+		}
 
 		// Add chunk info to manifest (marked as in_progress)
 		chunkInfo := core.ChunkInfo{
@@ -250,16 +265,17 @@ func IndexBuilderBatchedWithRegistry(registry *ingest.Registry, crawlerOptions *
 		manifest.AddChunk(chunkInfo)
 
 		// Save chunk to disk
-		err := storage.SaveChunk(chunk, chunkID)
+		checksum, err := storage.SaveChunk(chunk, chunkID)
 		if err != nil {
 			logger.Errorf("Error saving chunk %d: %+v", chunkID, err)
 			return manifest, err
 		}
 
 		// Mark chunk as complete
-		manifest.MarkChunkComplete(chunkID)
+		manifest.MarkChunkComplete(chunkID, checksum)
 
 		// Save manifest after each chunk (for crash recovery)
+		manifest.NextDocID = globalDocID
 		manifest.UpdateTotals()
 		err = storage.SaveManifest(manifest)
 		if err != nil {
@@ -287,146 +303,78 @@ func IndexBuilderBatchedWithRegistry(registry *ingest.Registry, crawlerOptions *
 	return manifest, nil
 }
 
-// processBatch processes a batch of files and returns a segment chunk
-func processBatch(files []string, globalDocID *uint, maxTokensPerDocument int) (*core.Segment, uint, uint) {
-	tokenFrequency := make(map[string]uint)
-	invertedIndex := make(map[string][]core.Posting)
-	docs := make([]core.Document, 0, len(files))
-	docCount := uint(0)
-
-	for _, filePath := range files {
-		// Reset token frequency for each file
-		clear(tokenFrequency)
-
-		fileContents, err := storage.ReadFileContents(filePath)
-		if err != nil {
-			logger.Errorf("Error reading file %s: %+v", filePath, err)
-			continue
-		}
-
-		for _, content := range fileContents {
-			tokens := Tokenize(content)
-			for _, token := range tokens {
-				tokenFrequency[token]++
-			}
-		}
-
-		// Skip file if token count exceeds max (when max > 0)
-		if maxTokensPerDocument > 0 && len(tokenFrequency) > maxTokensPerDocument {
-			logger.Debugf("Skipping file %s: token count %d exceeds max %d", filePath, len(tokenFrequency), maxTokensPerDocument)
-			continue
-		}
-
-		// Build inverted index for this document
-		for token, frequency := range tokenFrequency {
-			invertedIndex[token] = append(invertedIndex[token], core.Posting{
-				DocID: *globalDocID,
-				Freq:  frequency,
-			})
-		}
-
-		docs = append(docs, core.Document{
-			ID:         *globalDocID,
-			Path:       filepath.Clean(filePath),
-			TokenCount: uint(len(tokenFrequency)),
-		})
-
-		*globalDocID++
-		docCount++
-	}
-
-	avgDocLen := uint(0)
-	if len(docs) > 0 {
-		totalTokens := uint(0)
-		for _, doc := range docs {
-			totalTokens += doc.TokenCount
-		}
-		avgDocLen = totalTokens / uint(len(docs))
-	}
-
-	chunk := &core.Segment{
-		Docs:          docs,
-		InvertedIndex: invertedIndex,
-		TotalDocs:     docCount,
-		TotalTokens:   uint(len(invertedIndex)),
-		AvgDocLen:     avgDocLen,
-	}
-
-	return chunk, docCount, uint(len(invertedIndex))
-}
-
 // formatChunkFilename returns the filename for a chunk (e.g., "001.idx")
 func formatChunkFilename(chunkID int) string {
 	return fmt.Sprintf("%03d.idx", chunkID)
 }
 
-// processBatchWithRegistry processes a batch of document IDs using the ingestor registry
-func processBatchWithRegistry(docIDs []string, registry *ingest.Registry, globalDocID *uint, maxTokensPerDocument int) (*core.Segment, uint, uint) {
-	tokenFrequency := make(map[string]uint)
-	invertedIndex := make(map[string][]core.Posting)
-	docs := make([]core.Document, 0, len(docIDs))
-	docCount := uint(0)
-
-	for _, docID := range docIDs {
-		// Reset token frequency for each document
-		clear(tokenFrequency)
-
-		// Read document via the registry
-		doc, err := registry.ReadDocument(docID)
-		if err != nil || doc == nil {
-			logger.Errorf("Error reading document %s: %+v", docID, err)
-			continue
-		}
-
-		for _, content := range doc.Contents {
-			tokens := Tokenize(content)
-			for _, token := range tokens {
-				tokenFrequency[token]++
+// fileReader returns a reader of files from the filesystem as documents to
+// index. Archive members are read from archives, which may be nil.
+func fileReader(archives *storage.ArchiveBatch) documentReader {
+	return func(filePath string) (*ingest.Document, error) {
+		var text *extract.Text
+		var err error
+		if _, _, ok := storage.SplitArchivePath(filePath); ok {
+			var data []byte
+			if data, err = archives.ReadMember(filePath); err == nil {
+				text, err = extract.DefaultRegistry().Extract(filePath, data)
 			}
+		} else {
+			text, err = extract.ReadFile(filePath)
 		}
-
-		// Skip document if token count exceeds max (when max > 0)
-		if maxTokensPerDocument > 0 && len(tokenFrequency) > maxTokensPerDocument {
-			logger.Debugf("Skipping document %s: token count %d exceeds max %d", docID, len(tokenFrequency), maxTokensPerDocument)
-			continue
-		}
-
-		// Build inverted index for this document
-		for token, frequency := range tokenFrequency {
-			invertedIndex[token] = append(invertedIndex[token], core.Posting{
-				DocID: *globalDocID,
-				Freq:  frequency,
-			})
+		if err != nil {
+			return nil, err
 		}
-
-		docs = append(docs, core.Document{
-			ID:         *globalDocID,
-			Path:       filepath.Clean(doc.Path),
-			TokenCount: uint(len(tokenFrequency)),
-		})
-
-		*globalDocID++
-		docCount++
+		return &ingest.Document{
+			ID:       filePath,
+			Path:     filePath,
+			Contents: text.Lines,
+			Title:    text.Title,
+			Headings: text.Headings,
+			Tags:     text.Tags,
+			Date:     text.Date,
+		}, nil
 	}
+}
 
-	avgDocLen := uint(0)
-	if len(docs) > 0 {
-		totalTokens := uint(0)
-		for _, doc := range docs {
-			totalTokens += doc.TokenCount
-		}
-		avgDocLen = totalTokens / uint(len(docs))
+// hashDocument returns the hex SHA-256 of the document lines and, when it has
+// any, of its title, headings, tags and date. Documents without metadata hash
+// to the SHA-256 of their lines alone.
+func hashDocument(doc *ingest.Document) string {
+	hasher := sha256.New()
+	for _, line := range doc.Contents {
+		hasher.Write([]byte(line))
+		hasher.Write([]byte{'\n'})
 	}
-
-	chunk := &core.Segment{
-		Docs:          docs,
-		InvertedIndex: invertedIndex,
-		TotalDocs:     docCount,
-		TotalTokens:   uint(len(invertedIndex)),
-		AvgDocLen:     avgDocLen,
+	if doc.Title != "" || len(doc.Headings) > 0 || len(doc.Tags) > 0 || !doc.Date.IsZero() {
+		// The metadata follows a NUL so it does not read as another line
+		fmt.Fprintf(hasher, "\x00%q %q %q %d", doc.Title, doc.Headings, doc.Tags, doc.Date.UnixNano())
 	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
 
-	return chunk, docCount, uint(len(invertedIndex))
+// tokenizeLines tokenizes the lines of a single document as one continuous
+// token stream, filling tokenFrequency with per-term counts and tokenPositions
+// with the ascending, de-duplicated word positions of every term.
+// Both maps are cleared first so they can be reused across documents.
+func tokenizeLines(lines []string, tokenFrequency map[string]uint, tokenPositions map[string][]uint) {
+	clear(tokenFrequency)
+	clear(tokenPositions)
+
+	position := uint(0)
+	for _, line := range lines {
+		var tokens []PositionedToken
+		tokens, position = TokenizeContentWithPositions(line, position)
+		for _, token := range tokens {
+			tokenFrequency[token.Token]++
+
+			// Tokens from the same word share a position; record it once
+			positions := tokenPositions[token.Token]
+			if n := len(positions); n == 0 || positions[n-1] != token.Position {
+				tokenPositions[token.Token] = append(positions, token.Position)
+			}
+		}
+	}
 }
 
 // Tokenize takes file content as a string and returns a slice of normalized tokens.
@@ -0,0 +1,313 @@
+package index
+
+import (
+	"fmt"
+	"math"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+	"sort"
+	"time"
+)
+
+// CompactionPolicy controls which chunks the tiered merge policy merges.
+// Chunks are grouped into tiers by their number of live documents: tier 0 holds
+// chunks of up to TierBaseDocs documents and every further tier holds chunks
+// MergeFactor times larger. A tier with MergeFactor chunks is merged into a
+// single chunk of the next tier, so the number of chunks grows logarithmically.
+type CompactionPolicy struct {
+	MergeFactor     int     // Number of chunks in a tier that triggers a merge
+	TierBaseDocs    uint    // Maximum live documents of a tier 0 chunk
+	MaxDeletedRatio float64 // Share of deleted documents that triggers a rewrite of a single chunk
+}
+
+// DefaultCompactionPolicy returns the policy used for automatic compaction
+func DefaultCompactionPolicy() CompactionPolicy {
+	return CompactionPolicy{
+		MergeFactor:     constants.CompactionMergeFactor,
+		TierBaseDocs:    constants.CompactionTierBaseDocs,
+		MaxDeletedRatio: constants.CompactionMaxDeletedRatio,
+	}
+}
+
+// CompactionStats summarizes a compaction run
+type CompactionStats struct {
+	MergedChunks int  // Chunks replaced by compaction
+	NewChunks    int  // Chunks written by compaction
+	PurgedDocs   uint // Deleted documents dropped from the index
+	TotalChunks  int  // Chunks in the manifest after compaction
+	TotalDocs    uint // Live documents in the manifest after compaction
+}
+
+// HasChanges reports whether the compaction rewrote any chunk
+func (s *CompactionStats) HasChanges() bool {
+	return s.MergedChunks > 0
+}
+
+// tier returns the tier of a chunk with the given number of live documents
+func (p CompactionPolicy) tier(liveDocs uint) int {
+	if p.TierBaseDocs == 0 || p.MergeFactor < 2 || liveDocs <= p.TierBaseDocs {
+		return 0
+	}
+	return int(math.Ceil(math.Log(float64(liveDocs)/float64(p.TierBaseDocs)) / math.Log(float64(p.MergeFactor))))
+}
+
+// PlanMerges returns the groups of complete chunks the tiered merge policy would
+// merge, each group becoming a single new chunk. Every full tier contributes groups
+// of MergeFactor chunks, smallest first; chunks with too many deleted documents
+// that are not part of a group are rewritten on their own.
+func PlanMerges(manifest *core.Manifest, policy CompactionPolicy) [][]core.ChunkInfo {
+	if manifest == nil || policy.MergeFactor < 2 {
+		return nil
+	}
+
+	tiers := make(map[int][]core.ChunkInfo)
+	for _, chunk := range manifest.GetCompleteChunks() {
+		t := policy.tier(chunk.LiveDocCount())
+		tiers[t] = append(tiers[t], chunk)
+	}
+
+	tierNumbers := make([]int, 0, len(tiers))
+	for t := range tiers {
+		tierNumbers = append(tierNumbers, t)
+	}
+	sort.Ints(tierNumbers)
+
+	var groups [][]core.ChunkInfo
+	grouped := make(map[int]bool)
+
+	for _, t := range tierNumbers {
+		chunks := tiers[t]
+		sort.SliceStable(chunks, func(i, j int) bool {
+			return chunks[i].LiveDocCount() < chunks[j].LiveDocCount()
+		})
+
+		for len(chunks) >= policy.MergeFactor {
+			group := chunks[:policy.MergeFactor:policy.MergeFactor]
+			for _, chunk := range group {
+				grouped[chunk.ID] = true
+			}
+			groups = append(groups, group)
+			chunks = chunks[policy.MergeFactor:]
+		}
+	}
+
+	for _, chunk := range manifest.GetCompleteChunks() {
+		if grouped[chunk.ID] || chunk.DocCount == 0 {
+			continue
+		}
+		if float64(chunk.DeletedCount)/float64(chunk.DocCount) > policy.MaxDeletedRatio {
+			groups = append(groups, []core.ChunkInfo{chunk})
+		}
+	}
+
+	return groups
+}
+
+// CompactTiered applies the tiered merge policy to the existing index. It is cheap
+// when no tier is full and is meant to run after every incremental update.
+func CompactTiered(policy CompactionPolicy) (*CompactionStats, error) {
+	manifest, err := loadCompactableManifest()
+	if err != nil || manifest == nil {
+		return &CompactionStats{}, err
+	}
+
+	groups := PlanMerges(manifest, policy)
+	return applyMerges(manifest, groups, false)
+}
+
+// CompactAll merges all complete chunks into a single chunk, dropping deleted
+// documents and renumbering the remaining documents from zero. Chunks left
+// incomplete by an interrupted run are dropped as well.
+func CompactAll() (*CompactionStats, error) {
+	manifest, err := loadCompactableManifest()
+	if err != nil || manifest == nil {
+		return &CompactionStats{}, err
+	}
+
+	chunks := manifest.GetCompleteChunks()
+	if len(chunks) == len(manifest.Chunks) && len(chunks) == 1 && chunks[0].DeletedCount == 0 {
+		return newCompactionStats(manifest), nil
+	}
+
+	return applyMerges(manifest, [][]core.ChunkInfo{chunks}, true)
+}
+
+// loadCompactableManifest loads the manifest, returning nil if there is nothing to compact
+func loadCompactableManifest() (*core.Manifest, error) {
+	manifest, err := storage.LoadManifest()
+	if err != nil {
+		logger.Errorf("Error loading manifest: %+v", err)
+		return nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	if manifest == nil || len(manifest.GetCompleteChunks()) == 0 {
+		return nil, nil
+	}
+
+	if manifest.Version != core.ManifestVersion {
+		return nil, ErrFullRebuildRequired
+	}
+
+	return manifest, nil
+}
+
+// applyMerges writes one new chunk per group, then replaces the groups in the
+// manifest with a single atomic manifest update and finally moves the replaced
+// chunk files to the tombstones directory. New document IDs are taken from the
+// manifest's NextDocID; with replaceAll every chunk outside the groups is dropped
+// and document IDs start over from zero.
+func applyMerges(manifest *core.Manifest, groups [][]core.ChunkInfo, replaceAll bool) (*CompactionStats, error) {
+	stats := &CompactionStats{}
+	if len(groups) == 0 {
+		return newCompactionStats(manifest), nil
+	}
+
+	nextDocID := manifest.NextDocID
+	if replaceAll {
+		nextDocID = 0
+	}
+	chunkID := manifest.NextChunkID()
+	merged := make(map[int]bool)
+	replaced := make([]core.ChunkInfo, 0)
+	newChunks := make([]core.ChunkInfo, 0, len(groups))
+
+	for _, group := range groups {
+		chunk, purged, err := mergeChunks(group, &nextDocID)
+		if err != nil {
+			return stats, err
+		}
+
+		for _, chunkInfo := range group {
+			merged[chunkInfo.ID] = true
+		}
+		replaced = append(replaced, group...)
+		stats.MergedChunks += len(group)
+		stats.PurgedDocs += purged
+
+		// A group without live documents is simply dropped
+		if len(chunk.Docs) == 0 {
+			continue
+		}
+
+		checksum, err := storage.SaveChunk(chunk, chunkID)
+		if err != nil {
+			logger.Errorf("Error saving compacted chunk %d: %+v", chunkID, err)
+			return stats, err
+		}
+
+		newChunks = append(newChunks, core.ChunkInfo{
+			ID:         chunkID,
+			Filename:   formatChunkFilename(chunkID),
+			Status:     core.ChunkStatusComplete,
+			DocCount:   chunk.TotalDocs,
+			TokenCount: chunk.TotalTokens,
+			CreatedAt:  time.Now(),
+			Checksum:   checksum,
+		})
+		stats.NewChunks++
+		chunkID++
+	}
+
+	// Swap the merged chunks for the new ones in a single manifest update
+	chunks := make([]core.ChunkInfo, 0, len(manifest.Chunks)+len(newChunks))
+	for _, chunkInfo := range manifest.Chunks {
+		switch {
+		case merged[chunkInfo.ID]:
+		case replaceAll:
+			replaced = append(replaced, chunkInfo)
+		default:
+			chunks = append(chunks, chunkInfo)
+		}
+	}
+	manifest.Chunks = append(chunks, newChunks...)
+	manifest.NextDocID = nextDocID
+	manifest.UpdateTotals()
+
+	if err := storage.SaveManifest(manifest); err != nil {
+		logger.Errorf("Error saving manifest: %+v", err)
+		return stats, err
+	}
+
+	// The new manifest no longer references the replaced chunks
+	if err := storage.MoveChunksToTombstones(replaced); err != nil {
+		return stats, err
+	}
+
+	stats.TotalChunks = len(manifest.Chunks)
+	stats.TotalDocs = manifest.TotalDocs
+	logger.Infof("Compaction merged %d chunks into %d, purged %d deleted documents",
+		stats.MergedChunks, stats.NewChunks, stats.PurgedDocs)
+	return stats, nil
+}
+
+// mergeChunks merges the live documents of the chunks into a single segment,
+// renumbering them from nextDocID. It returns the segment and the number of
+// deleted documents that were dropped.
+func mergeChunks(group []core.ChunkInfo, nextDocID *uint) (*core.Segment, uint, error) {
+	docs := make([]core.Document, 0)
+	invertedIndex := make(map[string][]core.Posting)
+	var purged, totalTokens uint
+
+	for _, chunkInfo := range group {
+		chunk, err := storage.LoadChunk(chunkInfo)
+		if err != nil {
+			logger.Errorf("Error loading chunk %d: %+v", chunkInfo.ID, err)
+			return nil, 0, fmt.Errorf("failed to load chunk %d: %w", chunkInfo.ID, err)
+		}
+
+		deleted, err := storage.LoadDeletions(chunkInfo)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to load deletions of chunk %d: %w", chunkInfo.ID, err)
+		}
+
+		// Assign new IDs to live documents in their original order
+		newIDs := make(map[uint]uint, len(chunk.Docs))
+		for _, doc := range chunk.Docs {
+			if deleted.Contains(doc.ID) {
+				purged++
+				continue
+			}
+			newIDs[doc.ID] = *nextDocID
+			doc.ID = *nextDocID
+			docs = append(docs, doc)
+			totalTokens += doc.TokenCount
+			*nextDocID++
+		}
+
+		// Postings of a chunk are ordered by document, so appending the chunks
+		// in order keeps the merged postings ordered by the new IDs
+		for term, postings := range chunk.InvertedIndex {
+			for _, posting := range postings {
+				newID, live := newIDs[posting.DocID]
+				if !live {
+					continue
+				}
+				posting.DocID = newID
+				invertedIndex[term] = append(invertedIndex[term], posting)
+			}
+		}
+	}
+
+	avgDocLen := uint(0)
+	if len(docs) > 0 {
+		avgDocLen = totalTokens / uint(len(docs))
+	}
+
+	return &core.Segment{
+		Docs:          docs,
+		InvertedIndex: invertedIndex,
+		TotalDocs:     uint(len(docs)),
+		TotalTokens:   uint(len(invertedIndex)),
+		AvgDocLen:     avgDocLen,
+	}, purged, nil
+}
+
+// newCompactionStats returns the stats of a compaction that changed nothing
+func newCompactionStats(manifest *core.Manifest) *CompactionStats {
+	return &CompactionStats{
+		TotalChunks: len(manifest.Chunks),
+		TotalDocs:   manifest.TotalDocs,
+	}
+}
@@ -0,0 +1,141 @@
+package index
+
+import (
+	"fmt"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPlanMerges(t *testing.T) {
+	policy := CompactionPolicy{MergeFactor: 3, TierBaseDocs: 10, MaxDeletedRatio: 0.5}
+
+	manifest := core.NewManifest()
+	addChunk := func(id int, docs, deleted uint) {
+		manifest.AddChunk(core.ChunkInfo{ID: id, Status: core.ChunkStatusComplete, DocCount: docs, DeletedCount: deleted})
+	}
+	// Tier 0: chunks 1, 2, 3, 4; tier 1: chunks 5, 6
+	addChunk(1, 5, 0)
+	addChunk(2, 2, 0)
+	addChunk(3, 8, 0)
+	addChunk(4, 10, 0)
+	addChunk(5, 50, 0)
+	addChunk(6, 100, 80)
+	manifest.AddChunk(core.ChunkInfo{ID: 7, Status: core.ChunkStatusInProgress, DocCount: 1})
+
+	groups := PlanMerges(manifest, policy)
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 merge groups, got %d: %+v", len(groups), groups)
+	}
+
+	var ids []int
+	for _, chunk := range groups[0] {
+		ids = append(ids, chunk.ID)
+	}
+	if fmt.Sprint(ids) != "[2 1 3]" {
+		t.Errorf("Expected the three smallest tier 0 chunks to be merged, got %v", ids)
+	}
+
+	if len(groups[1]) != 1 || groups[1][0].ID != 6 {
+		t.Errorf("Expected chunk 6 to be rewritten to purge deletions, got %+v", groups[1])
+	}
+
+	if groups := PlanMerges(manifest, CompactionPolicy{MergeFactor: 1}); groups != nil {
+		t.Errorf("Expected no merges for an invalid policy, got %+v", groups)
+	}
+}
+
+func TestCompaction(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	options := core.DefaultCrawlerOptions()
+	base := time.Now().Add(-time.Hour)
+
+	config := quietBatchConfig()
+	config.BatchSize = 2
+
+	for i := 0; i < 6; i++ {
+		writeCorpusFile(t, filepath.Join(corpusDir, fmt.Sprintf("doc%d.md", i)), fmt.Sprintf("shared topic%d", i), base)
+	}
+	if _, err := IndexBuilderBatchedWithRegistry(registry, &options, config); err != nil {
+		t.Fatalf("Full build failed: %v", err)
+	}
+
+	// Delete one document and modify another, leaving both versions in the chunks
+	if err := os.Remove(filepath.Join(corpusDir, "doc0.md")); err != nil {
+		t.Fatal(err)
+	}
+	writeCorpusFile(t, filepath.Join(corpusDir, "doc1.md"), "shared rewritten", base.Add(time.Minute))
+	if _, _, err := IndexIncrementalWithRegistry(registry, &options, config); err != nil {
+		t.Fatalf("Incremental update failed: %v", err)
+	}
+
+	// Three full chunks and one delta chunk, all in tier 0
+	t.Run("tiered merges the smallest chunks of a full tier", func(t *testing.T) {
+		stats, err := CompactTiered(CompactionPolicy{MergeFactor: 3, TierBaseDocs: 10, MaxDeletedRatio: 0.5})
+		if err != nil {
+			t.Fatalf("Tiered compaction failed: %v", err)
+		}
+		if stats.MergedChunks != 3 || stats.NewChunks != 1 || stats.PurgedDocs != 2 || stats.TotalChunks != 2 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		if stats.TotalDocs != 5 {
+			t.Errorf("Expected 5 live docs, got %d", stats.TotalDocs)
+		}
+	})
+
+	t.Run("compact all renumbers documents", func(t *testing.T) {
+		stats, err := CompactAll()
+		if err != nil {
+			t.Fatalf("Compaction failed: %v", err)
+		}
+		if stats.TotalChunks != 1 || stats.TotalDocs != 5 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+
+		manifest, err := storage.LoadManifest()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manifest.NextDocID != 5 {
+			t.Errorf("Expected document IDs to be renumbered, next ID is %d", manifest.NextDocID)
+		}
+
+		segment, err := storage.LoadAllChunks()
+		if err != nil {
+			t.Fatalf("Failed to load chunks: %v", err)
+		}
+		if segment.Deleted != nil {
+			t.Error("Compacted index still has deleted documents")
+		}
+		for i, doc := range segment.Docs {
+			if doc.ID != uint(i) {
+				t.Errorf("Expected doc %s to have ID %d, got %d", doc.Path, i, doc.ID)
+			}
+		}
+		if df := segment.LiveDocFrequency("share"); df != 5 {
+			t.Errorf("Expected 5 docs containing the shared term, got %d", df)
+		}
+		if segment.LiveDocFrequency("rewritten") != 1 || segment.LiveDocFrequency("topic1") != 0 {
+			t.Error("Compacted index does not contain the latest version of doc1")
+		}
+
+		tombstones, err := os.ReadDir(filepath.Join(constants.DirPath, "tombstones"))
+		if err != nil || len(tombstones) == 0 {
+			t.Errorf("Expected replaced chunks in tombstones, got %v (%v)", tombstones, err)
+		}
+	})
+
+	t.Run("compact all is a no-op on a compact index", func(t *testing.T) {
+		stats, err := CompactAll()
+		if err != nil {
+			t.Fatalf("Compaction failed: %v", err)
+		}
+		if stats.HasChanges() {
+			t.Errorf("Expected no changes, got %+v", stats)
+		}
+	})
+}
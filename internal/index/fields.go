@@ -0,0 +1,98 @@
+package index
+
+import (
+	"path/filepath"
+	"strings"
+
+	"mneme/internal/core"
+	"mneme/internal/ingest"
+)
+
+// MaxPathFieldDirs is the number of parent directories of a document whose
+// names are indexed in core.FieldPath. Directories further up, such as the home
+// directory, are shared by most documents and carry no meaning.
+const MaxPathFieldDirs = 3
+
+// documentFields holds the tokens of the fields of a single document
+type documentFields struct {
+	freqs   map[string][]uint // Occurrences of every term per core.Field
+	lengths []uint            // Number of tokens per core.Field
+}
+
+// tokenizeFields tokenizes the file name and the nearest parent directories of
+// a document, and the headings, title and tags its extractor found, into their
+// fields
+func tokenizeFields(docPath string, doc *ingest.Document) documentFields {
+	fields := documentFields{
+		freqs:   make(map[string][]uint),
+		lengths: make([]uint, core.NumFields),
+	}
+
+	add := func(field core.Field, text string) {
+		tokens, _ := TokenizeContentWithPositions(text, 0)
+		counted := make(map[PositionedToken]bool, len(tokens))
+		for _, token := range tokens {
+			// Tokens from the same word share a position; count them once
+			if counted[token] {
+				continue
+			}
+			counted[token] = true
+
+			freqs := fields.freqs[token.Token]
+			if freqs == nil {
+				freqs = make([]uint, core.NumFields)
+				fields.freqs[token.Token] = freqs
+			}
+			freqs[field]++
+			fields.lengths[field]++
+		}
+	}
+
+	name := filepath.Base(docPath)
+	ext := filepath.Ext(name)
+	add(core.FieldFilename, strings.TrimSuffix(name, ext))
+
+	dir := filepath.Dir(docPath)
+	for range MaxPathFieldDirs {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break // Reached the root or a volume name
+		}
+		add(core.FieldPath, filepath.Base(dir))
+		dir = parent
+	}
+
+	for _, heading := range doc.Headings {
+		add(core.FieldHeading, heading)
+	}
+	add(core.FieldTitle, doc.Title)
+	for _, tag := range doc.Tags {
+		add(core.FieldTags, tag)
+	}
+
+	return fields
+}
+
+// addDocumentPostings appends the postings of a document to the inverted index:
+// one posting per term of its contents or fields. Terms that only occur in
+// fields get a posting with a Freq of 0.
+func addDocumentPostings(invertedIndex map[string][]core.Posting, docID uint, tokenFrequency map[string]uint, tokenPositions map[string][]uint, fields documentFields) {
+	for token, frequency := range tokenFrequency {
+		invertedIndex[token] = append(invertedIndex[token], core.Posting{
+			DocID:      docID,
+			Freq:       frequency,
+			Positions:  tokenPositions[token],
+			FieldFreqs: fields.freqs[token],
+		})
+	}
+
+	for token, freqs := range fields.freqs {
+		if _, inContents := tokenFrequency[token]; inContents {
+			continue
+		}
+		invertedIndex[token] = append(invertedIndex[token], core.Posting{
+			DocID:      docID,
+			FieldFreqs: freqs,
+		})
+	}
+}
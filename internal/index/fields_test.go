@@ -0,0 +1,69 @@
+package index
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"mneme/internal/core"
+	"mneme/internal/ingest"
+)
+
+func TestTokenizeFields(t *testing.T) {
+	docPath := filepath.Join(string(filepath.Separator), "home", "user", "notes", "infra", "kubernetes", "deployment.md")
+	fields := tokenizeFields(docPath, &ingest.Document{
+		Contents: []string{"# Rollback", "body text"},
+		Headings: []string{"Rollback"},
+		Title:    "Cluster upgrades",
+		Tags:     []string{"kubernetes", "ops"},
+	})
+
+	expectedLengths := []uint{1, 3, 1, 2, 2}
+	if !reflect.DeepEqual(fields.lengths, expectedLengths) {
+		t.Errorf("Expected field lengths %v, got %v", expectedLengths, fields.lengths)
+	}
+
+	if freqs := fields.freqs["deploy"]; freqs == nil || freqs[core.FieldFilename] != 1 {
+		t.Errorf("Expected the file name in the filename field, got %v", freqs)
+	}
+	if freqs := fields.freqs["note"]; freqs == nil || freqs[core.FieldPath] != 1 {
+		t.Errorf("Expected the third parent directory in the path field, got %v", freqs)
+	}
+	if _, ok := fields.freqs["user"]; ok {
+		t.Error("Expected directories above the nearest three to be skipped")
+	}
+	if freqs := fields.freqs["rollback"]; freqs == nil || freqs[core.FieldHeading] != 1 {
+		t.Errorf("Expected the heading in the heading field, got %v", freqs)
+	}
+	if freqs := fields.freqs["upgrad"]; freqs == nil || freqs[core.FieldTitle] != 1 {
+		t.Errorf("Expected the title in the title field, got %v", freqs)
+	}
+	if freqs := fields.freqs["kubernet"]; freqs == nil || freqs[core.FieldTags] != 1 || freqs[core.FieldPath] != 1 {
+		t.Errorf("Expected the tag in the tags and path fields, got %v", freqs)
+	}
+	if _, ok := fields.freqs["bodi"]; ok {
+		t.Error("Expected the body to be left out of the fields")
+	}
+
+	t.Run("contents are not parsed for headings", func(t *testing.T) {
+		fields := tokenizeFields(filepath.Join("notes", "script.sh"), &ingest.Document{Contents: []string{"# Rollback"}})
+		if fields.lengths[core.FieldHeading] != 0 {
+			t.Errorf("Expected no headings, got %v", fields.lengths)
+		}
+	})
+}
+
+func TestAddDocumentPostings(t *testing.T) {
+	invertedIndex := make(map[string][]core.Posting)
+	fields := tokenizeFields(filepath.Join("notes", "deployment.md"), &ingest.Document{})
+	addDocumentPostings(invertedIndex, 4, map[string]uint{"rollback": 2}, map[string][]uint{"rollback": {0, 5}}, fields)
+
+	expected := map[string][]core.Posting{
+		"rollback": {{DocID: 4, Freq: 2, Positions: []uint{0, 5}}},
+		"deploy":   {{DocID: 4, FieldFreqs: []uint{1, 0, 0, 0, 0}}},
+		"note":     {{DocID: 4, FieldFreqs: []uint{0, 1, 0, 0, 0}}},
+	}
+	if !reflect.DeepEqual(invertedIndex, expected) {
+		t.Errorf("Expected postings %v, got %v", expected, invertedIndex)
+	}
+}
@@ -0,0 +1,362 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"mneme/internal/core"
+	"mneme/internal/ingest"
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrFullRebuildRequired is returned when the existing index cannot be updated
+// incrementally, e.g. when there is no index yet or it predates document metadata.
+var ErrFullRebuildRequired = errors.New("existing index cannot be updated incrementally, full rebuild required")
+
+// IncrementalStats summarizes the changes applied by an incremental index update
+type IncrementalStats struct {
+	Added     int // Documents indexed for the first time
+	Modified  int // Documents re-indexed because their contents changed
+	Deleted   int // Documents that disappeared from their source
+	Unchanged int // Documents skipped because they did not change
+}
+
+// HasChanges reports whether the update modified the index
+func (s *IncrementalStats) HasChanges() bool {
+	return s.Added+s.Modified+s.Deleted > 0
+}
+
+// indexedDoc locates a live document within the existing chunks
+type indexedDoc struct {
+	chunkID int
+	doc     core.Document
+}
+
+// IndexIncrementalWithRegistry updates the existing index instead of rebuilding it.
+// Documents whose modification time and size match the index are skipped without
+// being read. Changed documents are read and re-indexed only if their content hash
+// differs; new documents are indexed and documents missing from the crawl are marked
+// as deleted. All new and re-indexed documents are written as delta chunks appended
+// to the existing manifest, and superseded versions are marked as deleted.
+// Returns ErrFullRebuildRequired if there is no compatible index to update.
+func IndexIncrementalWithRegistry(registry *ingest.Registry, crawlerOptions *core.CrawlerOptions, config *core.BatchConfig) (*core.Manifest, *IncrementalStats, error) {
+	if config == nil {
+		config = core.DefaultBatchConfig()
+	}
+
+	if !config.SuppressLogs {
+		logger.Info("Starting IndexIncrementalWithRegistry")
+	}
+
+	manifest, err := storage.LoadManifest()
+	if err != nil {
+		logger.Errorf("Error loading manifest: %+v", err)
+		return nil, nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	if manifest == nil || manifest.Version != core.ManifestVersion || len(manifest.GetCompleteChunks()) == 0 {
+		return nil, nil, ErrFullRebuildRequired
+	}
+
+	known, err := loadIndexedDocs(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	deletions := make(map[int][]uint)
+
+	// Crawl all enabled sources
+	allDocIDs, err := registry.CrawlAll(crawlerOptions)
+	if err != nil {
+		logger.Errorf("Error crawling sources: %+v", err)
+		return nil, nil, err
+	}
+
+	stats := &IncrementalStats{}
+
+	// Classify crawled documents by comparing metadata with the index
+	seen := make(map[string]bool, len(allDocIDs))
+	candidates := make([]string, 0)
+	for _, docID := range allDocIDs {
+		docPath := filepath.Clean(docID)
+		seen[docPath] = true
+
+		prev, exists := known[docPath]
+		if !exists {
+			candidates = append(candidates, docID)
+			continue
+		}
+
+		info, err := registry.StatDocument(docID)
+		if err == nil && prev.doc.ModTime == info.ModTime.UnixNano() && prev.doc.Size == info.Size {
+			stats.Unchanged++
+			continue
+		}
+		candidates = append(candidates, docID)
+	}
+
+	// Documents that are no longer crawled were removed from their source
+	for docPath, prev := range known {
+		if !seen[docPath] {
+			deletions[prev.chunkID] = append(deletions[prev.chunkID], prev.doc.ID)
+			stats.Deleted++
+			logger.Debugf("Document removed: %s", docPath)
+		}
+	}
+
+	if !config.SuppressLogs {
+		logger.Infof("Incremental update: %d candidates, %d unchanged, %d deleted (batch size: %d)",
+			len(candidates), stats.Unchanged, stats.Deleted, config.BatchSize)
+	}
+
+	if err := writeDeltaChunks(manifest, known, deletions, registry, candidates, stats, config); err != nil {
+		return manifest, stats, err
+	}
+
+	if !config.SuppressLogs {
+		logger.Infof("IndexIncrementalWithRegistry completed: %d added, %d modified, %d deleted, %d unchanged",
+			stats.Added, stats.Modified, stats.Deleted, stats.Unchanged)
+	}
+
+	return manifest, stats, nil
+}
+
+// IndexChangedPathsWithRegistry applies an incremental update for a known set of
+// changed paths, e.g. as reported by a filesystem watcher, without crawling the
+// sources. Paths that no longer exist are marked as deleted together with any
+// indexed documents below them; existing paths are re-indexed if they changed.
+// Callers are responsible for filtering paths with the crawler rules and for
+// passing the members of changed archives along with the archive itself, whose
+// members not passed are marked as deleted when archives are indexed.
+// Returns ErrFullRebuildRequired if there is no compatible index to update.
+func IndexChangedPathsWithRegistry(registry *ingest.Registry, paths []string, config *core.BatchConfig) (*core.Manifest, *IncrementalStats, error) {
+	if config == nil {
+		config = core.DefaultBatchConfig()
+	}
+
+	manifest, err := storage.LoadManifest()
+	if err != nil {
+		logger.Errorf("Error loading manifest: %+v", err)
+		return nil, nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	if manifest == nil || manifest.Version != core.ManifestVersion || len(manifest.GetCompleteChunks()) == 0 {
+		return nil, nil, ErrFullRebuildRequired
+	}
+
+	known, err := loadIndexedDocs(manifest)
+	if err != nil {
+		return nil, nil, err
+	}
+	deletions := make(map[int][]uint)
+
+	stats := &IncrementalStats{}
+	candidates := make([]string, 0, len(paths))
+	// A path reported more than once is indexed once, as both copies would stay live
+	changed := make(map[string]bool, len(paths))
+	unique := make([]string, 0, len(paths))
+	for _, path := range paths {
+		if docPath := filepath.Clean(path); !changed[docPath] {
+			changed[docPath] = true
+			unique = append(unique, path)
+		}
+	}
+
+	for _, path := range unique {
+		docPath := filepath.Clean(path)
+		prev, exists := known[docPath]
+
+		info, err := registry.StatDocument(path)
+		if errors.Is(err, ingest.ErrDocumentNotFound) {
+			// The path itself or a directory or archive containing indexed documents was removed
+			prefix := docPath + string(filepath.Separator)
+			memberPrefix := docPath + storage.ArchiveSeparator
+			for knownPath, doc := range known {
+				if knownPath == docPath || strings.HasPrefix(knownPath, prefix) || strings.HasPrefix(knownPath, memberPrefix) {
+					deletions[doc.chunkID] = append(deletions[doc.chunkID], doc.doc.ID)
+					delete(known, knownPath)
+					stats.Deleted++
+				}
+			}
+			continue
+		}
+
+		if config.IndexConfig.ArchiveDepth > 0 && storage.IsArchive(docPath) && storage.ArchivePath(docPath) == docPath {
+			// The archive is indexed through its members; drop those it no longer has
+			memberPrefix := docPath + storage.ArchiveSeparator
+			for knownPath, doc := range known {
+				if strings.HasPrefix(knownPath, memberPrefix) && !changed[knownPath] {
+					deletions[doc.chunkID] = append(deletions[doc.chunkID], doc.doc.ID)
+					delete(known, knownPath)
+					stats.Deleted++
+				}
+			}
+			continue
+		}
+
+		if err == nil && exists && prev.doc.ModTime == info.ModTime.UnixNano() && prev.doc.Size == info.Size {
+			stats.Unchanged++
+			continue
+		}
+		candidates = append(candidates, path)
+	}
+
+	if err := writeDeltaChunks(manifest, known, deletions, registry, candidates, stats, config); err != nil {
+		return manifest, stats, err
+	}
+
+	if !config.SuppressLogs {
+		logger.Infof("IndexChangedPathsWithRegistry completed: %d added, %d modified, %d deleted, %d unchanged",
+			stats.Added, stats.Modified, stats.Deleted, stats.Unchanged)
+	}
+
+	return manifest, stats, nil
+}
+
+// writeDeltaChunks indexes the candidate documents into new chunks appended to the
+// manifest, superseding previous versions of re-indexed documents, and persists the
+// manifest. Pending deletions (chunk ID -> document IDs) are written to the
+// deletion bitmaps of their chunks together with the first manifest update.
+func writeDeltaChunks(manifest *core.Manifest, known map[string]indexedDoc, deletions map[int][]uint, registry *ingest.Registry, candidates []string, stats *IncrementalStats, config *core.BatchConfig) error {
+	ctx := batchContext(config)
+	chunkID := manifest.NextChunkID()
+	globalDocID := manifest.NextDocID
+
+	for batchStart := 0; batchStart < len(candidates); batchStart += config.BatchSize {
+		batchEnd := batchStart + config.BatchSize
+		if batchEnd > len(candidates) {
+			batchEnd = len(candidates)
+		}
+
+		if config.ProgressCallback != nil {
+			config.ProgressCallback(batchEnd, len(candidates), fmt.Sprintf("Processing delta chunk %d: documents %d-%d", chunkID, batchStart+1, batchEnd))
+		}
+
+		release := registry.StartBatch(candidates[batchStart:batchEnd])
+		chunk, docCount, tokenCount, err := processDocuments(ctx, candidates[batchStart:batchEnd], registry.ReadDocument, &globalDocID, config.IndexConfig)
+		release()
+		if err != nil {
+			return err
+		}
+		if docCount == 0 {
+			continue
+		}
+
+		manifest.AddChunk(core.ChunkInfo{
+			ID:         chunkID,
+			Filename:   formatChunkFilename(chunkID),
+			Status:     core.ChunkStatusInProgress,
+			DocCount:   docCount,
+			TokenCount: tokenCount,
+			CreatedAt:  time.Now(),
+		})
+
+		checksum, err := storage.SaveChunk(chunk, chunkID)
+		if err != nil {
+			logger.Errorf("Error saving chunk %d: %+v", chunkID, err)
+			return err
+		}
+
+		// Supersede previous versions of re-indexed documents. Documents whose
+		// metadata changed but whose contents did not are written again all the
+		// same, so that their new modification time and size are recorded.
+		for _, doc := range chunk.Docs {
+			prev, exists := known[doc.Path]
+			switch {
+			case !exists:
+				stats.Added++
+			case prev.doc.ContentHash != "" && prev.doc.ContentHash == doc.ContentHash:
+				stats.Unchanged++
+			default:
+				stats.Modified++
+			}
+			if exists {
+				deletions[prev.chunkID] = append(deletions[prev.chunkID], prev.doc.ID)
+			}
+		}
+
+		if err := flushDeletions(manifest, deletions); err != nil {
+			return err
+		}
+
+		manifest.MarkChunkComplete(chunkID, checksum)
+		manifest.NextDocID = globalDocID
+		manifest.UpdateTotals()
+		if err := saveManifest(manifest); err != nil {
+			return err
+		}
+
+		if !config.SuppressLogs {
+			logger.Infof("Delta chunk %d completed: %d docs, %d unique tokens", chunkID, docCount, tokenCount)
+		}
+
+		chunkID++
+	}
+
+	// Persist deletions even when no delta chunk was written
+	if len(deletions) > 0 {
+		if err := flushDeletions(manifest, deletions); err != nil {
+			return err
+		}
+		manifest.UpdateTotals()
+		if err := saveManifest(manifest); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// saveManifest saves the manifest, then removes the deletion bitmaps it replaced.
+// Until the manifest is saved, the one on disk keeps pointing at the previous
+// bitmaps, so an interrupted update leaves a consistent index.
+func saveManifest(manifest *core.Manifest) error {
+	if err := storage.SaveManifest(manifest); err != nil {
+		logger.Errorf("Error saving manifest: %+v", err)
+		return err
+	}
+	if err := storage.RemoveUnreferencedDeletions(manifest); err != nil {
+		logger.Warnf("Failed to remove previous deletion bitmaps: %+v", err)
+	}
+	return nil
+}
+
+// flushDeletions writes pending deletions to new versions of the chunk deletion
+// bitmaps, recorded in the manifest but not saved, and clears them
+func flushDeletions(manifest *core.Manifest, deletions map[int][]uint) error {
+	if len(deletions) == 0 {
+		return nil
+	}
+	if err := storage.ApplyDeletions(manifest, deletions); err != nil {
+		logger.Errorf("Error applying deletions: %+v", err)
+		return err
+	}
+	clear(deletions)
+	return nil
+}
+
+// loadIndexedDocs returns the live documents of all complete chunks keyed by path
+func loadIndexedDocs(manifest *core.Manifest) (map[string]indexedDoc, error) {
+	known := make(map[string]indexedDoc)
+
+	for _, chunkInfo := range manifest.GetCompleteChunks() {
+		docs, err := storage.LoadChunkDocuments(chunkInfo)
+		if errors.Is(err, storage.ErrCorruptChunk) {
+			// The documents of a corrupt chunk are unknown, so only a rebuild restores them
+			logger.Warnf("Chunk %d is corrupt: %+v", chunkInfo.ID, err)
+			return nil, fmt.Errorf("%w: %w", ErrFullRebuildRequired, err)
+		}
+		if err != nil {
+			logger.Errorf("Error loading documents of chunk %d: %+v", chunkInfo.ID, err)
+			return nil, fmt.Errorf("failed to load documents of chunk %d: %w", chunkInfo.ID, err)
+		}
+
+		for _, doc := range docs {
+			known[doc.Path] = indexedDoc{chunkID: chunkInfo.ID, doc: doc}
+		}
+	}
+
+	return known, nil
+}
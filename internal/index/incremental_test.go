@@ -0,0 +1,356 @@
+package index
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/ingest"
+	"mneme/internal/storage"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupIncrementalTest points the data directory at a temp dir and returns a
+// corpus directory with a registry crawling it.
+func setupIncrementalTest(t *testing.T) (string, *ingest.Registry) {
+	t.Helper()
+
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+
+	tempDir := t.TempDir()
+	constants.DirPath = filepath.Join(tempDir, "data")
+	if err := os.MkdirAll(filepath.Join(constants.DirPath, "segments"), 0755); err != nil {
+		t.Fatalf("Failed to create segments dir: %v", err)
+	}
+
+	corpusDir := filepath.Join(tempDir, "corpus")
+	if err := os.MkdirAll(corpusDir, 0755); err != nil {
+		t.Fatalf("Failed to create corpus dir: %v", err)
+	}
+
+	registry := ingest.NewRegistry()
+	registry.Register(ingest.NewFilesystemIngestor([]string{corpusDir}, nil))
+	return corpusDir, registry
+}
+
+func writeCorpusFile(t *testing.T, path, content string, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("Failed to set mtime of %s: %v", path, err)
+	}
+}
+
+func quietBatchConfig() *core.BatchConfig {
+	config := core.DefaultBatchConfig()
+	config.SuppressLogs = true
+	return config
+}
+
+func TestIndexIncrementalWithRegistry(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	options := core.DefaultCrawlerOptions()
+	base := time.Now().Add(-time.Hour)
+
+	alpha := filepath.Join(corpusDir, "alpha.md")
+	beta := filepath.Join(corpusDir, "beta.md")
+	gamma := filepath.Join(corpusDir, "gamma.md")
+	writeCorpusFile(t, alpha, "kubernetes deployment notes", base)
+	writeCorpusFile(t, beta, "terraform module layout", base)
+	writeCorpusFile(t, gamma, "postgres replication setup", base)
+
+	if _, _, err := IndexIncrementalWithRegistry(registry, &options, quietBatchConfig()); !errors.Is(err, ErrFullRebuildRequired) {
+		t.Fatalf("Expected ErrFullRebuildRequired without an index, got %v", err)
+	}
+
+	if _, err := IndexBuilderBatchedWithRegistry(registry, &options, quietBatchConfig()); err != nil {
+		t.Fatalf("Full build failed: %v", err)
+	}
+
+	t.Run("nothing changed", func(t *testing.T) {
+		_, stats, err := IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+		if err != nil {
+			t.Fatalf("Incremental update failed: %v", err)
+		}
+		if stats.HasChanges() || stats.Unchanged != 3 {
+			t.Errorf("Expected 3 unchanged docs and no changes, got %+v", stats)
+		}
+	})
+
+	t.Run("touched file with same content is not re-indexed", func(t *testing.T) {
+		writeCorpusFile(t, beta, "terraform module layout", base.Add(time.Minute))
+
+		manifest, stats, err := IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+		if err != nil {
+			t.Fatalf("Incremental update failed: %v", err)
+		}
+		if stats.HasChanges() || stats.Unchanged != 3 {
+			t.Errorf("Expected no changes for identical content, got %+v", stats)
+		}
+		chunks := len(manifest.Chunks)
+
+		// The new modification time is recorded, so the file is not read again
+		manifest, stats, err = IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+		if err != nil {
+			t.Fatalf("Incremental update failed: %v", err)
+		}
+		if stats.HasChanges() || stats.Unchanged != 3 {
+			t.Errorf("Expected 3 unchanged docs and no changes, got %+v", stats)
+		}
+		if len(manifest.Chunks) != chunks {
+			t.Errorf("Expected no delta chunk for unchanged metadata, got %d chunks", len(manifest.Chunks))
+		}
+		if manifest.TotalDocs != 3 {
+			t.Errorf("Expected 3 live docs, got %d", manifest.TotalDocs)
+		}
+	})
+
+	t.Run("added, modified and deleted files", func(t *testing.T) {
+		writeCorpusFile(t, alpha, "kubernetes helm charts", base.Add(2*time.Minute))
+		writeCorpusFile(t, filepath.Join(corpusDir, "delta.md"), "grafana dashboards", base)
+		if err := os.Remove(gamma); err != nil {
+			t.Fatalf("Failed to remove gamma: %v", err)
+		}
+
+		manifest, stats, err := IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+		if err != nil {
+			t.Fatalf("Incremental update failed: %v", err)
+		}
+		if stats.Added != 1 || stats.Modified != 1 || stats.Deleted != 1 || stats.Unchanged != 1 {
+			t.Errorf("Unexpected stats: %+v", stats)
+		}
+		// The touched file was written to the first delta chunk
+		if len(manifest.Chunks) != 3 {
+			t.Errorf("Expected a delta chunk to be appended, got %d chunks", len(manifest.Chunks))
+		}
+		if manifest.TotalDocs != 3 {
+			t.Errorf("Expected 3 live docs, got %d", manifest.TotalDocs)
+		}
+
+		segment, err := storage.LoadAllChunks()
+		if err != nil {
+			t.Fatalf("Failed to load chunks: %v", err)
+		}
+
+		paths := make(map[string]bool)
+		for _, doc := range segment.Docs {
+			if paths[doc.Path] {
+				t.Errorf("Document %s is live more than once", doc.Path)
+			}
+			paths[doc.Path] = true
+		}
+		if paths[gamma] {
+			t.Error("Deleted document is still live")
+		}
+		if segment.LiveDocFrequency("deploy") != 0 {
+			t.Error("Terms of the superseded version are still live")
+		}
+		if segment.LiveDocFrequency("helm") != 1 {
+			t.Error("Terms of the modified version are missing")
+		}
+		if segment.LiveDocFrequency("postgr") != 0 {
+			t.Error("Terms of the deleted document are still live")
+		}
+		if segment.TotalDocs != 3 {
+			t.Errorf("Expected corpus statistics for 3 live docs, got %d", segment.TotalDocs)
+		}
+	})
+
+	t.Run("previous deletion bitmaps are removed once the manifest is saved", func(t *testing.T) {
+		writeCorpusFile(t, beta, "terraform state locking", base.Add(3*time.Minute))
+
+		manifest, _, err := IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+		if err != nil {
+			t.Fatalf("Incremental update failed: %v", err)
+		}
+
+		referenced := make(map[string]bool)
+		for _, chunkInfo := range manifest.Chunks {
+			if chunkInfo.DeletesFile != "" {
+				referenced[chunkInfo.DeletesFile] = true
+			}
+		}
+		if !referenced["001.2.del"] {
+			t.Errorf("Expected the second version of the bitmap of chunk 1, got %v", referenced)
+		}
+		bitmaps, err := filepath.Glob(filepath.Join(constants.DirPath, "segments", "*.del"))
+		if err != nil {
+			t.Fatalf("Failed to list deletion bitmaps: %v", err)
+		}
+		for _, bitmap := range bitmaps {
+			if !referenced[filepath.Base(bitmap)] {
+				t.Errorf("Deletion bitmap %s is not referenced by the manifest", filepath.Base(bitmap))
+			}
+		}
+		if len(bitmaps) != len(referenced) {
+			t.Errorf("Expected %d deletion bitmaps, got %v", len(referenced), bitmaps)
+		}
+	})
+}
+
+func TestIndexChangedPathsWithRegistry(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	options := core.DefaultCrawlerOptions()
+	base := time.Now().Add(-time.Hour)
+
+	notesDir := filepath.Join(corpusDir, "notes")
+	if err := os.MkdirAll(notesDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	alpha := filepath.Join(corpusDir, "alpha.md")
+	beta := filepath.Join(notesDir, "beta.md")
+	gamma := filepath.Join(notesDir, "gamma.md")
+	writeCorpusFile(t, alpha, "kubernetes deployment notes", base)
+	writeCorpusFile(t, beta, "terraform module layout", base)
+	writeCorpusFile(t, gamma, "postgres replication setup", base)
+
+	if _, err := IndexBuilderBatchedWithRegistry(registry, &options, quietBatchConfig()); err != nil {
+		t.Fatalf("Full build failed: %v", err)
+	}
+
+	// A new file, an unchanged file and a removed directory
+	delta := filepath.Join(corpusDir, "delta.md")
+	writeCorpusFile(t, delta, "grafana dashboards", base)
+	if err := os.RemoveAll(notesDir); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, stats, err := IndexChangedPathsWithRegistry(registry, []string{delta, alpha, notesDir}, quietBatchConfig())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stats.Added != 1 || stats.Deleted != 2 || stats.Unchanged != 1 || stats.Modified != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if manifest.TotalDocs != 2 {
+		t.Errorf("Expected 2 live docs, got %d", manifest.TotalDocs)
+	}
+}
+
+func TestIndexIncrementalWithRegistry_CorruptChunk(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	options := core.DefaultCrawlerOptions()
+	writeCorpusFile(t, filepath.Join(corpusDir, "alpha.md"), "kubernetes deployment notes", time.Now().Add(-time.Hour))
+
+	manifest, err := IndexBuilderBatchedWithRegistry(registry, &options, quietBatchConfig())
+	if err != nil {
+		t.Fatalf("Full build failed: %v", err)
+	}
+	if manifest.Chunks[0].Checksum == 0 {
+		t.Fatal("Expected the checksum of the chunk to be recorded")
+	}
+
+	chunkPath := filepath.Join(constants.DirPath, "segments", manifest.Chunks[0].Filename)
+	if err := os.Truncate(chunkPath, 16); err != nil {
+		t.Fatalf("Failed to truncate chunk: %v", err)
+	}
+
+	// The documents of a corrupt chunk are unknown, so the index is rebuilt
+	_, _, err = IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+	if !errors.Is(err, ErrFullRebuildRequired) || !errors.Is(err, storage.ErrCorruptChunk) {
+		t.Fatalf("Expected ErrFullRebuildRequired for a corrupt chunk, got %v", err)
+	}
+}
+
+func TestIndexChangedPathsWithRegistry_Archive(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	options := core.DefaultCrawlerOptions()
+	options.ArchiveDepth = 1
+	config := quietBatchConfig()
+	config.IndexConfig.ArchiveDepth = 1
+
+	archive := filepath.Join(corpusDir, "backup.zip")
+	writeZip := func(files map[string]string, modTime time.Time) {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, content := range files {
+			fw, err := w.Create(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := fw.Write([]byte(content)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		writeCorpusFile(t, archive, buf.String(), modTime)
+	}
+	writeZip(map[string]string{
+		"docs/alpha.md": "kubernetes deployment notes",
+		"docs/beta.md":  "terraform module layout",
+	}, time.Now().Add(-time.Hour))
+
+	manifest, err := IndexBuilderBatchedWithRegistry(registry, &options, config)
+	if err != nil {
+		t.Fatalf("Full build failed: %v", err)
+	}
+	if manifest.TotalDocs != 2 {
+		t.Fatalf("Expected both archive members to be indexed, got %d docs", manifest.TotalDocs)
+	}
+
+	// One member changed and the other was removed from the archive
+	writeZip(map[string]string{"docs/alpha.md": "kubernetes rollout notes"}, time.Now())
+	members, err := storage.Crawler(archive, options)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+	manifest, stats, err := IndexChangedPathsWithRegistry(registry, append([]string{archive}, members...), config)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stats.Modified != 1 || stats.Deleted != 1 || stats.Added != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if manifest.TotalDocs != 1 {
+		t.Errorf("Expected 1 live doc, got %d", manifest.TotalDocs)
+	}
+
+	// Removing the archive removes its members
+	if err := os.Remove(archive); err != nil {
+		t.Fatal(err)
+	}
+	manifest, stats, err = IndexChangedPathsWithRegistry(registry, []string{archive}, config)
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stats.Deleted != 1 || manifest.TotalDocs != 0 {
+		t.Errorf("Expected the remaining member to be deleted, got %+v with %d docs", stats, manifest.TotalDocs)
+	}
+}
+
+func TestIndexChangedPathsWithRegistry_DuplicatePaths(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	options := core.DefaultCrawlerOptions()
+	base := time.Now().Add(-time.Hour)
+
+	alpha := filepath.Join(corpusDir, "alpha.md")
+	writeCorpusFile(t, alpha, "kubernetes deployment notes", base)
+	if _, err := IndexBuilderBatchedWithRegistry(registry, &options, quietBatchConfig()); err != nil {
+		t.Fatalf("Full build failed: %v", err)
+	}
+
+	// A modified and a new file, each reported twice
+	writeCorpusFile(t, alpha, "kubernetes rollout notes", time.Now())
+	beta := filepath.Join(corpusDir, "beta.md")
+	writeCorpusFile(t, beta, "terraform module layout", base)
+
+	manifest, stats, err := IndexChangedPathsWithRegistry(registry, []string{alpha, beta, alpha, beta + string(filepath.Separator)}, quietBatchConfig())
+	if err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	if stats.Modified != 1 || stats.Added != 1 || stats.Deleted != 0 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if manifest.TotalDocs != 2 {
+		t.Errorf("Expected 2 live docs, got %d", manifest.TotalDocs)
+	}
+}
@@ -0,0 +1,200 @@
+package index
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+
+	"mneme/internal/core"
+	"mneme/internal/extract"
+	"mneme/internal/ingest"
+	"mneme/internal/logger"
+)
+
+// documentReader reads the document with the given ID
+type documentReader func(id string) (*ingest.Document, error)
+
+// tokenizedDocument is the result of reading and tokenizing a single document.
+// Its ID is assigned when the worker results are merged.
+type tokenizedDocument struct {
+	doc     core.Document
+	indexed bool // False if the document could not be read or exceeds the token limit
+}
+
+// partialIndex is the inverted index a worker builds from the documents it
+// tokenized. Postings refer to documents by their position in the batch.
+type partialIndex map[string][]core.Posting
+
+// indexWorkers returns the number of workers for the configured index.workers,
+// where 0 or less uses one worker per CPU
+func indexWorkers(configured int) int {
+	if configured > 0 {
+		return configured
+	}
+	return runtime.NumCPU()
+}
+
+// batchContext returns the context that cancels indexing, which never ends if none is set
+func batchContext(config *core.BatchConfig) context.Context {
+	if config.Context != nil {
+		return config.Context
+	}
+	return context.Background()
+}
+
+// processDocuments reads and indexes a batch of documents into a new chunk segment.
+// Documents are read and tokenized concurrently by indexConfig.Workers workers,
+// each building a partial inverted index; the partial indexes are then merged and
+// document IDs assigned from globalDocID in the order of ids, so the chunk does
+// not depend on scheduling.
+// It returns the context's error if ctx is cancelled before the batch is complete.
+func processDocuments(ctx context.Context, ids []string, read documentReader, globalDocID *uint, indexConfig core.IndexConfig) (*core.Segment, uint, uint, error) {
+	workers := min(indexWorkers(indexConfig.Workers), max(len(ids), 1))
+	results := make([]tokenizedDocument, len(ids))
+	partials := make([]partialIndex, workers)
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := range workers {
+		partials[w] = make(partialIndex)
+		wg.Add(1)
+		go func(partial partialIndex) {
+			defer wg.Done()
+			tokenFrequency := make(map[string]uint)
+			tokenPositions := make(map[string][]uint)
+			for i := range jobs {
+				results[i] = tokenizeDocument(ids[i], uint(i), read, indexConfig.MaxTokensPerDocument, partial, tokenFrequency, tokenPositions)
+			}
+		}(partials[w])
+	}
+
+	// Jobs are handed out in order, so the postings of every partial index are sorted
+feed:
+	for i := range ids {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	// Assign document IDs in batch order
+	docIDs := make([]uint, len(ids))
+	kept := make([]bool, len(ids))
+	docs := make([]core.Document, 0, len(ids))
+	for i, result := range results {
+		if !result.indexed {
+			continue
+		}
+
+		result.doc.ID = *globalDocID
+		docIDs[i] = *globalDocID
+		kept[i] = true
+		docs = append(docs, result.doc)
+		*globalDocID++
+	}
+
+	invertedIndex := mergePartialIndexes(partials, docIDs, kept)
+	docCount := uint(len(docs))
+
+	avgDocLen := uint(0)
+	if len(docs) > 0 {
+		totalTokens := uint(0)
+		for _, doc := range docs {
+			totalTokens += doc.TokenCount
+		}
+		avgDocLen = totalTokens / uint(len(docs))
+	}
+
+	chunk := &core.Segment{
+		Docs:          docs,
+		InvertedIndex: invertedIndex,
+		TotalDocs:     docCount,
+		TotalTokens:   uint(len(invertedIndex)),
+		AvgDocLen:     avgDocLen,
+	}
+
+	return chunk, docCount, uint(len(invertedIndex)), nil
+}
+
+// tokenizeDocument reads and tokenizes a single document, adding its postings
+// to partial under position. tokenFrequency and tokenPositions are scratch maps
+// reused across the documents of a worker.
+func tokenizeDocument(id string, position uint, read documentReader, maxTokensPerDocument int, partial partialIndex, tokenFrequency map[string]uint, tokenPositions map[string][]uint) tokenizedDocument {
+	doc, err := read(id)
+	if errors.Is(err, extract.ErrNoText) {
+		logger.Warnf("Skipping document %s: %v", id, err)
+		return tokenizedDocument{}
+	}
+	if err != nil || doc == nil {
+		logger.Errorf("Error reading document %s: %+v", id, err)
+		return tokenizedDocument{}
+	}
+
+	// Resets the maps and tokenizes the whole document as one positional stream
+	tokenizeLines(doc.Contents, tokenFrequency, tokenPositions)
+
+	// Skip document if token count exceeds max (when max > 0)
+	if maxTokensPerDocument > 0 && len(tokenFrequency) > maxTokensPerDocument {
+		logger.Debugf("Skipping document %s: token count %d exceeds max %d", id, len(tokenFrequency), maxTokensPerDocument)
+		return tokenizedDocument{}
+	}
+
+	docPath := filepath.Clean(doc.Path)
+	fields := tokenizeFields(docPath, doc)
+	addDocumentPostings(partial, position, tokenFrequency, tokenPositions, fields)
+
+	indexed := core.Document{
+		Path:         docPath,
+		TokenCount:   uint(len(tokenFrequency)),
+		Size:         doc.Size,
+		ContentHash:  hashDocument(doc),
+		Source:       doc.Source,
+		FieldLengths: fields.lengths,
+		Title:        doc.Title,
+		Tags:         doc.Tags,
+	}
+	if !doc.ModTime.IsZero() {
+		indexed.ModTime = doc.ModTime.UnixNano()
+	}
+	if !doc.Date.IsZero() {
+		indexed.Date = doc.Date.UnixNano()
+	}
+	return tokenizedDocument{doc: indexed, indexed: true}
+}
+
+// mergePartialIndexes merges the partial indexes of all workers, replacing
+// batch positions by document IDs. Postings of documents not kept are dropped.
+func mergePartialIndexes(partials []partialIndex, docIDs []uint, kept []bool) map[string][]core.Posting {
+	invertedIndex := make(map[string][]core.Posting)
+	for _, partial := range partials {
+		for term, postings := range partial {
+			for _, posting := range postings {
+				if kept[posting.DocID] {
+					posting.DocID = docIDs[posting.DocID]
+					invertedIndex[term] = append(invertedIndex[term], posting)
+				}
+			}
+		}
+	}
+
+	// Every partial index is sorted, but their postings interleave
+	if len(partials) > 1 {
+		for _, postings := range invertedIndex {
+			slices.SortFunc(postings, func(a, b core.Posting) int {
+				return cmp.Compare(a.DocID, b.DocID)
+			})
+		}
+	}
+	return invertedIndex
+}
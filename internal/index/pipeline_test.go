@@ -0,0 +1,208 @@
+package index
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mneme/internal/core"
+	"mneme/internal/extract"
+	"mneme/internal/ingest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryReader returns a documentReader serving documents from memory.
+// Documents missing from docs cannot be read.
+func memoryReader(docs map[string]string) documentReader {
+	return func(id string) (*ingest.Document, error) {
+		content, ok := docs[id]
+		if !ok {
+			return nil, ingest.ErrDocumentNotFound
+		}
+		return &ingest.Document{ID: id, Path: id, Contents: strings.Split(content, "\n")}, nil
+	}
+}
+
+func createPipelineCorpus(n int) ([]string, map[string]string) {
+	ids := make([]string, 0, n+1)
+	docs := make(map[string]string, n)
+	for i := range n {
+		id := fmt.Sprintf("/notes/note%03d.md", i)
+		ids = append(ids, id)
+		docs[id] = fmt.Sprintf("# Note %d\nshared words in every note\nunique%d term%d appears here\nterm%d again", i, i, i%7, i%5)
+	}
+	// Unreadable documents get no ID
+	return append(ids, "/notes/missing.md"), docs
+}
+
+func TestProcessDocuments_Deterministic(t *testing.T) {
+	ids, docs := createPipelineCorpus(200)
+
+	process := func(workers int) (*core.Segment, uint) {
+		nextDocID := uint(10)
+		chunk, docCount, _, err := processDocuments(context.Background(), ids, memoryReader(docs), &nextDocID, core.IndexConfig{Workers: workers})
+		if err != nil {
+			t.Fatalf("processDocuments returned error: %v", err)
+		}
+		if docCount != 200 {
+			t.Fatalf("Expected 200 documents, got %d", docCount)
+		}
+		return chunk, nextDocID
+	}
+
+	sequential, nextSequential := process(1)
+	if nextSequential != 210 {
+		t.Errorf("Expected the next document ID to be 210, got %d", nextSequential)
+	}
+	for i, doc := range sequential.Docs {
+		if doc.ID != uint(10+i) || doc.Path != ids[i] {
+			t.Fatalf("Document %d: expected ID %d for %s, got %d for %s", i, 10+i, ids[i], doc.ID, doc.Path)
+		}
+	}
+
+	for _, workers := range []int{2, 8} {
+		parallel, nextParallel := process(workers)
+		if nextParallel != nextSequential {
+			t.Errorf("%d workers: expected next ID %d, got %d", workers, nextSequential, nextParallel)
+		}
+		if !reflect.DeepEqual(parallel.Docs, sequential.Docs) {
+			t.Errorf("%d workers: documents differ from a single worker", workers)
+		}
+		if !reflect.DeepEqual(parallel.InvertedIndex, sequential.InvertedIndex) {
+			t.Errorf("%d workers: inverted index differs from a single worker", workers)
+		}
+	}
+
+	for term, postings := range sequential.InvertedIndex {
+		for i := 1; i < len(postings); i++ {
+			if postings[i-1].DocID >= postings[i].DocID {
+				t.Fatalf("Postings of %q are not sorted by document ID", term)
+			}
+		}
+	}
+}
+
+func TestProcessDocuments_NoText(t *testing.T) {
+	ids, docs := createPipelineCorpus(5)
+	read := func(id string) (*ingest.Document, error) {
+		if id == ids[1] {
+			return nil, fmt.Errorf("%w: PDF is encrypted", extract.ErrNoText)
+		}
+		return memoryReader(docs)(id)
+	}
+
+	nextDocID := uint(1)
+	chunk, docCount, _, err := processDocuments(context.Background(), ids, read, &nextDocID, core.IndexConfig{Workers: 2})
+	if err != nil {
+		t.Fatalf("processDocuments returned error: %v", err)
+	}
+	if docCount != 4 {
+		t.Fatalf("Expected the document without text to be skipped, got %d documents", docCount)
+	}
+	for _, doc := range chunk.Docs {
+		if doc.Path == ids[1] {
+			t.Errorf("Document without text was indexed: %+v", doc)
+		}
+	}
+}
+
+func TestProcessDocuments_Metadata(t *testing.T) {
+	date := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	read := func(id string) (*ingest.Document, error) {
+		doc := &ingest.Document{ID: id, Path: id, Contents: []string{"", "deploy steps"}}
+		if id == "/notes/runbook.md" {
+			doc.Title = "Deployment Runbook"
+			doc.Tags = []string{"ops"}
+			doc.Date = date
+		}
+		return doc, nil
+	}
+
+	nextDocID := uint(1)
+	chunk, _, _, err := processDocuments(context.Background(), []string{"/notes/runbook.md", "/notes/plain.md"}, read, &nextDocID, core.IndexConfig{Workers: 1})
+	if err != nil {
+		t.Fatalf("processDocuments returned error: %v", err)
+	}
+
+	runbook, plain := chunk.Docs[0], chunk.Docs[1]
+	if runbook.Title != "Deployment Runbook" || !reflect.DeepEqual(runbook.Tags, []string{"ops"}) || runbook.Date != date.UnixNano() {
+		t.Errorf("Expected the metadata to be indexed, got %+v", runbook)
+	}
+	if plain.Title != "" || plain.Tags != nil || plain.Date != 0 {
+		t.Errorf("Expected no metadata, got %+v", plain)
+	}
+	if runbook.ContentHash == plain.ContentHash {
+		t.Error("Expected the metadata to be part of the content hash")
+	}
+
+	postings := chunk.InvertedIndex["runbook"]
+	if len(postings) != 1 || postings[0].FieldFreqs[core.FieldTitle] != 1 {
+		t.Errorf("Expected the title in the title field, got %+v", postings)
+	}
+}
+
+func TestProcessDocuments_Cancelled(t *testing.T) {
+	ids, docs := createPipelineCorpus(50)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	nextDocID := uint(1)
+	_, _, _, err := processDocuments(ctx, ids, memoryReader(docs), &nextDocID, core.IndexConfig{Workers: 4})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if nextDocID != 1 {
+		t.Errorf("Expected no document IDs to be assigned, got next ID %d", nextDocID)
+	}
+}
+
+func TestIndexBuilderBatchedWithRegistry_Cancelled(t *testing.T) {
+	corpusDir, registry := setupIncrementalTest(t)
+	for i := range 5 {
+		writeCorpusFile(t, filepath.Join(corpusDir, fmt.Sprintf("note%d.md", i)), fmt.Sprintf("note %d", i), time.Now().Add(-time.Hour))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	config := quietBatchConfig()
+	config.BatchSize = 2
+	config.Context = ctx
+	config.ProgressCallback = func(current, total int, message string) {
+		// Cancel once the first chunk has been saved
+		if strings.HasPrefix(message, "Batch 1 completed") {
+			cancel()
+		}
+	}
+
+	options := core.DefaultCrawlerOptions()
+	manifest, err := IndexBuilderBatchedWithRegistry(registry, &options, config)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if manifest == nil || len(manifest.Chunks) != 1 || manifest.Chunks[0].Status != core.ChunkStatusComplete {
+		t.Fatalf("Expected a single complete chunk, got %+v", manifest)
+	}
+	if manifest.TotalDocs != 2 || manifest.NextDocID != 3 {
+		t.Errorf("Expected 2 documents and next ID 3, got %d and %d", manifest.TotalDocs, manifest.NextDocID)
+	}
+
+	// The next incremental run indexes the rest
+	manifest, stats, err := IndexIncrementalWithRegistry(registry, &options, quietBatchConfig())
+	if err != nil {
+		t.Fatalf("IndexIncrementalWithRegistry returned error: %v", err)
+	}
+	if stats.Added != 3 || manifest.TotalDocs != 5 {
+		t.Errorf("Expected 3 added documents for 5 in total, got %d and %d", stats.Added, manifest.TotalDocs)
+	}
+}
+
+func TestIndexWorkers(t *testing.T) {
+	if indexWorkers(3) != 3 {
+		t.Error("Expected the configured number of workers")
+	}
+	if indexWorkers(0) < 1 || indexWorkers(-1) < 1 {
+		t.Error("Expected at least one worker by default")
+	}
+}
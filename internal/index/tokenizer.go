@@ -64,6 +64,72 @@ func TokenizeContent(content string) []string {
 	return FilterStopwords(tokens)
 }
 
+// PositionedToken is a normalized token together with its word position in the
+// token stream of a document. Tokens derived from the same word (such as the
+// compound form of an identifier and its first part) share a position.
+type PositionedToken struct {
+	Token    string
+	Position uint
+}
+
+// TokenizeContentWithPositions tokenizes content exactly like TokenizeContent but
+// also numbers every word, starting at startPos. It returns the tokens and the
+// next free position so that consecutive lines can be numbered as one stream.
+// Stopwords are removed after numbering, leaving gaps that keep phrase offsets intact.
+func TokenizeContentWithPositions(content string, startPos uint) ([]PositionedToken, uint) {
+	if IsBinaryContent(content) {
+		return []PositionedToken{}, startPos
+	}
+
+	var tokens []PositionedToken
+	pos := startPos
+
+	addWord := func(word string) {
+		words, offsets, width := processIdentifierWithOffsets(word)
+		for i, token := range words {
+			tokens = append(tokens, PositionedToken{Token: token, Position: pos + offsets[i]})
+		}
+		pos += width
+	}
+
+	if containsCJK(content) && initGSE() == nil {
+		for _, seg := range gseSegmenter.Segment([]byte(content)) {
+			word := seg.Token().Text()
+			if strings.TrimSpace(word) == "" {
+				continue
+			}
+
+			if !containsCJK(word) {
+				addWord(word)
+			} else {
+				tokens = append(tokens, PositionedToken{Token: strings.ToLower(word), Position: pos})
+				pos++
+			}
+		}
+	} else {
+		var currentWord strings.Builder
+		for _, r := range content {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				currentWord.WriteRune(r)
+			} else if currentWord.Len() > 0 {
+				addWord(currentWord.String())
+				currentWord.Reset()
+			}
+		}
+		if currentWord.Len() > 0 {
+			addWord(currentWord.String())
+		}
+	}
+
+	filtered := make([]PositionedToken, 0, len(tokens))
+	for _, token := range tokens {
+		if !IsStopword(token.Token) {
+			filtered = append(filtered, token)
+		}
+	}
+	return filtered, pos
+}
+
 // tokenizeMixed handles content that may mix CJK and Latin text
 func tokenizeMixed(content string) []string {
 	var tokens []string
@@ -127,7 +193,17 @@ func tokenizeCode(content string) []string {
 // processIdentifier splits an identifier into tokens and applies normalization.
 // Handles camelCase, PascalCase, snake_case, and mixed identifiers.
 func processIdentifier(identifier string) []string {
+	tokens, _, _ := processIdentifierWithOffsets(identifier)
+	return tokens
+}
+
+// processIdentifierWithOffsets behaves like processIdentifier but also reports the
+// word offset of every token within the identifier and the number of word slots the
+// identifier occupies. The full compound form shares offset 0 with the first part,
+// so "findQueryToken" yields findquerytoken@0, find@0, queri@1, token@2 (width 3).
+func processIdentifierWithOffsets(identifier string) ([]string, []uint, uint) {
 	var result []string
+	var offsets []uint
 
 	// First, if the identifier itself is a valid token (and not just a mash of words),
 	// add it to the result. This enables fuzzy matching against the full identifier
@@ -145,12 +221,15 @@ func processIdentifier(identifier string) []string {
 			} else {
 				result = append(result, lowerID)
 			}
+			offsets = append(offsets, 0)
 		}
 	}
 
 	// First, split by underscores (snake_case)
 	parts := strings.Split(identifier, "_")
 
+	// offset counts every word slot, including skipped ones, so gaps are preserved
+	offset := uint(0)
 	for _, part := range parts {
 		if part == "" {
 			continue
@@ -160,6 +239,9 @@ func processIdentifier(identifier string) []string {
 		camelParts := camelcase.Split(part)
 
 		for _, word := range camelParts {
+			wordOffset := offset
+			offset++
+
 			// Normalize: lowercase
 			token := strings.ToLower(word)
 
@@ -189,10 +271,15 @@ func processIdentifier(identifier string) []string {
 				// Fall back to original token if stemming produces invalid result
 				result = append(result, token)
 			}
+			offsets = append(offsets, wordOffset)
 		}
 	}
 
-	return result
+	if offset == 0 && len(result) > 0 {
+		offset = 1
+	}
+
+	return result, offsets, offset
 }
 
 // isNumeric checks if a string consists only of digits
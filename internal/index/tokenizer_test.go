@@ -306,3 +306,64 @@ func TestFilterStopwords(t *testing.T) {
 		}
 	}
 }
+
+func TestTokenizeContentWithPositions(t *testing.T) {
+	t.Run("tokens match TokenizeContent", func(t *testing.T) {
+		inputs := []string{
+			"func getUserProfile(user_id int) error",
+			"The quick brown fox jumps over the lazy dog",
+			"parse HTTPServer config_file",
+		}
+		for _, input := range inputs {
+			positioned, _ := TokenizeContentWithPositions(input, 0)
+			plain := TokenizeContent(input)
+
+			if len(positioned) != len(plain) {
+				t.Fatalf("TokenizeContentWithPositions(%q) returned %d tokens, TokenizeContent returned %d", input, len(positioned), len(plain))
+			}
+			for i := range plain {
+				if positioned[i].Token != plain[i] {
+					t.Errorf("Token mismatch at %d for %q: positioned=%q, plain=%q", i, input, positioned[i].Token, plain[i])
+				}
+			}
+		}
+	})
+
+	t.Run("identifier parts get consecutive positions", func(t *testing.T) {
+		tokens, next := TokenizeContentWithPositions("findQueryToken results", 10)
+
+		positions := make(map[string]uint)
+		for _, token := range tokens {
+			positions[token.Token] = token.Position
+		}
+
+		if positions["find"] != 10 || positions["queri"] != 11 || positions["token"] != 12 {
+			t.Errorf("Unexpected identifier part positions: %v", positions)
+		}
+		if positions["result"] != 13 {
+			t.Errorf("Expected 'result' at position 13, got %d", positions["result"])
+		}
+		if next != 14 {
+			t.Errorf("Expected next position 14, got %d", next)
+		}
+	})
+
+	t.Run("stopwords keep their slot", func(t *testing.T) {
+		tokens, next := TokenizeContentWithPositions("error in handling", 0)
+
+		positions := make(map[string]uint)
+		for _, token := range tokens {
+			positions[token.Token] = token.Position
+		}
+
+		if _, ok := positions["in"]; ok {
+			t.Errorf("Expected stopword 'in' to be removed, got %v", tokens)
+		}
+		if positions["error"] != 0 || positions["handl"] != 2 {
+			t.Errorf("Unexpected positions around stopword: %v", positions)
+		}
+		if next != 3 {
+			t.Errorf("Expected next position 3, got %d", next)
+		}
+	})
+}
@@ -1,29 +1,47 @@
 package ingest
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"mneme/internal/core"
+	"mneme/internal/extract"
 	"mneme/internal/storage"
+	"os"
+	"sync/atomic"
 )
 
 // FilesystemIngestor implements the Ingestor interface for local filesystem sources.
-// It wraps the existing storage.Crawler and storage.ReadFileContents functions.
+// It wraps the existing storage.Crawler and reads files with an extract.Registry.
 type FilesystemIngestor struct {
 	// paths are the root paths to crawl
 	paths []string
 
 	// config holds the source-specific configuration
 	config *core.FilesystemSourceConfig
+
+	// extractors extract the text of files by their format
+	extractors *extract.Registry
+
+	// archives reads the archive members of the batch being indexed, if any
+	archives atomic.Pointer[storage.ArchiveBatch]
 }
 
 // NewFilesystemIngestor creates a new filesystem ingestor with the given paths and config.
+// Files are read with the extractors of extract.DefaultRegistry.
 func NewFilesystemIngestor(paths []string, config *core.FilesystemSourceConfig) *FilesystemIngestor {
 	return &FilesystemIngestor{
-		paths:  paths,
-		config: config,
+		paths:      paths,
+		config:     config,
+		extractors: extract.DefaultRegistry(),
 	}
 }
 
+// SetExtractors replaces the registry used to extract the text of files.
+func (f *FilesystemIngestor) SetExtractors(extractors *extract.Registry) {
+	f.extractors = extractors
+}
+
 // Name returns "filesystem" as the source identifier.
 func (f *FilesystemIngestor) Name() string {
 	return "filesystem"
@@ -50,18 +68,79 @@ func (f *FilesystemIngestor) Crawl(options *core.CrawlerOptions) ([]string, erro
 	return allFiles, nil
 }
 
-// Read uses storage.ReadFileContents to read a file and wraps it in a Document.
+// Read extracts the text of a file with the extractor registered for its format
+// and wraps it in a Document. Files without an extractor are read as plain text.
 func (f *FilesystemIngestor) Read(id string) (*Document, error) {
-	contents, err := storage.ReadFileContents(id)
+	info, err := f.Stat(id)
 	if err != nil {
 		return nil, err
 	}
 
+	var text *extract.Text
+	if batch := f.archives.Load(); batch != nil && isArchiveMember(id) {
+		data, err := batch.ReadMember(id)
+		if err != nil {
+			return nil, err
+		}
+		text, err = f.extractors.Extract(id, data)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		text, err = f.extractors.ReadFile(id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return &Document{
 		ID:       id,
 		Path:     id,
-		Contents: contents,
+		Contents: text.Lines,
 		Source:   f.Name(),
+		ModTime:  info.ModTime,
+		Size:     info.Size,
+		Title:    text.Title,
+		Headings: text.Headings,
+		Tags:     text.Tags,
+		Date:     text.Date,
+	}, nil
+}
+
+// StartBatch reads the archive members among ids in a single pass per archive
+// as they are requested by Read, until the returned function is called.
+func (f *FilesystemIngestor) StartBatch(ids []string) func() {
+	batch := storage.NewArchiveBatch(ids, f.extractors.ArchiveMaxSize())
+	if batch == nil {
+		return func() {}
+	}
+	f.archives.Store(batch)
+	return func() {
+		f.archives.CompareAndSwap(batch, nil)
+	}
+}
+
+// isArchiveMember reports whether a document ID is the virtual path of an archive member
+func isArchiveMember(id string) bool {
+	_, _, ok := storage.SplitArchivePath(id)
+	return ok
+}
+
+// Stat returns the modification time and size of a file without reading it.
+// Members of archives report those of their archive file, so that they are
+// read again whenever the archive changes.
+func (f *FilesystemIngestor) Stat(id string) (*DocumentInfo, error) {
+	info, err := os.Stat(storage.ArchivePath(id))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, id)
+		}
+		return nil, err
+	}
+
+	return &DocumentInfo{
+		ModTime: info.ModTime(),
+		Size:    info.Size(),
 	}, nil
 }
 
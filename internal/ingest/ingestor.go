@@ -3,7 +3,10 @@
 // to provide documents for indexing.
 package ingest
 
-import "mneme/internal/core"
+import (
+	"mneme/internal/core"
+	"time"
+)
 
 // Document represents a document from any source that can be indexed.
 type Document struct {
@@ -18,6 +21,32 @@ type Document struct {
 
 	// Source identifies which ingestor provided this document
 	Source string
+
+	// ModTime is the last modification time of the document, if known
+	ModTime time.Time
+
+	// Size is the size of the document in bytes, if known
+	Size int64
+
+	// Title is the title of the document, such as the HTML <title>, if any
+	Title string
+
+	// Headings are the section headings of the document, if any
+	Headings []string
+
+	// Tags are the tags of the document, such as front matter tags, if any
+	Tags []string
+
+	// Date is the date the document declares, such as its front matter date,
+	// if known. It takes precedence over ModTime for recency.
+	Date time.Time
+}
+
+// DocumentInfo holds the metadata used to detect whether a document changed
+// since it was last indexed.
+type DocumentInfo struct {
+	ModTime time.Time
+	Size    int64
 }
 
 // Ingestor defines the interface that all document sources must implement.
@@ -37,3 +66,19 @@ type Ingestor interface {
 	// IsEnabled checks if this ingestor is enabled in the configuration.
 	IsEnabled() bool
 }
+
+// Stater is implemented by ingestors that can report document metadata without
+// reading the contents. Incremental indexing uses it to skip unchanged documents.
+type Stater interface {
+	// Stat returns the metadata of a single document by its ID.
+	Stat(id string) (*DocumentInfo, error)
+}
+
+// BatchReader is implemented by ingestors that read a batch of documents faster
+// when they know it in advance, such as the members of an archive, which are
+// read in a single pass over the archive.
+type BatchReader interface {
+	// StartBatch prepares reading the documents ids and returns a function that
+	// releases what was prepared once the batch has been read.
+	StartBatch(ids []string) func()
+}
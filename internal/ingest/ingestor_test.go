@@ -1,10 +1,14 @@
 package ingest
 
 import (
+	"archive/zip"
+	"bytes"
 	"errors"
 	"mneme/internal/core"
+	"mneme/internal/extract"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -101,6 +105,73 @@ func TestFilesystemIngestor_Read(t *testing.T) {
 	}
 }
 
+// upperExtractor is a test extractor that reads files as upper-case text
+type upperExtractor struct{}
+
+func (upperExtractor) Name() string { return "UPPER" }
+
+func (upperExtractor) Extract(data []byte) (*extract.Text, error) {
+	return &extract.Text{Lines: strings.Split(strings.ToUpper(string(data)), "\n")}, nil
+}
+
+func TestFilesystemIngestor_ReadWithExtractors(t *testing.T) {
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "notes.upper")
+	if err := os.WriteFile(testFile, []byte("first\nsecond"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	extractors := extract.NewRegistry()
+	extractors.Register(upperExtractor{}, "upper")
+
+	ingestor := NewFilesystemIngestor([]string{tmpDir}, nil)
+	ingestor.SetExtractors(extractors)
+	doc, err := ingestor.Read(testFile)
+	if err != nil {
+		t.Fatalf("Read error: %v", err)
+	}
+
+	if len(doc.Contents) != 2 || doc.Contents[0] != "FIRST" || doc.Contents[1] != "SECOND" {
+		t.Errorf("Expected extracted contents [FIRST SECOND], got %q", doc.Contents)
+	}
+}
+
+func TestFilesystemIngestor_StartBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	archive := filepath.Join(tmpDir, "backup.zip")
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range map[string]string{"a.txt": "first", "b.txt": "second"} {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(archive, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := NewRegistry()
+	registry.Register(NewFilesystemIngestor([]string{tmpDir}, nil))
+	ids := []string{archive + "!/a.txt", archive + "!/b.txt"}
+	release := registry.StartBatch(ids)
+	defer release()
+
+	for i, expected := range []string{"first", "second"} {
+		doc, err := registry.ReadDocument(ids[i])
+		if err != nil {
+			t.Fatalf("ReadDocument(%q) error: %v", ids[i], err)
+		}
+		if len(doc.Contents) != 1 || doc.Contents[0] != expected {
+			t.Errorf("ReadDocument(%q) contents = %q, expected [%s]", ids[i], doc.Contents, expected)
+		}
+	}
+}
+
 func TestRegistry_Register(t *testing.T) {
 	registry := NewRegistry()
 
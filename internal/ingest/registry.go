@@ -76,6 +76,43 @@ func (r *Registry) ReadDocument(id string) (*Document, error) {
 	return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, id)
 }
 
+// StatDocument returns the change-detection metadata of a document from the first
+// enabled ingestor that implements Stater and knows the document.
+func (r *Registry) StatDocument(id string) (*DocumentInfo, error) {
+	for _, ing := range r.GetEnabledIngestors() {
+		stater, ok := ing.(Stater)
+		if !ok {
+			continue
+		}
+
+		info, err := stater.Stat(id)
+		if err == nil {
+			return info, nil
+		}
+
+		if !errors.Is(err, ErrDocumentNotFound) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrDocumentNotFound, id)
+}
+
+// StartBatch prepares every enabled ingestor implementing BatchReader for reading
+// the documents ids and returns a function releasing them once the batch is read.
+func (r *Registry) StartBatch(ids []string) func() {
+	var releases []func()
+	for _, ing := range r.GetEnabledIngestors() {
+		if batchReader, ok := ing.(BatchReader); ok {
+			releases = append(releases, batchReader.StartBatch(ids))
+		}
+	}
+	return func() {
+		for _, release := range releases {
+			release()
+		}
+	}
+}
+
 // GetIngestorForDocument returns the ingestor that can handle this document ID.
 // Currently returns the first enabled ingestor (filesystem).
 func (r *Registry) GetIngestorForDocument(id string) Ingestor {
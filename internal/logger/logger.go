@@ -22,6 +22,10 @@ var (
 	verboseEnabled bool
 	// Track current log level for error suppression logic
 	currentLogLevel zerolog.Level
+	// Destination of both loggers, stdout unless redirected with SetOutput
+	logOutput io.Writer = os.Stdout
+	// Track if logs are written as JSON instead of console output
+	jsonEnabled bool
 )
 
 // parseLogLevel converts a log level string to zerolog.Level
@@ -52,17 +56,6 @@ func parseLogLevel(level string) zerolog.Level {
 
 // Init initializes the global logger with CLI-optimized settings
 func Init(verbose bool, quiet bool, jsonOutput bool, logLevel string) {
-	var output io.Writer = os.Stdout
-
-	// Configure console writer for human-readable output
-	if !jsonOutput {
-		output = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
-			TimeFormat: time.RFC3339,
-			NoColor:    false,
-		}
-	}
-
 	// Set log level from config, defaulting to info if invalid/empty
 	currentLogLevel = parseLogLevel(logLevel)
 	zerolog.SetGlobalLevel(currentLogLevel)
@@ -80,6 +73,30 @@ func Init(verbose bool, quiet bool, jsonOutput bool, logLevel string) {
 		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
 	}
 
+	jsonEnabled = jsonOutput
+	createLoggers()
+}
+
+// SetOutput redirects all log output, e.g. to stderr when stdout carries
+// machine-readable command output. Log levels are left unchanged.
+func SetOutput(w io.Writer) {
+	logOutput = w
+	createLoggers()
+}
+
+// createLoggers creates the global and user loggers writing to logOutput
+func createLoggers() {
+	var output io.Writer = logOutput
+
+	// Configure console writer for human-readable output
+	if !jsonEnabled {
+		output = zerolog.ConsoleWriter{
+			Out:        logOutput,
+			TimeFormat: time.RFC3339,
+			NoColor:    false,
+		}
+	}
+
 	// Create logger with zero-allocation optimizations
 	logger := zerolog.New(output).
 		With().
@@ -89,10 +106,10 @@ func Init(verbose bool, quiet bool, jsonOutput bool, logLevel string) {
 	log = &Logger{logger}
 
 	// Create user-friendly logger without timestamps
-	var userOutput io.Writer = os.Stdout
-	if !jsonOutput {
+	var userOutput io.Writer = logOutput
+	if !jsonEnabled {
 		userOutput = zerolog.ConsoleWriter{
-			Out:        os.Stdout,
+			Out:        logOutput,
 			TimeFormat: "",
 			NoColor:    false,
 		}
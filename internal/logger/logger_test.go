@@ -53,6 +53,21 @@ func TestInit(t *testing.T) {
 	})
 }
 
+func TestSetOutput(t *testing.T) {
+	t.Cleanup(func() {
+		SetOutput(os.Stdout)
+		Init(false, false, false, "")
+	})
+
+	Init(false, false, true, "")
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	Info("redirected message")
+
+	assert.Contains(t, buf.String(), "redirected message")
+	assert.Equal(t, zerolog.InfoLevel, zerolog.GlobalLevel())
+}
+
 func TestGet(t *testing.T) {
 	t.Run("returns initialized logger", func(t *testing.T) {
 		log = nil
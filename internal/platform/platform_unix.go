@@ -5,6 +5,7 @@ package platform
 import (
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
 // GetDataDir returns the platform-appropriate data directory for Unix systems
@@ -45,3 +46,9 @@ func GetConfigDir() string {
 func GetConfigPath() string {
 	return filepath.Join(GetConfigDir(), "mneme.toml")
 }
+
+// DetachedProcAttr returns process attributes that start a child process in its
+// own session, so it keeps running after the launching terminal is closed
+func DetachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{Setsid: true}
+}
@@ -5,6 +5,7 @@ package platform
 import (
 	"os"
 	"path/filepath"
+	"syscall"
 )
 
 // GetDataDir returns the platform-appropriate data directory for Windows
@@ -41,3 +42,13 @@ func GetConfigDir() string {
 func GetConfigPath() string {
 	return filepath.Join(GetConfigDir(), "mneme.toml")
 }
+
+// detachedProcess is the DETACHED_PROCESS process creation flag
+const detachedProcess = 0x00000008
+
+// DetachedProcAttr returns process attributes that start a child process without
+// a console in its own process group, so it keeps running after the launching
+// console is closed
+func DetachedProcAttr() *syscall.SysProcAttr {
+	return &syscall.SysProcAttr{CreationFlags: detachedProcess | syscall.CREATE_NEW_PROCESS_GROUP}
+}
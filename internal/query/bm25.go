@@ -5,15 +5,16 @@ import (
 	"mneme/internal/core"
 )
 
-// BM25 parameters - standard values from literature
+// Default BM25 parameters - standard values from literature, used when
+// ranking.bm25_k1 or ranking.bm25_b are not set
 const (
-	// k1 controls term frequency saturation
+	// DefaultBM25K1 controls term frequency saturation
 	// Higher values give more weight to term frequency
-	k1 = 1.5
+	DefaultBM25K1 = 1.2
 
-	// b controls document length normalization
+	// DefaultBM25B controls document length normalization
 	// b=1 means full length normalization, b=0 means no normalization
-	b = 0.75
+	DefaultBM25B = 0.75
 )
 
 // calculateIDF calculates the Inverse Document Frequency for a term
@@ -33,13 +34,9 @@ func calculateIDF(df int, totalDocs int) float64 {
 
 // calculateTermBM25 calculates BM25 score for a single term in a document
 // Formula: IDF × (tf × (k1 + 1)) / (tf + k1 × (1 - b + b × (docLen / avgDocLen)))
-func calculateTermBM25(tf float64, idf float64, docLen float64, avgDocLen float64) float64 {
-	if avgDocLen <= 0 {
-		avgDocLen = 1 // Prevent division by zero
-	}
-
+func calculateTermBM25(tf float64, idf float64, docLen float64, avgDocLen float64, k1 float64, b float64) float64 {
 	// Length normalization factor
-	lengthNorm := 1 - b + b*(docLen/avgDocLen)
+	lengthNorm := lengthNormalization(docLen, avgDocLen, b)
 
 	// BM25 term score
 	numerator := tf * (k1 + 1)
@@ -48,78 +45,40 @@ func calculateTermBM25(tf float64, idf float64, docLen float64, avgDocLen float6
 	return idf * (numerator / denominator)
 }
 
+// lengthNormalization returns the BM25 length normalization factor of a document
+// Formula: 1 - b + b × (docLen / avgDocLen)
+func lengthNormalization(docLen float64, avgDocLen float64, b float64) float64 {
+	if avgDocLen <= 0 {
+		avgDocLen = 1 // Prevent division by zero
+	}
+	return 1 - b + b*(docLen/avgDocLen)
+}
+
 // CalculateBM25Scores computes BM25 relevance scores for all documents
-// against the given query tokens
+// against the given query tokens. Deleted documents are skipped.
 func CalculateBM25Scores(segment *core.Segment, tokens []string) map[uint]float64 {
-	scores := make(map[uint]float64)
-
 	if segment == nil || len(tokens) == 0 {
-		return scores
-	}
-
-	totalDocs := int(segment.TotalDocs)
-	avgDocLen := float64(segment.AvgDocLen)
-
-	// Handle empty index
-	if totalDocs == 0 {
-		return scores
+		return make(map[uint]float64)
 	}
 
-	// If avgDocLen is 0, calculate it from documents
-	if avgDocLen == 0 && len(segment.Docs) > 0 {
-		var totalTokens uint
-		for _, doc := range segment.Docs {
-			totalTokens += doc.TokenCount
-		}
-		avgDocLen = float64(totalTokens) / float64(len(segment.Docs))
-		if avgDocLen == 0 {
-			avgDocLen = 1
-		}
-	}
-
-	// Build document length map for quick lookup
-	docLengths := make(map[uint]float64)
-	for _, doc := range segment.Docs {
-		docLengths[doc.ID] = float64(doc.TokenCount)
-	}
-
-	// Process each query token
-	for _, token := range tokens {
-		postings, exists := segment.InvertedIndex[token]
-		if !exists {
-			continue
-		}
-
-		// Calculate IDF for this term
-		df := len(postings)
-		idf := calculateIDF(df, totalDocs)
-
-		// Score each document containing this term
-		for _, posting := range postings {
-			tf := float64(posting.Freq)
-			docLen := docLengths[posting.DocID]
-
-			// Accumulate BM25 score for this term
-			termScore := calculateTermBM25(tf, idf, docLen, avgDocLen)
-			scores[posting.DocID] += termScore
-		}
-	}
+	index := core.NewIndexReader(segment)
+	return CalculateSegmentBM25Scores(index.Segments[0], tokens, NewCorpusStats(index, tokens))
+}
 
-	return scores
+// CalculateSegmentBM25Scores computes BM25 relevance scores for the documents of a
+// single segment with the default parameters, using the corpus statistics of the
+// whole index for IDF and length normalization. Deleted documents are skipped.
+func CalculateSegmentBM25Scores(segment core.SegmentReader, tokens []string, stats *CorpusStats) map[uint]float64 {
+	return NewBM25(DefaultBM25K1, DefaultBM25B).Score(segment, tokens, stats)
 }
 
-// GetDocumentFrequency returns how many documents contain a given term
+// GetDocumentFrequency returns how many live documents contain a given term
 func GetDocumentFrequency(segment *core.Segment, token string) int {
 	if segment == nil {
 		return 0
 	}
 
-	postings, exists := segment.InvertedIndex[token]
-	if !exists {
-		return 0
-	}
-
-	return len(postings)
+	return segment.LiveDocFrequency(token)
 }
 
 // GetTermFrequencyInDoc returns the frequency of a term in a specific document
@@ -111,7 +111,7 @@ func TestCalculateTermBM25(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := calculateTermBM25(tt.tf, tt.idf, tt.docLen, tt.avgDocLen)
+			result := calculateTermBM25(tt.tf, tt.idf, tt.docLen, tt.avgDocLen, DefaultBM25K1, DefaultBM25B)
 			if tt.wantZero && result != 0 {
 				t.Errorf("calculateTermBM25() = %v, expected 0", result)
 			}
@@ -123,8 +123,8 @@ func TestCalculateTermBM25(t *testing.T) {
 
 	// Test that shorter docs get higher scores (all else equal)
 	t.Run("shorter docs score higher", func(t *testing.T) {
-		shortDocScore := calculateTermBM25(5.0, 2.0, 50, 100)
-		longDocScore := calculateTermBM25(5.0, 2.0, 200, 100)
+		shortDocScore := calculateTermBM25(5.0, 2.0, 50, 100, DefaultBM25K1, DefaultBM25B)
+		longDocScore := calculateTermBM25(5.0, 2.0, 200, 100, DefaultBM25K1, DefaultBM25B)
 		if shortDocScore <= longDocScore {
 			t.Errorf("Short doc score (%v) should be higher than long doc score (%v)",
 				shortDocScore, longDocScore)
@@ -271,3 +271,42 @@ func createTestSegment() *core.Segment {
 		AvgDocLen:   75,
 	}
 }
+
+func TestDeletedDocumentsAreIgnored(t *testing.T) {
+	segment := createTestSegment()
+	segment.Deleted = core.NewDeletionBitmap(2)
+	segment.TotalDocs = 2
+
+	t.Run("bm25", func(t *testing.T) {
+		scores := CalculateBM25Scores(segment, []string{"user", "config"})
+		if _, ok := scores[2]; ok {
+			t.Error("Deleted document was scored")
+		}
+		if len(scores) != 2 {
+			t.Errorf("Expected scores for docs 1 and 3, got %v", scores)
+		}
+	})
+
+	t.Run("vsm", func(t *testing.T) {
+		scores := CalculateVSMScores(segment, []string{"user", "config"})
+		if _, ok := scores[2]; ok {
+			t.Error("Deleted document was scored")
+		}
+	})
+
+	t.Run("document frequency", func(t *testing.T) {
+		if df := GetDocumentFrequency(segment, "data"); df != 2 {
+			t.Errorf("Expected 2 live docs containing data, got %d", df)
+		}
+	})
+
+	t.Run("vocabulary", func(t *testing.T) {
+		segment := createTestSegment()
+		segment.Deleted = core.NewDeletionBitmap(1)
+		for _, term := range GetVocabulary(segment) {
+			if term == "profil" {
+				t.Error("Term only found in deleted documents is in the vocabulary")
+			}
+		}
+	})
+}
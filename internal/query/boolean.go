@@ -0,0 +1,625 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"mneme/internal/core"
+	"mneme/internal/index"
+)
+
+// ErrInvalidQuery is returned when a query string cannot be parsed
+var ErrInvalidQuery = errors.New("invalid query")
+
+// Boolean operators. They are only recognized in upper case, so "and", "or"
+// and "not" remain ordinary (stop)words.
+const (
+	operatorAnd = "AND"
+	operatorOr  = "OR"
+	operatorNot = "NOT"
+)
+
+// Node is a node of a parsed query
+type Node interface {
+	// match returns the live documents of a segment matching the node
+	match(segment core.SegmentReader) docSet
+	String() string
+}
+
+// TermNode matches documents containing any token of a query word
+type TermNode struct {
+	Text   string   // The word as typed
+	Tokens []string // Stemmed tokens of the word
+}
+
+// PhraseNode matches documents containing a phrase
+type PhraseNode struct {
+	Phrase Phrase
+}
+
+// BooleanNode combines clauses. A document matches if it matches every Must
+// clause, at least one Should clause when there are no Must clauses, and no
+// MustNot clause. Should clauses next to Must clauses only contribute to scoring.
+// Filter clauses are required like Must clauses, but do not make Should clauses
+// optional, so "kubernetes helm ext:md" still needs one of the words.
+type BooleanNode struct {
+	Must    []Node
+	Should  []Node
+	MustNot []Node
+	Filter  []Node
+}
+
+// Query is a parsed search query
+type Query struct {
+	Root      Node
+	operators bool // The query uses operators, prefixes or grouping
+}
+
+// docSet is a set of document IDs
+type docSet map[uint]struct{}
+
+// ParseBooleanQuery parses a query string into a query tree. The grammar is
+//
+//	query   = or
+//	or      = and { "OR" and }
+//	and     = clauses { "AND" clauses }
+//	clauses = unary { unary }
+//	unary   = "NOT" unary | "+" primary | "-" primary | primary
+//	primary = filter | word | "\"" phrase "\"" | "(" query ")"
+//	filter  = field ":" value | field ":" "\"" value "\""
+//
+// Adjacent clauses without an operator are optional, as in a plain query, with
+// "+" marking a clause as required and "-" or NOT excluding it. Field filters
+// (see ParseFieldFilter) and groups of filters are always required unless
+// excluded. Words that reduce to no tokens (stopwords, single characters) are
+// dropped.
+func ParseBooleanQuery(text string) (*Query, error) {
+	p := &queryParser{tokens: lexQuery(text)}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, p.tokens[p.pos].text)
+	}
+
+	return &Query{Root: root, operators: p.operators}, nil
+}
+
+// QueryTextFromArgs joins command line arguments into a query string. The shell
+// strips quotes, so an argument containing whitespace but no operators or literal
+// quotes is quoted again to keep it a phrase.
+func QueryTextFromArgs(args []string) string {
+	parts := make([]string, 0, len(args))
+	for _, arg := range args {
+		if !strings.Contains(arg, `"`) && len(strings.Fields(arg)) > 1 && !hasQuerySyntax(arg) {
+			arg = `"` + arg + `"`
+		}
+		parts = append(parts, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// HasOperators reports whether the query uses boolean operators, required or
+// excluded clauses, grouping or field filters. Queries without them are plain
+// term lists.
+func (q *Query) HasOperators() bool {
+	return q.operators
+}
+
+// Terms returns the tokens of all words and phrases that are not excluded,
+// which are the tokens used for scoring
+func (q *Query) Terms() []string {
+	var terms []string
+	seen := make(map[string]bool)
+	walkPositive(q.Root, func(node Node) {
+		var tokens []string
+		switch n := node.(type) {
+		case *TermNode:
+			tokens = n.Tokens
+		case *PhraseNode:
+			tokens = index.TokenizeQuery(n.Phrase.Text)
+		}
+		for _, token := range tokens {
+			if !seen[token] {
+				seen[token] = true
+				terms = append(terms, token)
+			}
+		}
+	})
+	return terms
+}
+
+// Phrases returns the phrases that are not excluded
+func (q *Query) Phrases() []Phrase {
+	var phrases []Phrase
+	walkPositive(q.Root, func(node Node) {
+		if n, ok := node.(*PhraseNode); ok {
+			phrases = append(phrases, n.Phrase)
+		}
+	})
+	return phrases
+}
+
+// HighlightTerms returns the words and phrase texts that are not excluded, as
+// typed, for snippet highlighting
+func (q *Query) HighlightTerms() []string {
+	var terms []string
+	walkPositive(q.Root, func(node Node) {
+		switch n := node.(type) {
+		case *TermNode:
+			terms = append(terms, n.Text)
+		case *PhraseNode:
+			terms = append(terms, n.Phrase.Text)
+		}
+	})
+	return terms
+}
+
+// AutoCorrect corrects typos in the words of the query that are not excluded,
+// using the vocabulary of the index. Excluded words are left as typed so that a
+// correction never excludes unrelated documents. It returns the corrections made.
+func (q *Query) AutoCorrect(vocabulary []string) map[string]string {
+	corrections := make(map[string]string)
+	walkPositive(q.Root, func(node Node) {
+		term, ok := node.(*TermNode)
+		if !ok {
+			return
+		}
+
+		corrected, changes := AutoCorrectQueryWithVocabulary(vocabulary, []string{term.Text})
+		if len(changes) == 0 {
+			return
+		}
+		for original, correction := range changes {
+			corrections[original] = correction
+		}
+		if tokens := index.TokenizeQuery(strings.Join(corrected, " ")); len(tokens) > 0 {
+			term.Text = strings.Join(corrected, " ")
+			term.Tokens = tokens
+		}
+	})
+	return corrections
+}
+
+// Match returns the live documents of a segment matching the query
+func (q *Query) Match(segment core.SegmentReader) docSet {
+	if q == nil || q.Root == nil {
+		return docSet{}
+	}
+	return q.Root.match(segment)
+}
+
+func (q *Query) String() string {
+	if q == nil || q.Root == nil {
+		return ""
+	}
+	return q.Root.String()
+}
+
+func (n *TermNode) match(segment core.SegmentReader) docSet {
+	docs := make(docSet)
+	for _, token := range n.Tokens {
+		for _, posting := range segment.Postings(token) {
+			if !segment.IsDeleted(posting.DocID) {
+				docs[posting.DocID] = struct{}{}
+			}
+		}
+	}
+	return docs
+}
+
+func (n *TermNode) String() string {
+	return n.Text
+}
+
+func (n *PhraseNode) match(segment core.SegmentReader) docSet {
+	docs := make(docSet)
+	for docID := range FindSegmentPhraseMatches(segment, n.Phrase) {
+		docs[docID] = struct{}{}
+	}
+	return docs
+}
+
+func (n *PhraseNode) String() string {
+	return `"` + n.Phrase.Text + `"`
+}
+
+func (n *BooleanNode) match(segment core.SegmentReader) docSet {
+	var docs docSet
+	switch {
+	case len(n.Must) == 0 && len(n.Should) == 0 && len(n.Filter) > 0:
+		docs = n.Filter[0].match(segment)
+	case len(n.Must) > 0:
+		docs = n.Must[0].match(segment)
+		for _, clause := range n.Must[1:] {
+			if len(docs) == 0 {
+				break
+			}
+			docs = intersect(docs, clause.match(segment))
+		}
+	case len(n.Should) > 0:
+		docs = make(docSet)
+		for _, clause := range n.Should {
+			for docID := range clause.match(segment) {
+				docs[docID] = struct{}{}
+			}
+		}
+	default:
+		// Only exclusions: every live document not excluded
+		docs = make(docSet)
+		for _, doc := range segment.Documents() {
+			docs[doc.ID] = struct{}{}
+		}
+	}
+
+	for _, clause := range n.Filter {
+		if len(docs) == 0 {
+			break
+		}
+		docs = intersect(docs, clause.match(segment))
+	}
+
+	for _, clause := range n.MustNot {
+		if len(docs) == 0 {
+			break
+		}
+		for docID := range clause.match(segment) {
+			delete(docs, docID)
+		}
+	}
+	return docs
+}
+
+func (n *BooleanNode) String() string {
+	parts := make([]string, 0, len(n.Must)+len(n.Should)+len(n.MustNot)+len(n.Filter))
+	for _, clause := range n.Filter {
+		parts = append(parts, "#"+clause.String())
+	}
+	for _, clause := range n.Must {
+		parts = append(parts, "+"+clause.String())
+	}
+	for _, clause := range n.Should {
+		parts = append(parts, clause.String())
+	}
+	for _, clause := range n.MustNot {
+		parts = append(parts, "-"+clause.String())
+	}
+	return "(" + strings.Join(parts, " ") + ")"
+}
+
+// intersect returns the documents contained in both sets
+func intersect(a, b docSet) docSet {
+	if len(b) < len(a) {
+		a, b = b, a
+	}
+	docs := make(docSet, len(a))
+	for docID := range a {
+		if _, ok := b[docID]; ok {
+			docs[docID] = struct{}{}
+		}
+	}
+	return docs
+}
+
+// walkPositive calls fn for every term and phrase node that is not excluded
+func walkPositive(node Node, fn func(node Node)) {
+	switch n := node.(type) {
+	case *BooleanNode:
+		for _, clause := range n.Must {
+			walkPositive(clause, fn)
+		}
+		for _, clause := range n.Should {
+			walkPositive(clause, fn)
+		}
+	case nil:
+	default:
+		fn(n)
+	}
+}
+
+// queryTokenKind is the kind of a lexical query token
+type queryTokenKind int
+
+const (
+	tokenWord queryTokenKind = iota
+	tokenPhrase
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenRequired
+	tokenExcluded
+	tokenOpen
+	tokenClose
+)
+
+type queryToken struct {
+	kind queryTokenKind
+	text string
+}
+
+// lexQuery splits a query string into tokens
+func lexQuery(text string) []queryToken {
+	var tokens []queryToken
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, queryToken{kind: tokenOpen, text: "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, queryToken{kind: tokenClose, text: ")"})
+			i++
+		case r == '"':
+			// An unterminated quote runs to the end
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			tokens = append(tokens, queryToken{kind: tokenPhrase, text: string(runes[i+1 : end])})
+			i = end + 1
+		case (r == '+' || r == '-') && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) && (i == 0 || isClauseStart(runes[i-1])):
+			kind := tokenRequired
+			if r == '-' {
+				kind = tokenExcluded
+			}
+			tokens = append(tokens, queryToken{kind: kind, text: string(r)})
+			i++
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && runes[end] != '(' && runes[end] != ')' && runes[end] != '"' {
+				end++
+			}
+			// A field filter may quote its value, e.g. dir:"~/My Notes"
+			if end < len(runes) && runes[end] == '"' && runes[end-1] == ':' && isFilterWord(string(runes[i:end])) {
+				closing := end + 1
+				for closing < len(runes) && runes[closing] != '"' {
+					closing++
+				}
+				tokens = append(tokens, queryToken{kind: tokenWord, text: string(runes[i:end]) + string(runes[end+1:min(closing, len(runes))])})
+				i = closing + 1
+				continue
+			}
+
+			word := string(runes[i:end])
+			i = end
+
+			switch word {
+			case operatorAnd:
+				tokens = append(tokens, queryToken{kind: tokenAnd, text: word})
+			case operatorOr:
+				tokens = append(tokens, queryToken{kind: tokenOr, text: word})
+			case operatorNot:
+				tokens = append(tokens, queryToken{kind: tokenNot, text: word})
+			default:
+				tokens = append(tokens, queryToken{kind: tokenWord, text: word})
+			}
+		}
+	}
+
+	return tokens
+}
+
+// isClauseStart reports whether a "+" or "-" after r starts a new clause
+func isClauseStart(r rune) bool {
+	return unicode.IsSpace(r) || r == '('
+}
+
+// hasQuerySyntax reports whether text uses operators, prefixes, grouping or
+// field filters
+func hasQuerySyntax(text string) bool {
+	for _, token := range lexQuery(text) {
+		if token.kind != tokenWord && token.kind != tokenPhrase || token.kind == tokenWord && isFilterWord(token.text) {
+			return true
+		}
+	}
+	return false
+}
+
+// queryParser is a recursive descent parser over query tokens
+type queryParser struct {
+	tokens    []queryToken
+	pos       int
+	operators bool
+}
+
+func (p *queryParser) peek() (queryToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return queryToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *queryParser) parseOr() (Node, error) {
+	var clauses []Node
+	for {
+		node, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		if node != nil {
+			clauses = append(clauses, node)
+		}
+
+		token, ok := p.peek()
+		if !ok || token.kind != tokenOr {
+			break
+		}
+		p.pos++
+		p.operators = true
+	}
+
+	if len(clauses) == 1 {
+		return clauses[0], nil
+	}
+	if len(clauses) == 0 {
+		return nil, nil
+	}
+	return &BooleanNode{Should: clauses}, nil
+}
+
+func (p *queryParser) parseAnd() (Node, error) {
+	result := &BooleanNode{}
+	for {
+		node, err := p.parseClauses()
+		if err != nil {
+			return nil, err
+		}
+
+		// Lift pure exclusions, so "a AND NOT b" excludes b from a
+		if b, ok := node.(*BooleanNode); ok && len(b.Must) == 0 && len(b.Should) == 0 && len(b.Filter) == 0 {
+			result.MustNot = append(result.MustNot, b.MustNot...)
+		} else if node != nil {
+			result.Must = append(result.Must, node)
+		}
+
+		token, ok := p.peek()
+		if !ok || token.kind != tokenAnd {
+			break
+		}
+		p.pos++
+		p.operators = true
+	}
+
+	if len(result.Must) == 1 && len(result.MustNot) == 0 {
+		return result.Must[0], nil
+	}
+	if len(result.Must) == 0 && len(result.MustNot) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+func (p *queryParser) parseClauses() (Node, error) {
+	result := &BooleanNode{}
+	count := 0
+	for {
+		token, ok := p.peek()
+		if !ok || token.kind == tokenAnd || token.kind == tokenOr || token.kind == tokenClose {
+			break
+		}
+
+		node, occur, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		count++
+		if node == nil {
+			continue
+		}
+
+		switch {
+		case occur != tokenExcluded && isFilter(node):
+			result.Filter = append(result.Filter, node)
+		case occur == tokenRequired:
+			result.Must = append(result.Must, node)
+		case occur == tokenExcluded:
+			result.MustNot = append(result.MustNot, node)
+		default:
+			result.Should = append(result.Should, node)
+		}
+	}
+
+	if count == 0 {
+		token, ok := p.peek()
+		if !ok {
+			return nil, fmt.Errorf("%w: missing term", ErrInvalidQuery)
+		}
+		return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, token.text)
+	}
+
+	if len(result.Must) == 0 && len(result.MustNot) == 0 && len(result.Filter) == 0 && len(result.Should) == 1 {
+		return result.Should[0], nil
+	}
+	if len(result.Must) == 0 && len(result.MustNot) == 0 && len(result.Should) == 0 && len(result.Filter) == 1 {
+		return result.Filter[0], nil
+	}
+	if len(result.Must) == 0 && len(result.MustNot) == 0 && len(result.Should) == 0 && len(result.Filter) == 0 {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// parseUnary parses a clause with its prefix. The returned kind is
+// tokenRequired, tokenExcluded or tokenWord for an optional clause. The node
+// is nil when the clause has no tokens.
+func (p *queryParser) parseUnary() (Node, queryTokenKind, error) {
+	token, _ := p.peek()
+	switch token.kind {
+	case tokenNot, tokenExcluded:
+		p.pos++
+		p.operators = true
+		if token.kind == tokenExcluded {
+			node, err := p.parsePrimary()
+			return node, tokenExcluded, err
+		}
+		node, occur, err := p.parseUnary()
+		if occur == tokenExcluded {
+			// NOT of an exclusion is a requirement
+			return node, tokenRequired, err
+		}
+		return node, tokenExcluded, err
+	case tokenRequired:
+		p.pos++
+		p.operators = true
+		node, err := p.parsePrimary()
+		return node, tokenRequired, err
+	}
+
+	node, err := p.parsePrimary()
+	return node, tokenWord, err
+}
+
+func (p *queryParser) parsePrimary() (Node, error) {
+	token, ok := p.peek()
+	if !ok {
+		return nil, fmt.Errorf("%w: missing term", ErrInvalidQuery)
+	}
+
+	switch token.kind {
+	case tokenWord:
+		p.pos++
+		if filter, ok, err := ParseFieldFilter(token.text); err != nil {
+			return nil, err
+		} else if ok {
+			p.operators = true
+			return filter, nil
+		}
+		tokens := index.TokenizeQuery(token.text)
+		if len(tokens) == 0 {
+			return nil, nil
+		}
+		return &TermNode{Text: token.text, Tokens: tokens}, nil
+	case tokenPhrase:
+		p.pos++
+		if phrase, ok := ParsePhrase(token.text); ok {
+			return &PhraseNode{Phrase: phrase}, nil
+		}
+		// A phrase of a single word is a plain word
+		tokens := index.TokenizeQuery(token.text)
+		if len(tokens) == 0 {
+			return nil, nil
+		}
+		return &TermNode{Text: strings.TrimSpace(token.text), Tokens: tokens}, nil
+	case tokenOpen:
+		p.pos++
+		p.operators = true
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing, ok := p.peek(); !ok || closing.kind != tokenClose {
+			return nil, fmt.Errorf("%w: missing closing parenthesis", ErrInvalidQuery)
+		}
+		p.pos++
+		return node, nil
+	}
+
+	return nil, fmt.Errorf("%w: unexpected %q", ErrInvalidQuery, token.text)
+}
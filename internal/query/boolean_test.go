@@ -0,0 +1,186 @@
+package query
+
+import (
+	"errors"
+	"mneme/internal/core"
+	"sort"
+	"testing"
+)
+
+func TestParseBooleanQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		expected  string
+		operators bool
+	}{
+		{name: "plain terms", query: "kubernetes helm", expected: "(kubernetes helm)"},
+		{name: "single term", query: "kubernetes", expected: "kubernetes"},
+		{name: "phrase", query: `"aws region" deploy`, expected: `("aws region" deploy)`},
+		{name: "excluded term", query: "kubernetes -helm", expected: "(kubernetes -helm)", operators: true},
+		{name: "required term", query: "+deploy production", expected: "(+deploy production)", operators: true},
+		{name: "hyphenated word", query: "e-mail", expected: "e-mail"},
+		{name: "and", query: "postgres AND migration", expected: "(+postgres +migration)", operators: true},
+		{name: "or", query: "postgres OR mysql", expected: "(postgres mysql)", operators: true},
+		{name: "grouping", query: "(postgres OR mysql) AND migration", expected: "(+(postgres mysql) +migration)", operators: true},
+		{name: "and binds tighter than or", query: "a1 AND b1 OR c1", expected: "((+a1 +b1) c1)", operators: true},
+		{name: "and not", query: "postgres AND NOT mysql", expected: "(+postgres -mysql)", operators: true},
+		{name: "not", query: "kubernetes NOT helm", expected: "(kubernetes -helm)", operators: true},
+		{name: "excluded group", query: "deploy -(helm OR kustomize)", expected: "(deploy -(helm kustomize))", operators: true},
+		{name: "excluded phrase", query: `deploy -"helm chart"`, expected: `(deploy -"helm chart")`, operators: true},
+		{name: "lower case operators are words", query: "salt and pepper", expected: "(salt pepper)"},
+		{name: "stopwords are dropped", query: "+func deploy", expected: "deploy", operators: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := ParseBooleanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseBooleanQuery(%q) failed: %v", tt.query, err)
+			}
+			if got := q.String(); got != tt.expected {
+				t.Errorf("ParseBooleanQuery(%q) = %s, expected %s", tt.query, got, tt.expected)
+			}
+			if q.HasOperators() != tt.operators {
+				t.Errorf("HasOperators() = %v, expected %v", q.HasOperators(), tt.operators)
+			}
+		})
+	}
+
+	for _, invalid := range []string{"(postgres OR mysql", "postgres)", "postgres AND", "OR mysql", "()", "NOT"} {
+		t.Run("invalid "+invalid, func(t *testing.T) {
+			if _, err := ParseBooleanQuery(invalid); !errors.Is(err, ErrInvalidQuery) {
+				t.Errorf("ParseBooleanQuery(%q) error = %v, expected ErrInvalidQuery", invalid, err)
+			}
+		})
+	}
+}
+
+func TestQueryTextFromArgs(t *testing.T) {
+	tests := []struct {
+		args     []string
+		expected string
+	}{
+		{args: []string{"kubernetes", "-helm"}, expected: "kubernetes -helm"},
+		{args: []string{"aws region", "deploy"}, expected: `"aws region" deploy`},
+		{args: []string{"(postgres OR mysql) AND migration"}, expected: "(postgres OR mysql) AND migration"},
+		{args: []string{`say "hello world"`}, expected: `say "hello world"`},
+	}
+
+	for _, tt := range tests {
+		if got := QueryTextFromArgs(tt.args); got != tt.expected {
+			t.Errorf("QueryTextFromArgs(%q) = %q, expected %q", tt.args, got, tt.expected)
+		}
+	}
+}
+
+func createBooleanTestSegment() *core.Segment {
+	return &core.Segment{
+		Docs: []core.Document{
+			{ID: 1, Path: "k8s-helm.md", TokenCount: 4},
+			{ID: 2, Path: "k8s.md", TokenCount: 4},
+			{ID: 3, Path: "postgres-migration.md", TokenCount: 4},
+			{ID: 4, Path: "mysql-migration.md", TokenCount: 4},
+			{ID: 5, Path: "mongo-migration.md", TokenCount: 4},
+			{ID: 6, Path: "postgres.md", TokenCount: 4},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"kubernet":  {{DocID: 1, Freq: 1, Positions: []uint{0}}, {DocID: 2, Freq: 2, Positions: []uint{0, 2}}},
+			"helm":      {{DocID: 1, Freq: 1, Positions: []uint{1}}},
+			"postgr":    {{DocID: 3, Freq: 1, Positions: []uint{0}}, {DocID: 6, Freq: 1, Positions: []uint{0}}},
+			"mysql":     {{DocID: 4, Freq: 1, Positions: []uint{0}}},
+			"mongo":     {{DocID: 5, Freq: 1, Positions: []uint{0}}},
+			"migrat":    {{DocID: 3, Freq: 1, Positions: []uint{1}}, {DocID: 4, Freq: 1, Positions: []uint{1}}, {DocID: 5, Freq: 1, Positions: []uint{1}}},
+			"mongodump": {{DocID: 5, Freq: 1, Positions: []uint{3}}},
+		},
+		TotalDocs:   6,
+		TotalTokens: 24,
+		AvgDocLen:   4,
+	}
+}
+
+func TestQueryMatch(t *testing.T) {
+	reader := core.NewSegmentReader(createBooleanTestSegment())
+
+	tests := []struct {
+		query    string
+		expected []uint
+	}{
+		{query: "kubernetes -helm", expected: []uint{2}},
+		{query: "(postgres OR mysql) AND migration", expected: []uint{3, 4}},
+		{query: "+migration postgres", expected: []uint{3, 4, 5}},
+		{query: "migration -mongo -mysql", expected: []uint{3}},
+		{query: "migration AND NOT (postgres OR mysql)", expected: []uint{5}},
+		{query: "-migration", expected: []uint{1, 2, 6}},
+		{query: `"postgres migration"`, expected: []uint{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseBooleanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseBooleanQuery failed: %v", err)
+			}
+
+			var got []uint
+			for docID := range q.Match(reader) {
+				got = append(got, docID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Match() = %v, expected %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Match() = %v, expected %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestRankQuery(t *testing.T) {
+	index := core.NewIndexReader(createBooleanTestSegment())
+
+	t.Run("excluded terms filter results", func(t *testing.T) {
+		q, _ := ParseBooleanQuery("kubernetes -helm")
+		results := RankQuery(index, q, 10, nil)
+		if len(results) != 1 || results[0].Path != "k8s.md" {
+			t.Errorf("Expected only k8s.md, got %v", results)
+		}
+	})
+
+	t.Run("grouping", func(t *testing.T) {
+		q, _ := ParseBooleanQuery("(postgres OR mysql) AND migration")
+		results := RankQuery(index, q, 10, nil)
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %v", results)
+		}
+		for _, doc := range results {
+			if doc.Path != "postgres-migration.md" && doc.Path != "mysql-migration.md" {
+				t.Errorf("Unexpected result %s", doc.Path)
+			}
+		}
+	})
+
+	t.Run("excluded terms do not contribute to the score", func(t *testing.T) {
+		q, _ := ParseBooleanQuery("migration -mongo")
+		for _, term := range q.Terms() {
+			if term == "mongo" {
+				t.Error("Excluded term used for scoring")
+			}
+		}
+	})
+
+	t.Run("optional phrases boost without filtering", func(t *testing.T) {
+		q, _ := ParseBooleanQuery(`+migration "postgres migration"`)
+		results := RankQuery(index, q, 10, nil)
+		if len(results) != 3 {
+			t.Fatalf("Expected 3 results, got %v", results)
+		}
+		if results[0].Path != "postgres-migration.md" {
+			t.Errorf("Expected the phrase match first, got %s", results[0].Path)
+		}
+	})
+}
@@ -0,0 +1,168 @@
+package query
+
+import (
+	"math"
+	"time"
+
+	"mneme/internal/constants"
+	"mneme/internal/core"
+)
+
+// explainer builds the score explanations of ranked documents from the state
+// of a single ranking
+type explainer struct {
+	scorer  Scorer
+	stats   *CorpusStats
+	weights core.ExplanationWeights
+	b       float64 // Length normalization of the BM25 family
+
+	tokens       []string
+	fuzzyTerms   []string
+	fuzzyOrigins map[string]FuzzyMatch // Fuzzy terms and the query terms they were expanded from
+
+	maxExactRelevance float64
+	maxFuzzyRelevance float64
+	phraseScores      map[uint]float64
+	maxPhraseScore    float64
+
+	now      time.Time
+	halfLife time.Duration
+}
+
+// explainSegment sets the explanation of the ranked documents of a segment
+func (e *explainer) explainSegment(segment core.SegmentReader, s segmentScores, docs []core.RankedDocument) {
+	if len(docs) == 0 {
+		return
+	}
+
+	// Scores of every term on its own, shared by all documents of the segment
+	termScores := make(map[string]map[uint]float64, len(e.tokens)+len(e.fuzzyTerms))
+	for _, term := range append(e.tokens[:len(e.tokens):len(e.tokens)], e.fuzzyTerms...) {
+		if _, done := termScores[term]; !done {
+			termScores[term] = e.scorer.Score(segment, []string{term}, e.stats)
+		}
+	}
+
+	for i := range docs {
+		docs[i].Explanation = e.explainDocument(segment, s, docs[i].DocID, termScores)
+	}
+}
+
+// explainDocument explains the score of a single document
+func (e *explainer) explainDocument(segment core.SegmentReader, s segmentScores, docID uint, termScores map[string]map[uint]float64) *core.Explanation {
+	explanation := &core.Explanation{
+		Scorer:        e.scorer.Name(),
+		Weights:       e.weights,
+		RecencyFactor: 1,
+	}
+
+	doc, _ := segment.Document(docID)
+	exact := e.explainPass("exact", segment, doc, s.exactRelevance, s.exactVSM, e.maxExactRelevance, 1, e.tokens, termScores)
+	explanation.Passes = append(explanation.Passes, exact)
+	total := exact.Score
+	if len(e.fuzzyTerms) > 0 {
+		fuzzy := e.explainPass("fuzzy", segment, doc, s.fuzzyRelevance, s.fuzzyVSM, e.maxFuzzyRelevance, e.weights.FuzzyPenalty, e.fuzzyTerms, termScores)
+		explanation.Passes = append(explanation.Passes, fuzzy)
+		total += fuzzy.Score
+	}
+
+	if score, ok := e.phraseScores[docID]; ok && e.maxPhraseScore > 0 {
+		explanation.PhraseBoost = score / e.maxPhraseScore * PhraseBoostWeight
+		total += explanation.PhraseBoost
+	}
+
+	if e.halfLife > 0 {
+		explanation.RecencyFactor = RecencyFactor(doc.RecencyTime(), e.now, e.halfLife)
+	}
+	explanation.Score = total * explanation.RecencyFactor
+	return explanation
+}
+
+// explainPass explains the score of a document in the exact or fuzzy pass.
+// Fuzzy terms the document does not contain are left out.
+func (e *explainer) explainPass(pass string, segment core.SegmentReader, doc core.Document, relevanceScores, vsmScores map[uint]float64, maxRelevance, vsmPenalty float64, terms []string, termScores map[string]map[uint]float64) core.PassExplanation {
+	explanation := core.PassExplanation{
+		Pass:         pass,
+		Relevance:    relevanceScores[doc.ID],
+		MaxRelevance: maxRelevance,
+		VSM:          vsmScores[doc.ID],
+		Terms:        []core.TermExplanation{},
+	}
+	explanation.Score = combineScore(explanation.Relevance, maxRelevance, explanation.VSM*vsmPenalty, e.weights.Relevance, e.weights.VSM)
+
+	docLen := float64(doc.TokenCount)
+	for _, term := range terms {
+		posting, found := findPosting(segment, term, doc.ID)
+		if !found && pass == "fuzzy" {
+			continue
+		}
+
+		termExplanation := core.TermExplanation{
+			Term:       term,
+			TF:         posting.Freq,
+			DF:         e.stats.DocFreq[term],
+			IDF:        calculateIDF(e.stats.DocFreq[term], e.stats.TotalDocs),
+			DocLen:     docLen,
+			AvgDocLen:  e.stats.AvgDocLen,
+			LengthNorm: lengthNormalization(docLen, e.stats.AvgDocLen, e.b),
+			Score:      termScores[term][doc.ID],
+		}
+		for field := core.FieldFilename; field < core.NumFields; field++ {
+			if freq := posting.FieldFreq(field); freq > 0 {
+				if termExplanation.FieldTF == nil {
+					termExplanation.FieldTF = make(map[string]uint)
+				}
+				termExplanation.FieldTF[field.String()] = freq
+			}
+		}
+		if match, ok := e.fuzzyOrigins[term]; ok && pass == "fuzzy" {
+			termExplanation.ExpandedFrom = match.Original
+			termExplanation.Distance = match.Distance
+		}
+		explanation.Terms = append(explanation.Terms, termExplanation)
+	}
+	return explanation
+}
+
+// findPosting returns the posting of a term for a document of a segment
+func findPosting(segment core.SegmentReader, term string, docID uint) (core.Posting, bool) {
+	for _, posting := range segment.Postings(term) {
+		if posting.DocID == docID {
+			return posting, true
+		}
+	}
+	return core.Posting{}, false
+}
+
+// tieBreak returns the criterion of rankedBefore that ordered a document after
+// the previous one
+func tieBreak(previous, doc core.RankedDocument) string {
+	switch {
+	case math.Abs(previous.Score-doc.Score) > 1e-6:
+		return "score"
+	case previous.MatchCount != doc.MatchCount:
+		return "match_count"
+	default:
+		return "filename"
+	}
+}
+
+// setTieBreaks records the tie-break of every explained document
+func setTieBreaks(docs []core.RankedDocument) {
+	for i := 1; i < len(docs); i++ {
+		if docs[i].Explanation != nil {
+			docs[i].Explanation.TieBreak = tieBreak(docs[i-1], docs[i])
+		}
+	}
+}
+
+// explanationWeights returns the weights a ranking combines scores with
+func explanationWeights(bm25Weight, vsmWeight float64, halfLife time.Duration) core.ExplanationWeights {
+	return core.ExplanationWeights{
+		Relevance:           bm25Weight,
+		VSM:                 vsmWeight,
+		FuzzyPenalty:        constants.FuzzyScorePenalty,
+		PhraseBoost:         PhraseBoostWeight,
+		RecencyHalfLifeDays: int(halfLife / (24 * time.Hour)),
+	}
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"mneme/internal/core"
+)
+
+func TestRankIndexExplain(t *testing.T) {
+	segment := createTestSegment()
+	segment.Docs[0].ModTime = time.Now().Add(-48 * time.Hour).UnixNano()
+	index := core.NewIndexReader(segment)
+	cfg := &core.RankingConfig{RecencyHalfLifeDays: 30}
+
+	if results := RankIndex(index, []string{"config"}, nil, 10, cfg); results[0].Explanation != nil {
+		t.Error("Expected no explanation unless requested")
+	}
+
+	// "confi" has no exact match and is expanded to "config"
+	results := rankIndex(index, []string{"user", "confi"}, nil, nil, 10, cfg, true)
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %s", formatResults(results))
+	}
+
+	for i, doc := range results {
+		explanation := doc.Explanation
+		if explanation == nil {
+			t.Fatalf("Expected an explanation for document %d", doc.DocID)
+		}
+		if math.Abs(explanation.Score-doc.Score) > 1e-9 {
+			t.Errorf("Document %d: explained score %f, ranked score %f", doc.DocID, explanation.Score, doc.Score)
+		}
+		if explanation.Scorer != ScorerBM25F || explanation.Weights.Relevance != DefaultBM25Weight || explanation.Weights.RecencyHalfLifeDays != 30 {
+			t.Errorf("Unexpected scorer or weights: %+v", explanation)
+		}
+		if len(explanation.Passes) != 2 || explanation.Passes[0].Pass != "exact" || explanation.Passes[1].Pass != "fuzzy" {
+			t.Fatalf("Expected an exact and a fuzzy pass, got %+v", explanation.Passes)
+		}
+		if len(explanation.Passes[0].Terms) != 2 {
+			t.Errorf("Expected every query term in the exact pass, got %+v", explanation.Passes[0].Terms)
+		}
+		if (i == 0) != (explanation.TieBreak == "") {
+			t.Errorf("Result %d has tie-break %q", i, explanation.TieBreak)
+		}
+	}
+
+	doc1 := findRanked(results, 1)
+	if factor := doc1.Explanation.RecencyFactor; factor >= 1 || factor <= 1-RecencyWeight {
+		t.Errorf("Expected a recency factor for the modified document, got %f", factor)
+	}
+	user := doc1.Explanation.Passes[0].Terms[0]
+	if user.Term != "user" || user.TF != 3 || user.DF != 2 || user.DocLen != 50 || user.AvgDocLen != 75 {
+		t.Errorf("Unexpected term statistics %+v", user)
+	}
+	if math.Abs(user.IDF-calculateIDF(2, 3)) > 1e-9 || math.Abs(user.LengthNorm-lengthNormalization(50, 75, DefaultBM25B)) > 1e-9 {
+		t.Errorf("Unexpected idf or length normalization %+v", user)
+	}
+
+	doc2 := findRanked(results, 2)
+	fuzzy := doc2.Explanation.Passes[1].Terms
+	if len(fuzzy) != 1 || fuzzy[0].Term != "config" || fuzzy[0].ExpandedFrom != "confi" || fuzzy[0].Distance != 1 || fuzzy[0].TF != 5 {
+		t.Errorf("Expected the fuzzy expansion of confi, got %+v", fuzzy)
+	}
+}
+
+func TestTieBreak(t *testing.T) {
+	tests := []struct {
+		previous, doc core.RankedDocument
+		expected      string
+	}{
+		{core.RankedDocument{Score: 0.9}, core.RankedDocument{Score: 0.5}, "score"},
+		{core.RankedDocument{Score: 0.5, MatchCount: 3}, core.RankedDocument{Score: 0.5, MatchCount: 1}, "match_count"},
+		{core.RankedDocument{Score: 0.5, Path: "/a.md"}, core.RankedDocument{Score: 0.5, Path: "/b.md"}, "filename"},
+	}
+
+	for _, tt := range tests {
+		if got := tieBreak(tt.previous, tt.doc); got != tt.expected {
+			t.Errorf("tieBreak(%+v, %+v) = %q, expected %q", tt.previous, tt.doc, got, tt.expected)
+		}
+	}
+}
+
+// findRanked returns the ranked document with the given ID
+func findRanked(results []core.RankedDocument, docID uint) core.RankedDocument {
+	for _, doc := range results {
+		if doc.DocID == docID {
+			return doc
+		}
+	}
+	return core.RankedDocument{}
+}
@@ -0,0 +1,231 @@
+package query
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"mneme/internal/core"
+	"mneme/internal/utils"
+)
+
+// Field filter names, written as field:value in a query
+const (
+	FilterExtension = "ext"
+	FilterPath      = "path"
+	FilterDir       = "dir"
+	FilterSource    = "source"
+	FilterModified  = "modified"
+	FilterTitle     = "title"
+	FilterTag       = "tag"
+	FilterDate      = "date"
+)
+
+// dateLayouts are the accepted formats of modified: and date: values, from the
+// most to the least precise, with the precision of each
+var dateLayouts = []struct {
+	layout    string
+	precision time.Duration
+}{
+	{time.RFC3339, time.Second},
+	{"2006-01-02T15:04:05", time.Second},
+	{"2006-01-02T15:04", time.Minute},
+	{"2006-01-02", 24 * time.Hour},
+}
+
+// FilterNode matches documents by their metadata instead of their contents.
+// Filters restrict the candidate documents and never contribute to the score.
+type FilterNode struct {
+	Field   string
+	Value   string
+	matches func(doc core.Document) bool
+}
+
+// ParseFieldFilter parses a field:value query word. It returns false if the
+// word does not start with a known field, so that words like "std::vector"
+// remain ordinary words.
+func ParseFieldFilter(word string) (*FilterNode, bool, error) {
+	if !isFilterWord(word) {
+		return nil, false, nil
+	}
+	field, value, _ := strings.Cut(word, ":")
+	if value == "" {
+		return nil, true, fmt.Errorf("%w: missing value for %s:", ErrInvalidQuery, field)
+	}
+
+	node := &FilterNode{Field: field, Value: value}
+	switch field {
+	case FilterExtension:
+		extensions := make(map[string]bool)
+		for _, ext := range strings.Split(value, ",") {
+			if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+				extensions[ext] = true
+			}
+		}
+		node.matches = func(doc core.Document) bool {
+			return extensions[doc.Extension()]
+		}
+	case FilterPath:
+		needle := filepath.ToSlash(value)
+		node.matches = func(doc core.Document) bool {
+			return strings.Contains(filepath.ToSlash(doc.Path), needle)
+		}
+	case FilterDir:
+		dir, err := utils.ExpandFilePath(value)
+		if err != nil {
+			return nil, true, fmt.Errorf("%w: invalid directory %q: %v", ErrInvalidQuery, value, err)
+		}
+		node.matches = func(doc core.Document) bool {
+			return isWithinDir(doc.Dir(), dir)
+		}
+	case FilterSource:
+		node.matches = func(doc core.Document) bool {
+			return strings.EqualFold(doc.Source, value)
+		}
+	case FilterModified:
+		matches, err := parseDateFilter(field, value, func(doc core.Document) int64 { return doc.ModTime })
+		if err != nil {
+			return nil, true, err
+		}
+		node.matches = matches
+	case FilterTitle:
+		needle := strings.ToLower(value)
+		node.matches = func(doc core.Document) bool {
+			return strings.Contains(strings.ToLower(doc.Title), needle)
+		}
+	case FilterTag:
+		var tags []string
+		for _, tag := range strings.Split(value, ",") {
+			if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		node.matches = func(doc core.Document) bool {
+			return slices.ContainsFunc(tags, doc.HasTag)
+		}
+	case FilterDate:
+		matches, err := parseDateFilter(field, value, func(doc core.Document) int64 { return doc.Date })
+		if err != nil {
+			return nil, true, err
+		}
+		node.matches = matches
+	}
+
+	return node, true, nil
+}
+
+// isFilterWord reports whether a query word starts with the name of a field filter
+func isFilterWord(word string) bool {
+	field, _, ok := strings.Cut(word, ":")
+	if !ok {
+		return false
+	}
+	switch field {
+	case FilterExtension, FilterPath, FilterDir, FilterSource, FilterModified, FilterTitle, FilterTag, FilterDate:
+		return true
+	}
+	return false
+}
+
+// parseDateFilter parses a comparison of the time get returns, such as
+// ">2026-01-01" or "<=2026-02-01T12:00". A value without an operator matches
+// the whole period given by the precision of the date, e.g. the whole day.
+// Documents with an unknown time (0) never match.
+func parseDateFilter(field, value string, get func(doc core.Document) int64) (func(doc core.Document) bool, error) {
+	operator := ""
+	for _, op := range []string{">=", "<=", ">", "<", "="} {
+		if strings.HasPrefix(value, op) {
+			operator = op
+			break
+		}
+	}
+	date := strings.TrimPrefix(value, operator)
+
+	var start time.Time
+	var precision time.Duration
+	for _, candidate := range dateLayouts {
+		t, err := time.ParseInLocation(candidate.layout, date, time.Local)
+		if err == nil {
+			start, precision = t, candidate.precision
+			break
+		}
+	}
+	if start.IsZero() {
+		return nil, fmt.Errorf("%w: invalid date %q for %s:, expected YYYY-MM-DD", ErrInvalidQuery, date, field)
+	}
+
+	// Periods of a day end at the next midnight, even across DST changes
+	end := start.Add(precision)
+	if precision == 24*time.Hour {
+		end = start.AddDate(0, 0, 1)
+	}
+
+	from, until := int64(0), int64(0)
+	switch operator {
+	case ">":
+		from = end.UnixNano()
+	case ">=":
+		from = start.UnixNano()
+	case "<":
+		until = start.UnixNano()
+	case "<=":
+		until = end.UnixNano()
+	default:
+		from, until = start.UnixNano(), end.UnixNano()
+	}
+
+	return func(doc core.Document) bool {
+		t := get(doc)
+		if t == 0 {
+			return false
+		}
+		return (from == 0 || t >= from) && (until == 0 || t < until)
+	}, nil
+}
+
+// isWithinDir reports whether path is dir or one of its subdirectories
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+func (n *FilterNode) match(segment core.SegmentReader) docSet {
+	docs := make(docSet)
+	for _, doc := range segment.Documents() {
+		if n.matches(doc) {
+			docs[doc.ID] = struct{}{}
+		}
+	}
+	return docs
+}
+
+func (n *FilterNode) String() string {
+	if strings.ContainsFunc(n.Value, func(r rune) bool { return r == ' ' || r == '\t' }) {
+		return n.Field + `:"` + n.Value + `"`
+	}
+	return n.Field + ":" + n.Value
+}
+
+// isFilter reports whether a node only restricts documents by metadata, i.e.
+// is a filter or a group of filters
+func isFilter(node Node) bool {
+	switch n := node.(type) {
+	case *FilterNode:
+		return true
+	case *BooleanNode:
+		for _, clauses := range [][]Node{n.Must, n.Should, n.MustNot, n.Filter} {
+			for _, clause := range clauses {
+				if !isFilter(clause) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package query
+
+import (
+	"errors"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"mneme/internal/core"
+)
+
+func modTime(date string) int64 {
+	t, err := time.ParseInLocation("2006-01-02 15:04", date, time.Local)
+	if err != nil {
+		panic(err)
+	}
+	return t.UnixNano()
+}
+
+func createFilterTestSegment() *core.Segment {
+	// dir: filters resolve absolute paths, which include a drive on Windows
+	root, _ := filepath.Abs(filepath.FromSlash("/repo"))
+	return &core.Segment{
+		Docs: []core.Document{
+			{ID: 1, Path: filepath.Join(root, "internal", "query", "ranking.go"), TokenCount: 4, Source: "filesystem", ModTime: modTime("2026-01-10 09:00")},
+			{ID: 2, Path: filepath.Join(root, "internal", "query", "README.MD"), TokenCount: 4, Source: "filesystem", ModTime: modTime("2025-12-31 23:59"),
+				Title: "Query Internals", Tags: []string{"Go", "search"}, Date: modTime("2024-06-01 00:00")},
+			{ID: 3, Path: filepath.Join(root, "docs", "retry.md"), TokenCount: 4, Source: "github", ModTime: modTime("2026-01-01 12:00"),
+				Title: "Retry policy", Tags: []string{"ops"}},
+			{ID: 4, Path: filepath.Join(root, "docs", "notes", "retry.txt"), TokenCount: 4, Source: "filesystem"},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"retri": {
+				{DocID: 1, Freq: 1, Positions: []uint{0}},
+				{DocID: 2, Freq: 1, Positions: []uint{0}},
+				{DocID: 3, Freq: 2, Positions: []uint{0, 1}},
+				{DocID: 4, Freq: 1, Positions: []uint{0}},
+			},
+		},
+		TotalDocs:   4,
+		TotalTokens: 16,
+		AvgDocLen:   4,
+	}
+}
+
+func TestParseFieldFilter(t *testing.T) {
+	for _, word := range []string{"std::vector", "http://example.com", "kubernetes", "Ext:go"} {
+		if _, ok, err := ParseFieldFilter(word); ok || err != nil {
+			t.Errorf("ParseFieldFilter(%q) = %v, %v, expected an ordinary word", word, ok, err)
+		}
+	}
+
+	for _, word := range []string{"ext:", "modified:yesterday", "modified:>2026-13-01", "date:soon", "tag:"} {
+		if _, ok, err := ParseFieldFilter(word); !ok || !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("ParseFieldFilter(%q) = %v, %v, expected ErrInvalidQuery", word, ok, err)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	reader := core.NewSegmentReader(createFilterTestSegment())
+
+	tests := []struct {
+		query    string
+		expected []uint
+	}{
+		{query: "retry ext:go", expected: []uint{1}},
+		{query: "retry ext:.md", expected: []uint{2, 3}},
+		{query: "retry ext:go,txt", expected: []uint{1, 4}},
+		{query: "retry path:internal/query", expected: []uint{1, 2}},
+		{query: "retry dir:/repo/docs", expected: []uint{3, 4}},
+		{query: `retry dir:"/repo/docs/notes"`, expected: []uint{4}},
+		{query: "retry dir:/repo/doc", expected: nil},
+		{query: "retry source:GitHub", expected: []uint{3}},
+		{query: "retry -source:github", expected: []uint{1, 2, 4}},
+		{query: "retry modified:>2026-01-01", expected: []uint{1}},
+		{query: "retry modified:>=2026-01-01", expected: []uint{1, 3}},
+		{query: "retry modified:<2026-01-01", expected: []uint{2}},
+		{query: "retry modified:<=2026-01-01", expected: []uint{2, 3}},
+		{query: "retry modified:2026-01-01", expected: []uint{3}},
+		{query: "retry modified:>2026-01-10T08:59", expected: []uint{1}},
+		{query: "retry title:internals", expected: []uint{2}},
+		{query: `retry title:"retry POLICY"`, expected: []uint{3}},
+		{query: "retry tag:go", expected: []uint{2}},
+		{query: "retry tag:ops,#search", expected: []uint{2, 3}},
+		{query: "retry -tag:ops", expected: []uint{1, 2, 4}},
+		{query: "retry date:2024-06-01", expected: []uint{2}},
+		{query: "retry date:>2025-01-01", expected: nil},
+		{query: "retry (ext:go OR source:github)", expected: []uint{1, 3}},
+		{query: "retry ext:md path:internal", expected: []uint{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := ParseBooleanQuery(tt.query)
+			if err != nil {
+				t.Fatalf("ParseBooleanQuery failed: %v", err)
+			}
+			if !q.HasOperators() {
+				t.Error("Expected filters to count as query syntax")
+			}
+
+			var got []uint
+			for docID := range q.Match(reader) {
+				got = append(got, docID)
+			}
+			sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+
+			if len(got) != len(tt.expected) {
+				t.Fatalf("Match() = %v, expected %v", got, tt.expected)
+			}
+			for i := range got {
+				if got[i] != tt.expected[i] {
+					t.Fatalf("Match() = %v, expected %v", got, tt.expected)
+				}
+			}
+		})
+	}
+}
+
+func TestFilterParse(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+	}{
+		{query: "retry ext:go", expected: "(#ext:go retry)"},
+		{query: "retry backoff ext:go", expected: "(#ext:go retry backoff)"},
+		{query: "+ext:go retry", expected: "(#ext:go retry)"},
+		{query: "retry (ext:go OR ext:md)", expected: "(#(ext:go ext:md) retry)"},
+		{query: `retry dir:"~/My Notes"`, expected: `(#dir:"~/My Notes" retry)`},
+	}
+
+	for _, tt := range tests {
+		q, err := ParseBooleanQuery(tt.query)
+		if err != nil {
+			t.Fatalf("ParseBooleanQuery(%q) failed: %v", tt.query, err)
+		}
+		if got := q.String(); got != tt.expected {
+			t.Errorf("ParseBooleanQuery(%q) = %s, expected %s", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestRankQueryWithFilters(t *testing.T) {
+	index := core.NewIndexReader(createFilterTestSegment())
+
+	q, err := ParseBooleanQuery("retry ext:md")
+	if err != nil {
+		t.Fatalf("ParseBooleanQuery failed: %v", err)
+	}
+	if terms := q.Terms(); len(terms) != 1 || terms[0] != "retri" {
+		t.Errorf("Expected filters to contribute no terms, got %v", terms)
+	}
+
+	results := RankQuery(index, q, 10, nil)
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 results, got %v", results)
+	}
+	if filepath.Base(results[0].Path) != "retry.md" {
+		t.Errorf("Expected retry.md first, got %s", results[0].Path)
+	}
+}
@@ -20,6 +20,7 @@ type FuzzyMatch struct {
 }
 
 // GetVocabulary extracts all unique terms from a segment's inverted index.
+// Terms that only occur in deleted documents are left out.
 func GetVocabulary(segment *core.Segment) []string {
 	if segment == nil || len(segment.InvertedIndex) == 0 {
 		return nil
@@ -27,6 +28,9 @@ func GetVocabulary(segment *core.Segment) []string {
 
 	vocab := make([]string, 0, len(segment.InvertedIndex))
 	for term := range segment.InvertedIndex {
+		if segment.Deleted != nil && segment.LiveDocFrequency(term) == 0 {
+			continue
+		}
 		vocab = append(vocab, term)
 	}
 
@@ -116,15 +120,24 @@ func MergeTokensWithFuzzy(originalTokens []string, fuzzyMatches []FuzzyMatch) []
 // the closest match in the segment's vocabulary.
 // Returns the corrected terms and a map of original->corrected for any changes.
 func AutoCorrectQuery(segment *core.Segment, terms []string) ([]string, map[string]string) {
-	if segment == nil || len(segment.InvertedIndex) == 0 || len(terms) == 0 {
+	if segment == nil || len(segment.InvertedIndex) == 0 {
 		return terms, nil
 	}
+	return AutoCorrectQueryWithVocabulary(GetVocabulary(segment), terms)
+}
 
-	vocabulary := GetVocabulary(segment)
-	if len(vocabulary) == 0 {
+// AutoCorrectQueryWithVocabulary is AutoCorrectQuery against a prebuilt
+// vocabulary, such as the vocabulary of all segments of an index.
+func AutoCorrectQueryWithVocabulary(vocabulary []string, terms []string) ([]string, map[string]string) {
+	if len(vocabulary) == 0 || len(terms) == 0 {
 		return terms, nil
 	}
 
+	known := make(map[string]struct{}, len(vocabulary))
+	for _, term := range vocabulary {
+		known[term] = struct{}{}
+	}
+
 	// Build trigram index for fast fuzzy searching
 	trigramIndex := utils.BuildTrigramIndex(vocabulary)
 
@@ -133,14 +146,14 @@ func AutoCorrectQuery(segment *core.Segment, terms []string) ([]string, map[stri
 
 	for _, term := range terms {
 		// Try exact match first
-		if _, exists := segment.InvertedIndex[term]; exists {
+		if _, exists := known[term]; exists {
 			correctedTerms = append(correctedTerms, term)
 			continue
 		}
 
 		// Try lowercase
 		termLower := strings.ToLower(term)
-		if _, exists := segment.InvertedIndex[termLower]; exists {
+		if _, exists := known[termLower]; exists {
 			correctedTerms = append(correctedTerms, term) // Keep original casing
 			if term != termLower {
 				corrections[term] = termLower
@@ -214,7 +227,7 @@ func AutoCorrectQuery(segment *core.Segment, terms []string) ([]string, map[stri
 					originalParts = append(originalParts, part)
 
 					// Check exact match for part
-					if _, exists := segment.InvertedIndex[partLower]; exists {
+					if _, exists := known[partLower]; exists {
 						correctedParts = append(correctedParts, partLower)
 						allCorrected = false // exact match is not a fuzzy correction
 						continue
@@ -292,7 +305,7 @@ func AutoCorrectQuery(segment *core.Segment, terms []string) ([]string, map[stri
 					// This handles cases where a compound identifier was split and corrected, but the user likely
 					// meant the specific identifier.
 					recombined := strings.Join(correctedParts, "")
-					if _, exists := segment.InvertedIndex[recombined]; exists {
+					if _, exists := known[recombined]; exists {
 						correctedTerms = append(correctedTerms, recombined)
 						corrections[term] = recombined
 						logger.Infof("Auto-corrected compound typo to combined term: %q -> %q", term, recombined)
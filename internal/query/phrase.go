@@ -0,0 +1,209 @@
+package query
+
+import (
+	"strings"
+
+	"mneme/internal/core"
+	"mneme/internal/index"
+)
+
+// PhraseBoostWeight scales the normalized phrase score that is added on top of the
+// combined BM25/VSM score of documents containing every requested phrase.
+const PhraseBoostWeight = 0.5
+
+// Phrase is a quoted query fragment whose terms must appear adjacent and in order.
+type Phrase struct {
+	Text    string   // Original phrase text as typed by the user
+	Terms   []string // Normalized terms, in order
+	Offsets []uint   // Word offset of each term relative to the first term
+}
+
+// ParsePhrase tokenizes text with the indexing pipeline and returns the phrase terms
+// with their relative offsets. Identifiers are reduced to their parts, so the phrase
+// "getUserName" matches both "getUserName" and "get user name" in a document.
+// Stopwords are dropped but keep their slot, so offsets stay aligned with the index.
+// It returns false when fewer than two terms remain, as such a phrase is a plain term.
+func ParsePhrase(text string) (Phrase, bool) {
+	tokens, _ := index.TokenizeContentWithPositions(text, 0)
+
+	phrase := Phrase{Text: text}
+	for _, token := range tokens {
+		// Keep the last token emitted for each position, which is the word part
+		// rather than the compound form of an identifier
+		if n := len(phrase.Offsets); n > 0 && phrase.Offsets[n-1] == token.Position {
+			phrase.Terms[n-1] = token.Token
+			continue
+		}
+		phrase.Terms = append(phrase.Terms, token.Token)
+		phrase.Offsets = append(phrase.Offsets, token.Position)
+	}
+
+	if len(phrase.Terms) < 2 {
+		return Phrase{}, false
+	}
+
+	first := phrase.Offsets[0]
+	for i := range phrase.Offsets {
+		phrase.Offsets[i] -= first
+	}
+	return phrase, true
+}
+
+// IsPhraseArg reports whether a query argument should be treated as a phrase.
+// The shell strips quotes, so `mneme find "aws region"` arrives as a single
+// argument containing whitespace; literal double quotes are accepted as well.
+func IsPhraseArg(arg string) bool {
+	return strings.Contains(arg, `"`) || len(strings.Fields(arg)) > 1
+}
+
+// ExtractPhrases returns the multi-term phrases found in the query arguments.
+// Arguments with literal double quotes contribute each quoted section; any other
+// argument that contains whitespace is taken as a phrase as a whole.
+func ExtractPhrases(args []string) []Phrase {
+	var phrases []Phrase
+
+	add := func(text string) {
+		if phrase, ok := ParsePhrase(text); ok {
+			phrases = append(phrases, phrase)
+		}
+	}
+
+	for _, arg := range args {
+		if strings.Contains(arg, `"`) {
+			// Odd indexes are inside quotes; an unterminated quote runs to the end
+			parts := strings.Split(arg, `"`)
+			for i := 1; i < len(parts); i += 2 {
+				add(parts[i])
+			}
+			continue
+		}
+		if len(strings.Fields(arg)) > 1 {
+			add(arg)
+		}
+	}
+
+	return phrases
+}
+
+// FindPhraseMatches returns, for every document containing the phrase, the number
+// of places where all phrase terms occur at their expected relative positions.
+// Postings without positions (indexes built before positions were recorded) and
+// deleted documents never match.
+func FindPhraseMatches(segment *core.Segment, phrase Phrase) map[uint]uint {
+	if segment == nil {
+		return make(map[uint]uint)
+	}
+	return FindSegmentPhraseMatches(core.NewSegmentReader(segment), phrase)
+}
+
+// FindSegmentPhraseMatches is FindPhraseMatches for a single segment reader
+func FindSegmentPhraseMatches(segment core.SegmentReader, phrase Phrase) map[uint]uint {
+	matches := make(map[uint]uint)
+	if segment == nil || len(phrase.Terms) == 0 {
+		return matches
+	}
+
+	// Position sets of each term per document
+	termPositions := make([]map[uint]map[uint]struct{}, len(phrase.Terms))
+	for i, term := range phrase.Terms {
+		postings := segment.Postings(term)
+		if postings == nil {
+			return matches
+		}
+
+		byDoc := make(map[uint]map[uint]struct{}, len(postings))
+		for _, posting := range postings {
+			if len(posting.Positions) == 0 || segment.IsDeleted(posting.DocID) {
+				continue
+			}
+			positions := make(map[uint]struct{}, len(posting.Positions))
+			for _, pos := range posting.Positions {
+				positions[pos] = struct{}{}
+			}
+			byDoc[posting.DocID] = positions
+		}
+		termPositions[i] = byDoc
+	}
+
+	// Anchor on the first term and verify the remaining terms at their offsets
+	for _, posting := range segment.Postings(phrase.Terms[0]) {
+		if segment.IsDeleted(posting.DocID) {
+			continue
+		}
+
+		count := uint(0)
+		for _, start := range posting.Positions {
+			matched := true
+			for i := 1; i < len(phrase.Terms); i++ {
+				positions, ok := termPositions[i][posting.DocID]
+				if !ok {
+					matched = false
+					break
+				}
+				if _, ok := positions[start+phrase.Offsets[i]]; !ok {
+					matched = false
+					break
+				}
+			}
+			if matched {
+				count++
+			}
+		}
+		if count > 0 {
+			matches[posting.DocID] += count
+		}
+	}
+
+	return matches
+}
+
+// CalculatePhraseScores filters candidate documents down to those containing every
+// phrase and returns a BM25-style score per surviving document, treating each
+// phrase as a single pseudo-term whose frequency is its number of occurrences.
+func CalculatePhraseScores(segment *core.Segment, phrases []Phrase) map[uint]float64 {
+	if segment == nil || len(phrases) == 0 {
+		return make(map[uint]float64)
+	}
+
+	reader := core.NewSegmentReader(segment)
+	matches := make([]map[uint]uint, len(phrases))
+	for i, phrase := range phrases {
+		matches[i] = FindSegmentPhraseMatches(reader, phrase)
+	}
+
+	docLength := func(docID uint) float64 {
+		return documentLength(reader, docID)
+	}
+	stats := &CorpusStats{TotalDocs: int(segment.TotalDocs), AvgDocLen: float64(segment.AvgDocLen)}
+	return scorePhraseMatches(matches, docLength, stats, NewBM25(DefaultBM25K1, DefaultBM25B))
+}
+
+// scorePhraseMatches computes phrase scores from the matches of every phrase
+// across the whole index. Only documents matching all phrases are scored, and the
+// number of documents matching a phrase is its document frequency. Occurrences
+// are scored like terms with the parameters of bm25.
+func scorePhraseMatches(matches []map[uint]uint, docLength func(docID uint) float64, stats *CorpusStats, bm25 *BM25) map[uint]float64 {
+	scores := make(map[uint]float64)
+
+	for i, phraseMatches := range matches {
+		idf := calculateIDF(len(phraseMatches), stats.TotalDocs)
+
+		next := make(map[uint]float64, len(phraseMatches))
+		for docID, count := range phraseMatches {
+			// Documents must contain all phrases
+			if i > 0 {
+				if _, ok := scores[docID]; !ok {
+					continue
+				}
+			}
+			next[docID] = scores[docID] + calculateTermBM25(float64(count), idf, docLength(docID), stats.AvgDocLen, bm25.K1, bm25.B)
+		}
+		scores = next
+
+		if len(scores) == 0 {
+			break
+		}
+	}
+
+	return scores
+}
@@ -0,0 +1,187 @@
+package query
+
+import (
+	"mneme/internal/core"
+	"testing"
+)
+
+// createPhraseSegment builds a segment with positional postings:
+//
+//	doc 1: "aws region us east"
+//	doc 2: "region of aws"
+//	doc 3: "aws region aws region"
+//	doc 4: "default region settings"
+func createPhraseSegment() *core.Segment {
+	return &core.Segment{
+		Docs: []core.Document{
+			{ID: 1, Path: "/docs/one.md", TokenCount: 4},
+			{ID: 2, Path: "/docs/two.md", TokenCount: 3},
+			{ID: 3, Path: "/docs/three.md", TokenCount: 4},
+			{ID: 4, Path: "/docs/four.md", TokenCount: 3},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"aw": {
+				{DocID: 1, Freq: 1, Positions: []uint{0}},
+				{DocID: 2, Freq: 1, Positions: []uint{2}},
+				{DocID: 3, Freq: 2, Positions: []uint{0, 2}},
+			},
+			"region": {
+				{DocID: 1, Freq: 1, Positions: []uint{1}},
+				{DocID: 2, Freq: 1, Positions: []uint{0}},
+				{DocID: 3, Freq: 2, Positions: []uint{1, 3}},
+				{DocID: 4, Freq: 1, Positions: []uint{1}},
+			},
+			"us": {
+				{DocID: 1, Freq: 1, Positions: []uint{2}},
+			},
+			"east": {
+				{DocID: 1, Freq: 1, Positions: []uint{3}},
+			},
+			"default": {
+				{DocID: 4, Freq: 1, Positions: []uint{0}},
+			},
+			"set": {
+				{DocID: 4, Freq: 1, Positions: []uint{2}},
+			},
+		},
+		TotalDocs:   4,
+		TotalTokens: 14,
+		AvgDocLen:   3,
+	}
+}
+
+func TestParsePhrase(t *testing.T) {
+	t.Run("multi-word phrase", func(t *testing.T) {
+		phrase, ok := ParsePhrase("aws region")
+		if !ok {
+			t.Fatal("Expected phrase to be parsed")
+		}
+		if len(phrase.Terms) != 2 || phrase.Terms[0] != "aw" || phrase.Terms[1] != "region" {
+			t.Errorf("Unexpected terms: %v", phrase.Terms)
+		}
+		if phrase.Offsets[0] != 0 || phrase.Offsets[1] != 1 {
+			t.Errorf("Unexpected offsets: %v", phrase.Offsets)
+		}
+	})
+
+	t.Run("identifier is split into parts", func(t *testing.T) {
+		phrase, ok := ParsePhrase("getUserName")
+		if !ok {
+			t.Fatal("Expected identifier phrase to be parsed")
+		}
+		if len(phrase.Terms) != 3 || phrase.Terms[1] != "user" {
+			t.Errorf("Expected identifier parts, got %v", phrase.Terms)
+		}
+	})
+
+	t.Run("stopword leaves a gap", func(t *testing.T) {
+		phrase, ok := ParsePhrase("error in handling")
+		if !ok {
+			t.Fatal("Expected phrase to be parsed")
+		}
+		if len(phrase.Offsets) != 2 || phrase.Offsets[1] != 2 {
+			t.Errorf("Expected offsets [0 2], got %v", phrase.Offsets)
+		}
+	})
+
+	t.Run("single term is not a phrase", func(t *testing.T) {
+		if _, ok := ParsePhrase("region"); ok {
+			t.Error("Expected single term not to be treated as a phrase")
+		}
+	})
+}
+
+func TestExtractPhrases(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{"shell quoted phrase", []string{"aws region", "go"}, []string{"aws region"}},
+		{"literal quotes", []string{`"aws region" go`}, []string{"aws region"}},
+		{"plain terms", []string{"deploy", "production"}, nil},
+		{"unterminated quote", []string{`go "error handling`}, []string{"error handling"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			phrases := ExtractPhrases(tt.args)
+			if len(phrases) != len(tt.expected) {
+				t.Fatalf("Expected %d phrases, got %d (%v)", len(tt.expected), len(phrases), phrases)
+			}
+			for i, phrase := range phrases {
+				if phrase.Text != tt.expected[i] {
+					t.Errorf("Expected phrase %q, got %q", tt.expected[i], phrase.Text)
+				}
+			}
+		})
+	}
+}
+
+func TestFindPhraseMatches(t *testing.T) {
+	segment := createPhraseSegment()
+	phrase, _ := ParsePhrase("aws region")
+
+	matches := FindPhraseMatches(segment, phrase)
+
+	if matches[1] != 1 {
+		t.Errorf("Expected 1 match in doc 1, got %d", matches[1])
+	}
+	if matches[3] != 2 {
+		t.Errorf("Expected 2 matches in doc 3, got %d", matches[3])
+	}
+	if _, ok := matches[2]; ok {
+		t.Error("Doc 2 has the terms in the wrong order and must not match")
+	}
+	if _, ok := matches[4]; ok {
+		t.Error("Doc 4 lacks 'aws' and must not match")
+	}
+
+	t.Run("postings without positions never match", func(t *testing.T) {
+		legacy := &core.Segment{
+			Docs: []core.Document{{ID: 1, Path: "/a", TokenCount: 2}},
+			InvertedIndex: map[string][]core.Posting{
+				"aw":     {{DocID: 1, Freq: 1}},
+				"region": {{DocID: 1, Freq: 1}},
+			},
+			TotalDocs: 1,
+			AvgDocLen: 2,
+		}
+		if got := FindPhraseMatches(legacy, phrase); len(got) != 0 {
+			t.Errorf("Expected no matches without positions, got %v", got)
+		}
+	})
+}
+
+func TestRankDocumentsWithPhrases(t *testing.T) {
+	segment := createPhraseSegment()
+	phrase, _ := ParsePhrase("aws region")
+	tokens := ParseQuery("aws region")
+
+	t.Run("only documents containing the phrase are returned", func(t *testing.T) {
+		results := RankDocumentsWithPhrases(segment, tokens, []Phrase{phrase}, 10, nil)
+
+		if len(results) != 2 {
+			t.Fatalf("Expected 2 results, got %d: %+v", len(results), results)
+		}
+		// Doc 3 contains the phrase twice
+		if results[0].DocID != 3 || results[1].DocID != 1 {
+			t.Errorf("Expected docs [3 1], got [%d %d]", results[0].DocID, results[1].DocID)
+		}
+	})
+
+	t.Run("without phrases all term matches are returned", func(t *testing.T) {
+		results := RankDocuments(segment, tokens, 10, nil)
+		if len(results) != 4 {
+			t.Errorf("Expected 4 results without phrase filtering, got %d", len(results))
+		}
+	})
+
+	t.Run("all phrases must match", func(t *testing.T) {
+		second, _ := ParsePhrase("us east")
+		results := RankDocumentsWithPhrases(segment, tokens, []Phrase{phrase, second}, 10, nil)
+		if len(results) != 1 || results[0].DocID != 1 {
+			t.Errorf("Expected only doc 1, got %+v", results)
+		}
+	})
+}
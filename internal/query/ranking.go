@@ -4,8 +4,13 @@ import (
 	"math"
 	"mneme/internal/constants"
 	"mneme/internal/core"
+	"mneme/internal/logger"
+	"mneme/internal/utils"
 	"path/filepath"
+	"slices"
 	"sort"
+	"sync"
+	"time"
 )
 
 // MaxResults is the default limit for search results
@@ -20,10 +25,63 @@ const (
 // RankDocuments uses parallel execution to score documents based on exact and fuzzy matches.
 // It merges the results, sums the scores for documents found in both passes, and returns the top K.
 func RankDocuments(segment *core.Segment, tokens []string, limit int, rankingCfg *core.RankingConfig) []core.RankedDocument {
+	return RankDocumentsWithPhrases(segment, tokens, nil, limit, rankingCfg)
+}
+
+// RankDocumentsWithPhrases ranks documents like RankDocuments and additionally
+// requires every phrase to occur with its terms adjacent and in order.
+// Documents missing a phrase are dropped; matching documents receive a phrase
+// score, normalized to [0, 1] and scaled by PhraseBoostWeight, on top of their score.
+func RankDocumentsWithPhrases(segment *core.Segment, tokens []string, phrases []Phrase, limit int, rankingCfg *core.RankingConfig) []core.RankedDocument {
 	if segment == nil || len(tokens) == 0 {
 		return []core.RankedDocument{}
 	}
 
+	return RankIndex(core.NewIndexReader(segment), tokens, phrases, limit, rankingCfg)
+}
+
+// segmentScores holds the raw scores of the documents of a single segment
+type segmentScores struct {
+	exactRelevance map[uint]float64
+	exactVSM       map[uint]float64
+	fuzzyRelevance map[uint]float64
+	fuzzyVSM       map[uint]float64
+	phraseMatches  []map[uint]uint  // Occurrences of each phrase per document
+	docLengths     map[uint]float64 // Lengths of the documents matching a phrase
+	matches        docSet           // Documents matching the boolean query, if any
+}
+
+// RankIndex ranks the documents of all segments of an index like
+// RankDocumentsWithPhrases. Segments are scored in parallel with the corpus
+// statistics of the whole index, relevance and phrase scores are normalized across
+// all segments, and the top K results of every segment are merged into the
+// global top K. Relevance is computed by the Scorer selected in the ranking
+// configuration (BM25F by default) and blended with the VSM score. If
+// RecencyHalfLifeDays is set, final scores are scaled by the RecencyFactor of
+// each document.
+func RankIndex(index *core.IndexReader, tokens []string, phrases []Phrase, limit int, rankingCfg *core.RankingConfig) []core.RankedDocument {
+	return rankIndex(index, tokens, phrases, nil, limit, rankingCfg, false)
+}
+
+// RankQuery ranks the documents of an index matching a parsed query. Documents
+// are filtered by evaluating the query over the posting lists of every segment,
+// then scored like RankIndex with the words and phrases that are not excluded.
+// Phrases only restrict the results where the query requires them; otherwise
+// they boost the documents containing them.
+func RankQuery(index *core.IndexReader, q *Query, limit int, rankingCfg *core.RankingConfig) []core.RankedDocument {
+	if q == nil {
+		return []core.RankedDocument{}
+	}
+	return rankIndex(index, q.Terms(), q.Phrases(), q, limit, rankingCfg, false)
+}
+
+// rankIndex implements RankIndex and RankQuery. Without a filter, every phrase
+// is required. If explain is set, every result carries the breakdown of its score.
+func rankIndex(index *core.IndexReader, tokens []string, phrases []Phrase, filter *Query, limit int, rankingCfg *core.RankingConfig, explain bool) []core.RankedDocument {
+	if index == nil || len(index.Segments) == 0 || len(tokens) == 0 {
+		return []core.RankedDocument{}
+	}
+
 	if limit <= 0 {
 		limit = MaxResults
 	}
@@ -40,196 +98,359 @@ func RankDocuments(segment *core.Segment, tokens []string, limit int, rankingCfg
 		}
 	}
 
-	// Build document ID to path map upfront for efficient lookup
-	docPaths := make(map[uint]string, len(segment.Docs))
-	for _, doc := range segment.Docs {
-		docPaths[doc.ID] = doc.Path
+	scorer, err := NewScorer(rankingCfg)
+	if err != nil {
+		logger.Warnf("Falling back to %s: %v", ScorerBM25F, err)
+		scorer = NewBM25F(DefaultBM25K1, DefaultBM25B, DefaultFieldWeights)
 	}
+	phraseBM25 := NewBM25(bm25Parameters(rankingCfg))
+
+	// Every segment measures the age of its documents from the same instant
+	halfLife := recencyHalfLife(rankingCfg)
+	now := time.Now()
+
+	// Fuzzy terms come from the vocabulary of the whole index, so every segment
+	// scores the same expanded query
+	fuzzyTerms, fuzzyOrigins := expandFuzzyTerms(tokens, index.Vocabulary())
+
+	allTerms := make([]string, 0, len(tokens)+len(fuzzyTerms))
+	allTerms = append(allTerms, tokens...)
+	allTerms = append(allTerms, fuzzyTerms...)
+	stats := NewCorpusStats(index, allTerms)
+
+	exactVector := BuildCorpusQueryVector(tokens, stats)
+	fuzzyVector := BuildCorpusQueryVector(fuzzyTerms, stats)
+
+	// Phase 1: raw scores of every segment
+	scores := make([]segmentScores, len(index.Segments))
+	forEachSegment(index, func(i int, segment core.SegmentReader) {
+		scores[i] = scoreSegment(segment, scorer, tokens, fuzzyTerms, phrases, exactVector, fuzzyVector, stats)
+		if filter != nil {
+			scores[i].matches = filter.Match(segment)
+		}
+	})
 
-	// Channels for results
-	type searchResult struct {
-		docs []core.RankedDocument
+	// Relevance scores are normalized by the best score of the whole index
+	maxExactRelevance, maxFuzzyRelevance := 0.0, 0.0
+	for _, s := range scores {
+		maxExactRelevance = math.Max(maxExactRelevance, maxScore(s.exactRelevance))
+		maxFuzzyRelevance = math.Max(maxFuzzyRelevance, maxScore(s.fuzzyRelevance))
 	}
-	exactCh := make(chan searchResult)
-	fuzzyCh := make(chan searchResult)
 
-	// G1: Exact Search
-	go func() {
-		docs := performExactSearch(segment, tokens, bm25Weight, vsmWeight, docPaths)
-		exactCh <- searchResult{docs: docs}
-	}()
+	// Phrase scores need the number of matching documents across all segments
+	var phraseScores map[uint]float64
+	maxPhraseScore := 0.0
+	if len(phrases) > 0 {
+		matches := make([]map[uint]uint, len(phrases))
+		docLengths := make(map[uint]float64)
+		for p := range phrases {
+			matches[p] = make(map[uint]uint)
+			for _, s := range scores {
+				for docID, count := range s.phraseMatches[p] {
+					matches[p][docID] = count
+				}
+			}
+		}
+		for _, s := range scores {
+			for docID, length := range s.docLengths {
+				docLengths[docID] = length
+			}
+		}
 
-	// G2: Fuzzy Search
-	go func() {
-		docs := performFuzzySearch(segment, tokens, bm25Weight, vsmWeight, docPaths)
-		fuzzyCh <- searchResult{docs: docs}
-	}()
+		docLength := func(docID uint) float64 {
+			return docLengths[docID]
+		}
+		if filter == nil {
+			phraseScores = scorePhraseMatches(matches, docLength, stats, phraseBM25)
+		} else {
+			// Boolean queries decide which phrases are required, so every
+			// phrase is scored on its own
+			phraseScores = make(map[uint]float64)
+			for _, phraseMatches := range matches {
+				for docID, score := range scorePhraseMatches([]map[uint]uint{phraseMatches}, docLength, stats, phraseBM25) {
+					phraseScores[docID] += score
+				}
+			}
+		}
+		maxPhraseScore = maxScore(phraseScores)
+	}
 
-	// Gather results
-	exactRes := <-exactCh
-	fuzzyRes := <-fuzzyCh
+	var scoreExplainer *explainer
+	if explain {
+		scoreExplainer = &explainer{
+			scorer:            scorer,
+			stats:             stats,
+			weights:           explanationWeights(bm25Weight, vsmWeight, halfLife),
+			tokens:            tokens,
+			fuzzyTerms:        fuzzyTerms,
+			fuzzyOrigins:      fuzzyOrigins,
+			maxExactRelevance: maxExactRelevance,
+			maxFuzzyRelevance: maxFuzzyRelevance,
+			phraseScores:      phraseScores,
+			maxPhraseScore:    maxPhraseScore,
+			now:               now,
+			halfLife:          halfLife,
+		}
+		_, scoreExplainer.b = bm25Parameters(rankingCfg)
+	}
 
-	// Merge results
-	// Map docID -> RankedDocument
-	mergedDocs := make(map[uint]core.RankedDocument)
+	// Phase 2: combine the scores of every segment and keep its top K
+	results := make([][]core.RankedDocument, len(index.Segments))
+	forEachSegment(index, func(i int, segment core.SegmentReader) {
+		s := scores[i]
+		mergedDocs := make(map[uint]core.RankedDocument)
 
-	// Helper to merge
-	merge := func(docs []core.RankedDocument) {
-		for _, doc := range docs {
-			if existing, ok := mergedDocs[doc.DocID]; ok {
-				existing.Score += doc.Score
-				existing.MatchCount += doc.MatchCount
-				// Union of matched terms
-				existing.MatchedTerms = append(existing.MatchedTerms, doc.MatchedTerms...)
-				mergedDocs[doc.DocID] = existing
-			} else {
-				mergedDocs[doc.DocID] = doc
+		// Merge exact and fuzzy results, summing the scores of documents found in both
+		merge := func(relevanceScores, vsmScores map[uint]float64, maxRelevance, vsmPenalty float64, terms []string) {
+			if len(relevanceScores) == 0 && len(vsmScores) == 0 {
+				return
+			}
+			counts := matchCounts(segment, terms)
+			for docID := range unionKeys(relevanceScores, vsmScores) {
+				score := combineScore(relevanceScores[docID], maxRelevance, vsmScores[docID]*vsmPenalty, bm25Weight, vsmWeight)
+				if score <= 0 {
+					continue
+				}
+
+				doc, ok := mergedDocs[docID]
+				if !ok {
+					doc = core.RankedDocument{DocID: docID, Path: documentPath(segment, docID)}
+				}
+				doc.Score += score
+				doc.MatchCount += counts[docID]
+				doc.MatchedTerms = append(slices.Clip(doc.MatchedTerms), terms...)
+				mergedDocs[docID] = doc
 			}
 		}
-	}
 
-	merge(exactRes.docs)
-	merge(fuzzyRes.docs)
+		merge(s.exactRelevance, s.exactVSM, maxExactRelevance, 1, tokens)
+		// The fuzzy penalty on relevance scores cancels out by normalization, so it is
+		// only applied to the VSM score
+		merge(s.fuzzyRelevance, s.fuzzyVSM, maxFuzzyRelevance, constants.FuzzyScorePenalty, fuzzyTerms)
 
-	// Convert to slice for sorting
-	finalCandidates := make([]core.RankedDocument, 0, len(mergedDocs))
-	for _, doc := range mergedDocs {
-		if doc.Score > 0 {
-			finalCandidates = append(finalCandidates, doc)
+		// Phrase filtering and boosting
+		if len(phrases) > 0 {
+			applyPhraseScores(segment, phrases, s.docLengths, phraseScores, maxPhraseScore, mergedDocs, filter == nil)
 		}
-	}
-
-	// Sort with Tie-Breaking Logic
-	// 1. Score (Descending)
-	// 2. MatchCount (Descending)
-	// 3. Filename (Ascending)
-	sort.Slice(finalCandidates, func(i, j int) bool {
-		d1 := finalCandidates[i]
-		d2 := finalCandidates[j]
 
-		// 1. Score
-		if math.Abs(d1.Score-d2.Score) > 1e-6 {
-			return d1.Score > d2.Score
+		// Boolean query filtering
+		if filter != nil {
+			for docID := range mergedDocs {
+				if _, ok := s.matches[docID]; !ok {
+					delete(mergedDocs, docID)
+				}
+			}
 		}
 
-		// 2. MatchCount
-		if d1.MatchCount != d2.MatchCount {
-			return d1.MatchCount > d2.MatchCount
+		// Recently modified documents rank higher
+		if halfLife > 0 {
+			applyRecency(segment, mergedDocs, now, halfLife)
 		}
 
-		// 3. Filename (Alphabetical Ascending, so "a" before "b" -> i < j)
-		// We need to extract filename from path
-		f1 := filepath.Base(d1.Path)
-		f2 := filepath.Base(d2.Path)
-		return f1 < f2 // Ascending
+		candidates := make([]core.RankedDocument, 0, len(mergedDocs))
+		for _, doc := range mergedDocs {
+			if doc.Score > 0 {
+				candidates = append(candidates, doc)
+			}
+		}
+		results[i] = utils.TopKFunc(candidates, limit, rankedBefore)
+		if scoreExplainer != nil {
+			scoreExplainer.explainSegment(segment, s, results[i])
+		}
 	})
 
-	// Return Top K
-	if len(finalCandidates) > limit {
-		return finalCandidates[:limit]
+	// Merge the per-segment top K into the global top K
+	ranked := results[0]
+	if len(results) > 1 {
+		collected := 0
+		for _, docs := range results {
+			collected += len(docs)
+		}
+		limit = min(limit, collected)
+		merged := make([]core.RankedDocument, 0, collected)
+		for _, docs := range results {
+			merged = append(merged, docs...)
+		}
+		ranked = utils.TopKFunc(merged, limit, rankedBefore)
+	}
+	if scoreExplainer != nil {
+		setTieBreaks(ranked)
+	}
+	return ranked
+}
+
+// rankedBefore orders ranked documents with tie-breaking:
+// 1. Score (Descending)
+// 2. MatchCount (Descending)
+// 3. Filename (Ascending)
+func rankedBefore(d1, d2 core.RankedDocument) bool {
+	// 1. Score
+	if math.Abs(d1.Score-d2.Score) > 1e-6 {
+		return d1.Score > d2.Score
 	}
-	return finalCandidates
+
+	// 2. MatchCount
+	if d1.MatchCount != d2.MatchCount {
+		return d1.MatchCount > d2.MatchCount
+	}
+
+	// 3. Filename (Alphabetical Ascending, so "a" before "b")
+	return filepath.Base(d1.Path) < filepath.Base(d2.Path)
 }
 
-func performExactSearch(segment *core.Segment, tokens []string, bm25Weight, vsmWeight float64, docPaths map[uint]string) []core.RankedDocument {
-	// 1. BM25
-	bm25Scores := CalculateBM25Scores(segment, tokens)
+// forEachSegment calls fn for every segment of the index in parallel and waits
+// for all calls to return
+func forEachSegment(index *core.IndexReader, fn func(i int, segment core.SegmentReader)) {
+	if len(index.Segments) == 1 {
+		fn(0, index.Segments[0])
+		return
+	}
 
-	// 2. VSM
-	// We use standard VSM here.
-	vsmScores := CalculateVSMScores(segment, tokens)
+	var wg sync.WaitGroup
+	for i, segment := range index.Segments {
+		wg.Go(func() {
+			fn(i, segment)
+		})
+	}
+	wg.Wait()
+}
 
-	// 3. Combine
-	combined := CombineScores(bm25Scores, vsmScores, bm25Weight, vsmWeight)
+// scoreSegment computes the raw exact, fuzzy and phrase scores of a segment
+func scoreSegment(segment core.SegmentReader, scorer Scorer, tokens, fuzzyTerms []string, phrases []Phrase, exactVector, fuzzyVector *core.TFIDFVector, stats *CorpusStats) segmentScores {
+	s := segmentScores{
+		exactRelevance: scorer.Score(segment, tokens, stats),
+		exactVSM:       CalculateSegmentVSMScores(segment, exactVector, tokens, stats),
+	}
 
-	return convertScoresToRankedDocs(segment, combined, tokens, docPaths)
+	if len(fuzzyTerms) > 0 {
+		s.fuzzyRelevance = scorer.Score(segment, fuzzyTerms, stats)
+		s.fuzzyVSM = CalculateSegmentVSMScores(segment, fuzzyVector, fuzzyTerms, stats)
+	}
+
+	if len(phrases) > 0 {
+		s.phraseMatches = make([]map[uint]uint, len(phrases))
+		s.docLengths = make(map[uint]float64)
+		for i, phrase := range phrases {
+			s.phraseMatches[i] = FindSegmentPhraseMatches(segment, phrase)
+			for docID := range s.phraseMatches[i] {
+				s.docLengths[docID] = documentLength(segment, docID)
+			}
+		}
+	}
+
+	return s
 }
 
-func performFuzzySearch(segment *core.Segment, tokens []string, bm25Weight, vsmWeight float64, docPaths map[uint]string) []core.RankedDocument {
-	vocabulary := GetVocabulary(segment)
+// expandFuzzyTerms returns the vocabulary terms fuzzy matching the tokens,
+// excluding the tokens themselves which are scored by the exact pass, and the
+// closest match of every term
+func expandFuzzyTerms(tokens []string, vocabulary []string) ([]string, map[string]FuzzyMatch) {
 	if len(vocabulary) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	// 1. Identify and expand tokens
 	fuzzyMatches := ExpandTokensWithFuzzy(tokens, vocabulary)
 	if len(fuzzyMatches) == 0 {
-		return nil
+		return nil, nil
 	}
 
-	// Extract unique fuzzy terms (excluding the original tokens if they appeared in exact search)
-	fuzzyTermsMap := make(map[string]bool)
+	fuzzyTermsMap := make(map[string]FuzzyMatch)
 	for _, match := range fuzzyMatches {
-		// If match.Matched == match.Original, it's an exact match (if distance 0).
-		// We skip it because G1 handled it.
+		// An exact match (distance 0) is handled by the exact pass
 		if match.Distance == 0 && match.Matched == match.Original {
 			continue
 		}
-		// If the matched term exists in the original query tokens, skip it (it's handled by G1)
-		isOriginal := false
-		for _, t := range tokens {
-			if t == match.Matched {
-				isOriginal = true
-				break
-			}
+		if slices.Contains(tokens, match.Matched) {
+			continue
 		}
-		if !isOriginal {
-			fuzzyTermsMap[match.Matched] = true
+		if closest, ok := fuzzyTermsMap[match.Matched]; !ok || match.Distance < closest.Distance {
+			fuzzyTermsMap[match.Matched] = match
 		}
 	}
 
-	if len(fuzzyTermsMap) == 0 {
-		return nil
-	}
-
 	fuzzyTerms := make([]string, 0, len(fuzzyTermsMap))
 	for term := range fuzzyTermsMap {
 		fuzzyTerms = append(fuzzyTerms, term)
 	}
+	sort.Strings(fuzzyTerms)
+	return fuzzyTerms, fuzzyTermsMap
+}
 
-	// 2. Score these fuzzy terms
-	bm25Scores := CalculateBM25Scores(segment, fuzzyTerms)
-	vsmScores := CalculateVSMScores(segment, fuzzyTerms)
-
-	// Key Step: Apply Penalty to Fuzzy Scores
-	for docID := range bm25Scores {
-		bm25Scores[docID] *= constants.FuzzyScorePenalty
-	}
-	for docID := range vsmScores {
-		vsmScores[docID] *= constants.FuzzyScorePenalty
+// applyPhraseScores adds the normalized phrase score to the documents containing
+// every phrase and, if required, removes all other documents. Phrase matches of
+// the segment missed by the term passes (e.g. identifier parts not produced by
+// query tokenization) are added.
+func applyPhraseScores(segment core.SegmentReader, phrases []Phrase, segmentDocs map[uint]float64, phraseScores map[uint]float64, maxPhraseScore float64, mergedDocs map[uint]core.RankedDocument, required bool) {
+	if required {
+		for docID := range mergedDocs {
+			if _, ok := phraseScores[docID]; !ok {
+				delete(mergedDocs, docID)
+			}
+		}
 	}
 
-	combined := CombineScores(bm25Scores, vsmScores, bm25Weight, vsmWeight)
-
-	return convertScoresToRankedDocs(segment, combined, fuzzyTerms, docPaths)
-}
+	for docID := range segmentDocs {
+		score, ok := phraseScores[docID]
+		if !ok {
+			continue
+		}
 
-func convertScoresToRankedDocs(segment *core.Segment, scores map[uint]float64, terms []string, docPaths map[uint]string) []core.RankedDocument {
-	docs := make([]core.RankedDocument, 0, len(scores))
+		boost := 0.0
+		if maxPhraseScore > 0 {
+			boost = score / maxPhraseScore * PhraseBoostWeight
+		}
 
-	for docID, score := range scores {
-		if score <= 0 {
-			continue
+		doc, ok := mergedDocs[docID]
+		if !ok {
+			doc = core.RankedDocument{DocID: docID, Path: documentPath(segment, docID)}
+			for _, phrase := range phrases {
+				doc.MatchedTerms = append(doc.MatchedTerms, phrase.Terms...)
+			}
 		}
+		doc.Score += boost
+		mergedDocs[docID] = doc
+	}
+}
 
-		// Calculate MatchCount
-		matchCount := 0
-		for _, term := range terms {
-			tf := GetTermFrequencyInDoc(segment, term, docID)
-			matchCount += int(tf)
+// matchCounts returns the total frequency of the terms in every document of a
+// segment containing them, reading the postings of every term once
+func matchCounts(segment core.SegmentReader, terms []string) map[uint]int {
+	counts := make(map[uint]int)
+	for _, term := range terms {
+		for _, posting := range segment.Postings(term) {
+			counts[posting.DocID] += int(posting.Freq)
 		}
+	}
+	return counts
+}
 
-		docPath := docPaths[docID] // Use the map for O(1) correct lookup
+// documentPath returns the path of a document of a segment
+func documentPath(segment core.SegmentReader, docID uint) string {
+	doc, _ := segment.Document(docID)
+	return doc.Path
+}
 
-		docs = append(docs, core.RankedDocument{
-			DocID:        docID,
-			Path:         docPath,
-			Score:        score,
-			MatchCount:   matchCount,
-			MatchedTerms: terms,
-		})
+// maxScore returns the highest score, or 0 for no scores
+func maxScore(scores map[uint]float64) float64 {
+	highest := 0.0
+	for _, score := range scores {
+		if score > highest {
+			highest = score
+		}
+	}
+	return highest
+}
+
+// unionKeys returns the set of documents scored in either map
+func unionKeys(a, b map[uint]float64) map[uint]bool {
+	keys := make(map[uint]bool, len(a)+len(b))
+	for docID := range a {
+		keys[docID] = true
+	}
+	for docID := range b {
+		keys[docID] = true
 	}
-	return docs
+	return keys
 }
 
 // GetTopDocumentPaths ranks documents and returns only the file paths
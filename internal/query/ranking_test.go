@@ -395,3 +395,117 @@ func formatResults(results []core.RankedDocument) string {
 	}
 	return s
 }
+
+func TestRankIndex_MatchesSingleSegment(t *testing.T) {
+	merged := &core.Segment{
+		Docs: []core.Document{
+			{ID: 0, Path: "kube.md", TokenCount: 4},
+			{ID: 1, Path: "helm.md", TokenCount: 6},
+			{ID: 2, Path: "notes.md", TokenCount: 3},
+			{ID: 3, Path: "deploy.md", TokenCount: 5},
+			{ID: 4, Path: "other.md", TokenCount: 2},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"kubernetes": {
+				{DocID: 0, Freq: 2, Positions: []uint{0, 2}},
+				{DocID: 1, Freq: 1, Positions: []uint{3}},
+				{DocID: 3, Freq: 1, Positions: []uint{0}},
+			},
+			"deploy": {
+				{DocID: 0, Freq: 1, Positions: []uint{1}},
+				{DocID: 3, Freq: 3, Positions: []uint{1, 2, 4}},
+			},
+			"helm": {
+				{DocID: 1, Freq: 2, Positions: []uint{0, 4}},
+				{DocID: 2, Freq: 1, Positions: []uint{0}},
+			},
+			"other": {
+				{DocID: 4, Freq: 2, Positions: []uint{0, 1}},
+			},
+		},
+		TotalDocs:   5,
+		TotalTokens: 20,
+		AvgDocLen:   4,
+	}
+
+	// The same documents split over two segments
+	split := func(ids ...uint) *core.Segment {
+		keep := make(map[uint]bool, len(ids))
+		for _, id := range ids {
+			keep[id] = true
+		}
+		segment := &core.Segment{InvertedIndex: make(map[string][]core.Posting)}
+		for _, doc := range merged.Docs {
+			if keep[doc.ID] {
+				segment.Docs = append(segment.Docs, doc)
+			}
+		}
+		for term, postings := range merged.InvertedIndex {
+			for _, posting := range postings {
+				if keep[posting.DocID] {
+					segment.InvertedIndex[term] = append(segment.InvertedIndex[term], posting)
+				}
+			}
+		}
+		return segment
+	}
+	index := &core.IndexReader{
+		Segments: []core.SegmentReader{
+			core.NewSegmentReader(split(0, 1)),
+			core.NewSegmentReader(split(2, 3, 4)),
+		},
+		TotalDocs:   merged.TotalDocs,
+		TotalTokens: merged.TotalTokens,
+		AvgDocLen:   merged.AvgDocLen,
+	}
+
+	tests := []struct {
+		name    string
+		tokens  []string
+		phrases []Phrase
+	}{
+		{name: "exact terms", tokens: []string{"kubernetes", "deploy"}},
+		{name: "fuzzy term", tokens: []string{"kubernetse"}},
+		{name: "phrase", tokens: []string{"kubernetes", "deploy"}, phrases: []Phrase{{Terms: []string{"kubernetes", "deploy"}, Offsets: []uint{0, 1}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := RankDocumentsWithPhrases(merged, tt.tokens, tt.phrases, 10, nil)
+			actual := RankIndex(index, tt.tokens, tt.phrases, 10, nil)
+
+			if len(expected) == 0 {
+				t.Fatal("Expected results from the single segment")
+			}
+			if len(actual) != len(expected) {
+				t.Fatalf("Expected %d results, got %d", len(expected), len(actual))
+			}
+			for i := range expected {
+				if actual[i].Path != expected[i].Path || math.Abs(actual[i].Score-expected[i].Score) > 1e-9 {
+					t.Errorf("Result %d: expected %s (%.6f), got %s (%.6f)",
+						i, expected[i].Path, expected[i].Score, actual[i].Path, actual[i].Score)
+				}
+			}
+		})
+	}
+
+	t.Run("match counts sum the term frequencies", func(t *testing.T) {
+		expected := map[string]int{"kube.md": 3, "helm.md": 1, "deploy.md": 4}
+		results := RankIndex(index, []string{"kubernetes", "deploy"}, nil, 10, nil)
+		if len(results) != len(expected) {
+			t.Fatalf("Expected %d results, got %d", len(expected), len(results))
+		}
+		for _, result := range results {
+			if result.MatchCount != expected[result.Path] {
+				t.Errorf("Expected %d matches in %s, got %d", expected[result.Path], result.Path, result.MatchCount)
+			}
+		}
+	})
+
+	t.Run("limit applies to the merged results", func(t *testing.T) {
+		results := RankIndex(index, []string{"kubernetes", "helm"}, nil, 2, nil)
+		if len(results) != 2 {
+			t.Errorf("Expected 2 results, got %d", len(results))
+		}
+	})
+}
@@ -0,0 +1,51 @@
+package query
+
+import (
+	"math"
+	"time"
+
+	"mneme/internal/core"
+)
+
+// RecencyWeight is the share of a document's score that decays with its age.
+// A document modified one half-life ago keeps 1 - RecencyWeight/2 of its score,
+// and very old documents keep 1 - RecencyWeight.
+const RecencyWeight = 0.3
+
+// recencyHalfLife returns the configured half-life of the recency boost, or 0
+// if the boost is disabled
+func recencyHalfLife(rankingCfg *core.RankingConfig) time.Duration {
+	if rankingCfg == nil || rankingCfg.RecencyHalfLifeDays <= 0 {
+		return 0
+	}
+	return time.Duration(rankingCfg.RecencyHalfLifeDays) * 24 * time.Hour
+}
+
+// RecencyFactor returns the multiplier applied to the score of a document last
+// modified at modTime (Unix nanoseconds). The boost decays exponentially with
+// the age of the document: 1 for a document modified now, 1 - RecencyWeight/2
+// after one half-life, approaching 1 - RecencyWeight. Documents without a
+// modification time and a halfLife of 0 keep their score.
+func RecencyFactor(modTime int64, now time.Time, halfLife time.Duration) float64 {
+	if halfLife <= 0 || modTime == 0 {
+		return 1
+	}
+
+	// Modification times in the future (e.g. clock skew) count as now
+	age := max(now.Sub(time.Unix(0, modTime)), 0)
+	decay := math.Exp2(-float64(age) / float64(halfLife))
+	return 1 - RecencyWeight + RecencyWeight*decay
+}
+
+// applyRecency scales the scores of the ranked documents of a segment by their
+// RecencyFactor
+func applyRecency(segment core.SegmentReader, mergedDocs map[uint]core.RankedDocument, now time.Time, halfLife time.Duration) {
+	for docID, ranked := range mergedDocs {
+		doc, ok := segment.Document(docID)
+		if !ok {
+			continue
+		}
+		ranked.Score *= RecencyFactor(doc.RecencyTime(), now, halfLife)
+		mergedDocs[docID] = ranked
+	}
+}
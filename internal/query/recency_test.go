@@ -0,0 +1,76 @@
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"mneme/internal/core"
+)
+
+func TestRecencyFactor(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	halfLife := 30 * 24 * time.Hour
+	daysAgo := func(days int) int64 {
+		return now.AddDate(0, 0, -days).UnixNano()
+	}
+
+	tests := []struct {
+		name     string
+		modTime  int64
+		halfLife time.Duration
+		expected float64
+	}{
+		{"modified now", now.UnixNano(), halfLife, 1},
+		{"one half-life ago", daysAgo(30), halfLife, 1 - RecencyWeight/2},
+		{"two half-lives ago", daysAgo(60), halfLife, 1 - RecencyWeight*3/4},
+		{"in the future", now.Add(time.Hour).UnixNano(), halfLife, 1},
+		{"unknown modification time", 0, halfLife, 1},
+		{"disabled", daysAgo(365), 0, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RecencyFactor(tt.modTime, now, tt.halfLife)
+			if math.Abs(got-tt.expected) > 1e-9 {
+				t.Errorf("RecencyFactor = %f, expected %f", got, tt.expected)
+			}
+		})
+	}
+
+	if old := RecencyFactor(daysAgo(3650), now, halfLife); old < 1-RecencyWeight {
+		t.Errorf("Old documents must keep at least 1 - RecencyWeight of their score, got %f", old)
+	}
+}
+
+func TestRankIndexWithRecency(t *testing.T) {
+	now := time.Now()
+	segment := &core.Segment{
+		Docs: []core.Document{
+			{ID: 0, Path: "standup-2025.md", TokenCount: 3, ModTime: now.AddDate(-1, 0, 0).UnixNano()},
+			{ID: 1, Path: "standup-2026.md", TokenCount: 3, ModTime: now.AddDate(0, 0, -1).UnixNano()},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"standup": {{DocID: 0, Freq: 1, Positions: []uint{0}}, {DocID: 1, Freq: 1, Positions: []uint{0}}},
+			"note":    {{DocID: 0, Freq: 1, Positions: []uint{1}}, {DocID: 1, Freq: 1, Positions: []uint{1}}},
+		},
+		TotalDocs:   2,
+		TotalTokens: 6,
+		AvgDocLen:   3,
+	}
+	index := core.NewIndexReader(segment)
+
+	// Equal matches are ordered by file name without the boost
+	disabled := RankIndex(index, []string{"standup"}, nil, 10, &core.RankingConfig{})
+	if len(disabled) != 2 || disabled[0].Path != "standup-2025.md" {
+		t.Fatalf("Expected standup-2025.md first without recency, got %v", disabled)
+	}
+
+	enabled := RankIndex(index, []string{"standup"}, nil, 10, &core.RankingConfig{RecencyHalfLifeDays: 30})
+	if len(enabled) != 2 || enabled[0].Path != "standup-2026.md" {
+		t.Fatalf("Expected the recent document first, got %v", enabled)
+	}
+	if enabled[1].Score >= disabled[0].Score {
+		t.Errorf("Expected the old document to lose score, got %f and %f", enabled[1].Score, disabled[0].Score)
+	}
+}
@@ -0,0 +1,341 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"mneme/internal/core"
+	"mneme/internal/logger"
+)
+
+// Names of the scorers selectable with ranking.scorer
+const (
+	ScorerBM25      = "bm25"
+	ScorerBM25F     = "bm25f"
+	ScorerBM25Plus  = "bm25+"
+	ScorerBM25L     = "bm25l"
+	ScorerTFIDF     = "tfidf"
+	ScorerDirichlet = "dirichlet"
+)
+
+// ScorerNames lists the names of all scorers
+var ScorerNames = []string{ScorerBM25F, ScorerBM25, ScorerBM25Plus, ScorerBM25L, ScorerTFIDF, ScorerDirichlet}
+
+// Default parameters of the scorers, used when they are not configured
+const (
+	// DefaultBM25PlusDelta is the lower bound BM25+ adds to the score of every matching term
+	DefaultBM25PlusDelta = 1.0
+	// DefaultBM25LDelta shifts the length normalized term frequency in BM25L
+	DefaultBM25LDelta = 0.5
+	// DefaultDirichletMu is the amount of Dirichlet smoothing; smaller values suit short documents
+	DefaultDirichletMu = 2000.0
+)
+
+// DefaultFieldWeights are the BM25F field weights used when none are configured.
+// Matches in the file name count most, since notes are usually named after
+// their topic.
+var DefaultFieldWeights = core.FieldWeights{Body: 1, Filename: 3, Path: 1, Heading: 2, Title: 3, Tags: 2}
+
+// Scorer computes the relevance of documents to a query. Scores are only
+// compared with each other, so any non-negative scale works; the ranking
+// normalizes them by the best score of the whole index.
+type Scorer interface {
+	// Name returns the name the scorer is selected by
+	Name() string
+	// Score returns the raw scores of the live documents of a segment
+	// containing at least one of the terms, using the corpus statistics of the
+	// whole index
+	Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64
+}
+
+// NewScorer returns the scorer selected by the ranking configuration, with its
+// configured parameters. Unset parameters and an empty name select the defaults.
+func NewScorer(rankingCfg *core.RankingConfig) (Scorer, error) {
+	k1, b := bm25Parameters(rankingCfg)
+	if rankingCfg == nil {
+		return NewBM25F(k1, b, DefaultFieldWeights), nil
+	}
+
+	switch name := strings.ToLower(strings.TrimSpace(rankingCfg.Scorer)); name {
+	case "", ScorerBM25F:
+		return NewBM25F(k1, b, fieldWeights(rankingCfg)), nil
+	case ScorerBM25:
+		return NewBM25(k1, b), nil
+	case ScorerBM25Plus:
+		return &BM25Plus{K1: k1, B: b, Delta: DefaultBM25PlusDelta}, nil
+	case ScorerBM25L:
+		return &BM25L{K1: k1, B: b, Delta: DefaultBM25LDelta}, nil
+	case ScorerTFIDF:
+		return TFIDF{}, nil
+	case ScorerDirichlet:
+		mu := rankingCfg.DirichletMu
+		if mu <= 0 {
+			mu = DefaultDirichletMu
+		}
+		return &Dirichlet{Mu: mu}, nil
+	default:
+		return nil, fmt.Errorf("unknown scorer %q, expected one of %s", name, strings.Join(ScorerNames, ", "))
+	}
+}
+
+// bm25Parameters returns the configured k1 and b of the BM25 family, with
+// defaults for unset or invalid values. A b of 0 turns off length normalization.
+func bm25Parameters(rankingCfg *core.RankingConfig) (k1, b float64) {
+	k1, b = DefaultBM25K1, DefaultBM25B
+	if rankingCfg == nil {
+		return k1, b
+	}
+	if rankingCfg.BM25K1 > 0 {
+		k1 = rankingCfg.BM25K1
+	}
+	if configured := rankingCfg.BM25B; configured != nil {
+		if *configured >= 0 && *configured <= 1 {
+			b = *configured
+		} else {
+			logger.Warnf("Ignoring ranking.bm25_b = %g outside [0, 1], using %g", *configured, DefaultBM25B)
+		}
+	}
+	return k1, b
+}
+
+// fieldWeights returns the configured BM25F field weights. If none is set, the
+// defaults are used; negative weights are treated as 0.
+func fieldWeights(rankingCfg *core.RankingConfig) core.FieldWeights {
+	weights := rankingCfg.FieldWeights
+	if weights == (core.FieldWeights{}) {
+		return DefaultFieldWeights
+	}
+	weights.Body = math.Max(weights.Body, 0)
+	weights.Filename = math.Max(weights.Filename, 0)
+	weights.Path = math.Max(weights.Path, 0)
+	weights.Heading = math.Max(weights.Heading, 0)
+	weights.Title = math.Max(weights.Title, 0)
+	weights.Tags = math.Max(weights.Tags, 0)
+	return weights
+}
+
+// accumulateTermScores sums the scores of every term over the live documents
+// containing it. termScorer is called once per term and returns the function
+// scoring the term in a single document.
+func accumulateTermScores(segment core.SegmentReader, terms []string, stats *CorpusStats, termScorer func(term string) func(tf, docLen float64) float64) map[uint]float64 {
+	scores := make(map[uint]float64)
+
+	// Handle empty index
+	if segment == nil || stats == nil || stats.TotalDocs == 0 {
+		return scores
+	}
+
+	for _, term := range terms {
+		postings := segment.Postings(term)
+		if len(postings) == 0 {
+			continue
+		}
+
+		score := termScorer(term)
+		for _, posting := range postings {
+			// Postings with a Freq of 0 only match a field, which only BM25F scores
+			if posting.Freq == 0 || segment.IsDeleted(posting.DocID) {
+				continue
+			}
+			scores[posting.DocID] += score(float64(posting.Freq), documentLength(segment, posting.DocID))
+		}
+	}
+
+	return scores
+}
+
+// BM25 is the Okapi BM25 scorer
+type BM25 struct {
+	K1 float64 // Term frequency saturation
+	B  float64 // Document length normalization, from 0 (none) to 1 (full)
+}
+
+// NewBM25 returns a BM25 scorer with the given parameters
+func NewBM25(k1, b float64) *BM25 {
+	return &BM25{K1: k1, B: b}
+}
+
+// Name returns "bm25"
+func (s *BM25) Name() string {
+	return ScorerBM25
+}
+
+// Score computes BM25 scores
+// Formula: IDF × (tf × (k1 + 1)) / (tf + k1 × (1 - b + b × (docLen / avgDocLen)))
+func (s *BM25) Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64 {
+	return accumulateTermScores(segment, terms, stats, func(term string) func(tf, docLen float64) float64 {
+		idf := calculateIDF(stats.DocFreq[term], stats.TotalDocs)
+		return func(tf, docLen float64) float64 {
+			return calculateTermBM25(tf, idf, docLen, stats.AvgDocLen, s.K1, s.B)
+		}
+	})
+}
+
+// BM25F is BM25 over several fields of a document: its contents (the body),
+// file name, parent directories and headings. The length normalized term
+// frequencies of every field are weighted and summed before saturation, so a
+// term matching the file name of a short note outranks a long document
+// mentioning it in passing. Without field data it scores like BM25.
+type BM25F struct {
+	K1      float64
+	B       float64 // Length normalization of every field
+	Weights core.FieldWeights
+}
+
+// NewBM25F returns a BM25F scorer with the given parameters and field weights
+func NewBM25F(k1, b float64, weights core.FieldWeights) *BM25F {
+	return &BM25F{K1: k1, B: b, Weights: weights}
+}
+
+// Name returns "bm25f"
+func (s *BM25F) Name() string {
+	return ScorerBM25F
+}
+
+// weight returns the weight of a field
+func (s *BM25F) weight(field core.Field) float64 {
+	switch field {
+	case core.FieldFilename:
+		return s.Weights.Filename
+	case core.FieldPath:
+		return s.Weights.Path
+	case core.FieldHeading:
+		return s.Weights.Heading
+	case core.FieldTitle:
+		return s.Weights.Title
+	case core.FieldTags:
+		return s.Weights.Tags
+	}
+	return 0
+}
+
+// Score computes BM25F scores
+// Formula: IDF × (tf' × (k1 + 1)) / (tf' + k1), where
+// tf' = Σ weight(f) × tf(f) / (1 - b + b × (len(f) / avgLen(f))) over all fields f
+func (s *BM25F) Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64 {
+	scores := make(map[uint]float64)
+
+	// Handle empty index
+	if segment == nil || stats == nil || stats.TotalDocs == 0 {
+		return scores
+	}
+
+	for _, term := range terms {
+		postings := segment.Postings(term)
+		if len(postings) == 0 {
+			continue
+		}
+
+		idf := calculateIDF(stats.DocFreq[term], stats.TotalDocs)
+		for _, posting := range postings {
+			if segment.IsDeleted(posting.DocID) {
+				continue
+			}
+			doc, ok := segment.Document(posting.DocID)
+			if !ok {
+				continue
+			}
+
+			tf := s.Weights.Body * float64(posting.Freq) / lengthNormalization(float64(doc.TokenCount), stats.AvgDocLen, s.B)
+			for field := core.FieldFilename; field < core.NumFields; field++ {
+				if freq := posting.FieldFreq(field); freq > 0 {
+					tf += s.weight(field) * float64(freq) / lengthNormalization(float64(doc.FieldLength(field)), stats.AvgFieldLen[field], s.B)
+				}
+			}
+			if tf > 0 {
+				scores[posting.DocID] += idf * tf * (s.K1 + 1) / (tf + s.K1)
+			}
+		}
+	}
+
+	return scores
+}
+
+// BM25Plus is BM25 with a lower bound for every matching term, so that very
+// long documents containing a term still score higher than documents without it
+type BM25Plus struct {
+	K1    float64
+	B     float64
+	Delta float64 // Lower bound of the term frequency component
+}
+
+// Name returns "bm25+"
+func (s *BM25Plus) Name() string {
+	return ScorerBM25Plus
+}
+
+// Score computes BM25+ scores
+// Formula: IDF × ((tf × (k1 + 1)) / (tf + k1 × (1 - b + b × (docLen / avgDocLen))) + δ)
+func (s *BM25Plus) Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64 {
+	return accumulateTermScores(segment, terms, stats, func(term string) func(tf, docLen float64) float64 {
+		idf := calculateIDF(stats.DocFreq[term], stats.TotalDocs)
+		return func(tf, docLen float64) float64 {
+			return calculateTermBM25(tf, idf, docLen, stats.AvgDocLen, s.K1, s.B) + idf*s.Delta
+		}
+	})
+}
+
+// BM25L is BM25 with a shifted length normalized term frequency, which
+// penalizes long documents less than BM25
+type BM25L struct {
+	K1    float64
+	B     float64
+	Delta float64 // Shift of the length normalized term frequency
+}
+
+// Name returns "bm25l"
+func (s *BM25L) Name() string {
+	return ScorerBM25L
+}
+
+// Score computes BM25L scores
+// Formula: IDF × ((k1 + 1) × (c + δ)) / (k1 + c + δ), where c = tf / (1 - b + b × (docLen / avgDocLen))
+func (s *BM25L) Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64 {
+	return accumulateTermScores(segment, terms, stats, func(term string) func(tf, docLen float64) float64 {
+		idf := calculateIDF(stats.DocFreq[term], stats.TotalDocs)
+		return func(tf, docLen float64) float64 {
+			c := tf/lengthNormalization(docLen, stats.AvgDocLen, s.B) + s.Delta
+			return idf * ((s.K1 + 1) * c) / (s.K1 + c)
+		}
+	})
+}
+
+// TFIDF scores documents by the cosine similarity of their TF-IDF vectors to
+// the vector of the query, as the VSM part of the combined score does
+type TFIDF struct{}
+
+// Name returns "tfidf"
+func (TFIDF) Name() string {
+	return ScorerTFIDF
+}
+
+// Score computes TF-IDF cosine similarities
+func (TFIDF) Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64 {
+	return CalculateSegmentVSMScores(segment, BuildCorpusQueryVector(terms, stats), terms, stats)
+}
+
+// Dirichlet is a query likelihood language model with Dirichlet smoothing.
+// Each term contributes how much more likely it is in the document than in
+// the whole index; terms less likely than that contribute nothing.
+type Dirichlet struct {
+	Mu float64 // Amount of smoothing with the collection language model
+}
+
+// Name returns "dirichlet"
+func (s *Dirichlet) Name() string {
+	return ScorerDirichlet
+}
+
+// Score computes Dirichlet smoothed language model scores
+// Formula: max(0, ln(1 + tf / (μ × P(t|C))) + ln(μ / (docLen + μ)))
+func (s *Dirichlet) Score(segment core.SegmentReader, terms []string, stats *CorpusStats) map[uint]float64 {
+	return accumulateTermScores(segment, terms, stats, func(term string) func(tf, docLen float64) float64 {
+		// Probability of the term in the whole index, smoothed so that it is never 0
+		collectionProb := (float64(stats.CollectionFreq[term]) + 1) / (stats.CollectionLength() + 1)
+		return func(tf, docLen float64) float64 {
+			score := math.Log(1+tf/(s.Mu*collectionProb)) + math.Log(s.Mu/(docLen+s.Mu))
+			return math.Max(score, 0)
+		}
+	})
+}
@@ -0,0 +1,250 @@
+package query
+
+import (
+	"math"
+	"testing"
+
+	"mneme/internal/core"
+)
+
+func TestNewScorer(t *testing.T) {
+	for _, name := range ScorerNames {
+		scorer, err := NewScorer(&core.RankingConfig{Scorer: name})
+		if err != nil {
+			t.Fatalf("NewScorer(%q) failed: %v", name, err)
+		}
+		if scorer.Name() != name {
+			t.Errorf("NewScorer(%q) returned %s", name, scorer.Name())
+		}
+	}
+
+	t.Run("defaults", func(t *testing.T) {
+		invalidB, negativeB := 2.0, -0.5
+		for _, cfg := range []*core.RankingConfig{nil, {}, {BM25K1: -1, BM25B: &invalidB}, {BM25B: &negativeB}} {
+			scorer, err := NewScorer(cfg)
+			if err != nil {
+				t.Fatalf("NewScorer failed: %v", err)
+			}
+			bm25f, ok := scorer.(*BM25F)
+			if !ok || bm25f.K1 != DefaultBM25K1 || bm25f.B != DefaultBM25B || bm25f.Weights != DefaultFieldWeights {
+				t.Errorf("Expected BM25F with default parameters for %+v, got %+v", cfg, scorer)
+			}
+		}
+
+		scorer, _ := NewScorer(&core.RankingConfig{Scorer: ScorerDirichlet})
+		if mu := scorer.(*Dirichlet).Mu; mu != DefaultDirichletMu {
+			t.Errorf("Expected the default mu, got %f", mu)
+		}
+	})
+
+	t.Run("configured parameters", func(t *testing.T) {
+		b := 0.3
+		scorer, err := NewScorer(&core.RankingConfig{Scorer: "BM25+", BM25K1: 0.9, BM25B: &b})
+		if err != nil {
+			t.Fatalf("NewScorer failed: %v", err)
+		}
+		bm25Plus := scorer.(*BM25Plus)
+		if bm25Plus.K1 != 0.9 || bm25Plus.B != 0.3 {
+			t.Errorf("Expected the configured parameters, got %+v", bm25Plus)
+		}
+
+		// b = 0 turns off length normalization instead of selecting the default
+		b = 0
+		scorer, _ = NewScorer(&core.RankingConfig{Scorer: ScorerBM25, BM25B: &b})
+		if bm25 := scorer.(*BM25); bm25.B != 0 {
+			t.Errorf("Expected b = 0, got %f", bm25.B)
+		}
+	})
+
+	t.Run("configured field weights", func(t *testing.T) {
+		scorer, err := NewScorer(&core.RankingConfig{FieldWeights: core.FieldWeights{Body: 2, Filename: -1}})
+		if err != nil {
+			t.Fatalf("NewScorer failed: %v", err)
+		}
+		if weights := scorer.(*BM25F).Weights; weights != (core.FieldWeights{Body: 2}) {
+			t.Errorf("Expected the configured weights without negatives, got %+v", weights)
+		}
+	})
+
+	t.Run("unknown scorer", func(t *testing.T) {
+		if _, err := NewScorer(&core.RankingConfig{Scorer: "pagerank"}); err == nil {
+			t.Error("Expected an error for an unknown scorer")
+		}
+	})
+}
+
+// scoreTestSegment scores the test segment with a scorer
+func scoreTestSegment(scorer Scorer, segment *core.Segment, terms []string) map[uint]float64 {
+	index := core.NewIndexReader(segment)
+	return scorer.Score(index.Segments[0], terms, NewCorpusStats(index, terms))
+}
+
+func TestScorers(t *testing.T) {
+	segment := createTestSegment()
+	terms := []string{"user", "config"}
+
+	for _, name := range ScorerNames {
+		t.Run(name, func(t *testing.T) {
+			scorer, _ := NewScorer(&core.RankingConfig{Scorer: name})
+			scores := scoreTestSegment(scorer, segment, terms)
+			if len(scores) != 3 {
+				t.Fatalf("Expected scores for all documents, got %v", scores)
+			}
+			for docID, score := range scores {
+				if score < 0 || math.IsNaN(score) {
+					t.Errorf("Invalid score %f for document %d", score, docID)
+				}
+			}
+			// Document 2 contains both terms, "config" five times
+			if scores[2] <= scores[3] {
+				t.Errorf("Expected document 2 to outscore document 3, got %v", scores)
+			}
+		})
+	}
+
+	t.Run("bm25 matches CalculateBM25Scores", func(t *testing.T) {
+		expected := CalculateBM25Scores(segment, terms)
+		scores := scoreTestSegment(NewBM25(DefaultBM25K1, DefaultBM25B), segment, terms)
+		for docID, score := range expected {
+			if math.Abs(scores[docID]-score) > 1e-9 {
+				t.Errorf("Document %d: expected %f, got %f", docID, score, scores[docID])
+			}
+		}
+	})
+
+	t.Run("bm25f without fields matches bm25", func(t *testing.T) {
+		expected := scoreTestSegment(NewBM25(DefaultBM25K1, DefaultBM25B), segment, terms)
+		scores := scoreTestSegment(NewBM25F(DefaultBM25K1, DefaultBM25B, DefaultFieldWeights), segment, terms)
+		for docID, score := range expected {
+			if math.Abs(scores[docID]-score) > 1e-9 {
+				t.Errorf("Document %d: expected %f, got %f", docID, score, scores[docID])
+			}
+		}
+	})
+
+	t.Run("bm25 b controls length normalization", func(t *testing.T) {
+		// Document 1 is shorter than average, document 2 longer
+		full := scoreTestSegment(NewBM25(DefaultBM25K1, 1), segment, []string{"user"})
+		none := scoreTestSegment(NewBM25(DefaultBM25K1, 0), segment, []string{"user"})
+		if full[1] <= none[1] || full[2] >= none[2] {
+			t.Errorf("Expected length normalization to favour the short document, got %v and %v", full, none)
+		}
+	})
+
+	t.Run("bm25+ lower bound", func(t *testing.T) {
+		bm25 := scoreTestSegment(NewBM25(DefaultBM25K1, DefaultBM25B), segment, []string{"user"})
+		bm25Plus := scoreTestSegment(&BM25Plus{K1: DefaultBM25K1, B: DefaultBM25B, Delta: DefaultBM25PlusDelta}, segment, []string{"user"})
+		idf := calculateIDF(2, 3)
+		for docID, score := range bm25 {
+			if math.Abs(bm25Plus[docID]-score-idf*DefaultBM25PlusDelta) > 1e-9 {
+				t.Errorf("Document %d: expected BM25 + idf × delta, got %f and %f", docID, bm25Plus[docID], score)
+			}
+		}
+	})
+
+	t.Run("dirichlet ignores terms less likely than in the index", func(t *testing.T) {
+		// "data" occurs once in the 50 tokens of document 1 but 8 times in 225 tokens overall
+		scores := scoreTestSegment(&Dirichlet{Mu: 10}, segment, []string{"data"})
+		if scores[1] != 0 {
+			t.Errorf("Expected no score for document 1, got %f", scores[1])
+		}
+		if scores[3] <= 0 {
+			t.Errorf("Expected a positive score for document 3, got %f", scores[3])
+		}
+	})
+
+	t.Run("deleted documents are skipped", func(t *testing.T) {
+		deleted := createTestSegment()
+		deleted.Deleted = core.NewDeletionBitmap(2)
+		deleted.TotalDocs = 2
+		for _, name := range ScorerNames {
+			scorer, _ := NewScorer(&core.RankingConfig{Scorer: name})
+			if _, ok := scoreTestSegment(scorer, deleted, terms)[2]; ok {
+				t.Errorf("%s scored a deleted document", name)
+			}
+		}
+	})
+}
+
+func TestRankIndexWithScorer(t *testing.T) {
+	index := core.NewIndexReader(createTestSegment())
+
+	for _, name := range ScorerNames {
+		results := RankIndex(index, []string{"config"}, nil, 10, &core.RankingConfig{Scorer: name})
+		if len(results) != 2 || results[0].DocID != 2 {
+			t.Errorf("%s: expected document 2 first, got %v", name, results)
+		}
+	}
+
+	// Unknown scorers fall back to BM25F
+	fallback := RankIndex(index, []string{"config"}, nil, 10, &core.RankingConfig{Scorer: "pagerank"})
+	bm25f := RankIndex(index, []string{"config"}, nil, 10, &core.RankingConfig{Scorer: ScorerBM25F})
+	if formatResults(fallback) != formatResults(bm25f) {
+		t.Errorf("Expected the BM25F ranking, got %s", formatResults(fallback))
+	}
+}
+
+// createFieldTestSegment creates a segment of three notes about deployments:
+// a short note named after the topic and two longer notes mentioning it more often
+func createFieldTestSegment() *core.Segment {
+	return &core.Segment{
+		Docs: []core.Document{
+			{ID: 1, Path: "/notes/deploy.md", TokenCount: 10, FieldLengths: []uint{1, 1, 2}},
+			{ID: 2, Path: "/notes/meetings/weekly.md", TokenCount: 40, FieldLengths: []uint{1, 1, 1}},
+			{ID: 3, Path: "/notes/journal/monday.md", TokenCount: 40, FieldLengths: []uint{1, 1, 1}},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"deploy": {
+				{DocID: 1, Freq: 1, FieldFreqs: []uint{1, 0, 1}},
+				{DocID: 2, Freq: 3},
+				{DocID: 3, Freq: 2},
+			},
+			"meet": {
+				{DocID: 2, FieldFreqs: []uint{0, 1, 0}},
+			},
+		},
+		TotalDocs:   3,
+		TotalTokens: 90,
+		AvgDocLen:   30,
+	}
+}
+
+func TestBM25FFields(t *testing.T) {
+	segment := createFieldTestSegment()
+
+	t.Run("file name matches rank first", func(t *testing.T) {
+		index := core.NewIndexReader(segment)
+		results := RankIndex(index, []string{"deploy"}, nil, 10, &core.RankingConfig{Scorer: ScorerBM25F})
+		if len(results) != 3 || results[0].Path != "/notes/deploy.md" {
+			t.Errorf("Expected deploy.md first, got %s", formatResults(results))
+		}
+
+		results = RankIndex(index, []string{"deploy"}, nil, 10, &core.RankingConfig{Scorer: ScorerBM25})
+		if len(results) != 3 || results[0].Path == "/notes/deploy.md" {
+			t.Errorf("Expected BM25 to ignore the file name, got %s", formatResults(results))
+		}
+	})
+
+	t.Run("field only terms", func(t *testing.T) {
+		scores := scoreTestSegment(NewBM25F(DefaultBM25K1, DefaultBM25B, DefaultFieldWeights), segment, []string{"meet"})
+		if scores[2] <= 0 {
+			t.Errorf("Expected a score for the directory match, got %v", scores)
+		}
+
+		for _, name := range []string{ScorerBM25, ScorerBM25Plus, ScorerBM25L, ScorerDirichlet} {
+			scorer, _ := NewScorer(&core.RankingConfig{Scorer: name})
+			if scores := scoreTestSegment(scorer, segment, []string{"meet"}); len(scores) != 0 {
+				t.Errorf("%s scored a term missing from the contents: %v", name, scores)
+			}
+		}
+	})
+
+	t.Run("zero weight ignores a field", func(t *testing.T) {
+		weights := DefaultFieldWeights
+		weights.Path = 0
+		scores := scoreTestSegment(NewBM25F(DefaultBM25K1, DefaultBM25B, weights), segment, []string{"meet"})
+		if len(scores) != 0 {
+			t.Errorf("Expected no scores, got %v", scores)
+		}
+	})
+}
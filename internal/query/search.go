@@ -0,0 +1,141 @@
+package query
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"mneme/internal/core"
+)
+
+// ErrNoSearchTerms is returned when a query has no words left to search for,
+// e.g. because it only consists of stopwords or filters
+var ErrNoSearchTerms = errors.New("no valid search tokens found in query")
+
+// Search is a query prepared against an index: parsed, auto-corrected with the
+// vocabulary of the index and ready to be ranked. It is shared by every
+// frontend that runs queries, such as mneme find and mneme serve.
+type Search struct {
+	// Text is the query as shown to the user, with corrections applied to
+	// plain queries
+	Text string
+	// Corrections maps misspelled words to the terms they were corrected to
+	Corrections map[string]string
+	// HighlightTerms are the words and phrases searched for, as typed and
+	// corrected, for building snippets
+	HighlightTerms []string
+	// Explain makes Rank attach the breakdown of their score to the results
+	Explain bool
+
+	boolean *Query   // Set for queries with operators, prefixes, grouping or filters
+	tokens  []string // Stemmed tokens of a plain query
+	phrases []Phrase // Phrases of a plain query, which every result must contain
+}
+
+// PrepareSearch parses and auto-corrects a query given as command line style
+// arguments, where arguments containing whitespace are phrases. Queries with
+// operators are evaluated as boolean queries; all other queries keep the plain
+// semantics of optional terms and required phrases. It returns an error
+// wrapping ErrInvalidQuery for malformed queries and ErrNoSearchTerms when
+// nothing is left to search for.
+func PrepareSearch(index *core.IndexReader, args []string) (*Search, error) {
+	search := &Search{Text: strings.TrimSpace(strings.Join(args, " "))}
+	if search.Text == "" {
+		return nil, ErrNoSearchTerms
+	}
+
+	booleanQuery, err := ParseBooleanQuery(QueryTextFromArgs(args))
+	if err != nil {
+		return nil, err
+	}
+
+	if booleanQuery.HasOperators() {
+		search.Corrections = booleanQuery.AutoCorrect(index.Vocabulary())
+		if len(booleanQuery.Terms()) == 0 {
+			return nil, ErrNoSearchTerms
+		}
+
+		search.boolean = booleanQuery
+		search.HighlightTerms = booleanQuery.HighlightTerms()
+		return search, nil
+	}
+
+	// Quoted phrases must match exactly, so they are kept out of auto-correction
+	search.phrases = ExtractPhrases(args)
+
+	// Auto-correct typos in the raw query terms before tokenizing
+	search.HighlightTerms, search.Corrections = autoCorrectArgs(index.Vocabulary(), args)
+	if len(search.Corrections) > 0 {
+		search.Text = strings.Join(search.HighlightTerms, " ")
+	}
+
+	search.tokens = ParseQuery(search.Text)
+	if len(search.tokens) == 0 {
+		return nil, ErrNoSearchTerms
+	}
+	return search, nil
+}
+
+// Rank ranks the documents of the index for the search and returns at most
+// limit results
+func (s *Search) Rank(index *core.IndexReader, limit int, cfg *core.RankingConfig) []core.RankedDocument {
+	if s.boolean != nil {
+		return rankIndex(index, s.boolean.Terms(), s.boolean.Phrases(), s.boolean, limit, cfg, s.Explain)
+	}
+	return rankIndex(index, s.tokens, s.phrases, nil, limit, cfg, s.Explain)
+}
+
+// autoCorrectArgs auto-corrects the plain terms of a query and leaves phrase
+// arguments untouched. Phrases are returned first, followed by the corrected terms.
+func autoCorrectArgs(vocabulary []string, args []string) ([]string, map[string]string) {
+	var phraseArgs, terms []string
+	for _, arg := range args {
+		if IsPhraseArg(arg) {
+			phraseArgs = append(phraseArgs, arg)
+		} else {
+			terms = append(terms, arg)
+		}
+	}
+
+	if len(phraseArgs) == 0 {
+		return AutoCorrectQueryWithVocabulary(vocabulary, args)
+	}
+
+	correctedTerms, corrections := AutoCorrectQueryWithVocabulary(vocabulary, terms)
+	return append(phraseArgs, correctedTerms...), corrections
+}
+
+// SplitQueryArgs splits a query string into arguments the way a shell would:
+// at whitespace, except inside double quotes, which are removed. It turns a
+// query received as a single string into the arguments PrepareSearch expects.
+// Queries using operators or field filters are kept as a single argument,
+// since the boolean parser needs their quotes.
+func SplitQueryArgs(text string) []string {
+	if hasQuerySyntax(text) {
+		if text = strings.TrimSpace(text); text == "" {
+			return nil
+		}
+		return []string{text}
+	}
+
+	var args []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range text {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+		case unicode.IsSpace(r) && !inQuotes:
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return args
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"mneme/internal/core"
+)
+
+func TestSplitQueryArgs(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"", nil},
+		{"  kubernetes   helm ", []string{"kubernetes", "helm"}},
+		{`deploy "blue green" rollout`, []string{"deploy", "blue green", "rollout"}},
+		{"kubernetes -helm", []string{"kubernetes -helm"}},
+		{"ext:md postgres", []string{"ext:md postgres"}},
+	}
+
+	for _, tt := range tests {
+		if got := SplitQueryArgs(tt.text); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("SplitQueryArgs(%q) = %q, expected %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestPrepareSearch(t *testing.T) {
+	index := core.NewIndexReader(createBooleanTestSegment())
+
+	t.Run("plain query with correction", func(t *testing.T) {
+		search, err := PrepareSearch(index, []string{"mysqk", "migration"})
+		if err != nil {
+			t.Fatalf("PrepareSearch failed: %v", err)
+		}
+		if len(search.Corrections) == 0 {
+			t.Errorf("Expected mysqk to be corrected, got %v", search.Corrections)
+		}
+		results := search.Rank(index, 10, nil)
+		if len(results) == 0 || results[0].Path != "mysql-migration.md" {
+			t.Errorf("Expected mysql-migration.md first, got %v", results)
+		}
+	})
+
+	t.Run("boolean query", func(t *testing.T) {
+		search, err := PrepareSearch(index, SplitQueryArgs("kubernetes -helm"))
+		if err != nil {
+			t.Fatalf("PrepareSearch failed: %v", err)
+		}
+		results := search.Rank(index, 10, nil)
+		if len(results) != 1 || results[0].Path != "k8s.md" {
+			t.Errorf("Expected only k8s.md, got %v", results)
+		}
+	})
+
+	t.Run("nothing to search for", func(t *testing.T) {
+		if _, err := PrepareSearch(index, nil); !errors.Is(err, ErrNoSearchTerms) {
+			t.Errorf("Expected ErrNoSearchTerms, got %v", err)
+		}
+	})
+
+	t.Run("malformed query", func(t *testing.T) {
+		if _, err := PrepareSearch(index, []string{"(kubernetes"}); !errors.Is(err, ErrInvalidQuery) {
+			t.Errorf("Expected ErrInvalidQuery, got %v", err)
+		}
+	})
+}
+
+func TestSuggestTerms(t *testing.T) {
+	index := core.NewIndexReader(createBooleanTestSegment())
+
+	suggestions := SuggestTerms(index, "Mon", 10)
+	expected := []Suggestion{{Term: "mongo", DocFreq: 1}, {Term: "mongodump", DocFreq: 1}}
+	if !reflect.DeepEqual(suggestions, expected) {
+		t.Errorf("Expected %v, got %v", expected, suggestions)
+	}
+
+	if suggestions := SuggestTerms(index, "m", 1); len(suggestions) != 1 || suggestions[0].Term != "migrat" {
+		t.Errorf("Expected the most frequent term only, got %v", suggestions)
+	}
+
+	if suggestions := SuggestTerms(index, "mysqk", 10); len(suggestions) != 1 || suggestions[0].Term != "mysql" {
+		t.Errorf("Expected a fuzzy suggestion, got %v", suggestions)
+	}
+
+	if suggestions := SuggestTerms(index, "", 10); suggestions == nil || len(suggestions) != 0 {
+		t.Errorf("Expected an empty list, got %v", suggestions)
+	}
+}
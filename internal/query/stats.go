@@ -0,0 +1,112 @@
+package query
+
+import (
+	"mneme/internal/core"
+)
+
+// CorpusStats holds the statistics of the whole index that the scores of any single
+// segment are computed with, so that scores of different segments are comparable.
+type CorpusStats struct {
+	TotalDocs      int                     // Live documents in the index
+	AvgDocLen      float64                 // Average document length in the index
+	AvgFieldLen    [core.NumFields]float64 // Average length of every field in the index
+	DocFreq        map[string]int          // Live documents containing each query term, across all segments
+	CollectionFreq map[string]int          // Occurrences of each query term in live documents, across all segments
+}
+
+// NewCorpusStats computes the corpus statistics of an index for the given terms.
+// Document frequencies are summed over all segments, skipping deleted documents.
+func NewCorpusStats(index *core.IndexReader, terms []string) *CorpusStats {
+	stats := &CorpusStats{
+		TotalDocs:      int(index.TotalDocs),
+		AvgDocLen:      float64(index.AvgDocLen),
+		AvgFieldLen:    index.AvgFieldLengths(),
+		DocFreq:        make(map[string]int, len(terms)),
+		CollectionFreq: make(map[string]int, len(terms)),
+	}
+
+	for _, term := range terms {
+		if _, done := stats.DocFreq[term]; done {
+			continue
+		}
+		df, cf := 0, 0
+		for _, segment := range index.Segments {
+			segmentDF, segmentCF := liveTermStats(segment, term)
+			df += segmentDF
+			cf += segmentCF
+		}
+		stats.DocFreq[term] = df
+		stats.CollectionFreq[term] = cf
+	}
+
+	// If avgDocLen is 0, calculate it from documents
+	if stats.AvgDocLen == 0 && stats.TotalDocs > 0 {
+		var totalTokens, docCount uint
+		for _, segment := range index.Segments {
+			for _, doc := range segment.Documents() {
+				totalTokens += doc.TokenCount
+				docCount++
+			}
+		}
+		if docCount > 0 {
+			stats.AvgDocLen = float64(totalTokens) / float64(docCount)
+		}
+		if stats.AvgDocLen == 0 {
+			stats.AvgDocLen = 1
+		}
+	}
+
+	return stats
+}
+
+// liveDocFrequency returns the number of live documents of a segment containing a term
+func liveDocFrequency(segment core.SegmentReader, term string) int {
+	df := 0
+	for _, posting := range segment.Postings(term) {
+		if !segment.IsDeleted(posting.DocID) {
+			df++
+		}
+	}
+	return df
+}
+
+// liveTermStats returns the number of live documents of a segment containing a
+// term and the number of occurrences of the term in them
+func liveTermStats(segment core.SegmentReader, term string) (df, cf int) {
+	for _, posting := range segment.Postings(term) {
+		if !segment.IsDeleted(posting.DocID) {
+			df++
+			cf += int(posting.Freq)
+		}
+	}
+	return df, cf
+}
+
+// CollectionLength returns the number of tokens in all live documents
+func (s *CorpusStats) CollectionLength() float64 {
+	return float64(s.TotalDocs) * s.AvgDocLen
+}
+
+// termFrequencies returns the frequency of a term per live document of a segment
+func termFrequencies(segment core.SegmentReader, term string) map[uint]uint {
+	postings := segment.Postings(term)
+	freqs := make(map[uint]uint, len(postings))
+	for _, posting := range postings {
+		if segment.IsDeleted(posting.DocID) {
+			continue
+		}
+		if _, seen := freqs[posting.DocID]; !seen {
+			freqs[posting.DocID] = posting.Freq
+		}
+	}
+	return freqs
+}
+
+// documentLength returns the length of a document used for length normalization
+func documentLength(segment core.SegmentReader, docID uint) float64 {
+	doc, ok := segment.Document(docID)
+	if !ok {
+		return 0
+	}
+	return float64(doc.TokenCount)
+}
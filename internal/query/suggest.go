@@ -0,0 +1,63 @@
+package query
+
+import (
+	"sort"
+	"strings"
+
+	"mneme/internal/core"
+)
+
+// maxSuggestionCandidates limits how many vocabulary terms sharing a prefix
+// are looked up for their document frequency
+const maxSuggestionCandidates = 1000
+
+// Suggestion is an index term offered to complete or correct a query word
+type Suggestion struct {
+	Term    string `json:"term"`
+	DocFreq int    `json:"doc_freq"` // Live documents containing the term
+}
+
+// SuggestTerms returns up to limit index terms starting with prefix, the most
+// frequent first. If no term has the prefix, the closest fuzzy match of the
+// prefix is suggested instead. Terms are stemmed, as stored in the index.
+func SuggestTerms(index *core.IndexReader, prefix string, limit int) []Suggestion {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	if prefix == "" || limit <= 0 {
+		return []Suggestion{}
+	}
+
+	vocabulary := index.Vocabulary()
+	start := sort.SearchStrings(vocabulary, prefix)
+	end := start
+	for end < len(vocabulary) && end-start < maxSuggestionCandidates && strings.HasPrefix(vocabulary[end], prefix) {
+		end++
+	}
+
+	candidates := vocabulary[start:end]
+	if len(candidates) == 0 {
+		corrected, corrections := AutoCorrectQueryWithVocabulary(vocabulary, []string{prefix})
+		if len(corrections) == 0 {
+			return []Suggestion{}
+		}
+		candidates = corrected
+	}
+
+	suggestions := make([]Suggestion, 0, len(candidates))
+	for _, term := range candidates {
+		df := 0
+		for _, segment := range index.Segments {
+			df += liveDocFrequency(segment, term)
+		}
+		if df > 0 {
+			suggestions = append(suggestions, Suggestion{Term: term, DocFreq: df})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].DocFreq > suggestions[j].DocFreq
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions
+}
@@ -7,16 +7,21 @@ import (
 
 // BuildQueryTFIDFVector creates a TF-IDF vector for the query terms
 func BuildQueryTFIDFVector(segment *core.Segment, tokens []string) *core.TFIDFVector {
-	vector := &core.TFIDFVector{
-		Weights: make(map[string]float64),
+	if segment == nil || len(tokens) == 0 {
+		return &core.TFIDFVector{Weights: make(map[string]float64)}
 	}
 
-	if segment == nil || len(tokens) == 0 {
-		return vector
+	return BuildCorpusQueryVector(tokens, NewCorpusStats(core.NewIndexReader(segment), tokens))
+}
+
+// BuildCorpusQueryVector creates a TF-IDF vector for the query terms using the
+// corpus statistics of the whole index
+func BuildCorpusQueryVector(tokens []string, stats *CorpusStats) *core.TFIDFVector {
+	vector := &core.TFIDFVector{
+		Weights: make(map[string]float64),
 	}
 
-	totalDocs := int(segment.TotalDocs)
-	if totalDocs == 0 {
+	if stats == nil || len(tokens) == 0 || stats.TotalDocs == 0 {
 		return vector
 	}
 
@@ -30,13 +35,13 @@ func BuildQueryTFIDFVector(segment *core.Segment, tokens []string) *core.TFIDFVe
 	var sumSquares float64
 	for token, tf := range queryTF {
 		// Get document frequency
-		df := GetDocumentFrequency(segment, token)
+		df := stats.DocFreq[token]
 		if df == 0 {
 			continue // Skip terms not in index
 		}
 
 		// Calculate IDF using the same formula as BM25 for consistency
-		idf := calculateIDF(df, totalDocs)
+		idf := calculateIDF(df, stats.TotalDocs)
 
 		// TF-IDF weight (using log-normalized TF for query)
 		weight := (1 + math.Log(float64(tf))) * idf
@@ -133,30 +138,57 @@ func CalculateVSMScores(segment *core.Segment, tokens []string) map[uint]float64
 // (defining weights and the global norm) but only scanning for documents containing scanTokens.
 // This allows parallel execution where scanTokens is a subset of the global query.
 func CalculateVSMScoresWithGlobalNorm(segment *core.Segment, globalQueryVector *core.TFIDFVector, scanTokens []string) map[uint]float64 {
+	if segment == nil || len(scanTokens) == 0 {
+		return make(map[uint]float64)
+	}
+
+	index := core.NewIndexReader(segment)
+	return CalculateSegmentVSMScores(index.Segments[0], globalQueryVector, scanTokens, NewCorpusStats(index, scanTokens))
+}
+
+// CalculateSegmentVSMScores computes VSM cosine similarity scores for the live
+// documents of a single segment containing at least one of scanTokens. Document
+// vectors are weighted with the corpus statistics of the whole index.
+func CalculateSegmentVSMScores(segment core.SegmentReader, globalQueryVector *core.TFIDFVector, scanTokens []string, stats *CorpusStats) map[uint]float64 {
 	scores := make(map[uint]float64)
 
-	if segment == nil || len(scanTokens) == 0 || globalQueryVector == nil || globalQueryVector.Norm == 0 {
+	if segment == nil || stats == nil || len(scanTokens) == 0 || globalQueryVector == nil || globalQueryVector.Norm == 0 {
 		return scores
 	}
 
-	// Find all documents that contain at least one query term from scanTokens
+	// Term frequencies of every scan token, and all live documents that contain
+	// at least one of them
+	freqs := make([]map[uint]uint, len(scanTokens))
 	candidateDocs := make(map[uint]bool)
-	for _, token := range scanTokens {
-		postings, exists := segment.InvertedIndex[token]
-		if !exists {
-			continue
-		}
-		for _, posting := range postings {
-			candidateDocs[posting.DocID] = true
+	for i, token := range scanTokens {
+		freqs[i] = termFrequencies(segment, token)
+		for docID := range freqs[i] {
+			candidateDocs[docID] = true
 		}
 	}
 
 	// Calculate VSM score for each candidate document
 	for docID := range candidateDocs {
-		// Calculate document vector using scanTokens
-		docVector := BuildDocumentTFIDFVector(segment, docID, scanTokens)
-		similarity := CalculateCosineSimilarity(globalQueryVector, docVector)
-		scores[docID] = similarity
+		docVector := &core.TFIDFVector{Weights: make(map[string]float64, len(scanTokens))}
+
+		var sumSquares float64
+		for i, token := range scanTokens {
+			tf := freqs[i][docID]
+			df := stats.DocFreq[token]
+			if tf == 0 || df == 0 || stats.TotalDocs == 0 {
+				continue
+			}
+
+			// TF-IDF weight using log-normalized TF
+			weight := (1 + math.Log(float64(tf))) * calculateIDF(df, stats.TotalDocs)
+			docVector.Weights[token] = weight
+			sumSquares += weight * weight
+		}
+		if sumSquares > 0 {
+			docVector.Norm = math.Sqrt(sumSquares)
+		}
+
+		scores[docID] = CalculateCosineSimilarity(globalQueryVector, docVector)
 	}
 
 	return scores
@@ -188,15 +220,21 @@ func CombineScores(bm25Scores, vsmScores map[uint]float64, bm25Weight, vsmWeight
 		bm25 := bm25Scores[docID]
 		vsm := vsmScores[docID]
 
-		// Normalize BM25 score
-		var normalizedBM25 float64
-		if maxBM25 > 0 {
-			normalizedBM25 = bm25 / maxBM25
-		}
-
-		// Combined score
-		combined[docID] = bm25Weight*normalizedBM25 + vsmWeight*vsm
+		combined[docID] = combineScore(bm25, maxBM25, vsm, bm25Weight, vsmWeight)
 	}
 
 	return combined
 }
+
+// combineScore normalizes a BM25 score by the highest BM25 score among the
+// candidates and adds it to the VSM score, both weighted
+func combineScore(bm25, maxBM25, vsm, bm25Weight, vsmWeight float64) float64 {
+	// Normalize BM25 score to [0, 1] range for fair combination
+	var normalizedBM25 float64
+	if maxBM25 > 0 {
+		normalizedBM25 = bm25 / maxBM25
+	}
+
+	// Combined score
+	return bm25Weight*normalizedBM25 + vsmWeight*vsm
+}
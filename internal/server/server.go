@@ -0,0 +1,422 @@
+// Package server exposes the search index over a local JSON HTTP API. The index
+// is loaded once and reloaded when its manifest changes, so that clients issuing
+// many queries do not pay for opening the index on every request.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"mneme/internal/core"
+	"mneme/internal/display"
+	"mneme/internal/index"
+	"mneme/internal/logger"
+	"mneme/internal/query"
+	"mneme/internal/storage"
+)
+
+// DefaultSuggestLimit is the number of suggestions returned when no limit is given
+const DefaultSuggestLimit = 10
+
+// MaxLimit is the largest number of results or suggestions a request may ask for
+const MaxLimit = 1000
+
+var (
+	// ErrReindexInProgress is returned when a reindex is requested while one is running
+	ErrReindexInProgress = errors.New("reindex already in progress")
+	// ErrReindexUnavailable is returned when the server was created without a ReindexFunc
+	ErrReindexUnavailable = errors.New("reindexing is not available")
+)
+
+// ReindexFunc updates the index on disk, e.g. with an incremental update
+type ReindexFunc func() (*index.IncrementalStats, error)
+
+// Server answers search requests from an index loaded once
+type Server struct {
+	config  *core.Config
+	addr    string // Address the server listens on, whose host is accepted in Host headers
+	reindex ReindexFunc
+
+	mu       sync.RWMutex
+	index    *core.IndexReader
+	manifest manifestStamp // Manifest the index was loaded from
+	loadedAt time.Time
+
+	reindexing atomic.Bool
+}
+
+// manifestStamp identifies a version of the manifest file
+type manifestStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// StatsResponse is returned by the stats endpoint
+type StatsResponse struct {
+	Documents   uint      `json:"documents"`
+	Chunks      int       `json:"chunks"`
+	Terms       int       `json:"terms"`
+	TotalTokens uint      `json:"total_tokens"`
+	AvgDocLen   uint      `json:"avg_doc_len"`
+	LoadedAt    time.Time `json:"loaded_at"`
+}
+
+// SuggestResponse is returned by the suggest endpoint
+type SuggestResponse struct {
+	Query       string             `json:"query"`
+	Suggestions []query.Suggestion `json:"suggestions"`
+}
+
+// ReindexResponse is returned by the reindex endpoint
+type ReindexResponse struct {
+	Added     int `json:"added"`
+	Modified  int `json:"modified"`
+	Deleted   int `json:"deleted"`
+	Unchanged int `json:"unchanged"`
+}
+
+// ErrorResponse is returned with every unsuccessful status code
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a server for the given configuration, listening on addr. reindex
+// is called by the reindex endpoint and may be nil to disable it. Load must be
+// called before the server handles requests.
+func New(config *core.Config, addr string, reindex ReindexFunc) *Server {
+	return &Server{config: config, addr: addr, reindex: reindex}
+}
+
+// Load opens the index. It is reopened automatically when the manifest changes.
+func (s *Server) Load() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// load opens the index and replaces the current one. The caller must hold the write lock.
+func (s *Server) load() error {
+	stamp, err := currentManifestStamp()
+	if err != nil {
+		return err
+	}
+
+	reader, err := storage.OpenIndexReader()
+	if err != nil {
+		return fmt.Errorf("failed to open index: %w", err)
+	}
+
+	if s.index != nil {
+		if err := s.index.Close(); err != nil {
+			logger.Warnf("Failed to close previous index: %+v", err)
+		}
+	}
+	s.index = reader
+	s.manifest = stamp
+	s.loadedAt = time.Now()
+	logger.Infof("Loaded index with %d documents in %d chunks", reader.TotalDocs, len(reader.Segments))
+	return nil
+}
+
+// Close releases the index
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.index == nil {
+		return nil
+	}
+	err := s.index.Close()
+	s.index = nil
+	return err
+}
+
+// currentManifestStamp returns the stamp of the manifest on disk
+func currentManifestStamp() (manifestStamp, error) {
+	manifestPath, err := storage.ManifestPath()
+	if err != nil {
+		return manifestStamp{}, err
+	}
+	info, err := os.Stat(manifestPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifestStamp{}, nil
+	}
+	if err != nil {
+		return manifestStamp{}, fmt.Errorf("failed to stat manifest: %w", err)
+	}
+	return manifestStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+// reloadIfChanged reopens the index if the manifest changed since it was loaded.
+// The current index is kept if the new one cannot be opened.
+func (s *Server) reloadIfChanged() {
+	stamp, err := currentManifestStamp()
+	if err != nil {
+		logger.Debugf("Skipping reload check: %+v", err)
+		return
+	}
+
+	s.mu.RLock()
+	changed := stamp != s.manifest
+	s.mu.RUnlock()
+	if !changed {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if stamp == s.manifest {
+		return // Reloaded by a concurrent request
+	}
+	if err := s.load(); err != nil {
+		logger.Errorf("Failed to reload index: %+v", err)
+		return
+	}
+	logger.Info("Index reloaded after manifest change")
+}
+
+// acquireIndex returns the current index, reloading it first if it changed.
+// The index stays valid until release is called.
+func (s *Server) acquireIndex() (indexReader *core.IndexReader, release func()) {
+	s.reloadIfChanged()
+	s.mu.RLock()
+	return s.index, s.mu.RUnlock
+}
+
+// Handler returns the HTTP handler serving the API
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /search", s.withIndex(s.handleSearch))
+	mux.HandleFunc("GET /suggest", s.withIndex(s.handleSuggest))
+	mux.HandleFunc("GET /docs/{id}", s.withIndex(s.handleDocument))
+	mux.HandleFunc("GET /stats", s.withIndex(s.handleStats))
+	mux.HandleFunc("POST /reindex", s.handleReindex)
+	return s.checkOrigin(mux)
+}
+
+// checkOrigin answers 403 to requests that web pages may have sent to the
+// server from another site: requests for a Host other than a loopback name or
+// the host of the listen address, which DNS rebinding turns into same-origin
+// requests, and requests with an Origin other than the server itself.
+func (s *Server) checkOrigin(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowedHost(r.Host) {
+			logger.Debugf("Rejecting request for host %q", r.Host)
+			writeError(w, http.StatusForbidden, "host not allowed")
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			logger.Debugf("Rejecting request from origin %q", origin)
+			writeError(w, http.StatusForbidden, "cross-origin requests are not allowed")
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether a Host header names a loopback host or the host
+// of the listen address
+func (s *Server) allowedHost(hostport string) bool {
+	host := hostname(hostport)
+	if strings.EqualFold(host, "localhost") || strings.HasSuffix(strings.ToLower(host), ".localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	return host != "" && strings.EqualFold(host, hostname(s.addr))
+}
+
+// hostname returns the host of a host:port pair, without the brackets of IPv6
+// addresses
+func hostname(hostport string) string {
+	if host, _, err := net.SplitHostPort(hostport); err == nil {
+		return host
+	}
+	return strings.Trim(hostport, "[]")
+}
+
+// sameOrigin reports whether an Origin header names the host the request was
+// sent to. Opaque origins ("null") are never the same.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && strings.EqualFold(u.Host, host)
+}
+
+// indexHandler handles a request with the current index
+type indexHandler func(w http.ResponseWriter, r *http.Request, indexReader *core.IndexReader)
+
+// withIndex passes the current index to a handler, answering 503 while there is none
+func (s *Server) withIndex(handler indexHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		logger.Debugf("%s %s", r.Method, r.URL)
+
+		indexReader, release := s.acquireIndex()
+		defer release()
+		if indexReader == nil {
+			writeError(w, http.StatusServiceUnavailable, "no index loaded, run 'mneme index' first")
+			return
+		}
+		handler(w, r, indexReader)
+	}
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request, indexReader *core.IndexReader) {
+	limit, ok := parseLimit(w, r, s.config.Search.DefaultLimit)
+	if !ok {
+		return
+	}
+
+	search, err := query.PrepareSearch(indexReader, query.SplitQueryArgs(r.URL.Query().Get("q")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if explain := r.URL.Query().Get("explain"); explain != "" {
+		if search.Explain, err = strconv.ParseBool(explain); err != nil {
+			writeError(w, http.StatusBadRequest, "explain must be a boolean")
+			return
+		}
+	}
+
+	rankedDocs := search.Rank(indexReader, limit, &s.config.Ranking)
+	results := display.FormatSearchResults(rankedDocs, search.HighlightTerms)
+	if results == nil {
+		results = []*core.SearchResult{}
+	}
+
+	writeJSON(w, http.StatusOK, display.JSONResults{
+		Query:       search.Text,
+		Corrections: search.Corrections,
+		Results:     results,
+	})
+}
+
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request, indexReader *core.IndexReader) {
+	limit, ok := parseLimit(w, r, DefaultSuggestLimit)
+	if !ok {
+		return
+	}
+
+	prefix := r.URL.Query().Get("q")
+	writeJSON(w, http.StatusOK, SuggestResponse{
+		Query:       prefix,
+		Suggestions: query.SuggestTerms(indexReader, prefix, limit),
+	})
+}
+
+func (s *Server) handleDocument(w http.ResponseWriter, r *http.Request, indexReader *core.IndexReader) {
+	id, err := strconv.ParseUint(r.PathValue("id"), 10, 0)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid document id")
+		return
+	}
+
+	for _, segment := range indexReader.Segments {
+		if segment.IsDeleted(uint(id)) {
+			continue
+		}
+		if doc, ok := segment.Document(uint(id)); ok {
+			writeJSON(w, http.StatusOK, doc)
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "document not found")
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request, indexReader *core.IndexReader) {
+	// withIndex holds the read lock, so loadedAt belongs to indexReader
+	writeJSON(w, http.StatusOK, StatsResponse{
+		Documents:   indexReader.TotalDocs,
+		Chunks:      len(indexReader.Segments),
+		Terms:       len(indexReader.Vocabulary()),
+		TotalTokens: indexReader.TotalTokens,
+		AvgDocLen:   indexReader.AvgDocLen,
+		LoadedAt:    s.loadedAt,
+	})
+}
+
+func (s *Server) handleReindex(w http.ResponseWriter, r *http.Request) {
+	logger.Debugf("%s %s", r.Method, r.URL)
+
+	// Browsers send JSON only after a CORS preflight, which the server never
+	// answers, so HTML forms and scripts of other sites cannot trigger a reindex
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err != nil || mediaType != "application/json" {
+		writeError(w, http.StatusUnsupportedMediaType, "reindex requests must have the Content-Type application/json")
+		return
+	}
+
+	stats, err := s.Reindex()
+	switch {
+	case errors.Is(err, ErrReindexInProgress):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, ErrReindexUnavailable):
+		writeError(w, http.StatusNotImplemented, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, ReindexResponse{
+			Added:     stats.Added,
+			Modified:  stats.Modified,
+			Deleted:   stats.Deleted,
+			Unchanged: stats.Unchanged,
+		})
+	}
+}
+
+// Reindex updates the index on disk and reloads it. Only one reindex runs at a time.
+func (s *Server) Reindex() (*index.IncrementalStats, error) {
+	if s.reindex == nil {
+		return nil, ErrReindexUnavailable
+	}
+	if !s.reindexing.CompareAndSwap(false, true) {
+		return nil, ErrReindexInProgress
+	}
+	defer s.reindexing.Store(false)
+
+	stats, err := s.reindex()
+	if err != nil {
+		return nil, err
+	}
+	s.reloadIfChanged()
+	return stats, nil
+}
+
+// parseLimit parses the limit query parameter, answering 400 if it is invalid or
+// exceeds MaxLimit
+func parseLimit(w http.ResponseWriter, r *http.Request, defaultLimit int) (int, bool) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return defaultLimit, true
+	}
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 || limit > MaxLimit {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be an integer between 1 and %d", MaxLimit))
+		return 0, false
+	}
+	return limit, true
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		logger.Debugf("Failed to write response: %+v", err)
+	}
+}
+
+// writeError writes an ErrorResponse
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, ErrorResponse{Error: message})
+}
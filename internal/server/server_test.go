@@ -0,0 +1,328 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/display"
+	"mneme/internal/index"
+	"mneme/internal/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setupTestIndex writes two notes and a single chunk index of them into
+// temporary directories and returns the directory of the notes
+func setupTestIndex(t *testing.T) string {
+	t.Helper()
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(constants.DirPath, "segments"), 0755))
+
+	notesDir := t.TempDir()
+	clusterPath := filepath.Join(notesDir, "cluster.md")
+	helmPath := filepath.Join(notesDir, "helm.md")
+	require.NoError(t, os.WriteFile(clusterPath, []byte("cluster setup\nscale the cluster\n"), 0644))
+	require.NoError(t, os.WriteFile(helmPath, []byte("cluster charts with helm\n"), 0644))
+
+	chunk := &core.Segment{
+		Docs: []core.Document{
+			{ID: 0, Path: clusterPath, TokenCount: 4},
+			{ID: 1, Path: helmPath, TokenCount: 3},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"cluster": {{DocID: 0, Freq: 2, Positions: []uint{0, 3}}, {DocID: 1, Freq: 1, Positions: []uint{0}}},
+			"setup":   {{DocID: 0, Freq: 1, Positions: []uint{1}}},
+			"scale":   {{DocID: 0, Freq: 1, Positions: []uint{2}}},
+			"helm":    {{DocID: 1, Freq: 1, Positions: []uint{2}}},
+		},
+	}
+	_, err := storage.SaveChunk(chunk, 0)
+	require.NoError(t, err)
+
+	manifest := core.NewManifest()
+	manifest.AddChunk(core.ChunkInfo{ID: 0, Filename: "000.idx", Status: core.ChunkStatusComplete, DocCount: 2, TokenCount: 7})
+	manifest.UpdateTotals()
+	require.NoError(t, storage.SaveManifest(manifest))
+	return notesDir
+}
+
+// testAddr is the listen address of test servers
+const testAddr = "127.0.0.1:7171"
+
+func newTestServer(t *testing.T, reindex ReindexFunc) (*Server, http.Handler) {
+	t.Helper()
+	cfg := &core.Config{Search: core.SearchConfig{DefaultLimit: 10}}
+	srv := New(cfg, testAddr, reindex)
+	require.NoError(t, srv.Load())
+	t.Cleanup(func() { srv.Close() })
+	return srv, srv.Handler()
+}
+
+func get(t *testing.T, handler http.Handler, target string, body any) int {
+	t.Helper()
+	return do(t, handler, http.MethodGet, target, body)
+}
+
+func do(t *testing.T, handler http.Handler, method, target string, body any) int {
+	t.Helper()
+	request := httptest.NewRequest(method, "http://"+testAddr+target, nil)
+	if method == http.MethodPost {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	return doRequest(t, handler, request, body)
+}
+
+func doRequest(t *testing.T, handler http.Handler, request *http.Request, body any) int {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), body), recorder.Body.String())
+	return recorder.Code
+}
+
+func TestSearch(t *testing.T) {
+	notesDir := setupTestIndex(t)
+	_, handler := newTestServer(t, nil)
+
+	var response display.JSONResults
+	status := get(t, handler, "/search?q=cluster", &response)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "cluster", response.Query)
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, filepath.Join(notesDir, "cluster.md"), response.Results[0].DocPath)
+	assert.Equal(t, 2, response.Results[0].Snippets[1].LineNumber)
+
+	status = get(t, handler, "/search?q=cluster+-helm&limit=5", &response)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, filepath.Join(notesDir, "cluster.md"), response.Results[0].DocPath)
+
+	status = get(t, handler, "/search?q=cluster&limit=1", &response)
+	require.Equal(t, http.StatusOK, status)
+	assert.Len(t, response.Results, 1)
+
+	status = get(t, handler, "/search?q=helmm", &response)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, map[string]string{"helmm": "helm"}, response.Corrections)
+	require.Len(t, response.Results, 1)
+
+	assert.Nil(t, response.Results[0].Explanation)
+	status = get(t, handler, "/search?q=cluster&explain=true", &response)
+	require.Equal(t, http.StatusOK, status)
+	require.NotNil(t, response.Results[0].Explanation)
+	assert.InDelta(t, response.Results[0].Score, response.Results[0].Explanation.Score, 1e-9)
+
+	var errResponse ErrorResponse
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/search?q=cluster&explain=maybe", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/search?q=cluster&limit=0", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/search?q=cluster&limit=1001", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/search?q=cluster&limit=9223372036854775807", &errResponse))
+	assert.NotEmpty(t, errResponse.Error)
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/search?q=", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/search?q=(cluster", &errResponse))
+}
+
+func TestSuggest(t *testing.T) {
+	setupTestIndex(t)
+	_, handler := newTestServer(t, nil)
+
+	var response SuggestResponse
+	require.Equal(t, http.StatusOK, get(t, handler, "/suggest?q=cl", &response))
+	assert.Equal(t, "cl", response.Query)
+	require.Len(t, response.Suggestions, 1)
+	assert.Equal(t, "cluster", response.Suggestions[0].Term)
+	assert.Equal(t, 2, response.Suggestions[0].DocFreq)
+
+	require.Equal(t, http.StatusOK, get(t, handler, "/suggest?q=zz", &response))
+	assert.NotNil(t, response.Suggestions)
+	assert.Empty(t, response.Suggestions)
+
+	require.Equal(t, http.StatusOK, get(t, handler, "/suggest?q=cl&limit=1000", &response))
+	var errResponse ErrorResponse
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/suggest?q=cl&limit=1001", &errResponse))
+	assert.NotEmpty(t, errResponse.Error)
+}
+
+func TestDocument(t *testing.T) {
+	notesDir := setupTestIndex(t)
+	_, handler := newTestServer(t, nil)
+
+	var doc core.Document
+	require.Equal(t, http.StatusOK, get(t, handler, "/docs/1", &doc))
+	assert.Equal(t, filepath.Join(notesDir, "helm.md"), doc.Path)
+
+	var errResponse ErrorResponse
+	assert.Equal(t, http.StatusNotFound, get(t, handler, "/docs/42", &errResponse))
+	assert.Equal(t, http.StatusBadRequest, get(t, handler, "/docs/abc", &errResponse))
+}
+
+func TestStats(t *testing.T) {
+	setupTestIndex(t)
+	_, handler := newTestServer(t, nil)
+
+	var stats StatsResponse
+	require.Equal(t, http.StatusOK, get(t, handler, "/stats", &stats))
+	assert.Equal(t, uint(2), stats.Documents)
+	assert.Equal(t, 1, stats.Chunks)
+	assert.Equal(t, 4, stats.Terms)
+	assert.False(t, stats.LoadedAt.IsZero())
+}
+
+func TestReloadAfterManifestChange(t *testing.T) {
+	notesDir := setupTestIndex(t)
+	_, handler := newTestServer(t, nil)
+
+	terraformPath := filepath.Join(notesDir, "terraform.md")
+	require.NoError(t, os.WriteFile(terraformPath, []byte("terraform\n"), 0644))
+	chunk := &core.Segment{
+		Docs: []core.Document{{ID: 2, Path: terraformPath, TokenCount: 1}},
+		InvertedIndex: map[string][]core.Posting{
+			"terraform": {{DocID: 2, Freq: 1, Positions: []uint{0}}},
+		},
+	}
+	_, err := storage.SaveChunk(chunk, 1)
+	require.NoError(t, err)
+
+	manifest, err := storage.LoadManifest()
+	require.NoError(t, err)
+	manifest.AddChunk(core.ChunkInfo{ID: 1, Filename: "001.idx", Status: core.ChunkStatusComplete, DocCount: 1, TokenCount: 1})
+	manifest.UpdateTotals()
+	require.NoError(t, storage.SaveManifest(manifest))
+
+	var response display.JSONResults
+	require.Equal(t, http.StatusOK, get(t, handler, "/search?q=terraform", &response))
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, terraformPath, response.Results[0].DocPath)
+}
+
+func TestReindex(t *testing.T) {
+	setupTestIndex(t)
+
+	t.Run("unavailable", func(t *testing.T) {
+		_, handler := newTestServer(t, nil)
+		var errResponse ErrorResponse
+		assert.Equal(t, http.StatusNotImplemented, do(t, handler, http.MethodPost, "/reindex", &errResponse))
+	})
+
+	t.Run("success", func(t *testing.T) {
+		_, handler := newTestServer(t, func() (*index.IncrementalStats, error) {
+			return &index.IncrementalStats{Added: 1, Unchanged: 2}, nil
+		})
+		var response ReindexResponse
+		require.Equal(t, http.StatusOK, do(t, handler, http.MethodPost, "/reindex", &response))
+		assert.Equal(t, ReindexResponse{Added: 1, Unchanged: 2}, response)
+	})
+
+	t.Run("failure", func(t *testing.T) {
+		_, handler := newTestServer(t, func() (*index.IncrementalStats, error) {
+			return nil, errors.New("index is locked")
+		})
+		var errResponse ErrorResponse
+		assert.Equal(t, http.StatusInternalServerError, do(t, handler, http.MethodPost, "/reindex", &errResponse))
+		assert.Equal(t, "index is locked", errResponse.Error)
+	})
+
+	t.Run("one at a time", func(t *testing.T) {
+		started := make(chan struct{})
+		finish := make(chan struct{})
+		srv, handler := newTestServer(t, func() (*index.IncrementalStats, error) {
+			close(started)
+			<-finish
+			return &index.IncrementalStats{}, nil
+		})
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := srv.Reindex()
+			assert.NoError(t, err)
+		}()
+
+		select {
+		case <-started:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Reindex did not start")
+		}
+		var errResponse ErrorResponse
+		assert.Equal(t, http.StatusConflict, do(t, handler, http.MethodPost, "/reindex", &errResponse))
+
+		close(finish)
+		wg.Wait()
+	})
+}
+
+func TestNoIndex(t *testing.T) {
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+
+	srv := New(&core.Config{}, testAddr, nil)
+	assert.Error(t, srv.Load())
+
+	var errResponse ErrorResponse
+	assert.Equal(t, http.StatusServiceUnavailable, get(t, srv.Handler(), "/stats", &errResponse))
+}
+
+func TestRejectCrossSiteRequests(t *testing.T) {
+	setupTestIndex(t)
+	reindexed := false
+	_, handler := newTestServer(t, func() (*index.IncrementalStats, error) {
+		reindexed = true
+		return &index.IncrementalStats{}, nil
+	})
+
+	tests := []struct {
+		name    string
+		method  string
+		target  string
+		headers map[string]string
+		status  int
+	}{
+		{"foreign host", http.MethodGet, "http://attacker.example:7171/stats", nil, http.StatusForbidden},
+		{"foreign host with loopback address", http.MethodGet, "http://127.0.0.1.attacker.example/stats", nil, http.StatusForbidden},
+		{"localhost", http.MethodGet, "http://localhost:7171/stats", nil, http.StatusOK},
+		{"IPv6 loopback", http.MethodGet, "http://[::1]:7171/stats", nil, http.StatusOK},
+		{"same origin", http.MethodGet, "http://127.0.0.1:7171/stats", map[string]string{"Origin": "http://127.0.0.1:7171"}, http.StatusOK},
+		{"cross-site GET", http.MethodGet, "http://127.0.0.1:7171/stats", map[string]string{"Origin": "https://attacker.example"}, http.StatusForbidden},
+		{"cross-site POST", http.MethodPost, "http://127.0.0.1:7171/reindex",
+			map[string]string{"Origin": "https://attacker.example", "Content-Type": "application/json"}, http.StatusForbidden},
+		{"opaque origin POST", http.MethodPost, "http://127.0.0.1:7171/reindex",
+			map[string]string{"Origin": "null", "Content-Type": "application/json"}, http.StatusForbidden},
+		{"simple POST", http.MethodPost, "http://127.0.0.1:7171/reindex",
+			map[string]string{"Content-Type": "text/plain"}, http.StatusUnsupportedMediaType},
+		{"form POST", http.MethodPost, "http://127.0.0.1:7171/reindex", nil, http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(tt.method, tt.target, nil)
+			for name, value := range tt.headers {
+				request.Header.Set(name, value)
+			}
+			var response map[string]any
+			assert.Equal(t, tt.status, doRequest(t, handler, request, &response))
+		})
+	}
+	assert.False(t, reindexed, "a rejected request triggered a reindex")
+
+	t.Run("listen address", func(t *testing.T) {
+		srv := New(&core.Config{}, "search.internal:7171", nil)
+		require.NoError(t, srv.Load())
+		t.Cleanup(func() { srv.Close() })
+		var stats StatsResponse
+		assert.Equal(t, http.StatusOK, doRequest(t, srv.Handler(), httptest.NewRequest(http.MethodGet, "http://search.internal:7171/stats", nil), &stats))
+	})
+}
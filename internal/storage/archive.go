@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"mneme/internal/core"
+	"mneme/internal/logger"
+)
+
+// ArchiveSeparator separates the path of an archive from the path of a member
+// inside it, e.g. "backup.zip!/docs/readme.md". Members of nested archives
+// repeat it: "backup.zip!/old.tar.gz!/notes.txt".
+const ArchiveSeparator = "!/"
+
+// archiveReadLimit bounds the size of archive members read into memory when
+// no ArchiveMaxSize is configured, which protects against zip bombs
+const archiveReadLimit = 1 << 30
+
+// archiveSuffixes are the file name suffixes of the supported archive formats
+var archiveSuffixes = []string{".zip", ".tar", ".tar.gz", ".tgz"}
+
+// errStopWalk stops walkArchive without an error
+var errStopWalk = errors.New("stop archive walk")
+
+// IsArchive reports whether a file name has the suffix of a supported archive
+// format: zip, tar, tar.gz or tgz
+func IsArchive(name string) bool {
+	lower := strings.ToLower(name)
+	for _, suffix := range archiveSuffixes {
+		if strings.HasSuffix(lower, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SplitArchivePath splits the virtual path of an archive member into the path
+// of the archive file and the path of the member at every nesting level. It
+// returns false for paths that are not inside an archive.
+func SplitArchivePath(virtualPath string) (string, []string, bool) {
+	if filepath.Separator != '/' {
+		// filepath.Clean turns the slashes of virtual paths into backslashes
+		virtualPath = strings.ReplaceAll(virtualPath, "!"+string(filepath.Separator), ArchiveSeparator)
+	}
+	parts := strings.Split(virtualPath, ArchiveSeparator)
+	if len(parts) < 2 {
+		return "", nil, false
+	}
+	for i, part := range parts {
+		// Every part but the member itself is an archive
+		if part == "" || (i < len(parts)-1 && !IsArchive(part)) {
+			return "", nil, false
+		}
+	}
+	members := parts[1:]
+	for i, member := range members {
+		members[i] = filepath.ToSlash(member)
+	}
+	return parts[0], members, true
+}
+
+// ArchivePath returns the path of the archive file containing a virtual path,
+// or the path itself if it is not inside an archive
+func ArchivePath(virtualPath string) string {
+	if archivePath, _, ok := SplitArchivePath(virtualPath); ok {
+		return archivePath
+	}
+	return virtualPath
+}
+
+// archiveSizeLimit returns the largest archive member read into memory for the
+// given CrawlerOptions.ArchiveMaxSize, where 0 or less means no configured limit
+func archiveSizeLimit(maxSize int64) int64 {
+	if maxSize > 0 {
+		return maxSize
+	}
+	return archiveReadLimit
+}
+
+// ReadArchiveMember reads the contents of the archive member at a virtual path
+// such as "backup.zip!/docs/readme.md". Nested archives are read into memory.
+// The member and every nested archive must not exceed maxSize bytes, with the
+// meaning of CrawlerOptions.ArchiveMaxSize. Missing archives and members return
+// an error wrapping os.ErrNotExist.
+func ReadArchiveMember(virtualPath string, maxSize int64) ([]byte, error) {
+	archivePath, members, ok := SplitArchivePath(virtualPath)
+	if !ok {
+		return nil, fmt.Errorf("not an archive member: %s", virtualPath)
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	var archive io.ReaderAt = file
+	name, size := archivePath, info.Size()
+	for _, member := range members {
+		if data, err = readArchiveMember(name, archive, size, member, archiveSizeLimit(maxSize)); err != nil {
+			return nil, err
+		}
+		archive, name, size = bytes.NewReader(data), member, int64(len(data))
+	}
+	return data, nil
+}
+
+// readArchiveMember reads a single member of an archive of at most limit bytes
+func readArchiveMember(name string, archive io.ReaderAt, size int64, member string, limit int64) ([]byte, error) {
+	var data []byte
+	err := walkArchive(name, archive, size, func(entry string, entrySize int64, open func() (io.Reader, error)) error {
+		if entry != member {
+			return nil
+		}
+		if entrySize > limit {
+			return fmt.Errorf("archive member %s of %s exceeds %d bytes", member, name, limit)
+		}
+		content, err := open()
+		if err != nil {
+			return err
+		}
+		if data, err = readArchiveContent(content, limit); err != nil {
+			return fmt.Errorf("archive member %s of %s: %w", member, name, err)
+		}
+		return errStopWalk
+	})
+	if errors.Is(err, errStopWalk) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %w", name, err)
+	}
+	return nil, fmt.Errorf("%w: %s has no member %s", os.ErrNotExist, name, member)
+}
+
+// readArchiveContent reads the contents of an archive member of at most limit
+// bytes. Sizes declared by archives may be wrong, so the limit is enforced
+// while reading as well.
+func readArchiveContent(content io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(content, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("exceeds %d bytes", limit)
+	}
+	return data, nil
+}
+
+// walkArchive calls fn with the path and size of every regular file of the
+// archive named name, in archive order. open returns the contents of the file
+// and is only valid until fn returns. Returning errStopWalk from fn stops the
+// walk; other errors are returned.
+func walkArchive(name string, archive io.ReaderAt, size int64, fn func(member string, size int64, open func() (io.Reader, error)) error) error {
+	if strings.HasSuffix(strings.ToLower(name), ".zip") {
+		reader, err := zip.NewReader(archive, size)
+		if err != nil {
+			return err
+		}
+		for _, file := range reader.File {
+			member, ok := cleanArchiveMember(file.Name)
+			if !ok || !file.Mode().IsRegular() {
+				continue
+			}
+
+			var opened io.ReadCloser
+			err := fn(member, int64(file.UncompressedSize64), func() (io.Reader, error) {
+				var err error
+				opened, err = file.Open()
+				return opened, err
+			})
+			if opened != nil {
+				opened.Close()
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var stream io.Reader = io.NewSectionReader(archive, 0, size)
+	if lower := strings.ToLower(name); strings.HasSuffix(lower, ".gz") || strings.HasSuffix(lower, ".tgz") {
+		gz, err := gzip.NewReader(stream)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		stream = gz
+	}
+
+	reader := tar.NewReader(stream)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		member, ok := cleanArchiveMember(header.Name)
+		if !ok || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := fn(member, header.Size, func() (io.Reader, error) { return reader, nil }); err != nil {
+			return err
+		}
+	}
+}
+
+// cleanArchiveMember returns the cleaned slash separated path of an archive
+// member. It returns false for directories and for paths that would be
+// ambiguous in a virtual path, such as those leaving the archive.
+func cleanArchiveMember(name string) (string, bool) {
+	if name == "" || strings.HasSuffix(name, "/") {
+		return "", false
+	}
+	member := strings.TrimLeft(path.Clean(strings.ReplaceAll(name, "\\", "/")), "/")
+	if member == "." || member == ".." || strings.HasPrefix(member, "../") || strings.Contains(member, ArchiveSeparator) {
+		return "", false
+	}
+	return member, true
+}
+
+// crawlArchive appends the virtual paths of the members of an archive file
+// that pass the crawler rules to results. Damaged archives are skipped with a
+// warning, keeping the members listed before the damage.
+func crawlArchive(archivePath string, results *[]string, includeExtMap map[string]bool, excludeExtMap map[string]bool, skipFolderMap map[string]bool, globs sourceGlobs, options core.CrawlerOptions) {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		logger.Warnf("Skipping archive %s: %v", archivePath, err)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		logger.Warnf("Skipping archive %s: %v", archivePath, err)
+		return
+	}
+	if options.ArchiveMaxSize > 0 && info.Size() > options.ArchiveMaxSize {
+		logger.Debugf("Skipping archive %s: size %d exceeds %d bytes", archivePath, info.Size(), options.ArchiveMaxSize)
+		return
+	}
+
+	c := archiveCrawler{
+		results:       results,
+		includeExtMap: includeExtMap,
+		excludeExtMap: excludeExtMap,
+		skipFolderMap: skipFolderMap,
+		globs:         globs,
+		options:       options,
+		seen:          make(map[string]bool),
+	}
+	if err := c.crawl(archivePath, archivePath, file, info.Size(), 1); err != nil {
+		logger.Warnf("Skipping damaged archive %s: %v", archivePath, err)
+	}
+}
+
+// archiveCrawler applies the crawler rules to the members of an archive
+type archiveCrawler struct {
+	results       *[]string
+	includeExtMap map[string]bool
+	excludeExtMap map[string]bool
+	skipFolderMap map[string]bool
+	globs         sourceGlobs
+	options       core.CrawlerOptions
+	seen          map[string]bool // Virtual paths listed, as archives may repeat members
+}
+
+// crawl lists the members of the archive at virtualPath, named name, at the
+// given nesting depth
+func (c *archiveCrawler) crawl(virtualPath, name string, archive io.ReaderAt, size int64, depth int) error {
+	return walkArchive(name, archive, size, func(member string, memberSize int64, open func() (io.Reader, error)) error {
+		memberPath := virtualPath + ArchiveSeparator + member
+		if c.seen[memberPath] || c.skipDirectories(member) {
+			return nil
+		}
+		// The declared size is checked before anything is read into memory
+		limit := archiveSizeLimit(c.options.ArchiveMaxSize)
+		if memberSize > limit {
+			logger.Debugf("Skipping archive member %s: size %d exceeds %d bytes", memberPath, memberSize, limit)
+			return nil
+		}
+
+		fileName := path.Base(member)
+		if IsArchive(fileName) {
+			if depth >= c.options.ArchiveDepth || c.excludeExtMap[getFileExtension(fileName)] || c.globs.skipDirectory(memberPath) {
+				logger.Debugf("Skipping nested archive: %s", memberPath)
+				return nil
+			}
+			content, err := open()
+			if err != nil {
+				return err
+			}
+			data, err := readArchiveContent(content, limit)
+			if err != nil {
+				logger.Debugf("Skipping nested archive %s: %v", memberPath, err)
+				return nil
+			}
+			c.seen[memberPath] = true
+			if err := c.crawl(memberPath, member, bytes.NewReader(data), int64(len(data)), depth+1); err != nil {
+				logger.Warnf("Skipping damaged archive %s: %v", memberPath, err)
+			}
+			return nil
+		}
+
+		var head []byte
+		if c.needsContentCheck(fileName) {
+			content, err := open()
+			if err != nil {
+				// Members of unsupported compression methods or encrypted members
+				logger.Debugf("Skipping unreadable archive member %s: %v", memberPath, err)
+				return nil
+			}
+			head = make([]byte, 512)
+			n, _ := io.ReadFull(content, head)
+			head = head[:n]
+		}
+		if c.skipFile(memberPath, fileName, head) {
+			logger.Debugf("Skipping archive member: %s", memberPath)
+			return nil
+		}
+
+		c.seen[memberPath] = true
+		*c.results = append(*c.results, memberPath)
+		return nil
+	})
+}
+
+// skipDirectories reports whether a directory containing a member is hidden
+// or a skipped folder
+func (c *archiveCrawler) skipDirectories(member string) bool {
+	dirs := strings.Split(member, "/")
+	for _, dir := range dirs[:len(dirs)-1] {
+		if (!c.options.IncludeHidden && strings.HasPrefix(dir, ".")) || shouldSkipDirectory(dir, c.skipFolderMap, c.options) {
+			return true
+		}
+	}
+	return false
+}
+
+// needsContentCheck reports whether the first bytes of a member decide whether
+// it is binary, as for files without a known extension on disk
+func (c *archiveCrawler) needsContentCheck(fileName string) bool {
+	ext := getFileExtension(fileName)
+	return c.options.SkipBinaryFiles && len(c.includeExtMap) == 0 && (ext == "" || !(isCommonTextExtension(ext) || DocumentExtensions[ext]))
+}
+
+// skipFile applies the file rules of the crawler to a member, given its first
+// bytes if needsContentCheck
+func (c *archiveCrawler) skipFile(memberPath, fileName string, head []byte) bool {
+	ext := getFileExtension(fileName)
+	switch {
+	case WindowsSystemFiles[strings.ToLower(fileName)]:
+		return true
+	case !c.options.IncludeHidden && strings.HasPrefix(fileName, "."):
+		return true
+	case c.excludeExtMap[ext]:
+		return true
+	case c.options.SkipBinaryFiles && BinaryExtensions[ext]:
+		return true
+	case len(c.includeExtMap) > 0 && !c.includeExtMap[ext]:
+		return true
+	case c.globs.skipFile(memberPath):
+		return true
+	}
+	return c.needsContentCheck(fileName) && isBinaryContent(head)
+}
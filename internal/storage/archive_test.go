@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"mneme/internal/core"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// archiveFile is a member of a test archive
+type archiveFile struct {
+	name    string
+	content string
+}
+
+func buildZip(t *testing.T, files ...archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, f := range files {
+		fw, err := w.Create(f.name)
+		if err != nil {
+			t.Fatalf("Failed to create zip member: %v", err)
+		}
+		if _, err := fw.Write([]byte(f.content)); err != nil {
+			t.Fatalf("Failed to write zip member: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func buildTar(t *testing.T, compress bool, files ...archiveFile) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var gz *gzip.Writer
+	w := tar.NewWriter(&buf)
+	if compress {
+		gz = gzip.NewWriter(&buf)
+		w = tar.NewWriter(gz)
+	}
+	for _, f := range files {
+		header := &tar.Header{Name: f.name, Mode: 0644, Size: int64(len(f.content)), Typeflag: tar.TypeReg}
+		if err := w.WriteHeader(header); err != nil {
+			t.Fatalf("Failed to write tar header: %v", err)
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			t.Fatalf("Failed to write tar member: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close tar: %v", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			t.Fatalf("Failed to close gzip: %v", err)
+		}
+	}
+	return buf.Bytes()
+}
+
+func writeArchive(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to create archive: %v", err)
+	}
+}
+
+func TestIsArchive(t *testing.T) {
+	for _, name := range []string{"backup.zip", "logs.TAR", "site.tar.gz", "site.tgz"} {
+		if !IsArchive(name) {
+			t.Errorf("IsArchive(%q) = false, expected true", name)
+		}
+	}
+	for _, name := range []string{"notes.gz", "report.docx", "archive", "zip"} {
+		if IsArchive(name) {
+			t.Errorf("IsArchive(%q) = true, expected false", name)
+		}
+	}
+}
+
+func TestSplitArchivePath(t *testing.T) {
+	tests := []struct {
+		path    string
+		archive string
+		members []string
+		ok      bool
+	}{
+		{"/data/backup.zip!/docs/readme.md", "/data/backup.zip", []string{"docs/readme.md"}, true},
+		{"/data/backup.zip!/old.tar.gz!/notes.txt", "/data/backup.zip", []string{"old.tar.gz", "notes.txt"}, true},
+		{"/data/readme.md", "", nil, false},
+		{"/data/wow!/readme.md", "", nil, false},
+		{"/data/backup.zip!/", "", nil, false},
+		{"/data/backup.zip!/notes.txt!/x", "", nil, false},
+	}
+
+	for _, tt := range tests {
+		archive, members, ok := SplitArchivePath(tt.path)
+		if archive != tt.archive || !reflect.DeepEqual(members, tt.members) || ok != tt.ok {
+			t.Errorf("SplitArchivePath(%q) = %q, %q, %v, expected %q, %q, %v",
+				tt.path, archive, members, ok, tt.archive, tt.members, tt.ok)
+		}
+	}
+
+	if path := ArchivePath("/data/backup.zip!/docs/readme.md"); path != "/data/backup.zip" {
+		t.Errorf("ArchivePath of a member = %q, expected the archive", path)
+	}
+	if path := ArchivePath("/data/readme.md"); path != "/data/readme.md" {
+		t.Errorf("ArchivePath of a file = %q, expected the file", path)
+	}
+}
+
+func TestCrawler_Archives(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	nested := buildTar(t, true,
+		archiveFile{"notes.txt", "nested notes"},
+		archiveFile{"deeper.zip", string(buildZip(t, archiveFile{"deep.txt", "too deep"}))},
+	)
+	writeArchive(t, filepath.Join(tmpDir, "backup.zip"), buildZip(t,
+		archiveFile{"docs/readme.md", "# Readme"},
+		archiveFile{"docs/", ""},
+		archiveFile{"node_modules/lib.js", "skipped folder"},
+		archiveFile{".hidden/secret.txt", "hidden folder"},
+		archiveFile{"image.png", "skipped extension"},
+		archiveFile{"program", "binary\x00content"},
+		archiveFile{"../escape.txt", "outside the archive"},
+		archiveFile{"old.tar.gz", string(nested)},
+	))
+	writeArchive(t, filepath.Join(tmpDir, "logs.tar"), buildTar(t, false, archiveFile{"app.log", "started"}))
+
+	opts := core.DefaultCrawlerOptions()
+	opts.SkipBinaryFiles = true
+	opts.ArchiveDepth = 2
+	result, err := Crawler(tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+	sort.Strings(result)
+
+	archive := filepath.Join(tmpDir, "backup.zip")
+	expected := []string{
+		archive + "!/docs/readme.md",
+		archive + "!/old.tar.gz!/notes.txt",
+		filepath.Join(tmpDir, "logs.tar") + "!/app.log",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Crawler() = %v, expected %v", result, expected)
+	}
+
+	// Depth 1 lists the members of archives on disk only
+	opts.ArchiveDepth = 1
+	result, err = Crawler(archive, opts)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+	if !reflect.DeepEqual(result, []string{archive + "!/docs/readme.md"}) {
+		t.Errorf("Crawler() at depth 1 = %v, expected the readme only", result)
+	}
+
+	// Depth 0 treats archives like other binary files
+	opts.ArchiveDepth = 0
+	result, err = Crawler(tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected archives to be skipped, got %v", result)
+	}
+}
+
+func TestCrawler_ArchiveMaxSize(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeArchive(t, filepath.Join(tmpDir, "small.zip"), buildZip(t,
+		archiveFile{"small.txt", "small"},
+		archiveFile{"large.txt", string(bytes.Repeat([]byte("x"), 4096))},
+	))
+	writeArchive(t, filepath.Join(tmpDir, "large.tar"), buildTar(t, false,
+		archiveFile{"large.txt", string(bytes.Repeat([]byte("x"), 8192))},
+	))
+	writeArchive(t, filepath.Join(tmpDir, "nested.zip"), buildZip(t,
+		archiveFile{"inner.tar", string(buildTar(t, false, archiveFile{"a.txt", "small"}, archiveFile{"b.txt", "small"}))},
+	))
+
+	opts := core.DefaultCrawlerOptions()
+	opts.ArchiveDepth = 2
+	opts.ArchiveMaxSize = 2048
+	result, err := Crawler(tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+
+	// The large archive, the large member and the large nested archive are skipped
+	expected := []string{filepath.Join(tmpDir, "small.zip") + "!/small.txt"}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("Crawler() = %v, expected %v", result, expected)
+	}
+}
+
+func TestCrawler_DamagedArchive(t *testing.T) {
+	tmpDir := t.TempDir()
+	writeArchive(t, filepath.Join(tmpDir, "broken.zip"), []byte("PK\x03\x04 not really a zip"))
+	writeArchive(t, filepath.Join(tmpDir, "broken.tgz"), []byte("not gzip"))
+	if err := os.WriteFile(filepath.Join(tmpDir, "main.go"), []byte("package main"), 0644); err != nil {
+		t.Fatalf("Failed to create main.go: %v", err)
+	}
+
+	opts := core.DefaultCrawlerOptions()
+	opts.ArchiveDepth = 2
+	result, err := Crawler(tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+	if len(result) != 1 || filepath.Base(result[0]) != "main.go" {
+		t.Errorf("Expected damaged archives to be skipped, got %v", result)
+	}
+}
+
+func TestReadArchiveMember(t *testing.T) {
+	tmpDir := t.TempDir()
+	archive := filepath.Join(tmpDir, "backup.zip")
+	nested := buildTar(t, true, archiveFile{"notes.txt", "nested notes"})
+	writeArchive(t, archive, buildZip(t,
+		archiveFile{"docs/readme.md", "# Readme"},
+		archiveFile{"old.tgz", string(nested)},
+	))
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{archive + "!/docs/readme.md", "# Readme"},
+		{archive + "!/old.tgz!/notes.txt", "nested notes"},
+	}
+	for _, tt := range tests {
+		data, err := ReadArchiveMember(tt.path, 0)
+		if err != nil {
+			t.Fatalf("ReadArchiveMember(%q) error: %v", tt.path, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("ReadArchiveMember(%q) = %q, expected %q", tt.path, data, tt.expected)
+		}
+	}
+
+	for _, path := range []string{archive + "!/docs/missing.md", filepath.Join(tmpDir, "missing.zip") + "!/readme.md"} {
+		if _, err := ReadArchiveMember(path, 0); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("ReadArchiveMember(%q) error = %v, expected os.ErrNotExist", path, err)
+		}
+	}
+	if _, err := ReadArchiveMember(filepath.Join(tmpDir, "readme.md"), 0); err == nil {
+		t.Error("Expected an error for a path outside an archive")
+	}
+
+	// Members and nested archives larger than the limit are not read
+	if _, err := ReadArchiveMember(archive+"!/docs/readme.md", 4); err == nil {
+		t.Error("Expected an error for a member exceeding the limit")
+	}
+	if _, err := ReadArchiveMember(archive+"!/old.tgz!/notes.txt", int64(len(nested)-1)); err == nil {
+		t.Error("Expected an error for a nested archive exceeding the limit")
+	}
+}
@@ -0,0 +1,193 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// archiveBatchBudget bounds the size of the members an ArchiveBatch reads ahead
+// of time from a single archive. Members beyond it are read by another pass
+// over the archive once the members read ahead have been consumed.
+const archiveBatchBudget = 256 << 20
+
+// ArchiveBatch reads the members of archives for a batch of virtual paths.
+// Reading members one by one with ReadArchiveMember walks their archive every
+// time, which is quadratic in the number of members of compressed tarballs;
+// an ArchiveBatch walks every archive once and keeps the members of the batch
+// until they are read. It is safe for concurrent use.
+type ArchiveBatch struct {
+	maxSize  int64
+	mu       sync.Mutex
+	archives map[string]*archiveLoad // Keyed by the path of the archive file
+}
+
+// archiveLoad holds the members of an archive file read ahead for a batch
+type archiveLoad struct {
+	mu       sync.Mutex
+	path     string
+	members  map[string][]byte // Members read ahead, removed once read
+	pending  map[string]bool   // Members of the batch not read ahead yet
+	nested   map[string]int    // Number of pending members of every nested archive
+	buffered int64             // Total size of the members read ahead
+}
+
+// NewArchiveBatch returns an ArchiveBatch for the archive members among paths,
+// limited to maxSize bytes like ReadArchiveMember. It returns nil if no path is
+// inside an archive.
+func NewArchiveBatch(paths []string, maxSize int64) *ArchiveBatch {
+	var batch *ArchiveBatch
+	for _, virtualPath := range paths {
+		archivePath, members, ok := SplitArchivePath(virtualPath)
+		if !ok {
+			continue
+		}
+		if batch == nil {
+			batch = &ArchiveBatch{maxSize: maxSize, archives: make(map[string]*archiveLoad)}
+		}
+		load := batch.archives[archivePath]
+		if load == nil {
+			load = &archiveLoad{
+				path:    archivePath,
+				members: make(map[string][]byte),
+				pending: make(map[string]bool),
+				nested:  make(map[string]int),
+			}
+			batch.archives[archivePath] = load
+		}
+		key := archiveMemberKey(archivePath, members)
+		if load.pending[key] {
+			continue
+		}
+		load.pending[key] = true
+		for i := 1; i < len(members); i++ {
+			load.nested[archiveMemberKey(archivePath, members[:i])]++
+		}
+	}
+	return batch
+}
+
+// archiveMemberKey returns the virtual path of a member in a canonical form
+func archiveMemberKey(archivePath string, members []string) string {
+	return archivePath + ArchiveSeparator + strings.Join(members, ArchiveSeparator)
+}
+
+// ReadMember reads the contents of the archive member at a virtual path like
+// ReadArchiveMember. Members of the batch are returned once from the members
+// read ahead; other paths, and members read a second time, are read with
+// ReadArchiveMember. A nil ArchiveBatch reads every path with ReadArchiveMember.
+func (b *ArchiveBatch) ReadMember(virtualPath string) ([]byte, error) {
+	if b == nil {
+		return ReadArchiveMember(virtualPath, 0)
+	}
+	archivePath, members, ok := SplitArchivePath(virtualPath)
+	if !ok {
+		return ReadArchiveMember(virtualPath, b.maxSize)
+	}
+
+	b.mu.Lock()
+	load := b.archives[archivePath]
+	b.mu.Unlock()
+	if load == nil {
+		return ReadArchiveMember(virtualPath, b.maxSize)
+	}
+
+	key := archiveMemberKey(archivePath, members)
+	if data, ok := load.take(key, b.maxSize); ok {
+		return data, nil
+	}
+	// Members missing from the archive or too large report their error
+	return ReadArchiveMember(virtualPath, b.maxSize)
+}
+
+// take returns a member of the batch, walking the archive if it has not been
+// read ahead yet. It returns false if the member could not be read ahead.
+func (l *archiveLoad) take(key string, maxSize int64) ([]byte, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.pending[key] {
+		l.readAhead(key, archiveSizeLimit(maxSize))
+		l.done(key)
+	}
+	data, ok := l.members[key]
+	if ok {
+		delete(l.members, key)
+		l.buffered -= int64(len(data))
+	}
+	return data, ok
+}
+
+// done removes a member from the pending members
+func (l *archiveLoad) done(key string) {
+	if !l.pending[key] {
+		return
+	}
+	delete(l.pending, key)
+	for prefix := range l.nested {
+		if strings.HasPrefix(key, prefix+ArchiveSeparator) {
+			if l.nested[prefix]--; l.nested[prefix] == 0 {
+				delete(l.nested, prefix)
+			}
+		}
+	}
+}
+
+// readAhead walks the archive file, reading pending members until the budget
+// is used and the member key has been read. Members that cannot be read are
+// left to ReadArchiveMember.
+func (l *archiveLoad) readAhead(key string, limit int64) {
+	file, err := os.Open(l.path)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	found := false
+	var walk func(virtualPath, name string, archive io.ReaderAt, size int64) error
+	walk = func(virtualPath, name string, archive io.ReaderAt, size int64) error {
+		return walkArchive(name, archive, size, func(member string, memberSize int64, open func() (io.Reader, error)) error {
+			memberPath := virtualPath + ArchiveSeparator + member
+			nested := l.nested[memberPath] > 0
+			if (!nested && !l.pending[memberPath]) || memberSize > limit {
+				return nil
+			}
+			if !nested && memberPath != key && l.buffered >= archiveBatchBudget {
+				if found {
+					return errStopWalk
+				}
+				return nil
+			}
+
+			content, err := open()
+			if err != nil {
+				return nil
+			}
+			data, err := readArchiveContent(content, limit)
+			if err != nil {
+				return nil
+			}
+			if nested {
+				// Damaged nested archives are left to ReadArchiveMember as well
+				if err := walk(memberPath, member, bytes.NewReader(data), int64(len(data))); errors.Is(err, errStopWalk) {
+					return err
+				}
+				return nil
+			}
+
+			l.members[memberPath] = data
+			l.buffered += int64(len(data))
+			found = found || memberPath == key
+			l.done(memberPath)
+			return nil
+		})
+	}
+	walk(l.path, l.path, file, info.Size())
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveBatch(t *testing.T) {
+	tmpDir := t.TempDir()
+	archive := filepath.Join(tmpDir, "backup.zip")
+	writeArchive(t, archive, buildZip(t,
+		archiveFile{"docs/readme.md", "# Readme"},
+		archiveFile{"docs/guide.md", "# Guide"},
+		archiveFile{"old.tgz", string(buildTar(t, true, archiveFile{"notes.txt", "nested notes"}))},
+	))
+	plain := filepath.Join(tmpDir, "plain.txt")
+
+	expected := map[string]string{
+		archive + "!/docs/readme.md":     "# Readme",
+		archive + "!/docs/guide.md":      "# Guide",
+		archive + "!/old.tgz!/notes.txt": "nested notes",
+	}
+	paths := []string{plain, archive + "!/docs/readme.md", archive + "!/docs/guide.md", archive + "!/old.tgz!/notes.txt", archive + "!/missing.md"}
+	batch := NewArchiveBatch(paths, 0)
+	if batch == nil {
+		t.Fatal("NewArchiveBatch() = nil, expected a batch")
+	}
+
+	data, err := batch.ReadMember(archive + "!/docs/readme.md")
+	if err != nil || string(data) != "# Readme" {
+		t.Fatalf("ReadMember() = %q, %v, expected %q", data, err, "# Readme")
+	}
+
+	// The other members were read ahead by the same pass over the archive
+	if err := os.Remove(archive); err != nil {
+		t.Fatalf("Failed to remove archive: %v", err)
+	}
+	for path, content := range expected {
+		if path == archive+"!/docs/readme.md" {
+			continue
+		}
+		data, err := batch.ReadMember(path)
+		if err != nil || string(data) != content {
+			t.Errorf("ReadMember(%q) = %q, %v, expected %q", path, data, err, content)
+		}
+	}
+
+	// Members are kept until read once, missing members report their error
+	if _, err := batch.ReadMember(archive + "!/docs/readme.md"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Second ReadMember() error = %v, expected os.ErrNotExist", err)
+	}
+	if _, err := batch.ReadMember(archive + "!/missing.md"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ReadMember() of a missing member error = %v, expected os.ErrNotExist", err)
+	}
+
+	if NewArchiveBatch([]string{plain}, 0) != nil {
+		t.Error("Expected no batch without archive members")
+	}
+}
+
+func TestArchiveBatch_NilAndLimit(t *testing.T) {
+	tmpDir := t.TempDir()
+	archive := filepath.Join(tmpDir, "logs.tar")
+	writeArchive(t, archive, buildTar(t, false, archiveFile{"app.log", "started"}, archiveFile{"big.log", "0123456789"}))
+
+	var batch *ArchiveBatch
+	if data, err := batch.ReadMember(archive + "!/app.log"); err != nil || string(data) != "started" {
+		t.Errorf("ReadMember() on a nil batch = %q, %v, expected %q", data, err, "started")
+	}
+
+	batch = NewArchiveBatch([]string{archive + "!/app.log", archive + "!/big.log"}, 8)
+	if data, err := batch.ReadMember(archive + "!/app.log"); err != nil || string(data) != "started" {
+		t.Errorf("ReadMember() = %q, %v, expected %q", data, err, "started")
+	}
+	if _, err := batch.ReadMember(archive + "!/big.log"); err == nil {
+		t.Error("Expected an error for a member exceeding the limit")
+	}
+}
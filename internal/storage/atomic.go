@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"fmt"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+)
+
+// castagnoli is the CRC32C table used for chunk checksums
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// chunkChecksum returns the CRC32C checksum of the contents of a chunk file
+func chunkChecksum(data []byte) uint32 {
+	return crc32.Checksum(data, castagnoli)
+}
+
+// writeFileAtomic replaces a file so that readers and a crash at any point see
+// either the previous or the new contents in full. The data is written to a
+// temporary file in the same directory and flushed to disk before it is renamed
+// over path, then the directory is flushed so the rename itself is durable.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", tmpPath, err)
+	}
+
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", tmpPath, err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	if err := syncDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", filepath.Dir(path), err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"mneme/internal/core"
@@ -41,8 +42,7 @@ var BinaryExtensions = map[string]bool{
 	"ac3": true, "dts": true, "ra": true, "ram": true,
 
 	// Documents (binary formats)
-	"pdf": true, "doc": true, "docx": true, "xls": true, "xlsx": true, "ppt": true, "pptx": true,
-	"odt": true, "ods": true, "odp": true, "rtf": true, "wpd": true, "pages": true,
+	"doc": true, "xls": true, "ppt": true, "rtf": true, "wpd": true, "pages": true,
 	"numbers": true, "key": true, "epub": true, "mobi": true,
 
 	// Database files
@@ -63,6 +63,15 @@ var BinaryExtensions = map[string]bool{
 	"lnk": true, "url": true, "scr": true, "sys": true, "drv": true,
 }
 
+// DocumentExtensions are binary document formats whose text is extracted when
+// indexing (see extract.DefaultRegistry). They skip the content-based binary check,
+// which their compressed containers would fail.
+var DocumentExtensions = map[string]bool{
+	"pdf":  true,
+	"docx": true, "docm": true, "dotx": true, "xlsx": true, "xlsm": true, "pptx": true, "pptm": true,
+	"odt": true, "ods": true, "odp": true,
+}
+
 // WindowsSystemFiles are specific filenames that should always be skipped
 // These are common Windows system/metadata files
 var WindowsSystemFiles = map[string]bool{
@@ -105,12 +114,20 @@ func Crawler(inputPath string, options core.CrawlerOptions) ([]string, error) {
 	if !info.IsDir() {
 		logger.Debugf("Path is a file, returning single path: %s", expandedPath)
 
-		// Check if this file should be skipped based on extension
-		if shouldSkipFile(expandedPath, options) {
-			logger.Debugf("File skipped due to extension filter: %s", expandedPath)
+		// Check if this file should be skipped based on extension or globs
+		globs := newSourceGlobs(filepath.Dir(expandedPath), options)
+		if shouldSkipFile(expandedPath, globs, options) {
+			logger.Debugf("File skipped due to extension or glob filter: %s", expandedPath)
 			return []string{}, nil
 		}
 
+		// An archive stands for its members
+		if options.ArchiveDepth > 0 && IsArchive(expandedPath) {
+			results := []string{}
+			crawlArchive(expandedPath, &results, buildExtensionMap(options.IncludeExtensions), buildExtensionMap(options.ExcludeExtensions), buildFolderMap(options.SkipFolders), globs, options)
+			return results, nil
+		}
+
 		return []string{expandedPath}, nil
 	}
 
@@ -121,8 +138,9 @@ func Crawler(inputPath string, options core.CrawlerOptions) ([]string, error) {
 	includeExtMap := buildExtensionMap(options.IncludeExtensions)
 	excludeExtMap := buildExtensionMap(options.ExcludeExtensions)
 	skipFolderMap := buildFolderMap(options.SkipFolders)
+	globs := newSourceGlobs(expandedPath, options)
 
-	err = crawlDirectory(expandedPath, &results, includeExtMap, excludeExtMap, skipFolderMap, options)
+	err = crawlDirectory(expandedPath, &results, includeExtMap, excludeExtMap, skipFolderMap, globs, nil, options)
 	if err != nil {
 		logger.Errorf("Error during crawl: %+v", err)
 		return nil, err
@@ -132,8 +150,9 @@ func Crawler(inputPath string, options core.CrawlerOptions) ([]string, error) {
 	return results, nil
 }
 
-// crawlDirectory recursively crawls a directory and appends file paths to results
-func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]bool, excludeExtMap map[string]bool, skipFolderMap map[string]bool, options core.CrawlerOptions) error {
+// crawlDirectory recursively crawls a directory and appends file paths to results.
+// globs are those of the source root and rules the ignore rules of the parent directories.
+func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]bool, excludeExtMap map[string]bool, skipFolderMap map[string]bool, globs sourceGlobs, rules ignoreRules, options core.CrawlerOptions) error {
 	entries, err := os.ReadDir(dirPath)
 	if err != nil {
 		logger.Errorf("Error reading directory %s: %+v", dirPath, err)
@@ -149,6 +168,10 @@ func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]
 		}
 	}
 
+	if !options.DisableIgnoreFiles {
+		rules = rules.withDirectory(dirPath)
+	}
+
 	for _, entry := range entries {
 		entryName := entry.Name()
 		entryPath := filepath.Join(dirPath, entryName)
@@ -159,21 +182,26 @@ func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]
 			continue
 		}
 
+		// Skip entries excluded by .gitignore, .ignore or .mnemeignore files
+		if rules.ignored(entryPath, entry.IsDir()) {
+			logger.Debugf("Skipping ignored entry: %s", entryPath)
+			continue
+		}
+
 		if entry.IsDir() {
-			// Check if this is a Windows system folder (case-insensitive)
-			if WindowsSystemFiles[strings.ToLower(entryName)] {
-				logger.Debugf("Skipping Windows system folder: %s", entryPath)
+			// Check if this folder should be skipped
+			if shouldSkipDirectory(entryName, skipFolderMap, options) {
+				logger.Debugf("Skipping folder: %s", entryPath)
 				continue
 			}
 
-			// Check if this folder should be skipped
-			if skipFolderMap[entryName] {
-				logger.Debugf("Skipping folder: %s", entryPath)
+			if globs.skipDirectory(entryPath) {
+				logger.Debugf("Skipping folder due to exclude glob: %s", entryPath)
 				continue
 			}
 
 			// Recursively crawl subdirectory
-			err := crawlDirectory(entryPath, results, includeExtMap, excludeExtMap, skipFolderMap, options)
+			err := crawlDirectory(entryPath, results, includeExtMap, excludeExtMap, skipFolderMap, globs, rules, options)
 			if err != nil {
 				// Log the error but continue with other entries
 				logger.Warnf("Error crawling subdirectory %s: %+v", entryPath, err)
@@ -188,6 +216,17 @@ func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]
 				continue
 			}
 
+			// Archives are crawled like folders, and the extension and binary
+			// rules apply to their members
+			if options.ArchiveDepth > 0 && IsArchive(entryName) {
+				if globs.skipDirectory(entryPath) {
+					logger.Debugf("Skipping archive due to exclude glob: %s", entryPath)
+					continue
+				}
+				crawlArchive(entryPath, results, includeExtMap, excludeExtMap, skipFolderMap, globs, options)
+				continue
+			}
+
 			// Check if we should skip binary files
 			if options.SkipBinaryFiles && BinaryExtensions[ext] {
 				logger.Debugf("Skipping binary file: %s", entryPath)
@@ -201,10 +240,15 @@ func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]
 				continue
 			}
 
+			if globs.skipFile(entryPath) {
+				logger.Debugf("Skipping file due to glob filter: %s", entryPath)
+				continue
+			}
+
 			// Content-based binary check for files with no extension or unknown extensions
 			// This catches extensionless binaries (e.g., compiled executables in dist/)
 			if options.SkipBinaryFiles && len(includeExtMap) == 0 {
-				if ext == "" || !isCommonTextExtension(ext) {
+				if ext == "" || !(isCommonTextExtension(ext) || DocumentExtensions[ext]) {
 					if isBinaryFile(entryPath) {
 						logger.Debugf("Skipping binary file (content check): %s", entryPath)
 						continue
@@ -219,8 +263,69 @@ func crawlDirectory(dirPath string, results *[]string, includeExtMap map[string]
 	return nil
 }
 
-// shouldSkipFile checks if a file should be skipped based on options
-func shouldSkipFile(filePath string, options core.CrawlerOptions) bool {
+// shouldSkipDirectory checks if a directory should be skipped based on its name
+func shouldSkipDirectory(dirName string, skipFolderMap map[string]bool, options core.CrawlerOptions) bool {
+	// Skip hidden folders if configured
+	if !options.IncludeHidden && strings.HasPrefix(dirName, ".") {
+		return true
+	}
+
+	// Check if this is a Windows system folder (case-insensitive)
+	if WindowsSystemFiles[strings.ToLower(dirName)] {
+		return true
+	}
+
+	return skipFolderMap[dirName]
+}
+
+// ShouldCrawl reports whether the crawler would visit path when crawling rootPath.
+// Every directory between the root and path is checked against the folder, glob
+// and ignore file rules, and files are additionally checked against the
+// extension, glob and binary rules. Paths outside rootPath are never crawled.
+func ShouldCrawl(rootPath string, path string, isDir bool, options core.CrawlerOptions) bool {
+	rel, err := filepath.Rel(rootPath, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+	if rel == "." {
+		return true
+	}
+
+	skipFolderMap := buildFolderMap(options.SkipFolders)
+	globs := newSourceGlobs(filepath.Clean(rootPath), options)
+	parts := strings.Split(rel, string(filepath.Separator))
+
+	// All parent directories must be crawlable
+	var rules ignoreRules
+	dirPath := filepath.Clean(rootPath)
+	for _, dir := range parts[:len(parts)-1] {
+		if shouldSkipDirectory(dir, skipFolderMap, options) {
+			return false
+		}
+		if !options.DisableIgnoreFiles {
+			rules = rules.withDirectory(dirPath)
+		}
+		dirPath = filepath.Join(dirPath, dir)
+		if rules.ignored(dirPath, true) || globs.skipDirectory(dirPath) {
+			return false
+		}
+	}
+	if !options.DisableIgnoreFiles {
+		rules = rules.withDirectory(dirPath)
+	}
+	entryPath := filepath.Join(dirPath, parts[len(parts)-1])
+	if rules.ignored(entryPath, isDir) {
+		return false
+	}
+
+	if isDir {
+		return !shouldSkipDirectory(parts[len(parts)-1], skipFolderMap, options) && !globs.skipDirectory(entryPath)
+	}
+	return !shouldSkipFile(entryPath, globs, options)
+}
+
+// shouldSkipFile checks if a file should be skipped based on options and the globs of its source root
+func shouldSkipFile(filePath string, globs sourceGlobs, options core.CrawlerOptions) bool {
 	fileName := filepath.Base(filePath)
 
 	// Check if file is a known Windows system file (case-insensitive)
@@ -242,6 +347,11 @@ func shouldSkipFile(filePath string, options core.CrawlerOptions) bool {
 		}
 	}
 
+	// Archives are crawled like folders when archive traversal is enabled
+	if options.ArchiveDepth > 0 && IsArchive(fileName) {
+		return globs.skipDirectory(filePath)
+	}
+
 	// Check if we should skip binary files
 	if options.SkipBinaryFiles && BinaryExtensions[ext] {
 		return true
@@ -259,14 +369,20 @@ func shouldSkipFile(filePath string, options core.CrawlerOptions) bool {
 		if !found {
 			return true
 		}
-	} else if options.SkipBinaryFiles {
+	}
+
+	if globs.skipFile(filePath) {
+		return true
+	}
+
+	if len(options.IncludeExtensions) == 0 && options.SkipBinaryFiles {
 		// If NO specific extensions are included, we must be careful not to index binary files
 		// that don't have a known extension (like the 'mneme' binary).
 		// We perform a content-based check for files with no extension or unknown extension.
 
 		// If extension is known text (go, md, txt, etc.), we skip the expensive content check
 		// But if extension is empty or not in a common allowlist, we check content.
-		if ext == "" || !isCommonTextExtension(ext) {
+		if ext == "" || !(isCommonTextExtension(ext) || DocumentExtensions[ext]) {
 			if isBinaryFile(filePath) {
 				return true
 			}
@@ -301,15 +417,18 @@ func isBinaryFile(path string) bool {
 		return false // Empty file is safe
 	}
 
+	return isBinaryContent(buf[:n])
+}
+
+// isBinaryContent checks if the first bytes of a file contain null bytes
+func isBinaryContent(head []byte) bool {
 	// 1. Check for null bytes (common in binary files)
-	for i := 0; i < n; i++ {
-		if buf[i] == 0 {
-			return true
-		}
+	if bytes.IndexByte(head, 0) >= 0 {
+		return true
 	}
 
 	// 2. Use DetectContentType?
-	// http.DetectContentType(buf[:n]) often returns "application/octet-stream" for binaries,
+	// http.DetectContentType(head) often returns "application/octet-stream" for binaries,
 	// but can be flaky for some source types. Null byte check is robust for executables.
 
 	return false
@@ -242,7 +242,7 @@ func TestShouldSkipFile(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := shouldSkipFile(tt.filePath, tt.options)
+			result := shouldSkipFile(tt.filePath, sourceGlobs{}, tt.options)
 			if result != tt.expected {
 				t.Errorf("shouldSkipFile(%q, %+v) = %v, expected %v",
 					tt.filePath, tt.options, result, tt.expected)
@@ -400,6 +400,34 @@ func TestCrawler_Directory(t *testing.T) {
 	})
 }
 
+func TestCrawler_DocumentFormats(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// Zip containers of Office documents hold null bytes like binaries do
+	zipHeader := []byte("PK\x03\x04\x14\x00\x00\x00\x08\x00")
+	for _, f := range []string{"report.docx", "budget.xlsx", "notes.odt", "program.unknown"} {
+		if err := os.WriteFile(filepath.Join(tmpDir, f), zipHeader, 0644); err != nil {
+			t.Fatalf("Failed to create test file: %v", err)
+		}
+	}
+
+	opts := core.DefaultCrawlerOptions()
+	opts.SkipBinaryFiles = true
+	result, err := Crawler(tmpDir, opts)
+	if err != nil {
+		t.Fatalf("Crawler error: %v", err)
+	}
+	// The documents are crawled, the unknown binary is not
+	if len(result) != 3 {
+		t.Errorf("Expected 3 documents, got %d: %v", len(result), result)
+	}
+	for _, path := range result {
+		if filepath.Base(path) == "program.unknown" {
+			t.Errorf("Expected binary file to be skipped, got %v", result)
+		}
+	}
+}
+
 func TestCrawler_SkipFolders(t *testing.T) {
 	tmpDir := t.TempDir()
 
@@ -478,3 +506,35 @@ func TestCountFilesInEntries(t *testing.T) {
 		t.Errorf("Expected 2 files, got %d", count)
 	}
 }
+
+func TestShouldCrawl(t *testing.T) {
+	root := t.TempDir()
+	options := core.CrawlerOptions{
+		SkipFolders:       []string{"node_modules"},
+		ExcludeExtensions: []string{"log"},
+	}
+
+	tests := []struct {
+		name     string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"root itself", root, true, true},
+		{"regular file", filepath.Join(root, "docs", "readme.md"), false, true},
+		{"regular directory", filepath.Join(root, "docs"), true, true},
+		{"skipped folder", filepath.Join(root, "node_modules"), true, false},
+		{"file below skipped folder", filepath.Join(root, "node_modules", "pkg", "readme.md"), false, false},
+		{"hidden folder", filepath.Join(root, ".git", "config"), false, false},
+		{"excluded extension", filepath.Join(root, "debug.log"), false, false},
+		{"outside root", filepath.Join(filepath.Dir(root), "other.md"), false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ShouldCrawl(root, tt.path, tt.isDir, options); got != tt.expected {
+				t.Errorf("ShouldCrawl(%q) = %v, expected %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"mneme/internal/config"
 	"mneme/internal/constants"
 	"mneme/internal/core"
@@ -16,7 +17,9 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/protobuf/proto"
@@ -25,6 +28,9 @@ import (
 // ErrNoSegments is returned when there are no segments available to search
 var ErrNoSegments = errors.New("no segments found: please run 'mneme index' first to create an index")
 
+// ErrCorruptChunk is returned when a chunk file does not match its checksum or cannot be decoded
+var ErrCorruptChunk = errors.New("corrupt chunk")
+
 // CreateDir Create directory function
 func CreateDir(path string) error {
 	expandedPath, err := utils.ExpandFilePath(path)
@@ -450,14 +456,8 @@ func ReadFileContents(path string) ([]string, error) {
 	}
 	defer file.Close()
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	scanner.Buffer(make([]byte, 0, constants.ScannerInitialBufSize), constants.ScannerMaxBufSize)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
-	}
-
-	if err := scanner.Err(); err != nil {
+	lines, err := ReadLines(file)
+	if err != nil {
 		logger.Errorf("Error reading file %s: %+v", expandedPath, err)
 		return nil, err
 	}
@@ -465,6 +465,17 @@ func ReadFileContents(path string) ([]string, error) {
 	return lines, nil
 }
 
+// ReadLines reads all lines of r, without their line endings
+func ReadLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, constants.ScannerInitialBufSize), constants.ScannerMaxBufSize)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
 // SaveSegmentIndex saves the segment index using the binary protobuf format (default)
 func SaveSegmentIndex(segmentIndex *core.Segment) error {
 	return SaveSegmentIndexBinary(segmentIndex)
@@ -636,78 +647,526 @@ func LoadSegmentIndexBinary() (*core.Segment, error) {
 // ============================================================================
 
 // SaveChunk saves a segment chunk as a numbered file (e.g., 001.idx, 002.idx)
-func SaveChunk(chunk *core.Segment, chunkID int) error {
+// in the segment file format. The file is replaced atomically and flushed to
+// disk. It returns the CRC32C checksum of the file, which callers record in the
+// chunk's ChunkInfo so that LoadChunk can detect a damaged file.
+func SaveChunk(chunk *core.Segment, chunkID int) (uint32, error) {
 	logger.Infof("Saving chunk %03d...", chunkID)
 
-	// Format chunk filename with zero-padding
-	chunkFilename := fmt.Sprintf("%03d.idx", chunkID)
-	chunkPath := filepath.Join(constants.DirPath, "segments", chunkFilename)
+	expandedPath, err := chunkPath(chunkID)
+	if err != nil {
+		return 0, err
+	}
+
+	data := EncodeSegmentFile(chunk)
+	if err := writeFileAtomic(expandedPath, data, 0644); err != nil {
+		logger.Errorf("Error writing chunk to file %s: %+v", expandedPath, err)
+		return 0, fmt.Errorf("failed to write chunk: %w", err)
+	}
+
+	logger.Infof("Chunk %03d saved successfully (%d bytes)", chunkID, len(data))
+	return chunkChecksum(data), nil
+}
+
+// LoadChunk loads a chunk and verifies it against the checksum in its ChunkInfo.
+// Chunks in the protobuf format of storage engine 0.1.0 are still read. Returns
+// an error wrapping ErrCorruptChunk if the chunk is damaged.
+func LoadChunk(chunkInfo core.ChunkInfo) (*core.Segment, error) {
+	logger.Debugf("Loading chunk %03d...", chunkInfo.ID)
 
-	expandedPath, err := utils.ExpandFilePath(chunkPath)
+	expandedPath, err := chunkPath(chunkInfo.ID)
 	if err != nil {
-		logger.Errorf("Error expanding chunk path: %+v", err)
-		return fmt.Errorf("failed to expand chunk path: %w", err)
+		return nil, err
 	}
 
-	// Convert to protobuf and marshal
-	pbSegment := chunk.ToPB()
-	binaryData, err := proto.Marshal(pbSegment)
+	binaryData, err := os.ReadFile(expandedPath)
 	if err != nil {
-		logger.Errorf("Error marshaling chunk to protobuf: %+v", err)
-		return fmt.Errorf("failed to marshal chunk: %w", err)
+		logger.Errorf("Error reading chunk from file %s: %+v", expandedPath, err)
+		return nil, fmt.Errorf("failed to read chunk: %w", err)
 	}
 
-	// Write to file
-	err = os.WriteFile(expandedPath, binaryData, 0644)
+	if err := verifyChunkChecksum(chunkInfo, binaryData); err != nil {
+		logger.Errorf("Error verifying chunk %03d: %+v", chunkInfo.ID, err)
+		return nil, err
+	}
+
+	var segment *core.Segment
+	if IsSegmentFile(binaryData) {
+		segment, err = DecodeSegmentFile(binaryData)
+	} else {
+		segment, err = decodeProtobufChunk(binaryData)
+	}
 	if err != nil {
-		logger.Errorf("Error writing chunk to file %s: %+v", expandedPath, err)
-		return fmt.Errorf("failed to write chunk: %w", err)
+		logger.Errorf("Error decoding chunk %03d: %+v", chunkInfo.ID, err)
+		return nil, fmt.Errorf("%w: failed to decode chunk %d: %w", ErrCorruptChunk, chunkInfo.ID, err)
 	}
 
-	logger.Infof("Chunk %03d saved successfully (%d bytes)", chunkID, len(binaryData))
+	logger.Debugf("Chunk %03d loaded successfully (%d bytes)", chunkInfo.ID, len(binaryData))
+	return segment, nil
+}
+
+// verifyChunkChecksum compares the contents of a chunk file with the checksum
+// recorded in the manifest. Chunks saved before checksums were recorded have
+// none and are not verified.
+func verifyChunkChecksum(chunkInfo core.ChunkInfo, data []byte) error {
+	if chunkInfo.Checksum == 0 {
+		return nil
+	}
+	if checksum := chunkChecksum(data); checksum != chunkInfo.Checksum {
+		return fmt.Errorf("%w: chunk %d has checksum %08x, expected %08x", ErrCorruptChunk, chunkInfo.ID, checksum, chunkInfo.Checksum)
+	}
 	return nil
 }
 
-// LoadChunk loads a specific chunk by ID
-func LoadChunk(chunkID int) (*core.Segment, error) {
-	logger.Debugf("Loading chunk %03d...", chunkID)
+// decodeProtobufChunk decodes a chunk written by storage engine 0.1.0
+func decodeProtobufChunk(data []byte) (*core.Segment, error) {
+	var pbSegment pb.Segment
+	if err := proto.Unmarshal(data, &pbSegment); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal chunk: %w", err)
+	}
+	return core.SegmentFromPB(&pbSegment), nil
+}
 
+// chunkPath returns the expanded path of a chunk file
+func chunkPath(chunkID int) (string, error) {
 	chunkFilename := fmt.Sprintf("%03d.idx", chunkID)
-	chunkPath := filepath.Join(constants.DirPath, "segments", chunkFilename)
-
-	expandedPath, err := utils.ExpandFilePath(chunkPath)
+	expandedPath, err := utils.ExpandFilePath(filepath.Join(constants.DirPath, "segments", chunkFilename))
 	if err != nil {
 		logger.Errorf("Error expanding chunk path: %+v", err)
-		return nil, fmt.Errorf("failed to expand chunk path: %w", err)
+		return "", fmt.Errorf("failed to expand chunk path: %w", err)
 	}
+	return expandedPath, nil
+}
 
-	binaryData, err := os.ReadFile(expandedPath)
+// VerifyChunk reads a chunk and its deletion bitmap completely, verifies the
+// chunk against the checksum in its ChunkInfo and decodes it. Returns an error
+// wrapping ErrCorruptChunk if the chunk is damaged.
+func VerifyChunk(chunkInfo core.ChunkInfo) error {
+	if _, err := LoadChunk(chunkInfo); err != nil {
+		return err
+	}
+	if _, err := LoadDeletions(chunkInfo); err != nil {
+		return fmt.Errorf("%w: failed to load deletions of chunk %d: %w", ErrCorruptChunk, chunkInfo.ID, err)
+	}
+	return nil
+}
+
+// LoadChunkDocuments loads the live documents of a chunk. Only the document
+// table of a segment file is decoded; its postings and checksum are left alone.
+// Protobuf chunks of storage engine 0.1.0 are decoded completely. Returns an
+// error wrapping ErrCorruptChunk if the document table cannot be decoded.
+func LoadChunkDocuments(chunkInfo core.ChunkInfo) ([]core.Document, error) {
+	deleted, err := LoadDeletions(chunkInfo)
 	if err != nil {
-		logger.Errorf("Error reading chunk from file %s: %+v", expandedPath, err)
-		return nil, fmt.Errorf("failed to read chunk: %w", err)
+		return nil, fmt.Errorf("failed to load deletions of chunk %d: %w", chunkInfo.ID, err)
 	}
 
-	var pbSegment pb.Segment
-	err = proto.Unmarshal(binaryData, &pbSegment)
+	expandedPath, err := chunkPath(chunkInfo.ID)
 	if err != nil {
-		logger.Errorf("Error unmarshaling chunk from protobuf: %+v", err)
-		return nil, fmt.Errorf("failed to unmarshal chunk: %w", err)
+		return nil, err
 	}
 
-	segment := core.SegmentFromPB(&pbSegment)
-	logger.Debugf("Chunk %03d loaded successfully (%d bytes)", chunkID, len(binaryData))
-	return segment, nil
+	isSegmentFile, err := hasSegmentFileMagic(expandedPath)
+	if err != nil {
+		return nil, err
+	}
+	if !isSegmentFile {
+		chunk, err := LoadChunk(chunkInfo)
+		if err != nil {
+			return nil, err
+		}
+		docs := make([]core.Document, 0, len(chunk.Docs))
+		for _, doc := range chunk.Docs {
+			if !deleted.Contains(doc.ID) {
+				docs = append(docs, doc)
+			}
+		}
+		return docs, nil
+	}
+
+	segment, err := OpenSegmentFile(expandedPath, deleted)
+	if errors.Is(err, ErrInvalidSegmentFile) {
+		return nil, fmt.Errorf("%w: %w", ErrCorruptChunk, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer segment.Close()
+
+	// Decoded documents do not refer to the mapped file
+	return segment.Documents(), nil
 }
 
-// SaveManifest saves the manifest as JSON in the segments directory
-func SaveManifest(manifest *core.Manifest) error {
-	logger.Info("Saving manifest...")
+// openChunkReader opens a reader over a chunk with its deletions applied.
+// Chunks in the segment file format are memory-mapped and only their structure
+// is validated, so that a query touches just the pages of its terms; the
+// checksum is verified where chunks are read completely (LoadChunk, VerifyChunk).
+// Protobuf chunks of storage engine 0.1.0 are decoded into memory.
+func openChunkReader(chunkInfo core.ChunkInfo) (core.SegmentReader, error) {
+	if err := corruptChunkError(chunkInfo); err != nil {
+		return nil, err
+	}
+
+	deleted, deadTerms, err := loadDeletionFile(chunkInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load deletions of chunk %d: %w", chunkInfo.ID, err)
+	}
+	if deleted.IsEmpty() {
+		deleted = nil
+	}
+
+	expandedPath, err := chunkPath(chunkInfo.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	isSegmentFile, err := hasSegmentFileMagic(expandedPath)
+	if err != nil {
+		return nil, err
+	}
+	if isSegmentFile {
+		segment, err := OpenSegmentFile(expandedPath, deleted)
+		if errors.Is(err, ErrInvalidSegmentFile) {
+			return nil, fmt.Errorf("%w: %w", ErrCorruptChunk, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if deleted != nil {
+			segment.deadTerms = deadTerms
+		}
+		segment.onCorrupt = func(err error) {
+			markChunkCorrupt(chunkInfo, err)
+		}
+		return segment, nil
+	}
+
+	chunk, err := LoadChunk(chunkInfo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load chunk %d: %w", chunkInfo.ID, err)
+	}
+	chunk.Deleted = deleted
+	return core.NewSegmentReader(chunk), nil
+}
+
+// corruptChunk identifies a version of a chunk file
+type corruptChunk struct {
+	id       int
+	checksum uint32
+}
+
+// corruptChunks holds the chunks whose postings failed to decode while they
+// were searched. Readers opened later by the same process, e.g. when
+// 'mneme serve' reloads the index, leave them out like chunks that fail to open.
+var (
+	corruptChunksMu sync.Mutex
+	corruptChunks   = make(map[corruptChunk]error)
+)
+
+// markChunkCorrupt records that the postings of a chunk cannot be decoded
+func markChunkCorrupt(chunkInfo core.ChunkInfo, err error) {
+	corruptChunksMu.Lock()
+	defer corruptChunksMu.Unlock()
+	corruptChunks[corruptChunk{id: chunkInfo.ID, checksum: chunkInfo.Checksum}] = err
+}
+
+// corruptChunkError returns the error recorded by markChunkCorrupt for a chunk, or nil
+func corruptChunkError(chunkInfo core.ChunkInfo) error {
+	corruptChunksMu.Lock()
+	defer corruptChunksMu.Unlock()
+	return corruptChunks[corruptChunk{id: chunkInfo.ID, checksum: chunkInfo.Checksum}]
+}
+
+// hasSegmentFileMagic reports whether a chunk file is in the segment file format
+func hasSegmentFileMagic(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		logger.Errorf("Error opening chunk %s: %+v", path, err)
+		return false, fmt.Errorf("failed to open chunk: %w", err)
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(segmentMagic))
+	n, _ := file.Read(magic)
+	return IsSegmentFile(magic[:n]), nil
+}
+
+// MigrateChunks rewrites all chunks still in the protobuf format of storage
+// engine 0.1.0 in the segment file format and records the current storage
+// version in the VERSION file. It returns the number of migrated chunks.
+// The caller must hold the index lock.
+func MigrateChunks() (int, error) {
+	manifest, err := LoadManifest()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	migrated := 0
+	if manifest != nil {
+		for _, chunkInfo := range manifest.GetCompleteChunks() {
+			expandedPath, err := chunkPath(chunkInfo.ID)
+			if err != nil {
+				return migrated, err
+			}
+
+			isSegmentFile, err := hasSegmentFileMagic(expandedPath)
+			if err != nil {
+				return migrated, err
+			}
+			if isSegmentFile {
+				continue
+			}
+
+			chunk, err := LoadChunk(chunkInfo)
+			if err != nil {
+				return migrated, err
+			}
+			checksum, err := SaveChunk(chunk, chunkInfo.ID)
+			if err != nil {
+				return migrated, err
+			}
+			manifest.GetChunk(chunkInfo.ID).Checksum = checksum
+			migrated++
+		}
+
+		if migrated > 0 {
+			if err := SaveManifest(manifest); err != nil {
+				return migrated, err
+			}
+		}
+	}
+
+	if err := writeVersionFile(); err != nil {
+		return migrated, err
+	}
+
+	logger.Infof("Migrated %d chunks to storage engine %s", migrated, version.MnemeStorageEngineVersion)
+	return migrated, nil
+}
+
+// NeedsMigration reports whether the data directory was written by another
+// storage engine version
+func NeedsMigration() (bool, error) {
+	content, err := ReadVersionFile()
+	if err != nil {
+		return false, err
+	}
+
+	storageVersion, _, _, err := ParseVersionFile(content)
+	if err != nil {
+		return false, err
+	}
+
+	return storageVersion != version.MnemeStorageEngineVersion, nil
+}
+
+// writeVersionFile replaces the VERSION file with the current versions
+func writeVersionFile() error {
+	expandedPath, err := utils.ExpandFilePath(filepath.Join(constants.DirPath, "VERSION"))
+	if err != nil {
+		logger.Errorf("Error expanding version path: %+v", err)
+		return fmt.Errorf("failed to expand version path: %w", err)
+	}
+
+	if err := writeFileAtomic(expandedPath, []byte(getVersionFileContents()), 0644); err != nil {
+		logger.Errorf("Error writing VERSION file: %+v", err)
+		return fmt.Errorf("failed to write VERSION file: %w", err)
+	}
+	return nil
+}
+
+// LoadDeletions loads the deletion bitmap of a chunk.
+// Chunks without deleted documents yield an empty bitmap.
+func LoadDeletions(chunkInfo core.ChunkInfo) (*core.DeletionBitmap, error) {
+	bitmap, _, err := loadDeletionFile(chunkInfo)
+	return bitmap, err
+}
+
+// loadDeletionFile loads the deletion bitmap of a chunk and the dictionary
+// positions of the terms of the chunk without live postings. A deletion file
+// holds the deletion bitmap, followed by the dead terms in a bitmap of the
+// same format. The dead terms are nil for chunks without deleted documents
+// and for deletion files written before they were recorded.
+func loadDeletionFile(chunkInfo core.ChunkInfo) (*core.DeletionBitmap, *core.DeletionBitmap, error) {
+	bitmap := &core.DeletionBitmap{}
+	if chunkInfo.DeletesFile == "" {
+		return bitmap, nil, nil
+	}
+
+	deletesPath := filepath.Join(constants.DirPath, "segments", chunkInfo.DeletesFile)
+	expandedPath, err := utils.ExpandFilePath(deletesPath)
+	if err != nil {
+		logger.Errorf("Error expanding deletion bitmap path: %+v", err)
+		return nil, nil, fmt.Errorf("failed to expand deletion bitmap path: %w", err)
+	}
 
+	data, err := os.ReadFile(expandedPath)
+	if err != nil {
+		logger.Errorf("Error reading deletion bitmap from file %s: %+v", expandedPath, err)
+		return nil, nil, fmt.Errorf("failed to read deletion bitmap: %w", err)
+	}
+
+	rest, err := bitmap.UnmarshalPrefix(data)
+	var deadTerms *core.DeletionBitmap
+	if err == nil && len(rest) > 0 {
+		deadTerms = &core.DeletionBitmap{}
+		err = deadTerms.UnmarshalBinary(rest)
+	}
+	if err != nil {
+		logger.Errorf("Error decoding deletion bitmap %s: %+v", expandedPath, err)
+		return nil, nil, fmt.Errorf("failed to decode deletion bitmap %s: %w", chunkInfo.DeletesFile, err)
+	}
+
+	return bitmap, deadTerms, nil
+}
+
+// ApplyDeletions marks documents as deleted in the deletion bitmaps of their chunks
+// (chunk ID -> document IDs) and records the bitmaps in the manifest. Every
+// update writes a new version of a bitmap (e.g. "001.3.del"), so the manifest on
+// disk keeps pointing at the previous bitmaps until the caller saves it; the
+// caller then removes them with RemoveUnreferencedDeletions.
+func ApplyDeletions(manifest *core.Manifest, deletions map[int][]uint) error {
+	for chunkID, docIDs := range deletions {
+		if len(docIDs) == 0 {
+			continue
+		}
+
+		chunkInfo := manifest.GetChunk(chunkID)
+		if chunkInfo == nil {
+			return fmt.Errorf("failed to apply deletions: chunk %d not found in manifest", chunkID)
+		}
+
+		bitmap, err := LoadDeletions(*chunkInfo)
+		if err != nil {
+			return err
+		}
+
+		for _, docID := range docIDs {
+			bitmap.Add(docID)
+		}
+
+		data, err := encodeDeletions(chunkID, bitmap)
+		if err != nil {
+			return fmt.Errorf("failed to encode deletion bitmap of chunk %d: %w", chunkID, err)
+		}
+
+		deletesFilename := nextDeletesFilename(chunkID, chunkInfo.DeletesFile)
+		deletesPath := filepath.Join(constants.DirPath, "segments", deletesFilename)
+		expandedPath, err := utils.ExpandFilePath(deletesPath)
+		if err != nil {
+			logger.Errorf("Error expanding deletion bitmap path: %+v", err)
+			return fmt.Errorf("failed to expand deletion bitmap path: %w", err)
+		}
+
+		if err := writeFileAtomic(expandedPath, data, 0644); err != nil {
+			logger.Errorf("Error writing deletion bitmap to file %s: %+v", expandedPath, err)
+			return fmt.Errorf("failed to write deletion bitmap: %w", err)
+		}
+
+		chunkInfo.DeletesFile = deletesFilename
+		chunkInfo.DeletedCount = bitmap.Count()
+		logger.Debugf("Chunk %03d has %d deleted documents", chunkID, chunkInfo.DeletedCount)
+	}
+
+	manifest.UpdatedAt = time.Now()
+	return nil
+}
+
+// nextDeletesFilename returns the name of the next version of the deletion
+// bitmap of a chunk, given the current one. Bitmaps written before they were
+// versioned ("001.del") count as version 0.
+func nextDeletesFilename(chunkID int, current string) string {
+	version := 0
+	if parts := strings.Split(strings.TrimSuffix(current, ".del"), "."); len(parts) == 2 {
+		version, _ = strconv.Atoi(parts[1])
+	}
+	return fmt.Sprintf("%03d.%d.del", chunkID, version+1)
+}
+
+// RemoveUnreferencedDeletions removes the deletion bitmaps in the segments
+// directory that no chunk of the saved manifest references: the previous
+// versions of updated bitmaps and those written before an interrupted update
+// saved its manifest. Bitmaps that cannot be removed are left for the next call.
+func RemoveUnreferencedDeletions(manifest *core.Manifest) error {
+	expandedPath, err := utils.ExpandFilePath(filepath.Join(constants.DirPath, "segments"))
+	if err != nil {
+		logger.Errorf("Error expanding segments path: %+v", err)
+		return fmt.Errorf("failed to expand segments path: %w", err)
+	}
+	entries, err := os.ReadDir(expandedPath)
+	if err != nil {
+		return fmt.Errorf("failed to read segments directory: %w", err)
+	}
+
+	referenced := make(map[string]bool, len(manifest.Chunks))
+	for _, chunkInfo := range manifest.Chunks {
+		referenced[chunkInfo.DeletesFile] = true
+	}
+
+	var errs []error
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".del") || referenced[name] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(expandedPath, name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			errs = append(errs, err)
+			continue
+		}
+		logger.Debugf("Removed unreferenced deletion bitmap %s", name)
+	}
+	return errors.Join(errs...)
+}
+
+// encodeDeletions encodes the deletion file of a chunk: its deletion bitmap and,
+// for chunks in the segment file format, the dictionary positions of the terms
+// left without live postings, so that readers need not decode every posting
+// list to find them
+func encodeDeletions(chunkID int, deleted *core.DeletionBitmap) ([]byte, error) {
+	data, err := deleted.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+
+	expandedPath, err := chunkPath(chunkID)
+	if err != nil {
+		return nil, err
+	}
+	isSegmentFile, err := hasSegmentFileMagic(expandedPath)
+	if err != nil || !isSegmentFile {
+		return data, err
+	}
+	segment, err := OpenSegmentFile(expandedPath, deleted)
+	if err != nil {
+		return nil, err
+	}
+	defer segment.Close()
+
+	deadTerms, err := segment.deadTermOrdinals().MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	return append(data, deadTerms...), nil
+}
+
+// ManifestPath returns the expanded path of the manifest in the segments directory
+func ManifestPath() (string, error) {
 	manifestPath := filepath.Join(constants.DirPath, "segments", "manifest.json")
 	expandedPath, err := utils.ExpandFilePath(manifestPath)
 	if err != nil {
 		logger.Errorf("Error expanding manifest path: %+v", err)
-		return fmt.Errorf("failed to expand manifest path: %w", err)
+		return "", fmt.Errorf("failed to expand manifest path: %w", err)
+	}
+	return expandedPath, nil
+}
+
+// SaveManifest saves the manifest as JSON in the segments directory
+func SaveManifest(manifest *core.Manifest) error {
+	logger.Info("Saving manifest...")
+
+	expandedPath, err := ManifestPath()
+	if err != nil {
+		return err
 	}
 
 	jsonData, err := json.MarshalIndent(manifest, "", "  ")
@@ -716,8 +1175,9 @@ func SaveManifest(manifest *core.Manifest) error {
 		return fmt.Errorf("failed to marshal manifest: %w", err)
 	}
 
-	err = os.WriteFile(expandedPath, jsonData, 0644)
-	if err != nil {
+	// Readers never see a partial manifest and a crash or full disk leaves
+	// the previous manifest intact
+	if err := writeFileAtomic(expandedPath, jsonData, 0644); err != nil {
 		logger.Errorf("Error writing manifest to file %s: %+v", expandedPath, err)
 		return fmt.Errorf("failed to write manifest: %w", err)
 	}
@@ -730,11 +1190,9 @@ func SaveManifest(manifest *core.Manifest) error {
 func LoadManifest() (*core.Manifest, error) {
 	logger.Info("Loading manifest...")
 
-	manifestPath := filepath.Join(constants.DirPath, "segments", "manifest.json")
-	expandedPath, err := utils.ExpandFilePath(manifestPath)
+	expandedPath, err := ManifestPath()
 	if err != nil {
-		logger.Errorf("Error expanding manifest path: %+v", err)
-		return nil, fmt.Errorf("failed to expand manifest path: %w", err)
+		return nil, err
 	}
 
 	jsonData, err := os.ReadFile(expandedPath)
@@ -758,7 +1216,9 @@ func LoadManifest() (*core.Manifest, error) {
 	return &manifest, nil
 }
 
-// LoadAllChunks loads all complete chunks and merges them into a single segment
+// LoadAllChunks loads all complete chunks and merges them into a single segment.
+// Chunks that cannot be loaded, e.g. because they are corrupt, are reported and
+// left out; an error is returned only if none of the chunks could be loaded.
 func LoadAllChunks() (*core.Segment, error) {
 	logger.Info("Loading all chunks...")
 
@@ -781,19 +1241,35 @@ func LoadAllChunks() (*core.Segment, error) {
 		return nil, ErrNoSegments
 	}
 
-	// Merge all chunks into a single segment
+	// Merge all chunks into a single segment. Postings of deleted documents are
+	// kept and masked by the merged deletion bitmap.
 	mergedDocs := make([]core.Document, 0)
 	mergedIndex := make(map[string][]core.Posting)
+	deleted := &core.DeletionBitmap{}
+	loaded := make([]core.ChunkInfo, 0, len(completeChunks))
+	var errs []error
 
 	for _, chunkInfo := range completeChunks {
-		chunk, err := LoadChunk(chunkInfo.ID)
+		chunk, err := LoadChunk(chunkInfo)
 		if err != nil {
-			logger.Errorf("Error loading chunk %d: %+v", chunkInfo.ID, err)
-			return nil, fmt.Errorf("failed to load chunk %d: %w", chunkInfo.ID, err)
+			errs = append(errs, fmt.Errorf("failed to load chunk %d: %w", chunkInfo.ID, err))
+			continue
 		}
 
-		// Merge documents
-		mergedDocs = append(mergedDocs, chunk.Docs...)
+		chunkDeletions, err := LoadDeletions(chunkInfo)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to load deletions of chunk %d: %w", chunkInfo.ID, err))
+			continue
+		}
+		deleted.Union(chunkDeletions)
+		loaded = append(loaded, chunkInfo)
+
+		// Merge live documents
+		for _, doc := range chunk.Docs {
+			if !chunkDeletions.Contains(doc.ID) {
+				mergedDocs = append(mergedDocs, doc)
+			}
+		}
 
 		// Merge inverted index
 		for term, postings := range chunk.InvertedIndex {
@@ -801,19 +1277,114 @@ func LoadAllChunks() (*core.Segment, error) {
 		}
 	}
 
+	if len(loaded) == 0 {
+		return nil, errors.Join(errs...)
+	}
+	totals := skipUnloadedChunks(manifest, loaded, errs)
+
 	mergedSegment := &core.Segment{
 		Docs:          mergedDocs,
 		InvertedIndex: mergedIndex,
-		TotalDocs:     manifest.TotalDocs,
-		TotalTokens:   manifest.TotalTokens,
-		AvgDocLen:     manifest.AvgDocLen,
+		TotalDocs:     totals.TotalDocs,
+		TotalTokens:   totals.TotalTokens,
+		AvgDocLen:     totals.AvgDocLen,
+	}
+
+	// The manifest totals already exclude deleted documents
+	if !deleted.IsEmpty() {
+		mergedSegment.Deleted = deleted
 	}
 
 	logger.Debugf("Merged %d chunks into single segment (%d docs, %d tokens)",
-		len(completeChunks), len(mergedDocs), len(mergedIndex))
+		len(loaded), len(mergedDocs), len(mergedIndex))
 	return mergedSegment, nil
 }
 
+// skipUnloadedChunks reports the chunks that failed to load and returns the
+// manifest with only the loaded chunks, so that corpus statistics match the
+// documents that can actually be searched
+func skipUnloadedChunks(manifest *core.Manifest, loaded []core.ChunkInfo, errs []error) *core.Manifest {
+	if len(errs) == 0 {
+		return manifest
+	}
+
+	for _, err := range errs {
+		logger.Warnf("Skipping chunk: %+v", err)
+	}
+	logger.Warnf("%d chunks could not be loaded and are left out of search results; run 'mneme index' to rebuild them", len(errs))
+
+	totals := *manifest
+	totals.Chunks = loaded
+	totals.UpdateTotals()
+	return &totals
+}
+
+// OpenIndexReader opens a reader per complete chunk without merging them, so that
+// queries can score the chunks in parallel. Chunks are opened concurrently and the
+// corpus statistics are taken from the manifest. Chunks that fail to open, e.g.
+// because they are corrupt, are reported and left out. Without a manifest the
+// legacy single segment is opened instead.
+func OpenIndexReader() (*core.IndexReader, error) {
+	logger.Info("Opening index reader...")
+
+	manifest, err := LoadManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest: %w", err)
+	}
+
+	// If no manifest exists, fall back to legacy single segment
+	if manifest == nil {
+		logger.Debug("No manifest found, trying legacy segment format...")
+		segment, err := LoadSegmentIndexBinary()
+		if err != nil {
+			return nil, err
+		}
+		return core.NewIndexReader(segment), nil
+	}
+
+	completeChunks := manifest.GetCompleteChunks()
+	if len(completeChunks) == 0 {
+		logger.Warn("No complete chunks found in manifest")
+		return nil, ErrNoSegments
+	}
+
+	readers := make([]core.SegmentReader, len(completeChunks))
+	errs := make([]error, len(completeChunks))
+
+	var wg sync.WaitGroup
+	for i, chunkInfo := range completeChunks {
+		wg.Go(func() {
+			readers[i], errs[i] = openChunkReader(chunkInfo)
+		})
+	}
+	wg.Wait()
+
+	// Chunks that fail to open are left out instead of failing every query
+	opened := readers[:0]
+	loaded := make([]core.ChunkInfo, 0, len(completeChunks))
+	var failed []error
+	for i, reader := range readers {
+		if errs[i] != nil {
+			failed = append(failed, fmt.Errorf("failed to open chunk %d: %w", completeChunks[i].ID, errs[i]))
+			continue
+		}
+		opened = append(opened, reader)
+		loaded = append(loaded, completeChunks[i])
+	}
+	if len(opened) == 0 {
+		return nil, errors.Join(failed...)
+	}
+	totals := skipUnloadedChunks(manifest, loaded, failed)
+
+	logger.Debugf("Opened %d chunks (%d live docs)", len(opened), totals.TotalDocs)
+	return &core.IndexReader{
+		Segments:    opened,
+		TotalDocs:   totals.TotalDocs,
+		TotalTokens: totals.TotalTokens,
+		AvgDocLen:   totals.AvgDocLen,
+	}, nil
+}
+
 // MoveSegmentsToTombstones moves all segment files to the tombstones directory
 // instead of deleting them. Files are prefixed with a timestamp to prevent naming conflicts.
 func MoveSegmentsToTombstones() error {
@@ -877,6 +1448,58 @@ func MoveSegmentsToTombstones() error {
 	return nil
 }
 
+// MoveChunksToTombstones moves the files of the given chunks, including their
+// deletion bitmaps, to the tombstones directory. It is used after the chunks were
+// replaced in the manifest, e.g. by compaction.
+func MoveChunksToTombstones(chunks []core.ChunkInfo) error {
+	segmentsPath := filepath.Join(constants.DirPath, "segments")
+	expandedSegmentsPath, err := utils.ExpandFilePath(segmentsPath)
+	if err != nil {
+		logger.Errorf("Error expanding segments path: %+v", err)
+		return fmt.Errorf("failed to expand segments path: %w", err)
+	}
+
+	tombstonesPath := filepath.Join(constants.DirPath, "tombstones")
+	expandedTombstonesPath, err := utils.ExpandFilePath(tombstonesPath)
+	if err != nil {
+		logger.Errorf("Error expanding tombstones path: %+v", err)
+		return fmt.Errorf("failed to expand tombstones path: %w", err)
+	}
+
+	if err := os.MkdirAll(expandedTombstonesPath, os.ModePerm); err != nil {
+		logger.Errorf("Error creating tombstones directory: %+v", err)
+		return fmt.Errorf("failed to create tombstones directory: %w", err)
+	}
+
+	timestamp := time.Now().Format("2006-01-02T15-04-05")
+	movedCount := 0
+
+	for _, chunk := range chunks {
+		for _, name := range []string{chunk.Filename, chunk.DeletesFile} {
+			if name == "" {
+				continue
+			}
+
+			srcPath := filepath.Join(expandedSegmentsPath, name)
+			destPath := filepath.Join(expandedTombstonesPath, fmt.Sprintf("%s_%s", timestamp, name))
+
+			if err := os.Rename(srcPath, destPath); err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					logger.Debugf("Chunk file %s already gone, skipping", name)
+					continue
+				}
+				logger.Errorf("Error moving file %s to tombstones: %+v", name, err)
+				return fmt.Errorf("failed to move file %s: %w", name, err)
+			}
+			logger.Debugf("Moved to tombstones: %s -> %s", name, filepath.Base(destPath))
+			movedCount++
+		}
+	}
+
+	logger.Infof("Moved %d chunk files to tombstones", movedCount)
+	return nil
+}
+
 // GetTombstonesSize calculates the total size of files in the tombstones directory
 func GetTombstonesSize() (int64, error) {
 	tombstonesPath := filepath.Join(constants.DirPath, "tombstones")
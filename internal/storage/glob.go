@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"mneme/internal/core"
+	"mneme/internal/logger"
+	"mneme/internal/utils"
+)
+
+// globPattern is a doublestar pattern matched against slash separated paths
+// relative to a source root. "**" matches any number of directories, "*" and
+// "?" match within a single name, and {a,b} matches either alternative.
+type globPattern struct {
+	segments []string
+}
+
+// compileGlob compiles a pattern into one globPattern per brace alternative
+func compileGlob(pattern string) ([]globPattern, error) {
+	alternatives, err := expandBraces(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	globs := make([]globPattern, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		var glob globPattern
+		for _, segment := range strings.Split(strings.Trim(alternative, "/"), "/") {
+			if segment == "" {
+				continue
+			}
+			if segment == "**" && len(glob.segments) > 0 && glob.segments[len(glob.segments)-1] == "**" {
+				continue
+			}
+			if _, err := path.Match(segment, ""); err != nil {
+				return nil, fmt.Errorf("invalid glob %q: %w", pattern, err)
+			}
+			glob.segments = append(glob.segments, segment)
+		}
+		globs = append(globs, glob)
+	}
+	return globs, nil
+}
+
+// expandBraces expands the first {a,b} group of a pattern, recursively
+func expandBraces(pattern string) ([]string, error) {
+	start := -1
+	depth := 0
+	var commas []int
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if depth == 0 {
+				start = i
+				commas = commas[:0]
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				return nil, fmt.Errorf("invalid glob %q: unmatched }", pattern)
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+
+			prefix, suffix := pattern[:start], pattern[i+1:]
+			bounds := append(append([]int{start}, commas...), i)
+			var expanded []string
+			for j := 0; j+1 < len(bounds); j++ {
+				rest, err := expandBraces(prefix + pattern[bounds[j]+1:bounds[j+1]] + suffix)
+				if err != nil {
+					return nil, err
+				}
+				expanded = append(expanded, rest...)
+			}
+			return expanded, nil
+		}
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("invalid glob %q: unmatched {", pattern)
+	}
+	return []string{pattern}, nil
+}
+
+// match reports whether the pattern matches a slash separated relative path
+func (g globPattern) match(relPath string) bool {
+	return matchSegments(g.segments, strings.Split(relPath, "/"))
+}
+
+// matchesAllBelow reports whether the pattern matches every path inside a
+// directory, e.g. "docs/archive/**" for "docs/archive"
+func (g globPattern) matchesAllBelow(relDir string) bool {
+	n := len(g.segments)
+	return n > 0 && g.segments[n-1] == "**" && matchSegments(g.segments[:n-1], strings.Split(relDir, "/"))
+}
+
+// matchSegments matches path segments against pattern segments, where a "**"
+// segment matches zero or more path segments
+func matchSegments(pattern []string, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, err := path.Match(pattern[0], segments[0]); err != nil || !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// sourceGlobs holds the compiled include and exclude globs of one source root
+type sourceGlobs struct {
+	root    string
+	include []globPattern
+	exclude []globPattern
+}
+
+// newSourceGlobs compiles the globs that apply below root: those of the source
+// override for root if there is one, otherwise the global ones. Invalid
+// patterns are logged and ignored.
+func newSourceGlobs(root string, options core.CrawlerOptions) sourceGlobs {
+	include, exclude := options.IncludeGlobs, options.ExcludeGlobs
+	for _, override := range options.SourceOverrides {
+		overridePath, err := utils.ExpandFilePath(override.Path)
+		if err == nil && filepath.Clean(overridePath) == filepath.Clean(root) {
+			include, exclude = override.IncludeGlobs, override.ExcludeGlobs
+		}
+	}
+
+	return sourceGlobs{
+		root:    root,
+		include: compileGlobs(include),
+		exclude: compileGlobs(exclude),
+	}
+}
+
+// compileGlobs compiles a list of patterns, skipping invalid ones
+func compileGlobs(patterns []string) []globPattern {
+	var globs []globPattern
+	for _, pattern := range patterns {
+		compiled, err := compileGlob(pattern)
+		if err != nil {
+			logger.Warnf("Ignoring glob: %+v", err)
+			continue
+		}
+		globs = append(globs, compiled...)
+	}
+	return globs
+}
+
+// relPath returns the slash separated path relative to the source root, or
+// false if path is not below the root
+func (s sourceGlobs) relPath(filePath string) (string, bool) {
+	rel, err := filepath.Rel(s.root, filePath)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// skipFile reports whether the globs exclude a file: it matches an exclude
+// glob, or include globs are given and it matches none of them
+func (s sourceGlobs) skipFile(filePath string) bool {
+	if len(s.include) == 0 && len(s.exclude) == 0 {
+		return false
+	}
+	rel, ok := s.relPath(filePath)
+	if !ok {
+		return false
+	}
+
+	for _, glob := range s.exclude {
+		if glob.match(rel) {
+			return true
+		}
+	}
+	if len(s.include) == 0 {
+		return false
+	}
+	for _, glob := range s.include {
+		if glob.match(rel) {
+			return false
+		}
+	}
+	return true
+}
+
+// skipDirectory reports whether an exclude glob matches a directory itself
+// or everything inside it. Include globs never skip directories.
+func (s sourceGlobs) skipDirectory(dirPath string) bool {
+	if len(s.exclude) == 0 {
+		return false
+	}
+	rel, ok := s.relPath(dirPath)
+	if !ok {
+		return false
+	}
+
+	for _, glob := range s.exclude {
+		if glob.match(rel) || glob.matchesAllBelow(rel) {
+			return true
+		}
+	}
+	return false
+}
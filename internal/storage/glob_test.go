@@ -0,0 +1,130 @@
+package storage
+
+import (
+	"mneme/internal/core"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{"*.md", "notes.md", true},
+		{"*.md", "docs/notes.md", false},
+		{"**/*.md", "notes.md", true},
+		{"**/*.md", "docs/a/notes.md", true},
+		{"docs/**/*.md", "docs/notes.md", true},
+		{"docs/**/*.md", "docs/a/b/notes.md", true},
+		{"docs/**/*.md", "src/notes.md", false},
+		{"**/Dockerfile", "deploy/api/Dockerfile", true},
+		{"**/{Dockerfile,Makefile}", "Makefile", true},
+		{"**/{Dockerfile,Makefile}", "README", false},
+		{"*.{md,txt}", "todo.txt", true},
+		{"src/{a,b{1,2}}/x", "src/b2/x", true},
+		{"src/{a,b{1,2}}/x", "src/b3/x", false},
+		{"file?.txt", "file1.txt", true},
+		{"file[0-9].txt", "filex.txt", false},
+		{"docs/archive/**", "docs/archive/2020/old.md", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			globs, err := compileGlob(tt.pattern)
+			if err != nil {
+				t.Fatalf("compileGlob(%q) returned error: %v", tt.pattern, err)
+			}
+			got := false
+			for _, glob := range globs {
+				got = got || glob.match(tt.path)
+			}
+			if got != tt.expected {
+				t.Errorf("%q matching %q = %v, expected %v", tt.pattern, tt.path, got, tt.expected)
+			}
+		})
+	}
+
+	for _, pattern := range []string{"docs/{a,b", "docs/a}", "file[.txt"} {
+		if _, err := compileGlob(pattern); err == nil {
+			t.Errorf("Expected an error for %q", pattern)
+		}
+	}
+}
+
+func TestCrawler_Globs(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		"docs/guide.md":           "guide",
+		"docs/api/reference.md":   "reference",
+		"docs/api/schema.json":    "{}",
+		"docs/archive/old.md":     "old",
+		"deploy/Dockerfile":       "FROM scratch",
+		"Makefile":                "all:",
+		"src/main.go":             "package main",
+		"src/docs/not-in-docs.md": "not below docs/",
+	})
+
+	crawl := func(t *testing.T, options core.CrawlerOptions) []string {
+		t.Helper()
+		results, err := Crawler(root, options)
+		if err != nil {
+			t.Fatalf("Crawler returned error: %v", err)
+		}
+		rel := make([]string, len(results))
+		for i, path := range results {
+			r, _ := filepath.Rel(root, path)
+			rel[i] = filepath.ToSlash(r)
+		}
+		sort.Strings(rel)
+		return rel
+	}
+
+	options := core.CrawlerOptions{
+		IncludeGlobs: []string{"docs/**/*.md", "**/{Dockerfile,Makefile}"},
+		ExcludeGlobs: []string{"docs/archive/**"},
+	}
+	expected := []string{"Makefile", "deploy/Dockerfile", "docs/api/reference.md", "docs/guide.md"}
+	if got := crawl(t, options); !equalStrings(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	for _, name := range expected {
+		if !ShouldCrawl(root, filepath.Join(root, filepath.FromSlash(name)), false, options) {
+			t.Errorf("ShouldCrawl(%q) disagrees with the crawler", name)
+		}
+	}
+	if ShouldCrawl(root, filepath.Join(root, "docs", "archive"), true, options) {
+		t.Error("ShouldCrawl should skip directories matched by an exclude glob")
+	}
+	if ShouldCrawl(root, filepath.Join(root, "src", "docs", "not-in-docs.md"), false, options) {
+		t.Error("ShouldCrawl should match globs relative to the source root")
+	}
+
+	t.Run("source override", func(t *testing.T) {
+		overridden := options
+		overridden.SourceOverrides = []core.SourceOverride{
+			{Path: filepath.Join(root, "other")},
+			{Path: root, IncludeGlobs: []string{"src/**"}},
+		}
+		expected := []string{"src/docs/not-in-docs.md", "src/main.go"}
+		if got := crawl(t, overridden); !equalStrings(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+
+		overridden.SourceOverrides[1].ExcludeGlobs = []string{"**/*.go"}
+		expected = []string{"src/docs/not-in-docs.md"}
+		if got := crawl(t, overridden); !equalStrings(got, expected) {
+			t.Errorf("Expected %v, got %v", expected, got)
+		}
+	})
+
+	t.Run("invalid globs are ignored", func(t *testing.T) {
+		got := crawl(t, core.CrawlerOptions{ExcludeGlobs: []string{"[", "**/*.json"}})
+		if len(got) != 7 {
+			t.Errorf("Expected all files but the JSON schema, got %v", got)
+		}
+	})
+}
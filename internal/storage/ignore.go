@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"mneme/internal/logger"
+)
+
+// IgnoreFileNames are the files read from every crawled directory for gitignore
+// style exclusion rules. Rules of later files take precedence over earlier ones,
+// so .mnemeignore can re-include files that .gitignore excludes.
+var IgnoreFileNames = []string{".gitignore", ".ignore", ".mnemeignore"}
+
+// ignoreRule is a single pattern of an ignore file
+type ignoreRule struct {
+	base     string   // Slash separated directory of the ignore file
+	segments []string // Pattern split at slashes, "**" matching any number of segments
+	negate   bool     // Pattern started with !, re-including matching paths
+	dirOnly  bool     // Pattern ended with /, matching directories only
+}
+
+// ignoreRules are the rules of all ignore files between the crawl root and a
+// directory, outermost first. The last matching rule decides whether a path is ignored.
+type ignoreRules []ignoreRule
+
+// withDirectory returns the rules extended by the ignore files found in dirPath.
+// The receiver is never modified, so sibling directories can share it.
+func (r ignoreRules) withDirectory(dirPath string) ignoreRules {
+	var loaded []ignoreRule
+	for _, name := range IgnoreFileNames {
+		rules, err := parseIgnoreFile(filepath.Join(dirPath, name), dirPath)
+		if err != nil {
+			logger.Warnf("Failed to read ignore file %s: %+v", filepath.Join(dirPath, name), err)
+			continue
+		}
+		loaded = append(loaded, rules...)
+	}
+
+	if len(loaded) == 0 {
+		return r
+	}
+	return append(r[:len(r):len(r)], loaded...)
+}
+
+// ignored reports whether the rules exclude path
+func (r ignoreRules) ignored(filePath string, isDir bool) bool {
+	slashPath := filepath.ToSlash(filePath)
+	ignored := false
+	for _, rule := range r {
+		if rule.matches(slashPath, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// matches reports whether the rule matches a slash separated path
+func (rule ignoreRule) matches(slashPath string, isDir bool) bool {
+	if rule.dirOnly && !isDir {
+		return false
+	}
+
+	prefix := rule.base
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	rel, ok := strings.CutPrefix(slashPath, prefix)
+	if !ok || rel == "" {
+		return false
+	}
+	return matchSegments(rule.segments, strings.Split(rel, "/"))
+}
+
+// parseIgnoreFile reads the rules of an ignore file located in dirPath.
+// A missing file has no rules.
+func parseIgnoreFile(filePath string, dirPath string) ([]ignoreRule, error) {
+	f, err := os.Open(filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	base := filepath.ToSlash(dirPath)
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnorePattern(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	logger.Debugf("Loaded %d rules from %s", len(rules), filePath)
+	return rules, nil
+}
+
+// parseIgnorePattern parses one line of an ignore file with gitignore semantics.
+// It returns false for blank lines and comments.
+func parseIgnorePattern(line string, base string) (ignoreRule, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimUnescapedSpaces(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash at the start or in the middle anchors the pattern to the
+	// directory of the ignore file; otherwise it matches at any depth
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	if !anchored {
+		rule.segments = []string{"**"}
+	}
+
+	for _, segment := range strings.Split(line, "/") {
+		if segment == "" {
+			continue
+		}
+		// Consecutive ** segments are equivalent to a single one
+		if segment == "**" && len(rule.segments) > 0 && rule.segments[len(rule.segments)-1] == "**" {
+			continue
+		}
+		rule.segments = append(rule.segments, segment)
+	}
+
+	// A trailing /** matches everything inside a directory, but not the directory itself
+	if len(rule.segments) > 1 && rule.segments[len(rule.segments)-1] == "**" {
+		rule.segments = append(rule.segments, "*")
+	}
+	return rule, true
+}
+
+// trimUnescapedSpaces removes trailing spaces unless they are escaped with a backslash
+func trimUnescapedSpaces(line string) string {
+	end := len(line)
+	for end > 0 && line[end-1] == ' ' {
+		if end > 1 && line[end-2] == '\\' {
+			return line[:end-2] + " "
+		}
+		end--
+	}
+	return line[:end]
+}
@@ -0,0 +1,142 @@
+package storage
+
+import (
+	"mneme/internal/core"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"name at any depth", []string{"*.log"}, "a/b/debug.log", false, true},
+		{"name not matching", []string{"*.log"}, "a/b/debug.md", false, false},
+		{"comment", []string{"# *.md"}, "notes.md", false, false},
+		{"escaped hash", []string{`\#notes.md`}, "#notes.md", false, true},
+		{"directory only matches directory", []string{"build/"}, "build", true, true},
+		{"directory only skips file", []string{"build/"}, "build", false, false},
+		{"anchored at root", []string{"/out"}, "out", true, true},
+		{"anchored not nested", []string{"/out"}, "src/out", true, false},
+		{"middle slash anchors", []string{"docs/gen"}, "docs/gen", true, true},
+		{"middle slash not nested", []string{"docs/gen"}, "x/docs/gen", true, false},
+		{"leading double star", []string{"**/gen"}, "a/b/gen", true, true},
+		{"middle double star", []string{"a/**/z.md"}, "a/b/c/z.md", false, true},
+		{"middle double star without directories", []string{"a/**/z.md"}, "a/z.md", false, true},
+		{"trailing double star contents", []string{"vendor/**"}, "vendor/pkg/x.go", false, true},
+		{"trailing double star excludes directory itself", []string{"vendor/**"}, "vendor", true, false},
+		{"negation", []string{"*.md", "!keep.md"}, "keep.md", false, false},
+		{"negation overridden", []string{"!keep.md", "*.md"}, "keep.md", false, true},
+		{"character class", []string{"file[0-9].txt"}, "file3.txt", false, true},
+		{"question mark", []string{"?.txt"}, "ab.txt", false, false},
+		{"escaped trailing space", []string{`space\ `}, "space ", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules ignoreRules
+			for _, pattern := range tt.patterns {
+				if rule, ok := parseIgnorePattern(pattern, "/root"); ok {
+					rules = append(rules, rule)
+				}
+			}
+			if got := rules.ignored("/root/"+tt.path, tt.isDir); got != tt.expected {
+				t.Errorf("patterns %q on %q = %v, expected %v", tt.patterns, tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCrawler_IgnoreFiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFiles(t, root, map[string]string{
+		".gitignore":          "dist/\n*.gen.md\n",
+		".mnemeignore":        "!keep.gen.md\n",
+		"notes.md":            "notes",
+		"a.gen.md":            "generated",
+		"keep.gen.md":         "kept",
+		"dist/out.md":         "build output",
+		"sub/.ignore":         "/local.md\n",
+		"sub/local.md":        "ignored in sub",
+		"sub/deep/local.md":   "anchored rule does not apply",
+		"sub/deep/b.gen.md":   "generated",
+		"other/local.md":      "rule of sub does not apply",
+		"other/.gitignore":    "*\n!*.md\n",
+		"other/data.txt":      "ignored by star",
+		"sub/deep/.gitignore": "!b.gen.md\n",
+	})
+
+	relPaths := func(paths []string) []string {
+		rel := make([]string, len(paths))
+		for i, path := range paths {
+			r, _ := filepath.Rel(root, path)
+			rel[i] = filepath.ToSlash(r)
+		}
+		sort.Strings(rel)
+		return rel
+	}
+
+	results, err := Crawler(root, core.CrawlerOptions{})
+	if err != nil {
+		t.Fatalf("Crawler returned error: %v", err)
+	}
+	expected := []string{"keep.gen.md", "notes.md", "other/local.md", "sub/deep/b.gen.md", "sub/deep/local.md"}
+	if got := relPaths(results); !equalStrings(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	for _, path := range results {
+		if !ShouldCrawl(root, path, false, core.CrawlerOptions{}) {
+			t.Errorf("ShouldCrawl(%q) disagrees with the crawler", path)
+		}
+	}
+	for _, name := range []string{"a.gen.md", "dist/out.md", "sub/local.md", "other/data.txt"} {
+		if ShouldCrawl(root, filepath.Join(root, filepath.FromSlash(name)), false, core.CrawlerOptions{}) {
+			t.Errorf("ShouldCrawl(%q) should respect the ignore files", name)
+		}
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		results, err := Crawler(root, core.CrawlerOptions{DisableIgnoreFiles: true})
+		if err != nil {
+			t.Fatalf("Crawler returned error: %v", err)
+		}
+		if len(results) != 9 {
+			t.Errorf("Expected all 9 visible files, got %v", relPaths(results))
+		}
+		if !ShouldCrawl(root, filepath.Join(root, "dist", "out.md"), false, core.CrawlerOptions{DisableIgnoreFiles: true}) {
+			t.Error("ShouldCrawl should ignore the ignore files when disabled")
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
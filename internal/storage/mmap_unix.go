@@ -0,0 +1,33 @@
+//go:build unix
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// mapFile memory-maps a file read-only. The returned function unmaps it.
+func mapFile(path string) ([]byte, func() error, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	if info.Size() == 0 {
+		return nil, nil, fmt.Errorf("failed to map %s: %w", path, ErrInvalidSegmentFile)
+	}
+
+	data, err := syscall.Mmap(int(file.Fd()), 0, int(info.Size()), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to map %s: %w", path, err)
+	}
+
+	return data, func() error { return syscall.Munmap(data) }, nil
+}
@@ -0,0 +1,760 @@
+package storage
+
+import (
+	"bytes"
+	"container/list"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"mneme/internal/core"
+	"mneme/internal/logger"
+	"slices"
+	"sort"
+	"sync"
+)
+
+// Segment file format (storage engine 0.2.0)
+//
+//	header     "MSEG" | uint32 format version
+//	documents  uvarint count, then per document: uvarint id, uvarint token count,
+//	           varint mod time, varint size, uvarint length + path,
+//	           uvarint length + content hash, uvarint length + source
+//	           (format version 2 and later), uvarint field count and uvarint
+//	           field lengths (format version 3 and later), uvarint length +
+//	           title, uvarint tag count and uvarint length + tag per tag, and
+//	           varint date (format version 4 and later)
+//	postings   the postings of every term in dictionary order, each posting as
+//	           uvarint doc ID delta, uvarint freq, uvarint position count,
+//	           uvarint position deltas, uvarint field count and uvarint field
+//	           frequencies (format version 3 and later)
+//	dictionary terms in sorted order, in blocks of segmentBlockSize entries.
+//	           Each entry is uvarint shared prefix length with the previous
+//	           term of the block, uvarint suffix length, suffix, uvarint
+//	           document frequency, uvarint postings offset and uvarint
+//	           postings length. The first entry of a block stores its full term.
+//	block index uint64 offset of every dictionary block
+//	footer     fixed size, see segmentFooter
+//
+// Integers of fixed size are little-endian. Offsets are absolute file offsets,
+// postings offsets are relative to the postings section.
+const (
+	segmentMagic         = "MSEG"
+	segmentFormatVersion = 4
+	segmentBlockSize     = 16
+	segmentHeaderSize    = len(segmentMagic) + 4
+	segmentFooterSize    = 6*8 + 3*4 + len(segmentMagic)
+	// segmentMaxFields bounds the field counts of documents and postings, leaving
+	// room for fields added later
+	segmentMaxFields = 64
+	// postingsCacheLimit bounds the decoded postings a SegmentFile keeps, counting
+	// every posting and position, so that long-running readers do not grow with
+	// every term ever looked up
+	postingsCacheLimit = 1 << 20
+)
+
+// ErrInvalidSegmentFile is returned when a segment file cannot be decoded
+var ErrInvalidSegmentFile = errors.New("invalid segment file")
+
+// segmentFooter locates the sections of a segment file
+type segmentFooter struct {
+	docsOffset       uint64
+	postingsOffset   uint64
+	dictOffset       uint64
+	blockIndexOffset uint64
+	termCount        uint64
+	blockCount       uint64
+	totalDocs        uint32
+	totalTokens      uint32
+	avgDocLen        uint32
+}
+
+// IsSegmentFile reports whether data starts with the segment file magic.
+// Chunks written by older storage engines are protobuf encoded instead.
+func IsSegmentFile(data []byte) bool {
+	return len(data) >= len(segmentMagic) && string(data[:len(segmentMagic)]) == segmentMagic
+}
+
+// EncodeSegmentFile encodes a segment in the segment file format
+func EncodeSegmentFile(segment *core.Segment) []byte {
+	buf := make([]byte, 0, segmentHeaderSize+64*len(segment.Docs))
+	buf = append(buf, segmentMagic...)
+	buf = binary.LittleEndian.AppendUint32(buf, segmentFormatVersion)
+
+	// Documents
+	footer := segmentFooter{
+		docsOffset:  uint64(len(buf)),
+		totalDocs:   uint32(segment.TotalDocs),
+		totalTokens: uint32(segment.TotalTokens),
+		avgDocLen:   uint32(segment.AvgDocLen),
+	}
+	buf = binary.AppendUvarint(buf, uint64(len(segment.Docs)))
+	for _, doc := range segment.Docs {
+		buf = binary.AppendUvarint(buf, uint64(doc.ID))
+		buf = binary.AppendUvarint(buf, uint64(doc.TokenCount))
+		buf = binary.AppendVarint(buf, doc.ModTime)
+		buf = binary.AppendVarint(buf, doc.Size)
+		buf = appendString(buf, doc.Path)
+		buf = appendString(buf, doc.ContentHash)
+		buf = appendString(buf, doc.Source)
+		buf = appendUvarints(buf, doc.FieldLengths)
+		buf = appendString(buf, doc.Title)
+		buf = appendStrings(buf, doc.Tags)
+		buf = binary.AppendVarint(buf, doc.Date)
+	}
+
+	terms := make([]string, 0, len(segment.InvertedIndex))
+	for term := range segment.InvertedIndex {
+		terms = append(terms, term)
+	}
+	sort.Strings(terms)
+
+	// Postings
+	footer.postingsOffset = uint64(len(buf))
+	postingOffsets := make([]uint64, len(terms)+1)
+	for i, term := range terms {
+		postingOffsets[i] = uint64(len(buf)) - footer.postingsOffset
+		buf = appendPostings(buf, segment.InvertedIndex[term])
+	}
+	postingOffsets[len(terms)] = uint64(len(buf)) - footer.postingsOffset
+
+	// Dictionary
+	footer.dictOffset = uint64(len(buf))
+	blockOffsets := make([]uint64, 0, (len(terms)+segmentBlockSize-1)/segmentBlockSize)
+	previous := ""
+	for i, term := range terms {
+		shared := 0
+		if i%segmentBlockSize == 0 {
+			blockOffsets = append(blockOffsets, uint64(len(buf)))
+		} else {
+			shared = sharedPrefixLength(previous, term)
+		}
+		buf = binary.AppendUvarint(buf, uint64(shared))
+		buf = appendString(buf, term[shared:])
+		buf = binary.AppendUvarint(buf, uint64(len(segment.InvertedIndex[term])))
+		buf = binary.AppendUvarint(buf, postingOffsets[i])
+		buf = binary.AppendUvarint(buf, postingOffsets[i+1]-postingOffsets[i])
+		previous = term
+	}
+
+	// Block index
+	footer.blockIndexOffset = uint64(len(buf))
+	for _, offset := range blockOffsets {
+		buf = binary.LittleEndian.AppendUint64(buf, offset)
+	}
+	footer.termCount = uint64(len(terms))
+	footer.blockCount = uint64(len(blockOffsets))
+
+	// Footer
+	for _, value := range []uint64{footer.docsOffset, footer.postingsOffset, footer.dictOffset,
+		footer.blockIndexOffset, footer.termCount, footer.blockCount} {
+		buf = binary.LittleEndian.AppendUint64(buf, value)
+	}
+	for _, value := range []uint32{footer.totalDocs, footer.totalTokens, footer.avgDocLen} {
+		buf = binary.LittleEndian.AppendUint32(buf, value)
+	}
+	return append(buf, segmentMagic...)
+}
+
+// appendPostings encodes the postings of a term ordered by document ID, with
+// document IDs and positions delta encoded
+func appendPostings(buf []byte, postings []core.Posting) []byte {
+	if !slices.IsSortedFunc(postings, comparePostings) {
+		postings = slices.Clone(postings)
+		slices.SortStableFunc(postings, comparePostings)
+	}
+
+	previousDoc := uint(0)
+	for _, posting := range postings {
+		buf = binary.AppendUvarint(buf, uint64(posting.DocID-previousDoc))
+		buf = binary.AppendUvarint(buf, uint64(posting.Freq))
+		previousDoc = posting.DocID
+
+		positions := posting.Positions
+		if !slices.IsSorted(positions) {
+			positions = slices.Clone(positions)
+			slices.Sort(positions)
+		}
+		buf = binary.AppendUvarint(buf, uint64(len(positions)))
+		previousPos := uint(0)
+		for _, pos := range positions {
+			buf = binary.AppendUvarint(buf, uint64(pos-previousPos))
+			previousPos = pos
+		}
+		buf = appendUvarints(buf, posting.FieldFreqs)
+	}
+	return buf
+}
+
+// appendUvarints encodes a count followed by the values
+func appendUvarints(buf []byte, values []uint) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(values)))
+	for _, value := range values {
+		buf = binary.AppendUvarint(buf, uint64(value))
+	}
+	return buf
+}
+
+func comparePostings(a, b core.Posting) int {
+	switch {
+	case a.DocID < b.DocID:
+		return -1
+	case a.DocID > b.DocID:
+		return 1
+	}
+	return 0
+}
+
+func appendString(buf []byte, s string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(s)))
+	return append(buf, s...)
+}
+
+// appendStrings encodes a count followed by the strings
+func appendStrings(buf []byte, values []string) []byte {
+	buf = binary.AppendUvarint(buf, uint64(len(values)))
+	for _, value := range values {
+		buf = appendString(buf, value)
+	}
+	return buf
+}
+
+func sharedPrefixLength(a, b string) int {
+	n := min(len(a), len(b))
+	for i := 0; i < n; i++ {
+		if a[i] != b[i] {
+			return i
+		}
+	}
+	return n
+}
+
+// segmentDecoder reads varints from a segment file, recording the first error
+type segmentDecoder struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (d *segmentDecoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	if d.pos < 0 || d.pos >= len(d.data) {
+		d.err = ErrInvalidSegmentFile
+		return 0
+	}
+	value, n := binary.Uvarint(d.data[d.pos:])
+	if n <= 0 {
+		d.err = ErrInvalidSegmentFile
+		return 0
+	}
+	d.pos += n
+	return value
+}
+
+func (d *segmentDecoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	if d.pos < 0 || d.pos >= len(d.data) {
+		d.err = ErrInvalidSegmentFile
+		return 0
+	}
+	value, n := binary.Varint(d.data[d.pos:])
+	if n <= 0 {
+		d.err = ErrInvalidSegmentFile
+		return 0
+	}
+	d.pos += n
+	return value
+}
+
+// uvarints decodes a count followed by the values, returning nil for a count of 0.
+// The count is bounded by limit.
+func (d *segmentDecoder) uvarints(limit uint64) []uint {
+	count := d.uvarint()
+	if count == 0 || d.err != nil {
+		return nil
+	}
+	if count > limit {
+		d.err = ErrInvalidSegmentFile
+		return nil
+	}
+	values := make([]uint, count)
+	for i := range values {
+		values[i] = uint(d.uvarint())
+	}
+	return values
+}
+
+// strings decodes a count followed by the strings, returning nil for a count
+// of 0. Every string takes at least a byte, which bounds the count.
+func (d *segmentDecoder) strings() []string {
+	count := d.uvarint()
+	if count == 0 || d.err != nil {
+		return nil
+	}
+	if count > uint64(len(d.data)-d.pos) {
+		d.err = ErrInvalidSegmentFile
+		return nil
+	}
+	values := make([]string, count)
+	for i := range values {
+		values[i] = string(d.bytes())
+	}
+	return values
+}
+
+func (d *segmentDecoder) bytes() []byte {
+	length := d.uvarint()
+	if d.err != nil {
+		return nil
+	}
+	if length > uint64(len(d.data)-d.pos) {
+		d.err = ErrInvalidSegmentFile
+		return nil
+	}
+	b := d.data[d.pos : d.pos+int(length)]
+	d.pos += int(length)
+	return b
+}
+
+// dictEntry is a decoded term dictionary entry
+type dictEntry struct {
+	term           string
+	docFreq        uint64
+	postingsOffset uint64
+	postingsLength uint64
+}
+
+// SegmentFile is a segment chunk in the segment file format. Documents are decoded
+// when the file is opened; the term dictionary and postings are read from the
+// memory-mapped file on demand, so a query only touches the pages of its terms.
+// It implements core.SegmentReader.
+type SegmentFile struct {
+	path    string // Path of the file, empty for files decoded from memory
+	data    []byte
+	unmap   func() error
+	version uint32 // Format version
+	footer  segmentFooter
+	docs    []core.Document
+	docIdx  map[uint]int
+	deleted *core.DeletionBitmap
+
+	// deadTerms holds the dictionary positions of the terms without live
+	// postings, as recorded with the deletions, or nil if none were recorded
+	deadTerms *core.DeletionBitmap
+
+	// onCorrupt is called once with the first error decoding postings
+	onCorrupt   func(err error)
+	corruptOnce sync.Once
+
+	mu         sync.Mutex
+	postings   map[string]*list.Element // Decoded postings of recently looked up terms
+	recent     *list.List               // Cached postings, most recently used first
+	cacheSize  int                      // Size of the cached postings, see postingsSize
+	cacheLimit int                      // Largest cacheSize
+}
+
+// cachedPostings are the decoded postings of a term
+type cachedPostings struct {
+	term     string
+	postings []core.Posting
+	size     int
+}
+
+// OpenSegmentFile memory-maps a segment file. Documents in deleted are reported
+// as deleted.
+func OpenSegmentFile(path string, deleted *core.DeletionBitmap) (*SegmentFile, error) {
+	data, unmap, err := mapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	segment, err := newSegmentFile(data, unmap, deleted)
+	if err != nil {
+		unmap()
+		return nil, fmt.Errorf("failed to open segment file %s: %w", path, err)
+	}
+	segment.path = path
+	return segment, nil
+}
+
+// newSegmentFile validates the footer of a segment file and decodes its documents
+func newSegmentFile(data []byte, unmap func() error, deleted *core.DeletionBitmap) (*SegmentFile, error) {
+	if len(data) < segmentHeaderSize+segmentFooterSize || !IsSegmentFile(data) ||
+		string(data[len(data)-len(segmentMagic):]) != segmentMagic {
+		return nil, ErrInvalidSegmentFile
+	}
+	// Version 1 files lack the document source and version 2 files the fields;
+	// both remain readable
+	formatVersion := binary.LittleEndian.Uint32(data[len(segmentMagic):])
+	if formatVersion < 1 || formatVersion > segmentFormatVersion {
+		return nil, fmt.Errorf("%w: unsupported format version", ErrInvalidSegmentFile)
+	}
+
+	raw := data[len(data)-segmentFooterSize:]
+	footer := segmentFooter{
+		docsOffset:       binary.LittleEndian.Uint64(raw[0:]),
+		postingsOffset:   binary.LittleEndian.Uint64(raw[8:]),
+		dictOffset:       binary.LittleEndian.Uint64(raw[16:]),
+		blockIndexOffset: binary.LittleEndian.Uint64(raw[24:]),
+		termCount:        binary.LittleEndian.Uint64(raw[32:]),
+		blockCount:       binary.LittleEndian.Uint64(raw[40:]),
+		totalDocs:        binary.LittleEndian.Uint32(raw[48:]),
+		totalTokens:      binary.LittleEndian.Uint32(raw[52:]),
+		avgDocLen:        binary.LittleEndian.Uint32(raw[56:]),
+	}
+
+	end := uint64(len(data) - segmentFooterSize)
+	if footer.docsOffset > footer.postingsOffset || footer.postingsOffset > footer.dictOffset ||
+		footer.dictOffset > footer.blockIndexOffset || footer.blockIndexOffset > end ||
+		footer.blockCount > (end-footer.blockIndexOffset)/8 ||
+		footer.blockCount != (footer.termCount+segmentBlockSize-1)/segmentBlockSize {
+		return nil, ErrInvalidSegmentFile
+	}
+
+	s := &SegmentFile{
+		data:       data,
+		unmap:      unmap,
+		version:    formatVersion,
+		footer:     footer,
+		deleted:    deleted,
+		postings:   make(map[string]*list.Element),
+		recent:     list.New(),
+		cacheLimit: postingsCacheLimit,
+	}
+
+	d := &segmentDecoder{data: data[:footer.postingsOffset], pos: int(footer.docsOffset)}
+	count := d.uvarint()
+	if count > uint64(len(data)) {
+		return nil, ErrInvalidSegmentFile
+	}
+	s.docs = make([]core.Document, 0, count)
+	s.docIdx = make(map[uint]int, count)
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		doc := core.Document{
+			ID:         uint(d.uvarint()),
+			TokenCount: uint(d.uvarint()),
+			ModTime:    d.varint(),
+			Size:       d.varint(),
+		}
+		doc.Path = string(d.bytes())
+		doc.ContentHash = string(d.bytes())
+		if formatVersion >= 2 {
+			doc.Source = string(d.bytes())
+		}
+		if formatVersion >= 3 {
+			doc.FieldLengths = d.uvarints(segmentMaxFields)
+		}
+		if formatVersion >= 4 {
+			doc.Title = string(d.bytes())
+			doc.Tags = d.strings()
+			doc.Date = d.varint()
+		}
+		s.docIdx[doc.ID] = len(s.docs)
+		s.docs = append(s.docs, doc)
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	return s, nil
+}
+
+// blockOffset returns the file offset of a dictionary block
+func (s *SegmentFile) blockOffset(block int) int {
+	return int(binary.LittleEndian.Uint64(s.data[s.footer.blockIndexOffset+uint64(block)*8:]))
+}
+
+// forEachEntry decodes the entries of a dictionary block until fn returns false
+func (s *SegmentFile) forEachEntry(block int, fn func(entry dictEntry) bool) error {
+	d := &segmentDecoder{data: s.data[:s.footer.blockIndexOffset], pos: s.blockOffset(block)}
+	entries := min(segmentBlockSize, int(s.footer.termCount)-block*segmentBlockSize)
+
+	var term []byte
+	for i := 0; i < entries; i++ {
+		shared := d.uvarint()
+		suffix := d.bytes()
+		entry := dictEntry{
+			docFreq:        d.uvarint(),
+			postingsOffset: d.uvarint(),
+			postingsLength: d.uvarint(),
+		}
+		if d.err != nil {
+			return d.err
+		}
+		if shared > uint64(len(term)) {
+			return ErrInvalidSegmentFile
+		}
+		term = append(term[:shared], suffix...)
+		entry.term = string(term)
+		if !fn(entry) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// firstTerm returns the first term of a dictionary block, which is stored in full
+func (s *SegmentFile) firstTerm(block int) []byte {
+	d := &segmentDecoder{data: s.data[:s.footer.blockIndexOffset], pos: s.blockOffset(block)}
+	d.uvarint()
+	return d.bytes()
+}
+
+// lookup finds the dictionary entry of a term by binary search over the blocks
+func (s *SegmentFile) lookup(term string) (dictEntry, bool) {
+	blocks := int(s.footer.blockCount)
+	// The last block whose first term is <= term
+	block := sort.Search(blocks, func(i int) bool {
+		return bytes.Compare(s.firstTerm(i), []byte(term)) > 0
+	}) - 1
+	if block < 0 {
+		return dictEntry{}, false
+	}
+
+	var found dictEntry
+	ok := false
+	s.forEachEntry(block, func(entry dictEntry) bool {
+		if entry.term >= term {
+			found, ok = entry, entry.term == term
+			return false
+		}
+		return true
+	})
+	return found, ok
+}
+
+// decodePostings decodes the postings of a dictionary entry
+func (s *SegmentFile) decodePostings(entry dictEntry) ([]core.Posting, error) {
+	start := s.footer.postingsOffset + entry.postingsOffset
+	end := start + entry.postingsLength
+	if end > s.footer.dictOffset || end < start || entry.docFreq > entry.postingsLength {
+		return nil, ErrInvalidSegmentFile
+	}
+
+	d := &segmentDecoder{data: s.data[:end], pos: int(start)}
+	postings := make([]core.Posting, entry.docFreq)
+	docID := uint(0)
+	for i := range postings {
+		docID += uint(d.uvarint())
+		postings[i].DocID = docID
+		postings[i].Freq = uint(d.uvarint())
+
+		count := d.uvarint()
+		if count > entry.postingsLength {
+			return nil, ErrInvalidSegmentFile
+		}
+		if count > 0 {
+			positions := make([]uint, count)
+			pos := uint(0)
+			for j := range positions {
+				pos += uint(d.uvarint())
+				positions[j] = pos
+			}
+			postings[i].Positions = positions
+		}
+		if s.version >= 3 {
+			postings[i].FieldFreqs = d.uvarints(segmentMaxFields)
+		}
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	return postings, nil
+}
+
+// Postings returns the postings of a term. Decoded postings are cached, up to
+// postingsCacheLimit, evicting the least recently used terms first; terms
+// missing from the dictionary are not cached.
+func (s *SegmentFile) Postings(term string) []core.Posting {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if element, ok := s.postings[term]; ok {
+		s.recent.MoveToFront(element)
+		return element.Value.(*cachedPostings).postings
+	}
+
+	entry, ok := s.lookup(term)
+	if !ok {
+		return nil
+	}
+	postings, err := s.decodePostings(entry)
+	if err != nil {
+		s.markCorrupt(term, err)
+		return nil
+	}
+
+	size := postingsSize(postings)
+	if size > s.cacheLimit {
+		return postings
+	}
+	for s.cacheSize+size > s.cacheLimit {
+		oldest := s.recent.Back()
+		evicted := s.recent.Remove(oldest).(*cachedPostings)
+		delete(s.postings, evicted.term)
+		s.cacheSize -= evicted.size
+	}
+	s.postings[term] = s.recent.PushFront(&cachedPostings{term: term, postings: postings, size: size})
+	s.cacheSize += size
+	return postings
+}
+
+// markCorrupt reports the first term whose postings cannot be decoded. The
+// postings of the term are left out of search results.
+func (s *SegmentFile) markCorrupt(term string, err error) {
+	s.corruptOnce.Do(func() {
+		err = fmt.Errorf("%w: postings of %q in %s: %w", ErrCorruptChunk, term, s.path, err)
+		logger.Warnf("Some search results are missing; run 'mneme verify' to check the index: %+v", err)
+		if s.onCorrupt != nil {
+			s.onCorrupt(err)
+		}
+	})
+}
+
+// postingsSize returns the number of postings and positions of a posting list
+func postingsSize(postings []core.Posting) int {
+	size := len(postings)
+	for _, posting := range postings {
+		size += len(posting.Positions)
+	}
+	return size
+}
+
+// Terms returns the terms with at least one live posting. Terms without live
+// postings are skipped by the positions recorded with the deletions; only
+// deletion files written before those were recorded require decoding the
+// postings of every term.
+func (s *SegmentFile) Terms() []string {
+	hasDeletions := !s.deleted.IsEmpty()
+	terms := make([]string, 0, s.footer.termCount)
+	for block := 0; block < int(s.footer.blockCount); block++ {
+		ordinal := uint(block * segmentBlockSize)
+		s.forEachEntry(block, func(entry dictEntry) bool {
+			dead := false
+			switch {
+			case !hasDeletions:
+			case s.deadTerms != nil:
+				dead = s.deadTerms.Contains(ordinal)
+			default:
+				dead = !s.hasLivePosting(entry)
+			}
+			ordinal++
+			if !dead {
+				terms = append(terms, entry.term)
+			}
+			return true
+		})
+	}
+	return terms
+}
+
+// deadTermOrdinals returns the dictionary positions of the terms without live
+// postings, decoding the postings of every term
+func (s *SegmentFile) deadTermOrdinals() *core.DeletionBitmap {
+	dead := &core.DeletionBitmap{}
+	if s.deleted.IsEmpty() {
+		return dead
+	}
+	for block := 0; block < int(s.footer.blockCount); block++ {
+		ordinal := uint(block * segmentBlockSize)
+		s.forEachEntry(block, func(entry dictEntry) bool {
+			if !s.hasLivePosting(entry) {
+				dead.Add(ordinal)
+			}
+			ordinal++
+			return true
+		})
+	}
+	return dead
+}
+
+// hasLivePosting reports whether a term occurs in a document that was not deleted
+func (s *SegmentFile) hasLivePosting(entry dictEntry) bool {
+	postings, err := s.decodePostings(entry)
+	if err != nil {
+		s.markCorrupt(entry.term, err)
+		return false
+	}
+	for _, posting := range postings {
+		if !s.deleted.Contains(posting.DocID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SegmentFile) Document(docID uint) (core.Document, bool) {
+	i, ok := s.docIdx[docID]
+	if !ok {
+		return core.Document{}, false
+	}
+	return s.docs[i], true
+}
+
+func (s *SegmentFile) Documents() []core.Document {
+	if s.deleted.IsEmpty() {
+		return s.docs
+	}
+	docs := make([]core.Document, 0, len(s.docs))
+	for _, doc := range s.docs {
+		if !s.deleted.Contains(doc.ID) {
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+func (s *SegmentFile) IsDeleted(docID uint) bool {
+	return s.deleted.Contains(docID)
+}
+
+// Close unmaps the file. The reader must not be used afterwards.
+func (s *SegmentFile) Close() error {
+	if s.unmap == nil {
+		return nil
+	}
+	err := s.unmap()
+	s.unmap = nil
+	return err
+}
+
+// Segment decodes the whole file into an in-memory segment
+func (s *SegmentFile) Segment() (*core.Segment, error) {
+	segment := &core.Segment{
+		Docs:          slices.Clone(s.docs),
+		InvertedIndex: make(map[string][]core.Posting, s.footer.termCount),
+		TotalDocs:     uint(s.footer.totalDocs),
+		TotalTokens:   uint(s.footer.totalTokens),
+		AvgDocLen:     uint(s.footer.avgDocLen),
+	}
+
+	for block := 0; block < int(s.footer.blockCount); block++ {
+		var decodeErr error
+		err := s.forEachEntry(block, func(entry dictEntry) bool {
+			postings, err := s.decodePostings(entry)
+			if err != nil {
+				decodeErr = err
+				return false
+			}
+			segment.InvertedIndex[entry.term] = postings
+			return true
+		})
+		if err = errors.Join(err, decodeErr); err != nil {
+			return nil, err
+		}
+	}
+
+	return segment, nil
+}
+
+// DecodeSegmentFile decodes a segment file held in memory
+func DecodeSegmentFile(data []byte) (*core.Segment, error) {
+	segment, err := newSegmentFile(data, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return segment.Segment()
+}
@@ -0,0 +1,206 @@
+package storage
+
+import (
+	"fmt"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/version"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// createPositionalSegment creates a segment with enough terms to span several
+// dictionary blocks, sharing prefixes, with positions on every posting
+func createPositionalSegment() *core.Segment {
+	segment := &core.Segment{
+		Docs: []core.Document{
+			{ID: 3, Path: "/notes/a.md", TokenCount: 12, ModTime: 1700000000000000000, Size: 120, ContentHash: "abc", Source: "filesystem", FieldLengths: []uint{1, 0, 2, 2, 1},
+				Title: "Notes A", Tags: []string{"go", "search"}, Date: 1690000000000000000},
+			{ID: 7, Path: "/notes/b.md", TokenCount: 5, ModTime: -1, Size: 0},
+			{ID: 200, Path: "/notes/c.md", TokenCount: 40},
+		},
+		InvertedIndex: make(map[string][]core.Posting),
+		TotalDocs:     3,
+		TotalTokens:   50,
+		AvgDocLen:     19,
+	}
+
+	for i := 0; i < 50; i++ {
+		term := fmt.Sprintf("kube%02d", i)
+		segment.InvertedIndex[term] = []core.Posting{
+			{DocID: 3, Freq: 2, Positions: []uint{uint(i), uint(i + 10)}},
+			{DocID: 200, Freq: 1, Positions: []uint{1000 + uint(i)}},
+		}
+	}
+	segment.InvertedIndex["alpha"] = []core.Posting{{DocID: 7, Freq: 1}}
+	// Document 3 only mentions zulu in a heading
+	segment.InvertedIndex["zulu"] = []core.Posting{{DocID: 200, Freq: 3, Positions: []uint{0, 5, 9}}, {DocID: 3, FieldFreqs: []uint{0, 0, 1}}}
+	return segment
+}
+
+func TestSegmentFileRoundTrip(t *testing.T) {
+	segment := createPositionalSegment()
+	data := EncodeSegmentFile(segment)
+	require.True(t, IsSegmentFile(data))
+
+	decoded, err := DecodeSegmentFile(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, segment.Docs, decoded.Docs)
+	assert.Equal(t, segment.TotalDocs, decoded.TotalDocs)
+	assert.Equal(t, segment.TotalTokens, decoded.TotalTokens)
+	assert.Equal(t, segment.AvgDocLen, decoded.AvgDocLen)
+	assert.Len(t, decoded.InvertedIndex, len(segment.InvertedIndex))
+	assert.Equal(t, segment.InvertedIndex["kube42"], decoded.InvertedIndex["kube42"])
+
+	// Postings are stored ordered by document ID
+	assert.Equal(t, []core.Posting{
+		{DocID: 3, FieldFreqs: []uint{0, 0, 1}},
+		{DocID: 200, Freq: 3, Positions: []uint{0, 5, 9}},
+	}, decoded.InvertedIndex["zulu"])
+}
+
+func TestSegmentFileReader(t *testing.T) {
+	segment := createPositionalSegment()
+	path := filepath.Join(t.TempDir(), "000.idx")
+	require.NoError(t, os.WriteFile(path, EncodeSegmentFile(segment), 0644))
+
+	reader, err := OpenSegmentFile(path, core.NewDeletionBitmap(7))
+	require.NoError(t, err)
+	defer reader.Close()
+
+	t.Run("looks up every term", func(t *testing.T) {
+		for term, postings := range segment.InvertedIndex {
+			if term == "zulu" {
+				continue
+			}
+			assert.Equal(t, postings, reader.Postings(term), term)
+		}
+		assert.Nil(t, reader.Postings("kube"))
+		assert.Nil(t, reader.Postings("aaa"))
+		assert.Nil(t, reader.Postings("zzz"))
+	})
+
+	t.Run("applies deletions", func(t *testing.T) {
+		assert.True(t, reader.IsDeleted(7))
+		assert.Len(t, reader.Documents(), 2)
+		assert.NotContains(t, reader.Terms(), "alpha")
+		assert.Contains(t, reader.Terms(), "kube00")
+		assert.Len(t, reader.Terms(), 51)
+	})
+
+	t.Run("finds documents by ID", func(t *testing.T) {
+		doc, ok := reader.Document(200)
+		require.True(t, ok)
+		assert.Equal(t, "/notes/c.md", doc.Path)
+
+		_, ok = reader.Document(4)
+		assert.False(t, ok)
+	})
+}
+
+func TestSegmentFilePostingsCache(t *testing.T) {
+	segment := createPositionalSegment()
+	path := filepath.Join(t.TempDir(), "000.idx")
+	require.NoError(t, os.WriteFile(path, EncodeSegmentFile(segment), 0644))
+
+	reader, err := OpenSegmentFile(path, nil)
+	require.NoError(t, err)
+	defer reader.Close()
+
+	// Every kube term has 2 postings with 3 positions, so 2 of them fit
+	reader.cacheLimit = 12
+	for _, term := range []string{"kube00", "kube01", "kube00", "kube02"} {
+		assert.Equal(t, segment.InvertedIndex[term], reader.Postings(term), term)
+	}
+	assert.Nil(t, reader.Postings("missing"))
+
+	assert.Contains(t, reader.postings, "kube00", "recently used terms are kept")
+	assert.Contains(t, reader.postings, "kube02")
+	assert.NotContains(t, reader.postings, "kube01", "the least recently used term is evicted")
+	assert.NotContains(t, reader.postings, "missing", "misses are not cached")
+	assert.Equal(t, 10, reader.cacheSize)
+	assert.Equal(t, segment.InvertedIndex["kube01"], reader.Postings("kube01"), "evicted terms are decoded again")
+}
+
+func TestSegmentFileInvalid(t *testing.T) {
+	data := EncodeSegmentFile(createPositionalSegment())
+
+	for name, corrupt := range map[string][]byte{
+		"empty":           {},
+		"protobuf":        {0x0a, 0x02, 0x08, 0x01},
+		"truncated":       data[:len(data)-10],
+		"missing footer":  data[:segmentHeaderSize+4],
+		"corrupt version": append([]byte(segmentMagic+"\x09\x00\x00\x00"), data[segmentHeaderSize:]...),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := DecodeSegmentFile(corrupt)
+			assert.ErrorIs(t, err, ErrInvalidSegmentFile)
+		})
+	}
+}
+
+func TestMigrateChunks(t *testing.T) {
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(constants.DirPath, "segments"), 0755))
+
+	// A chunk and VERSION file written by storage engine 0.1.0
+	segment := createPositionalSegment()
+	protobufData, err := proto.Marshal(segment.ToPB())
+	require.NoError(t, err)
+	chunkFile := filepath.Join(constants.DirPath, "segments", "000.idx")
+	require.NoError(t, os.WriteFile(chunkFile, protobufData, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(constants.DirPath, "VERSION"),
+		[]byte("STORAGE_VERSION: 0.1.0\nMNEME_CLI_VERSION: 0.6.0\n"), 0644))
+
+	manifest := core.NewManifest()
+	manifest.AddChunk(core.ChunkInfo{ID: 0, Filename: "000.idx", Status: core.ChunkStatusComplete, DocCount: 3, TokenCount: 52})
+	manifest.UpdateTotals()
+	require.NoError(t, SaveManifest(manifest))
+
+	// Old chunks are still readable before the migration
+	reader, err := OpenIndexReader()
+	require.NoError(t, err)
+	assert.Equal(t, segment.InvertedIndex["kube07"], reader.Segments[0].Postings("kube07"))
+	require.NoError(t, reader.Close())
+
+	needsMigration, err := NeedsMigration()
+	require.NoError(t, err)
+	assert.True(t, needsMigration)
+
+	migrated, err := MigrateChunks()
+	require.NoError(t, err)
+	assert.Equal(t, 1, migrated)
+
+	data, err := os.ReadFile(chunkFile)
+	require.NoError(t, err)
+	assert.True(t, IsSegmentFile(data))
+
+	content, err := ReadVersionFile()
+	require.NoError(t, err)
+	storageVersion, _, _, err := ParseVersionFile(content)
+	require.NoError(t, err)
+	assert.Equal(t, version.MnemeStorageEngineVersion, storageVersion)
+
+	needsMigration, err = NeedsMigration()
+	require.NoError(t, err)
+	assert.False(t, needsMigration)
+
+	// Migrated chunks are memory-mapped
+	reader, err = OpenIndexReader()
+	require.NoError(t, err)
+	defer reader.Close()
+	require.IsType(t, &SegmentFile{}, reader.Segments[0])
+	assert.Equal(t, segment.InvertedIndex["kube07"], reader.Segments[0].Postings("kube07"))
+
+	migrated, err = MigrateChunks()
+	require.NoError(t, err)
+	assert.Zero(t, migrated)
+}
@@ -2,6 +2,8 @@ package storage
 
 import (
 	"encoding/json"
+	"fmt"
+	"mneme/internal/constants"
 	"mneme/internal/core"
 	"os"
 	"path/filepath"
@@ -100,6 +102,29 @@ func TestSegmentProtobufMarshalUnmarshal(t *testing.T) {
 	assert.Equal(t, len(original.Docs), len(restored.Docs))
 }
 
+func TestSegmentProtobufPositions(t *testing.T) {
+	original := &core.Segment{
+		Docs: []core.Document{{ID: 1, Path: "/a.md", TokenCount: 4}},
+		InvertedIndex: map[string][]core.Posting{
+			"aws":    {{DocID: 1, Freq: 2, Positions: []uint{0, 7}}},
+			"region": {{DocID: 1, Freq: 1, Positions: []uint{1}}},
+			"legacy": {{DocID: 1, Freq: 1}},
+		},
+		TotalDocs: 1,
+	}
+
+	data, err := proto.Marshal(original.ToPB())
+	require.NoError(t, err)
+
+	restoredPB := original.ToPB()
+	require.NoError(t, proto.Unmarshal(data, restoredPB))
+	restored := core.SegmentFromPB(restoredPB)
+
+	assert.Equal(t, []uint{0, 7}, restored.InvertedIndex["aws"][0].Positions)
+	assert.Equal(t, []uint{1}, restored.InvertedIndex["region"][0].Positions)
+	assert.Nil(t, restored.InvertedIndex["legacy"][0].Positions, "postings without positions stay empty")
+}
+
 func TestSegmentBinarySizeReduction(t *testing.T) {
 	segment := createTestSegment(1000, 500)
 
@@ -213,3 +238,284 @@ func TestFileSaveLoadDirect(t *testing.T) {
 	assert.Equal(t, original.TotalDocs, loaded.TotalDocs)
 	assert.Equal(t, len(original.Docs), len(loaded.Docs))
 }
+
+func TestApplyDeletions(t *testing.T) {
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(constants.DirPath, "segments"), 0755))
+
+	chunk := &core.Segment{
+		Docs: []core.Document{
+			{ID: 0, Path: "/a.md", TokenCount: 2},
+			{ID: 1, Path: "/b.md", TokenCount: 2},
+			{ID: 2, Path: "/c.md", TokenCount: 2},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"alpha": {{DocID: 0, Freq: 1}, {DocID: 1, Freq: 1}},
+			"gamma": {{DocID: 2, Freq: 1}},
+		},
+	}
+	_, err := SaveChunk(chunk, 1)
+	require.NoError(t, err)
+
+	manifest := core.NewManifest()
+	manifest.AddChunk(core.ChunkInfo{ID: 1, Filename: "001.idx", Status: core.ChunkStatusComplete, DocCount: 3, TokenCount: 2})
+
+	require.NoError(t, ApplyDeletions(manifest, map[int][]uint{1: {1}}))
+	require.NoError(t, ApplyDeletions(manifest, map[int][]uint{1: {2, 1}}))
+	assert.Error(t, ApplyDeletions(manifest, map[int][]uint{7: {0}}))
+
+	chunkInfo := manifest.GetChunk(1)
+	assert.Equal(t, "001.2.del", chunkInfo.DeletesFile, "every update writes a new version")
+	assert.Equal(t, uint(2), chunkInfo.DeletedCount)
+
+	deleted, err := LoadDeletions(*chunkInfo)
+	require.NoError(t, err)
+	assert.False(t, deleted.Contains(0))
+	assert.True(t, deleted.Contains(1))
+	assert.True(t, deleted.Contains(2))
+
+	docs, err := LoadChunkDocuments(*chunkInfo)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "/a.md", docs[0].Path)
+
+	// The terms left without live postings are recorded by dictionary position
+	_, deadTerms, err := loadDeletionFile(*chunkInfo)
+	require.NoError(t, err)
+	require.NotNil(t, deadTerms)
+	assert.False(t, deadTerms.Contains(0), "alpha")
+	assert.True(t, deadTerms.Contains(1), "gamma")
+
+	manifest.UpdateTotals()
+	require.NoError(t, SaveManifest(manifest))
+
+	// Previous versions are kept until the saved manifest no longer references them
+	segmentsPath := filepath.Join(constants.DirPath, "segments")
+	assert.FileExists(t, filepath.Join(segmentsPath, "001.1.del"))
+	require.NoError(t, os.WriteFile(filepath.Join(segmentsPath, "001.del"), []byte("MDEL"), 0644))
+	require.NoError(t, RemoveUnreferencedDeletions(manifest))
+	assert.NoFileExists(t, filepath.Join(segmentsPath, "001.1.del"))
+	assert.NoFileExists(t, filepath.Join(segmentsPath, "001.del"))
+	assert.FileExists(t, filepath.Join(segmentsPath, "001.2.del"))
+
+	segment, err := LoadAllChunks()
+	require.NoError(t, err)
+	assert.Len(t, segment.Docs, 1)
+	assert.Equal(t, uint(1), segment.TotalDocs)
+	assert.True(t, segment.IsDeleted(1))
+	assert.Equal(t, 1, segment.LiveDocFrequency("alpha"))
+	assert.Equal(t, 0, segment.LiveDocFrequency("gamma"))
+}
+
+func TestNextDeletesFilename(t *testing.T) {
+	assert.Equal(t, "001.1.del", nextDeletesFilename(1, ""))
+	assert.Equal(t, "001.1.del", nextDeletesFilename(1, "001.del"), "unversioned bitmaps are version 0")
+	assert.Equal(t, "012.10.del", nextDeletesFilename(12, "012.9.del"))
+}
+
+func TestOpenIndexReader(t *testing.T) {
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(constants.DirPath, "segments"), 0755))
+
+	_, err := OpenIndexReader()
+	assert.Error(t, err, "an empty data directory has no index")
+
+	first := &core.Segment{
+		Docs: []core.Document{
+			{ID: 0, Path: "/a.md", TokenCount: 2},
+			{ID: 1, Path: "/b.md", TokenCount: 2},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"alpha": {{DocID: 0, Freq: 1}, {DocID: 1, Freq: 1}},
+			"beta":  {{DocID: 1, Freq: 1}},
+		},
+	}
+	second := &core.Segment{
+		Docs: []core.Document{
+			{ID: 2, Path: "/c.md", TokenCount: 2},
+		},
+		InvertedIndex: map[string][]core.Posting{
+			"alpha": {{DocID: 2, Freq: 2}},
+		},
+	}
+	firstChecksum, err := SaveChunk(first, 0)
+	require.NoError(t, err)
+	secondChecksum, err := SaveChunk(second, 1)
+	require.NoError(t, err)
+
+	manifest := core.NewManifest()
+	manifest.AddChunk(core.ChunkInfo{ID: 0, Filename: "000.idx", Status: core.ChunkStatusComplete, DocCount: 2, TokenCount: 2, Checksum: firstChecksum})
+	manifest.AddChunk(core.ChunkInfo{ID: 1, Filename: "001.idx", Status: core.ChunkStatusComplete, DocCount: 1, TokenCount: 1, Checksum: secondChecksum})
+	manifest.AddChunk(core.ChunkInfo{ID: 2, Filename: "002.idx", Status: core.ChunkStatusInProgress})
+	require.NoError(t, ApplyDeletions(manifest, map[int][]uint{0: {1}}))
+	manifest.UpdateTotals()
+	require.NoError(t, SaveManifest(manifest))
+
+	reader, err := OpenIndexReader()
+	require.NoError(t, err)
+	defer reader.Close()
+
+	require.Len(t, reader.Segments, 2, "incomplete chunks are not opened")
+	assert.Equal(t, uint(2), reader.TotalDocs)
+	assert.True(t, reader.Segments[0].IsDeleted(1))
+	assert.Len(t, reader.Segments[0].Documents(), 1)
+	assert.Equal(t, []string{"alpha"}, reader.Vocabulary(), "terms of deleted documents are not live")
+	segment, ok := reader.Segments[0].(*SegmentFile)
+	require.True(t, ok)
+	assert.NotNil(t, segment.deadTerms, "dead terms are read from the deletion file")
+
+	doc, ok := reader.Segments[1].Document(2)
+	require.True(t, ok)
+	assert.Equal(t, "/c.md", doc.Path)
+}
+
+func TestCorruptChunksAreSkipped(t *testing.T) {
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() { constants.DirPath = originalDirPath })
+	constants.DirPath = t.TempDir()
+	segmentsDir := filepath.Join(constants.DirPath, "segments")
+	require.NoError(t, os.MkdirAll(segmentsDir, 0755))
+
+	manifest := core.NewManifest()
+	for id, path := range []string{"/a.md", "/b.md", "/c.md"} {
+		chunk := &core.Segment{
+			Docs:          []core.Document{{ID: uint(id), Path: path, TokenCount: 1}},
+			InvertedIndex: map[string][]core.Posting{"alpha": {{DocID: uint(id), Freq: 1}}},
+		}
+		checksum, err := SaveChunk(chunk, id)
+		require.NoError(t, err)
+		assert.NotZero(t, checksum)
+		manifest.AddChunk(core.ChunkInfo{ID: id, Filename: fmt.Sprintf("%03d.idx", id), Status: core.ChunkStatusComplete, DocCount: 1, TokenCount: 1, Checksum: checksum})
+	}
+	manifest.UpdateTotals()
+	require.NoError(t, SaveManifest(manifest))
+
+	// Truncate the first chunk and flip a byte in the middle of the second
+	require.NoError(t, os.Truncate(filepath.Join(segmentsDir, "000.idx"), 10))
+	data, err := os.ReadFile(filepath.Join(segmentsDir, "001.idx"))
+	require.NoError(t, err)
+	data[len(data)/2] ^= 0xff
+	require.NoError(t, os.WriteFile(filepath.Join(segmentsDir, "001.idx"), data, 0644))
+
+	_, err = LoadChunk(manifest.Chunks[1])
+	assert.ErrorIs(t, err, ErrCorruptChunk)
+
+	segment, err := LoadAllChunks()
+	require.NoError(t, err)
+	require.Len(t, segment.Docs, 1)
+	assert.Equal(t, "/c.md", segment.Docs[0].Path)
+	assert.Equal(t, uint(1), segment.TotalDocs, "corpus statistics only count loaded chunks")
+
+	reader, err := OpenIndexReader()
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Len(t, reader.Segments, 1)
+	assert.Equal(t, uint(1), reader.TotalDocs)
+	assert.ErrorIs(t, VerifyChunk(manifest.Chunks[1]), ErrCorruptChunk)
+	assert.NoError(t, VerifyChunk(manifest.Chunks[2]))
+
+	// An intact file is only verified if the manifest records a checksum
+	mismatched := manifest.Chunks[2]
+	mismatched.Checksum++
+	_, err = LoadChunk(mismatched)
+	assert.ErrorIs(t, err, ErrCorruptChunk)
+	mismatched.Checksum = 0
+	_, err = LoadChunk(mismatched)
+	assert.NoError(t, err)
+
+	// Readers only validate the structure of a chunk and leave the checksum to
+	// VerifyChunk, so that queries do not read whole chunks
+	manifest.Chunks[2].Checksum++
+	require.NoError(t, SaveManifest(manifest))
+	reader, err = OpenIndexReader()
+	require.NoError(t, err)
+	require.Len(t, reader.Segments, 1)
+	reader.Close()
+	assert.ErrorIs(t, VerifyChunk(manifest.Chunks[2]), ErrCorruptChunk)
+
+	// Without a single readable chunk the error is reported
+	require.NoError(t, os.Truncate(filepath.Join(segmentsDir, "002.idx"), 0))
+	_, err = LoadAllChunks()
+	assert.ErrorIs(t, err, ErrCorruptChunk)
+	_, err = OpenIndexReader()
+	assert.ErrorIs(t, err, ErrCorruptChunk)
+}
+
+func TestCorruptPostingsAreReported(t *testing.T) {
+	originalDirPath := constants.DirPath
+	t.Cleanup(func() {
+		constants.DirPath = originalDirPath
+		clear(corruptChunks)
+	})
+	constants.DirPath = t.TempDir()
+	segmentsDir := filepath.Join(constants.DirPath, "segments")
+	require.NoError(t, os.MkdirAll(segmentsDir, 0755))
+
+	manifest := core.NewManifest()
+	for id, path := range []string{"/a.md", "/b.md"} {
+		chunk := &core.Segment{
+			Docs:          []core.Document{{ID: uint(id), Path: path, TokenCount: 1}},
+			InvertedIndex: map[string][]core.Posting{"alpha": {{DocID: uint(id), Freq: 1}}},
+		}
+		checksum, err := SaveChunk(chunk, id)
+		require.NoError(t, err)
+		manifest.AddChunk(core.ChunkInfo{ID: id, Filename: fmt.Sprintf("%03d.idx", id), Status: core.ChunkStatusComplete, DocCount: 1, TokenCount: 1, Checksum: checksum})
+	}
+	manifest.UpdateTotals()
+	require.NoError(t, SaveManifest(manifest))
+
+	// Overwrite the postings of the first chunk, leaving its structure intact
+	chunkPath := filepath.Join(segmentsDir, "000.idx")
+	segment, err := OpenSegmentFile(chunkPath, nil)
+	require.NoError(t, err)
+	footer := segment.footer
+	require.NoError(t, segment.Close())
+	data, err := os.ReadFile(chunkPath)
+	require.NoError(t, err)
+	for i := footer.postingsOffset; i < footer.dictOffset; i++ {
+		data[i] = 0xff
+	}
+	require.NoError(t, os.WriteFile(chunkPath, data, 0644))
+
+	reader, err := OpenIndexReader()
+	require.NoError(t, err)
+	require.Len(t, reader.Segments, 2, "the structure of the chunk is valid")
+	assert.Nil(t, reader.Segments[0].Postings("alpha"))
+	assert.Len(t, reader.Segments[1].Postings("alpha"), 1)
+	reader.Close()
+
+	// Readers opened later leave the chunk out and report it
+	assert.ErrorIs(t, corruptChunkError(manifest.Chunks[0]), ErrCorruptChunk)
+	reader, err = OpenIndexReader()
+	require.NoError(t, err)
+	defer reader.Close()
+	require.Len(t, reader.Segments, 1)
+	assert.Equal(t, uint(1), reader.TotalDocs)
+	assert.Equal(t, "/b.md", reader.Segments[0].Documents()[0].Path)
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+
+	require.NoError(t, writeFileAtomic(path, []byte("first"), 0644))
+	require.NoError(t, writeFileAtomic(path, []byte("second"), 0644))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left behind")
+
+	// A failed write leaves the previous contents intact
+	assert.Error(t, writeFileAtomic(filepath.Join(dir, "missing", "manifest.json"), []byte("third"), 0644))
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "second", string(data))
+}
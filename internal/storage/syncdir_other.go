@@ -0,0 +1,8 @@
+//go:build !unix
+
+package storage
+
+// syncDir is a no-op on platforms where directories cannot be opened for flushing
+func syncDir(path string) error {
+	return nil
+}
@@ -7,22 +7,26 @@ type Scored interface {
 	GetScore() float64
 }
 
-// minHeap implements heap.Interface for any Scored type
-type minHeap[T Scored] []T
+// minHeap implements heap.Interface for any type, keeping the item that ranks
+// lowest according to better at the root
+type minHeap[T any] struct {
+	items  []T
+	better func(a, b T) bool
+}
 
-func (h minHeap[T]) Len() int           { return len(h) }
-func (h minHeap[T]) Less(i, j int) bool { return h[i].GetScore() < h[j].GetScore() }
-func (h minHeap[T]) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h minHeap[T]) Len() int           { return len(h.items) }
+func (h minHeap[T]) Less(i, j int) bool { return h.better(h.items[j], h.items[i]) }
+func (h minHeap[T]) Swap(i, j int)      { h.items[i], h.items[j] = h.items[j], h.items[i] }
 
 func (h *minHeap[T]) Push(x any) {
-	*h = append(*h, x.(T))
+	h.items = append(h.items, x.(T))
 }
 
 func (h *minHeap[T]) Pop() any {
-	old := *h
+	old := h.items
 	n := len(old)
 	x := old[n-1]
-	*h = old[0 : n-1]
+	h.items = old[0 : n-1]
 	return x
 }
 
@@ -30,17 +34,26 @@ func (h *minHeap[T]) Pop() any {
 // Results are returned in descending order (highest score first).
 // Time complexity: O(n log k), Space complexity: O(k)
 func TopK[T Scored](items []T, k int) []T {
+	return TopKFunc(items, k, func(a, b T) bool {
+		return a.GetScore() > b.GetScore()
+	})
+}
+
+// TopKFunc returns the top K items of the input slice, where better reports
+// whether a ranks before b. Results are returned best first.
+// Time complexity: O(n log k), Space complexity: O(k)
+func TopKFunc[T any](items []T, k int, better func(a, b T) bool) []T {
 	if k <= 0 || len(items) == 0 {
 		return []T{}
 	}
 
-	h := &minHeap[T]{}
+	h := &minHeap[T]{better: better}
 	heap.Init(h)
 
 	for _, item := range items {
 		if h.Len() < k {
 			heap.Push(h, item)
-		} else if item.GetScore() > (*h)[0].GetScore() {
+		} else if better(item, h.items[0]) {
 			heap.Pop(h)
 			heap.Push(h, item)
 		}
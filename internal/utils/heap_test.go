@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+type scoredItem struct {
+	name  string
+	score float64
+}
+
+func (s scoredItem) GetScore() float64 { return s.score }
+
+func TestTopK(t *testing.T) {
+	items := []scoredItem{{"a", 0.2}, {"b", 0.9}, {"c", 0.5}, {"d", 0.7}}
+
+	got := TopK(items, 2)
+	want := []scoredItem{{"b", 0.9}, {"d", 0.7}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopK() = %v, want %v", got, want)
+	}
+
+	if got := TopK(items, 0); len(got) != 0 {
+		t.Errorf("TopK() with k=0 = %v, want empty", got)
+	}
+	if got := TopK(items, 10); len(got) != len(items) {
+		t.Errorf("TopK() with k > len = %d items, want %d", len(got), len(items))
+	}
+}
+
+func TestTopKFunc(t *testing.T) {
+	// Ties on score are broken by name ascending
+	items := []scoredItem{{"z", 1}, {"b", 2}, {"a", 1}, {"c", 1}}
+	better := func(x, y scoredItem) bool {
+		if x.score != y.score {
+			return x.score > y.score
+		}
+		return x.name < y.name
+	}
+
+	got := TopKFunc(items, 3, better)
+	want := []scoredItem{{"b", 2}, {"a", 1}, {"c", 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopKFunc() = %v, want %v", got, want)
+	}
+}
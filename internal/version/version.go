@@ -2,5 +2,5 @@ package version
 
 const (
 	MnemeVersion              = "0.6.0"
-	MnemeStorageEngineVersion = "0.1.0"
+	MnemeStorageEngineVersion = "0.2.0"
 )
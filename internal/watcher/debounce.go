@@ -0,0 +1,60 @@
+package watcher
+
+import "time"
+
+// maxDebounceDelays bounds how long a batch is held back: it is emitted at the
+// latest this many delays after its first path arrived, even if paths keep coming
+const maxDebounceDelays = 10
+
+// Debounce groups the paths received on in into batches. A batch is emitted once
+// no new path has arrived for delay, so a burst of writes to the same files results
+// in a single update. Files written continuously, such as logs, cannot hold a batch
+// back for longer than maxDebounceDelays times delay. Every path appears once per
+// batch, in order of first arrival. The returned channel is closed after in is
+// closed and any pending batch is flushed.
+func Debounce(in <-chan string, delay time.Duration) <-chan []string {
+	out := make(chan []string)
+
+	go func() {
+		defer close(out)
+
+		var pending []string
+		var deadline time.Time // Latest time to emit the pending batch
+		seen := make(map[string]bool)
+
+		timer := time.NewTimer(delay)
+		timer.Stop()
+
+		flush := func() {
+			if len(pending) == 0 {
+				return
+			}
+			out <- pending
+			pending = nil
+			seen = make(map[string]bool)
+		}
+
+		for {
+			select {
+			case path, ok := <-in:
+				if !ok {
+					timer.Stop()
+					flush()
+					return
+				}
+				if len(pending) == 0 {
+					deadline = time.Now().Add(maxDebounceDelays * delay)
+				}
+				if !seen[path] {
+					seen[path] = true
+					pending = append(pending, path)
+				}
+				timer.Reset(min(delay, time.Until(deadline)))
+			case <-timer.C:
+				flush()
+			}
+		}
+	}()
+
+	return out
+}
@@ -0,0 +1,80 @@
+package watcher
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDebounce(t *testing.T) {
+	t.Run("burst is emitted as one deduplicated batch", func(t *testing.T) {
+		in := make(chan string)
+		out := Debounce(in, 50*time.Millisecond)
+
+		for _, path := range []string{"/a", "/b", "/a", "/c", "/b"} {
+			in <- path
+		}
+
+		select {
+		case batch := <-out:
+			expected := []string{"/a", "/b", "/c"}
+			if len(batch) != len(expected) {
+				t.Fatalf("Expected batch %v, got %v", expected, batch)
+			}
+			for i := range expected {
+				if batch[i] != expected[i] {
+					t.Errorf("Expected %q at %d, got %q", expected[i], i, batch[i])
+				}
+			}
+		case <-time.After(time.Second):
+			t.Fatal("Timed out waiting for batch")
+		}
+		close(in)
+	})
+
+	t.Run("pending batch is flushed when input closes", func(t *testing.T) {
+		in := make(chan string)
+		out := Debounce(in, time.Hour)
+
+		in <- "/a"
+		close(in)
+
+		batch, ok := <-out
+		if !ok || len(batch) != 1 || batch[0] != "/a" {
+			t.Errorf("Expected flushed batch [/a], got %v (ok=%v)", batch, ok)
+		}
+		if _, ok := <-out; ok {
+			t.Error("Expected output channel to be closed")
+		}
+	})
+	t.Run("continuous writes are flushed after the maximum wait", func(t *testing.T) {
+		in := make(chan string)
+		delay := 20 * time.Millisecond
+		out := Debounce(in, delay)
+		defer close(in)
+
+		// A file written more often than delay never leaves a quiet period
+		ticker := time.NewTicker(delay / 4)
+		defer ticker.Stop()
+		timeout := time.After(maxDebounceDelays * delay * 5)
+		for {
+			select {
+			case <-ticker.C:
+				select {
+				case in <- "/app.log":
+				case batch := <-out:
+					if len(batch) != 1 || batch[0] != "/app.log" {
+						t.Errorf("Expected batch [/app.log], got %v", batch)
+					}
+					return
+				}
+			case batch := <-out:
+				if len(batch) != 1 || batch[0] != "/app.log" {
+					t.Errorf("Expected batch [/app.log], got %v", batch)
+				}
+				return
+			case <-timeout:
+				t.Fatal("Timed out waiting for batch while paths keep arriving")
+			}
+		}
+	})
+}
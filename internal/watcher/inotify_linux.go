@@ -0,0 +1,233 @@
+//go:build linux
+
+package watcher
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mneme/internal/core"
+	"mneme/internal/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// inotifyMask selects the events that can change the indexed contents of a directory
+const inotifyMask = syscall.IN_CREATE | syscall.IN_CLOSE_WRITE | syscall.IN_MODIFY |
+	syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO |
+	syscall.IN_DELETE_SELF | syscall.IN_MOVE_SELF
+
+// inotifyWatcher watches every crawlable directory below the roots with inotify.
+// Directories created or moved in later are added on the fly.
+type inotifyWatcher struct {
+	fd      int
+	file    *os.File
+	roots   []string
+	options core.CrawlerOptions
+
+	mu      sync.Mutex
+	watches map[int32]string // watch descriptor -> directory path
+
+	events    chan string
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newNativeWatcher creates an inotify based watcher for the roots
+func newNativeWatcher(roots []string, options core.CrawlerOptions) (Watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+
+	w := &inotifyWatcher{
+		fd:      fd,
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		roots:   roots,
+		options: options,
+		watches: make(map[int32]string),
+		events:  make(chan string),
+		errors:  make(chan error),
+		done:    make(chan struct{}),
+	}
+
+	for _, root := range roots {
+		info, err := os.Stat(root)
+		if err != nil {
+			logger.Warnf("Skipping watch root %s: %+v", root, err)
+			continue
+		}
+
+		// A single file root is watched through its parent directory
+		dir := root
+		if !info.IsDir() {
+			dir = filepath.Dir(root)
+		}
+
+		if err := w.addTree(dir, false); err != nil {
+			w.file.Close()
+			return nil, err
+		}
+	}
+
+	go w.readLoop()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan string { return w.events }
+
+func (w *inotifyWatcher) Errors() <-chan error { return w.errors }
+
+func (w *inotifyWatcher) Close() error {
+	var err error
+	w.closeOnce.Do(func() {
+		close(w.done)
+		// Closing the file unblocks the pending read in readLoop
+		err = w.file.Close()
+	})
+	return err
+}
+
+// addTree adds watches for dir and every crawlable directory below it.
+// If emit is true, crawlable files found on the way are reported as events;
+// this covers directories that were created or moved in with contents.
+func (w *inotifyWatcher) addTree(dir string, emit bool) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// The tree may change while it is walked; skip what vanished
+			logger.Debugf("Error walking %s: %+v", path, err)
+			if entry != nil && entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !entry.IsDir() {
+			if emit && crawlable(w.roots, path, false, w.options) {
+				w.send(path)
+			}
+			return nil
+		}
+
+		if path != dir && !crawlable(w.roots, path, true, w.options) {
+			return filepath.SkipDir
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, path, inotifyMask)
+		if err != nil {
+			if errors.Is(err, syscall.ENOSPC) {
+				return fmt.Errorf("inotify watch limit reached while watching %s (raise fs.inotify.max_user_watches): %w", path, err)
+			}
+			logger.Warnf("Failed to watch %s: %+v", path, err)
+			return nil
+		}
+
+		w.mu.Lock()
+		w.watches[int32(wd)] = path
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+// readLoop decodes inotify events until the watcher is closed
+func (w *inotifyWatcher) readLoop() {
+	defer close(w.events)
+	defer close(w.errors)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			if !errors.Is(err, os.ErrClosed) {
+				w.sendError(fmt.Errorf("failed to read inotify events: %w", err))
+			}
+			return
+		}
+
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			wd := int32(binary.NativeEndian.Uint32(buf[offset:]))
+			mask := binary.NativeEndian.Uint32(buf[offset+4:])
+			nameLen := int(binary.NativeEndian.Uint32(buf[offset+12:]))
+
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+nameLen]), "\x00")
+			offset = nameStart + nameLen
+
+			if !w.handle(wd, mask, name) {
+				return
+			}
+		}
+	}
+}
+
+// handle processes a single event; it returns false once the watcher is closed
+func (w *inotifyWatcher) handle(wd int32, mask uint32, name string) bool {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		return w.sendError(ErrOverflow)
+	}
+
+	w.mu.Lock()
+	dir, ok := w.watches[wd]
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(w.watches, wd)
+	}
+	w.mu.Unlock()
+
+	if !ok || mask&syscall.IN_IGNORED != 0 {
+		return true
+	}
+
+	// Events on the watched directory itself
+	if name == "" {
+		if mask&(syscall.IN_DELETE_SELF|syscall.IN_MOVE_SELF) != 0 {
+			return w.send(dir)
+		}
+		return true
+	}
+
+	path := filepath.Join(dir, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+	if !crawlable(w.roots, path, isDir, w.options) {
+		return true
+	}
+
+	if isDir {
+		if mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+			if err := w.addTree(path, true); err != nil {
+				return w.sendError(err)
+			}
+			return true
+		}
+		if mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0 {
+			return w.send(path)
+		}
+		return true
+	}
+
+	return w.send(path)
+}
+
+// send delivers a path unless the watcher is closed
+func (w *inotifyWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// sendError delivers an error unless the watcher is closed
+func (w *inotifyWatcher) sendError(err error) bool {
+	select {
+	case w.errors <- err:
+		return true
+	case <-w.done:
+		return false
+	}
+}
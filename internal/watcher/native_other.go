@@ -0,0 +1,10 @@
+//go:build !linux
+
+package watcher
+
+import "mneme/internal/core"
+
+// newNativeWatcher is not available on this platform; New falls back to polling.
+func newNativeWatcher(roots []string, options core.CrawlerOptions) (Watcher, error) {
+	return nil, errNativeUnsupported
+}
@@ -0,0 +1,125 @@
+package watcher
+
+import (
+	"mneme/internal/core"
+	"mneme/internal/storage"
+	"os"
+	"sync"
+	"time"
+)
+
+// fileState is the metadata compared between two scans of the polling watcher
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// pollingWatcher detects changes by periodically crawling the roots and comparing
+// modification times and sizes. It is used where inotify is not available.
+type pollingWatcher struct {
+	roots    []string
+	options  core.CrawlerOptions
+	interval time.Duration
+	snapshot map[string]fileState
+
+	events    chan string
+	errors    chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPollingWatcher creates a watcher that rescans the roots every interval.
+func NewPollingWatcher(roots []string, options core.CrawlerOptions, interval time.Duration) (Watcher, error) {
+	w := &pollingWatcher{
+		roots:    roots,
+		options:  options,
+		interval: interval,
+		events:   make(chan string),
+		errors:   make(chan error),
+		done:     make(chan struct{}),
+	}
+	w.snapshot = w.scan()
+
+	go w.loop()
+	return w, nil
+}
+
+func (w *pollingWatcher) Events() <-chan string { return w.events }
+
+func (w *pollingWatcher) Errors() <-chan error { return w.errors }
+
+func (w *pollingWatcher) Close() error {
+	w.closeOnce.Do(func() {
+		close(w.done)
+	})
+	return nil
+}
+
+// loop rescans the roots on every tick and reports the differences
+func (w *pollingWatcher) loop() {
+	defer close(w.events)
+	defer close(w.errors)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+
+		current := w.scan()
+		for path, state := range current {
+			if prev, ok := w.snapshot[path]; ok && prev == state {
+				continue
+			}
+			if !w.send(path) {
+				return
+			}
+		}
+		for path := range w.snapshot {
+			if _, ok := current[path]; !ok {
+				if !w.send(path) {
+					return
+				}
+			}
+		}
+		w.snapshot = current
+	}
+}
+
+// send delivers a path unless the watcher is closed
+func (w *pollingWatcher) send(path string) bool {
+	select {
+	case w.events <- path:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// scan crawls all roots and records the state of every crawlable file
+func (w *pollingWatcher) scan() map[string]fileState {
+	states := make(map[string]fileState)
+	for _, root := range w.roots {
+		paths, err := storage.Crawler(root, w.options)
+		if err != nil {
+			continue
+		}
+		for _, path := range paths {
+			// Members of archives change with their archive file
+			path = storage.ArchivePath(path)
+			if _, ok := states[path]; ok {
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				continue
+			}
+			states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
+		}
+	}
+	return states
+}
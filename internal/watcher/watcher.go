@@ -0,0 +1,80 @@
+// Package watcher reports filesystem changes below the configured source paths
+// so that the index can be updated incrementally instead of being rebuilt.
+package watcher
+
+import (
+	"errors"
+	"mneme/internal/constants"
+	"mneme/internal/core"
+	"mneme/internal/logger"
+	"mneme/internal/storage"
+	"mneme/internal/utils"
+	"path/filepath"
+	"strings"
+)
+
+// ErrOverflow is reported on the Errors channel when the kernel dropped events.
+// Consumers should fall back to a full incremental crawl of the sources.
+var ErrOverflow = errors.New("watch event queue overflowed, some changes were lost")
+
+// errNativeUnsupported is returned by newNativeWatcher on platforms without inotify
+var errNativeUnsupported = errors.New("native file watching is not supported on this platform")
+
+// Watcher reports changed paths below a set of root paths.
+type Watcher interface {
+	// Events returns the paths of created, modified and removed files.
+	// A removed directory is reported as a single path.
+	Events() <-chan string
+
+	// Errors returns non-fatal watch errors such as ErrOverflow.
+	Errors() <-chan error
+
+	// Close stops watching and closes both channels.
+	Close() error
+}
+
+// New creates a watcher for the given roots that only reports paths the crawler
+// would index according to options. It uses inotify where available and falls
+// back to periodically rescanning the roots otherwise.
+func New(roots []string, options core.CrawlerOptions) (Watcher, error) {
+	expandedRoots := make([]string, 0, len(roots))
+	for _, root := range roots {
+		expanded, err := utils.ExpandFilePath(root)
+		if err != nil {
+			logger.Errorf("Error expanding path %s: %+v", root, err)
+			continue
+		}
+		expandedRoots = append(expandedRoots, filepath.Clean(expanded))
+	}
+
+	w, err := newNativeWatcher(expandedRoots, options)
+	if err == nil {
+		return w, nil
+	}
+
+	if errors.Is(err, errNativeUnsupported) {
+		logger.Debug("Native file watching unsupported, using polling watcher")
+	} else {
+		logger.Warnf("Native file watching unavailable, falling back to polling: %+v", err)
+	}
+	return NewPollingWatcher(expandedRoots, options, constants.WatcherPollInterval)
+}
+
+// crawlable reports whether path is below one of the roots and passes the crawler rules.
+// When roots are nested, the rules are evaluated relative to the innermost root.
+func crawlable(roots []string, path string, isDir bool, options core.CrawlerOptions) bool {
+	bestRoot := ""
+	for _, root := range roots {
+		if path != root && !strings.HasPrefix(path, root+string(filepath.Separator)) {
+			continue
+		}
+		if len(root) > len(bestRoot) {
+			bestRoot = root
+		}
+	}
+
+	if bestRoot == "" {
+		return false
+	}
+	return storage.ShouldCrawl(bestRoot, path, isDir, options)
+}
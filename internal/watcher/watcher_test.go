@@ -0,0 +1,121 @@
+package watcher
+
+import (
+	"mneme/internal/core"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitForPath reads events until path is reported or the timeout expires
+func waitForPath(t *testing.T, w Watcher, path string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case got, ok := <-w.Events():
+			if !ok {
+				t.Fatalf("Event channel closed before %s was reported", path)
+			}
+			if got == path {
+				return
+			}
+		case err := <-w.Errors():
+			t.Fatalf("Unexpected watch error: %v", err)
+		case <-timeout:
+			t.Fatalf("Timed out waiting for event on %s", path)
+		}
+	}
+}
+
+// assertNoEventExcept fails if an event for any path other than allowed arrives
+// within the wait period. Native watchers report several events per write.
+func assertNoEventExcept(t *testing.T, w Watcher, allowed string, wait time.Duration) {
+	t.Helper()
+	timeout := time.After(wait)
+	for {
+		select {
+		case got := <-w.Events():
+			if got != allowed {
+				t.Errorf("Unexpected event for %s", got)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
+
+func testWatcher(t *testing.T, newWatcher func(root string, options core.CrawlerOptions) (Watcher, error)) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "node_modules"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	options := core.DefaultCrawlerOptions()
+	options.SkipFolders = []string{"node_modules"}
+	options.ExcludeExtensions = []string{"log"}
+
+	w, err := newWatcher(root, options)
+	if err != nil {
+		t.Fatalf("Failed to create watcher: %v", err)
+	}
+	defer w.Close()
+
+	notes := filepath.Join(root, "notes.md")
+	if err := os.WriteFile(notes, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForPath(t, w, notes)
+
+	// Ignored folders and excluded extensions are not reported
+	if err := os.WriteFile(filepath.Join(root, "node_modules", "pkg.md"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "debug.log"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	assertNoEventExcept(t, w, notes, 300*time.Millisecond)
+
+	if err := os.Remove(notes); err != nil {
+		t.Fatal(err)
+	}
+	waitForPath(t, w, notes)
+}
+
+func TestPollingWatcher(t *testing.T) {
+	testWatcher(t, func(root string, options core.CrawlerOptions) (Watcher, error) {
+		return NewPollingWatcher([]string{root}, options, 50*time.Millisecond)
+	})
+}
+
+func TestNew(t *testing.T) {
+	testWatcher(t, func(root string, options core.CrawlerOptions) (Watcher, error) {
+		return New([]string{root}, options)
+	})
+}
+
+func TestCrawlable(t *testing.T) {
+	options := core.DefaultCrawlerOptions()
+	options.SkipFolders = []string{"vendor"}
+	roots := []string{"/data/notes", "/data/notes/projects"}
+
+	tests := []struct {
+		path     string
+		isDir    bool
+		expected bool
+	}{
+		{"/data/notes/todo.md", false, true},
+		{"/data/notes/vendor", true, false},
+		{"/data/notes/vendor/lib.md", false, false},
+		{"/data/notes/.git/config", false, false},
+		{"/data/other/todo.md", false, false},
+		{"/data/notes/projects/plan.md", false, true},
+	}
+
+	for _, tt := range tests {
+		if got := crawlable(roots, tt.path, tt.isDir, options); got != tt.expected {
+			t.Errorf("crawlable(%q) = %v, expected %v", tt.path, got, tt.expected)
+		}
+	}
+}